@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,11 +15,19 @@ import (
 	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
+	"github.com/szczursonn/uek-planzajec-v3/internal/admincli"
+	"github.com/szczursonn/uek-planzajec-v3/internal/archive"
 	"github.com/szczursonn/uek-planzajec-v3/internal/badgercache"
 	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+	"github.com/szczursonn/uek-planzajec-v3/internal/digest"
+	"github.com/szczursonn/uek-planzajec-v3/internal/memcache"
+	"github.com/szczursonn/uek-planzajec-v3/internal/occupancy"
 	"github.com/szczursonn/uek-planzajec-v3/internal/server"
+	"github.com/szczursonn/uek-planzajec-v3/internal/tieredcache"
 	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
 	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+	"github.com/szczursonn/uek-planzajec-v3/internal/webhook"
+	"github.com/szczursonn/uek-planzajec-v3/internal/webpush"
 )
 
 func main() {
@@ -39,6 +50,8 @@ func run() int {
 
 	mockDownloadUrl := ""
 	flag.StringVar(&mockDownloadUrl, "mockdl", "", "url to download mock data from")
+	adminUrl := ""
+	flag.StringVar(&adminUrl, "adminurl", "", "url of the server used by admin commands, defaults to local server")
 	flag.Parse()
 	mockDownloadUrl = strings.TrimSpace(mockDownloadUrl)
 
@@ -52,6 +65,29 @@ func run() int {
 		logger.Info("Shutting down...")
 	}()
 
+	if flag.Arg(0) == "cache" {
+		if adminUrl == "" {
+			_, port, _ := net.SplitHostPort(cfg.Addr)
+			adminUrl = "http://localhost:" + port
+		}
+
+		if err := admincli.Run(ctx, admincli.Config{
+			BaseUrl: adminUrl,
+			Token:   cfg.AdminToken,
+			Input:   os.Stdin,
+			Output:  os.Stdout,
+		}, flag.Args()[1:]); err != nil {
+			if errors.Is(err, admincli.ErrUsage) {
+				fmt.Fprintln(os.Stderr, err)
+			} else {
+				logger.Error("Admin command failed", slog.Any("err", err))
+			}
+			return 1
+		}
+
+		return 0
+	}
+
 	if mockDownloadUrl != "" {
 		logger.Info("Downloading mock response...", slog.String("downloadUrl", mockDownloadUrl))
 
@@ -65,23 +101,87 @@ func run() int {
 	}
 
 	uekClientConfig := uek.ClientConfig{
-		CacheTimes: cfg.CacheTimes,
+		BaseUrl:       cfg.UekBaseUrl,
+		CacheTimes:    cfg.CacheTimes,
+		StrictParsing: cfg.StrictParsing,
+		Logger:        logger.With("source", "uekClient"),
 	}
 
+	if cfg.CacheTimes.SchedulesPolicyFile != "" || cfg.CacheTimes.SchedulesPolicy != "" {
+		var err error
+		if cfg.CacheTimes.SchedulesPolicyFile != "" {
+			uekClientConfig.TTLPolicy, err = uek.LoadTTLPolicyFile(cfg.CacheTimes.SchedulesPolicyFile)
+		} else {
+			uekClientConfig.TTLPolicy, err = uek.ParseTTLPolicy([]byte(cfg.CacheTimes.SchedulesPolicy))
+		}
+		if err != nil {
+			logger.Error("Invalid schedules cache time policy", slog.Any("err", err))
+			return 1
+		}
+	}
+
+	if cfg.Archive.Enabled {
+		scheduleArchive, err := archive.New(cfg.Archive.Path, logger.With("source", "archive"))
+		if err != nil {
+			logger.Error("Failed to initialize archive", slog.Any("err", err))
+			return 1
+		}
+		uekClientConfig.Archive = scheduleArchive
+	}
+
+	var badgerCache *badgercache.Cache
 	if cfg.BadgerCache.Enabled {
-		badgerLogger := logger.With("source", "badgerCache")
+		codec, err := badgercache.ParseCodec(cfg.BadgerCache.Codec)
+		if err != nil {
+			logger.Error("Invalid badger cache codec", slog.Any("err", err))
+			return 1
+		}
 
-		var badgerCache *badgercache.Cache
-		var err error
-		if cfg.BadgerCache.Path != "" {
-			badgerCache, err = badgercache.New(cfg.BadgerCache.Path, badgerLogger)
+		compression, err := badgercache.ParseCompression(cfg.BadgerCache.Compression)
+		if err != nil {
+			logger.Error("Invalid badger cache compression", slog.Any("err", err))
+			return 1
+		}
+
+		preset, err := badgercache.ParsePreset(cfg.BadgerCache.Preset)
+		if err != nil {
+			logger.Error("Invalid badger cache preset", slog.Any("err", err))
+			return 1
+		}
+
+		badgerCacheConfig := badgercache.Config{
+			Path:        cfg.BadgerCache.Path,
+			Codec:       codec,
+			Compression: compression,
+			Preset:      preset,
+			Tuning: badgercache.Tuning{
+				ValueLogFileSize:        cfg.BadgerCache.ValueLogFileSize,
+				MemTableSize:            cfg.BadgerCache.MemTableSize,
+				ValueThreshold:          cfg.BadgerCache.ValueThreshold,
+				NumMemtables:            cfg.BadgerCache.NumMemtables,
+				NumLevelZeroTables:      cfg.BadgerCache.NumLevelZeroTables,
+				NumLevelZeroTablesStall: cfg.BadgerCache.NumLevelZeroTablesStall,
+				BlockCacheSize:          cfg.BadgerCache.BlockCacheSize,
+				IndexCacheSize:          cfg.BadgerCache.IndexCacheSize,
+				NumCompactors:           cfg.BadgerCache.NumCompactors,
+			},
+			MaxDiskUsage:   cfg.BadgerCache.MaxDiskUsage,
+			BackupPath:     cfg.BadgerCache.BackupPath,
+			BackupInterval: cfg.BadgerCache.BackupInterval,
+			Logger:         logger.With("source", "badgerCache"),
+		}
+
+		if badgerCacheConfig.Path != "" {
+			badgerCache, err = badgercache.New(badgerCacheConfig)
 			if err != nil {
 				logger.Error("Failed to initialize badger file-based cache, falling back to in-memory cache", slog.Any("err", err))
 			}
 		}
 
-		if badgerCache == nil {
-			badgerCache, err = badgercache.New("", badgerLogger)
+		// native in-memory cache is cheaper than in-memory badger
+		if badgerCache == nil && !cfg.MemoryCache.Enabled {
+			badgerCacheConfig.Path = ""
+			badgerCache, err = badgercache.New(badgerCacheConfig)
 			if err != nil {
 				logger.Error("Failed to initialize badger in-memory cache", slog.Any("err", err))
 			}
@@ -93,20 +193,140 @@ func run() int {
 		}
 	}
 
+	if cfg.MemoryCache.Enabled {
+		memoryCache := memcache.New(cfg.MemoryCache.MaxSize, logger.With("source", "memoryCache"))
+		defer memoryCache.Close()
+
+		if badgerCache != nil {
+			tieredCache := tieredcache.New(memoryCache, badgerCache, logger.With("source", "tieredCache"))
+			go tieredCache.WarmUp(ctx)
+			uekClientConfig.Cache = tieredCache
+		} else {
+			uekClientConfig.Cache = memoryCache
+		}
+	}
+
 	if cfg.Mock.Enabled {
+		mockRoundTripper, err := uekmock.NewRoundTripper(cfg.Mock)
+		if err != nil {
+			logger.Error("Failed to initialize mock", slog.Any("err", err))
+			return 1
+		}
+
 		uekClientConfig.HttpClient = &http.Client{
-			Transport: &uekmock.RoundTripper{
-				Mock: cfg.Mock,
+			Transport: mockRoundTripper,
+		}
+	}
+
+	uekClient := uek.NewClient(uekClientConfig)
+
+	var webhooks *webhook.Manager
+	if cfg.Webhooks.Enabled {
+		var err error
+		webhooks, err = webhook.New(webhook.Config{
+			Path:         cfg.Webhooks.Path,
+			Uek:          uekClient,
+			PollInterval: cfg.Webhooks.PollInterval,
+			MaxAttempts:  cfg.Webhooks.MaxAttempts,
+			RetryBackoff: cfg.Webhooks.RetryBackoff,
+			DisableAfter: cfg.Webhooks.DisableAfter,
+			Logger:       logger.With("source", "webhooks"),
+		})
+		if err != nil {
+			logger.Error("Failed to initialize webhooks", slog.Any("err", err))
+			return 1
+		}
+		defer webhooks.Close()
+	}
+
+	var digests *digest.Manager
+	if cfg.Digest.Enabled {
+		sendAt, err := digest.ParseSendTime(cfg.Digest.SendAt)
+		if err != nil {
+			logger.Error("Invalid digest send time", slog.Any("err", err))
+			return 1
+		}
+
+		smtpSecurity, err := digest.ParseSMTPSecurity(cfg.SMTP.Security)
+		if err != nil {
+			logger.Error("Invalid smtp security", slog.Any("err", err))
+			return 1
+		}
+
+		digests, err = digest.New(digest.Config{
+			Path: cfg.Digest.Path,
+			Uek:  uekClient,
+			SMTP: digest.SMTPConfig{
+				Host:     cfg.SMTP.Host,
+				Port:     cfg.SMTP.Port,
+				Username: cfg.SMTP.Username,
+				Password: cfg.SMTP.Password,
+				Security: smtpSecurity,
+				From:     cfg.SMTP.From,
 			},
+			BaseUrl: cfg.Digest.BaseUrl,
+			SendAt:  sendAt,
+			Logger:  logger.With("source", "digest"),
+		})
+		if err != nil {
+			logger.Error("Failed to initialize email digest", slog.Any("err", err))
+			return 1
+		}
+		defer digests.Close()
+	}
+
+	var webPush *webpush.Manager
+	if cfg.WebPush.Enabled {
+		var err error
+		webPush, err = webpush.New(webpush.Config{
+			Path:            cfg.WebPush.Path,
+			Uek:             uekClient,
+			Subject:         cfg.WebPush.Subject,
+			VAPIDPrivateKey: cfg.WebPush.VAPIDPrivateKey,
+			ReminderLead:    cfg.WebPush.ReminderLead,
+			Logger:          logger.With("source", "webPush"),
+		})
+		if err != nil {
+			logger.Error("Failed to initialize web push", slog.Any("err", err))
+			return 1
+		}
+		defer webPush.Close()
+	}
+
+	var occupancyReports *occupancy.Manager
+	if cfg.Occupancy.Enabled {
+		var err error
+		occupancyReports, err = occupancy.New(occupancy.Config{
+			Path:     cfg.Occupancy.Path,
+			Uek:      uekClient,
+			Interval: cfg.Occupancy.Interval,
+			Logger:   logger.With("source", "occupancy"),
+		})
+		if err != nil {
+			logger.Error("Failed to initialize occupancy reports", slog.Any("err", err))
+			return 1
 		}
+		defer occupancyReports.Close()
 	}
 
-	srv := server.New(cfg.Addr, uek.NewClient(uekClientConfig), logger)
+	cacheAdmin, _ := uekClientConfig.Cache.(uek.CacheAdmin)
+	srv := server.New(server.Config{
+		Addr:       cfg.Addr,
+		Uek:        uekClient,
+		CacheAdmin: cacheAdmin,
+		Webhooks:   webhooks,
+		Digests:    digests,
+		WebPush:    webPush,
+		Occupancy:  occupancyReports,
+		AdminToken: cfg.AdminToken,
+		Logger:     logger,
+	})
 	go func() {
 		logger.Info("Server started",
 			slog.Bool("debug", cfg.Debug),
 			slog.String("addr", cfg.Addr),
 			slog.Bool("mock", cfg.Mock.Enabled),
+			slog.Bool("memoryCache", cfg.MemoryCache.Enabled),
 			slog.String("badgerCachePath", cfg.BadgerCache.Path),
 		)
 		if err := srv.Run(); err != nil {
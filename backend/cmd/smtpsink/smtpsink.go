@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxMessageSize = 10 << 20
+
+func main() {
+	os.Exit(run())
+}
+
+// run starts an smtp server that accepts every message and prints or saves it, for developing emails locally.
+// It doesn't support tls or auth, so the app needs UEKPZ3_SMTP_SECURITY=none
+func run() int {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	addr, outputDirectoryPath := ":2525", ""
+	flag.StringVar(&addr, "addr", addr, "address to listen on")
+	flag.StringVar(&outputDirectoryPath, "dir", outputDirectoryPath, "directory to save messages to as .eml files, messages are printed when empty")
+	flag.Parse()
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("Failed to listen", slog.Any("err", err))
+		return 1
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	logger.Info("SMTP sink started", slog.String("addr", addr), slog.String("dir", outputDirectoryPath))
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0
+			}
+			logger.Error("SMTP sink stopped unexpectedly", slog.Any("err", err))
+			return 1
+		}
+
+		go func() {
+			defer conn.Close()
+			if err := handleSession(conn, func(from string, to []string, data []byte) error {
+				return handleMessage(logger, outputDirectoryPath, from, to, data)
+			}); err != nil && !errors.Is(err, io.EOF) {
+				logger.Warn("SMTP session failed", slog.String("remoteAddr", conn.RemoteAddr().String()), slog.Any("err", err))
+			}
+		}()
+	}
+}
+
+func handleSession(conn net.Conn, onMessage func(from string, to []string, data []byte) error) error {
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) error {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+		return tp.PrintfLine("%d %s", code, msg)
+	}
+
+	if err := reply(220, "smtpsink ready"); err != nil {
+		return err
+	}
+
+	from, to := "", []string{}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return err
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			err = tp.PrintfLine("250-smtpsink\r\n250-8BITMIME\r\n250 SIZE %d", maxMessageSize)
+		case "HELO":
+			err = reply(250, "smtpsink")
+		case "MAIL":
+			from, to = parsePath(arg, "FROM:"), []string{}
+			err = reply(250, "OK")
+		case "RCPT":
+			to = append(to, parsePath(arg, "TO:"))
+			err = reply(250, "OK")
+		case "DATA":
+			if len(to) == 0 {
+				err = reply(503, "RCPT first")
+				break
+			}
+			if err = reply(354, "End data with <CR><LF>.<CR><LF>"); err != nil {
+				return err
+			}
+
+			data, readErr := io.ReadAll(io.LimitReader(tp.DotReader(), maxMessageSize))
+			if readErr != nil {
+				return readErr
+			}
+			if handleErr := onMessage(from, to, data); handleErr != nil {
+				err = reply(451, handleErr.Error())
+			} else {
+				err = reply(250, "OK")
+			}
+		case "RSET":
+			from, to = "", []string{}
+			err = reply(250, "OK")
+		case "NOOP":
+			err = reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return nil
+		default:
+			err = reply(502, "Command not implemented")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// "FROM:<a@b.c> SIZE=123" -> "a@b.c"
+func parsePath(arg string, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	path, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+
+	return strings.Trim(path, "<>")
+}
+
+func handleMessage(logger *slog.Logger, outputDirectoryPath string, from string, to []string, data []byte) error {
+	subject := ""
+	if msg, err := mail.ReadMessage(strings.NewReader(string(data))); err == nil {
+		subject, _ = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	}
+	logger = logger.With(slog.String("from", from), slog.Any("to", to), slog.String("subject", subject))
+
+	if outputDirectoryPath == "" {
+		logger.Info("Message received")
+		os.Stdout.Write(data)
+		fmt.Println()
+		return nil
+	}
+
+	filePath := filepath.Join(outputDirectoryPath, fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405Z"), uuid.NewString()[:8]))
+	if err := os.MkdirAll(outputDirectoryPath, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		return err
+	}
+	logger.Info("Message saved", slog.String("path", filePath))
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	godotenv.Overload()
+	cfg := config.FromEnv()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: func() slog.Level {
+			if cfg.Debug {
+				return slog.LevelDebug
+			}
+			return slog.LevelInfo
+		}(),
+	}))
+	slog.SetDefault(logger)
+
+	flag.StringVar(&cfg.Mock.ServerAddr, "addr", cfg.Mock.ServerAddr, "address to listen on")
+	flag.StringVar(&cfg.Mock.DirectoryPath, "dir", cfg.Mock.DirectoryPath, "directory with mock responses")
+	// the app falls back to UEK by default, but a fake server quietly proxying missing fixtures to the real one is a surprise
+	if _, ok := os.LookupEnv("MOCK_PASSTHROUGH"); !ok {
+		cfg.Mock.Passthrough = false
+	}
+	flag.BoolVar(&cfg.Mock.Passthrough, "passthrough", cfg.Mock.Passthrough, "proxy requests without a mock response to UEK")
+	generate := false
+	flag.BoolVar(&generate, "generate", false, "generate synthetic mock responses into the mock directory and exit")
+	generatorOpts := uekmock.GeneratorOptions{}
+	flag.Uint64Var(&generatorOpts.Seed, "seed", 1, "generator random seed")
+	flag.IntVar(&generatorOpts.Groups, "groups", 1000, "number of generated groups")
+	flag.IntVar(&generatorOpts.Lecturers, "lecturers", 600, "number of generated lecturers")
+	flag.IntVar(&generatorOpts.Rooms, "rooms", 200, "number of generated rooms")
+	flag.IntVar(&generatorOpts.Years, "years", 1, "number of generated academic years")
+	flag.IntVar(&generatorOpts.StartYear, "startyear", 0, "first generated academic year, defaults to the current one")
+	flag.Parse()
+
+	if generate {
+		logger.Info("Generating mock responses...", slog.String("dir", cfg.Mock.DirectoryPath))
+
+		result, err := uekmock.Generate(cfg.Mock.DirectoryPath, generatorOpts)
+		if err != nil {
+			logger.Error("Failed to generate mock responses", slog.Any("err", err))
+			return 1
+		}
+		logger.Info("Done!", slog.Int("files", result.Files), slog.Int("classes", result.Classes), slog.Int("sessions", result.Sessions))
+
+		return 0
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	go func() {
+		// ensure subsequent interrupts kill app immediately
+		<-ctx.Done()
+		cancelCtx()
+		logger.Info("Shutting down...")
+	}()
+
+	roundTripper, err := uekmock.NewRoundTripper(cfg.Mock)
+	if err != nil {
+		logger.Error("Failed to initialize mock", slog.Any("err", err))
+		return 1
+	}
+
+	srv := uekmock.NewServer(cfg.Mock.ServerAddr, roundTripper, cfg.Debug, logger)
+	go func() {
+		logger.Info("Mock server started",
+			slog.Bool("debug", cfg.Debug),
+			slog.String("addr", cfg.Mock.ServerAddr),
+			slog.String("dir", cfg.Mock.DirectoryPath),
+			slog.Bool("passthrough", cfg.Mock.Passthrough),
+		)
+		if err := srv.Run(); err != nil {
+			logger.Error("Mock server stopped unexpectedly", slog.Any("err", err))
+		}
+		cancelCtx()
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancelShutdownCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdownCtx()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to shut down gracefully", slog.Any("err", err))
+		return 1
+	}
+
+	logger.Info("Shut down gracefully")
+
+	return 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/webhook"
+)
+
+// maximum accepted age of a delivery timestamp
+const maxTimestampSkew = 5 * time.Minute
+
+func main() {
+	os.Exit(run())
+}
+
+// run starts a receiver that verifies and prints webhook deliveries, for developing webhooks locally
+func run() int {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	addr, secret := ":3902", ""
+	failureRate := 0.0
+	flag.StringVar(&addr, "addr", addr, "address to listen on")
+	flag.StringVar(&secret, "secret", secret, "subscription secret, signatures are not checked when empty")
+	flag.Float64Var(&failureRate, "fail", failureRate, "fraction of deliveries answered with 500, for testing retries")
+	flag.Parse()
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelCtx()
+
+	srv := &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: 15 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 16<<20))
+			if err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+
+			logger := logger.With(slog.String("deliveryId", r.Header.Get(webhook.IdHeader)))
+			if secret != "" {
+				timestamp := r.Header.Get(webhook.TimestampHeader)
+				unixTimestamp, err := strconv.ParseInt(timestamp, 10, 64)
+				if err != nil || time.Since(time.Unix(unixTimestamp, 0)).Abs() > maxTimestampSkew {
+					logger.Warn("Rejected delivery with invalid timestamp", slog.String("timestamp", timestamp))
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				if !webhook.Verify(secret, timestamp, body, r.Header.Get(webhook.SignatureHeader)) {
+					logger.Warn("Rejected delivery with invalid signature")
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+			}
+
+			if rand.Float64() < failureRate {
+				logger.Info("Simulating failure")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			indentedBody := bytes.Buffer{}
+			if err := json.Indent(&indentedBody, body, "", "  "); err != nil {
+				indentedBody.Write(body)
+			}
+			logger.Info("Delivery received", slog.Bool("verified", secret != ""))
+			os.Stdout.Write(append(indentedBody.Bytes(), '\n'))
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	logger.Info("Webhook receiver started", slog.String("addr", addr), slog.Bool("verifySignatures", secret != ""))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("Webhook receiver stopped unexpectedly", slog.Any("err", err))
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,176 @@
+package admincli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+type Config struct {
+	HttpClient *http.Client
+	// e.g. http://localhost:3001
+	BaseUrl string
+	Token   string
+	Input   io.Reader
+	Output  io.Writer
+}
+
+const usage = `usage: cache <command> [arguments]
+
+commands:
+  list [-prefix prefix]     list cached keys with expiry and size
+  get <key>                 print decoded entry
+  invalidate <key>          delete entry
+  invalidate -pattern glob  delete entries matching pattern, e.g. "schedule-*-*-3"
+  refresh <key>             fetch fresh data for key, bypassing cache
+  stats                     print cache stats, including badger LSM/vlog sizes, and parse warning counts
+  export                    write all entries as json lines to stdout
+  import [file]             load entries written by export from file or stdin`
+
+var ErrUsage = errors.New(usage)
+
+// Run executes a cache admin command against a running server
+func Run(ctx context.Context, cfg Config, args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	switch command, args := args[0], args[1:]; command {
+	case "list":
+		flagSet := flag.NewFlagSet("list", flag.ContinueOnError)
+		prefix := flagSet.String("prefix", "", "key prefix")
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+
+		entries := []uek.CacheEntryInfo{}
+		buff := &bytes.Buffer{}
+		if err := cfg.do(ctx, http.MethodGet, "/api/admin/cache/entries?"+url.Values{"prefix": {*prefix}}.Encode(), nil, buff); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(buff.Bytes(), &entries); err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(cfg.Output, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tEXPIRES IN\tSIZE")
+		for _, entry := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", entry.Key, time.Until(entry.ExpirationDate).Round(time.Second), entry.Size)
+		}
+		return tw.Flush()
+	case "get":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+
+		return cfg.doAndPrint(ctx, http.MethodGet, "/api/admin/cache/entries/"+url.PathEscape(args[0]), nil)
+	case "invalidate":
+		flagSet := flag.NewFlagSet("invalidate", flag.ContinueOnError)
+		pattern := flagSet.String("pattern", "", "glob pattern, * matches any sequence and ? any single character")
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+
+		queryParams := url.Values{}
+		if *pattern != "" && flagSet.NArg() == 0 {
+			queryParams.Set("pattern", *pattern)
+		} else if *pattern == "" && flagSet.NArg() == 1 {
+			queryParams.Set("key", flagSet.Arg(0))
+		} else {
+			return ErrUsage
+		}
+
+		return cfg.doAndPrint(ctx, http.MethodDelete, "/api/admin/cache/entries?"+queryParams.Encode(), nil)
+	case "refresh":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+
+		return cfg.doAndPrint(ctx, http.MethodPost, "/api/admin/cache/refresh?"+url.Values{"key": {args[0]}}.Encode(), nil)
+	case "stats":
+		return cfg.doAndPrint(ctx, http.MethodGet, "/api/admin/cache/stats", nil)
+	case "export":
+		if len(args) != 0 {
+			return ErrUsage
+		}
+
+		return cfg.do(ctx, http.MethodGet, "/api/admin/cache/export", nil, cfg.Output)
+	case "import":
+		input := cfg.Input
+		switch len(args) {
+		case 0:
+		case 1:
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			input = f
+		default:
+			return ErrUsage
+		}
+
+		return cfg.doAndPrint(ctx, http.MethodPost, "/api/admin/cache/import", input)
+	}
+
+	return ErrUsage
+}
+
+func (cfg Config) doAndPrint(ctx context.Context, method string, path string, body io.Reader) error {
+	res := &bytes.Buffer{}
+	if err := cfg.do(ctx, method, path, body, res); err != nil {
+		return err
+	}
+
+	buff := &bytes.Buffer{}
+	if err := json.Indent(buff, bytes.TrimSpace(res.Bytes()), "", "  "); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	buff.WriteByte('\n')
+
+	_, err := buff.WriteTo(cfg.Output)
+	return err
+}
+
+// copies response body to w
+func (cfg Config) do(ctx context.Context, method string, path string, body io.Reader, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(cfg.BaseUrl, "/")+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+
+	httpClient := cfg.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	httpRes, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(httpRes.Body, 1024))
+		return fmt.Errorf("unexpected status code: %d: %s", httpRes.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if _, err := io.Copy(w, httpRes.Body); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return nil
+}
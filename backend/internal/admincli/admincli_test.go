@@ -0,0 +1,71 @@
+package admincli
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	requests := []string{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer testServer.Close()
+
+	for _, tc := range []struct {
+		args []string
+		// empty when the arguments are invalid
+		expectedRequest string
+	}{
+		{args: []string{"get", "headers-lecturer-"}, expectedRequest: "GET /api/admin/cache/entries/headers-lecturer-"},
+		{args: []string{"get", "headers-group-A/B"}, expectedRequest: "GET /api/admin/cache/entries/headers-group-A%2FB"},
+		{args: []string{"invalidate", "schedule-group-1-2"}, expectedRequest: "DELETE /api/admin/cache/entries?key=schedule-group-1-2"},
+		{args: []string{"invalidate", "-pattern", "schedule-*-*-3"}, expectedRequest: "DELETE /api/admin/cache/entries?pattern=schedule-%2A-%2A-3"},
+		{args: []string{"refresh", "headers-lecturer-"}, expectedRequest: "POST /api/admin/cache/refresh?key=headers-lecturer-"},
+		{args: []string{"stats"}, expectedRequest: "GET /api/admin/cache/stats"},
+		{args: []string{}},
+		{args: []string{"unknown"}},
+		{args: []string{"get"}},
+		{args: []string{"invalidate"}},
+		{args: []string{"invalidate", "-pattern", "headers-*", "headers-lecturer-"}},
+		{args: []string{"refresh", "a", "b"}},
+		{args: []string{"export", "file"}},
+	} {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			requests = requests[:0]
+			output := &bytes.Buffer{}
+			err := Run(t.Context(), Config{BaseUrl: testServer.URL + "/", Token: "secret", Output: output}, tc.args)
+
+			if tc.expectedRequest == "" {
+				if !errors.Is(err, ErrUsage) || len(requests) != 0 {
+					t.Errorf("expected usage error without requests, got %v and %v", err, requests)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != 1 || requests[0] != tc.expectedRequest {
+				t.Errorf("expected %q, got %v", tc.expectedRequest, requests)
+			}
+			if output.String() != "{\n  \"ok\": true\n}\n" {
+				t.Errorf("expected indented json output, got %q", output.String())
+			}
+		})
+	}
+
+	if err := Run(t.Context(), Config{BaseUrl: testServer.URL, Token: "wrong", Output: &bytes.Buffer{}}, []string{"stats"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the status code in the error, got %v", err)
+	}
+}
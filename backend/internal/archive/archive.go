@@ -0,0 +1,227 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// Archive stores the latest version of every fetched schedule on disk, grouped by period dates, because UEK period ids
+// are positional and shift when periods rotate. A period is frozen once UEK stops listing it
+type Archive struct {
+	dirPath string
+	logger  *slog.Logger
+
+	mu    sync.Mutex
+	index index
+	// serializes writes of snapshot files, and freezing periods with them. Locked before mu
+	fileMu sync.Mutex
+}
+
+type index struct {
+	Periods []indexPeriod `json:"periods"`
+}
+
+type indexPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// negative, assigned when frozen
+	ArchivedId int       `json:"archivedId,omitempty"`
+	FrozenAt   time.Time `json:"frozenAt,omitzero"`
+}
+
+func (p *indexPeriod) frozen() bool {
+	return p.ArchivedId != 0
+}
+
+func (p *indexPeriod) matches(period uek.SchedulePeriod) bool {
+	return p.Start.Equal(period.Start) && p.End.Equal(period.End)
+}
+
+func (p *indexPeriod) dirName() string {
+	return fmt.Sprintf("%s_%s", p.Start.Format("2006-01-02"), p.End.Format("2006-01-02"))
+}
+
+type snapshot struct {
+	FetchedAt time.Time     `json:"fetchedAt"`
+	Schedule  *uek.Schedule `json:"schedule"`
+}
+
+func New(dirPath string, logger *slog.Logger) (*Archive, error) {
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	a := &Archive{
+		dirPath: dirPath,
+		logger:  logger,
+	}
+
+	if err := jsonfile.Read(a.indexPath(), &a.index); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	return a, nil
+}
+
+func (a *Archive) indexPath() string {
+	return filepath.Join(a.dirPath, "index.json")
+}
+
+func (a *Archive) snapshotPath(period *indexPeriod, scheduleType uek.ScheduleType, scheduleId int) string {
+	return filepath.Join(a.dirPath, period.dirName(), fmt.Sprintf("%s-%d.json", scheduleType, scheduleId))
+}
+
+// must be called with mu locked
+func (a *Archive) saveIndex() {
+	if err := jsonfile.Write(a.indexPath(), a.index); err != nil {
+		a.logger.Error("Failed to save index", slog.Any("err", err))
+	}
+}
+
+func (a *Archive) SaveSchedule(scheduleType uek.ScheduleType, scheduleId int, period uek.SchedulePeriod, schedule *uek.Schedule) {
+	a.mu.Lock()
+	periodIndex := slices.IndexFunc(a.index.Periods, func(p indexPeriod) bool {
+		return p.matches(period)
+	})
+	if periodIndex == -1 {
+		a.index.Periods = append(a.index.Periods, indexPeriod{
+			Start: period.Start,
+			End:   period.End,
+		})
+		periodIndex = len(a.index.Periods) - 1
+		a.saveIndex()
+	}
+	indexedPeriod := a.index.Periods[periodIndex]
+	a.mu.Unlock()
+
+	if indexedPeriod.frozen() {
+		return
+	}
+
+	encodedSchedule, err := json.Marshal(schedule)
+	if err != nil {
+		a.logger.Error("Failed to encode schedule", slog.Any("err", err))
+		return
+	}
+
+	a.fileMu.Lock()
+	defer a.fileMu.Unlock()
+
+	// the period could have been frozen while the lock was awaited
+	a.mu.Lock()
+	frozen := a.index.Periods[periodIndex].frozen()
+	a.mu.Unlock()
+	if frozen {
+		return
+	}
+
+	snapshotPath := a.snapshotPath(&indexedPeriod, scheduleType, scheduleId)
+	existingSnapshot := struct {
+		Schedule json.RawMessage `json:"schedule"`
+	}{}
+	if err := jsonfile.Read(snapshotPath, &existingSnapshot); err == nil {
+		compactExistingSchedule := &bytes.Buffer{}
+		if json.Compact(compactExistingSchedule, existingSnapshot.Schedule) == nil && bytes.Equal(compactExistingSchedule.Bytes(), encodedSchedule) {
+			return
+		}
+	}
+
+	if err := jsonfile.Write(snapshotPath, snapshot{
+		FetchedAt: time.Now(),
+		Schedule:  schedule,
+	}); err != nil {
+		a.logger.Error("Failed to save snapshot", slog.String("path", snapshotPath), slog.Any("err", err))
+	}
+}
+
+func (a *Archive) UpdatePeriods(periods []uek.SchedulePeriod) {
+	// an empty list is more likely an upstream hiccup than all periods disappearing at once
+	if len(periods) == 0 {
+		return
+	}
+
+	// snapshots being written finish before their period is frozen
+	a.fileMu.Lock()
+	defer a.fileMu.Unlock()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	lowestArchivedId := 0
+	for _, indexedPeriod := range a.index.Periods {
+		lowestArchivedId = min(lowestArchivedId, indexedPeriod.ArchivedId)
+	}
+
+	changed := false
+	for i := range a.index.Periods {
+		indexedPeriod := &a.index.Periods[i]
+		if indexedPeriod.frozen() || slices.ContainsFunc(periods, indexedPeriod.matches) {
+			continue
+		}
+
+		lowestArchivedId--
+		indexedPeriod.ArchivedId = lowestArchivedId
+		indexedPeriod.FrozenAt = time.Now()
+		changed = true
+
+		a.logger.Info("Period archived", slog.Int("archivedId", indexedPeriod.ArchivedId), slog.Time("start", indexedPeriod.Start), slog.Time("end", indexedPeriod.End))
+	}
+
+	if changed {
+		a.saveIndex()
+	}
+}
+
+func (a *Archive) GetPeriods(_ context.Context) ([]uek.SchedulePeriod, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	periods := []uek.SchedulePeriod{}
+	for _, indexedPeriod := range a.index.Periods {
+		if indexedPeriod.frozen() {
+			periods = append(periods, uek.SchedulePeriod{
+				Id:       indexedPeriod.ArchivedId,
+				Start:    indexedPeriod.Start,
+				End:      indexedPeriod.End,
+				Archived: true,
+			})
+		}
+	}
+
+	return periods, nil
+}
+
+func (a *Archive) GetSchedule(_ context.Context, scheduleType uek.ScheduleType, scheduleId int, periodId int) (*uek.Schedule, error) {
+	a.mu.Lock()
+	periodIndex := slices.IndexFunc(a.index.Periods, func(p indexPeriod) bool {
+		return p.frozen() && p.ArchivedId == periodId
+	})
+	if periodIndex == -1 {
+		a.mu.Unlock()
+		return nil, uek.ErrScheduleNotArchived
+	}
+	indexedPeriod := a.index.Periods[periodIndex]
+	a.mu.Unlock()
+
+	archivedSnapshot := snapshot{}
+	if err := jsonfile.Read(a.snapshotPath(&indexedPeriod, scheduleType, scheduleId), &archivedSnapshot); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, uek.ErrScheduleNotArchived
+		}
+		return nil, err
+	}
+
+	return archivedSnapshot.Schedule, nil
+}
@@ -0,0 +1,188 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func newTestArchive(t *testing.T, dirPath string) *Archive {
+	t.Helper()
+
+	a, err := New(dirPath, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+func testPeriod(startYear int, startMonth time.Month, id int) uek.SchedulePeriod {
+	start := time.Date(startYear, startMonth, 1, 0, 0, 0, 0, uek.Location())
+	return uek.SchedulePeriod{Id: id, Start: start, End: start.AddDate(0, 5, 0)}
+}
+
+func testSchedule(subject string) *uek.Schedule {
+	return &uek.Schedule{
+		Header: uek.ScheduleHeader{Id: 1, Name: "KrDUIs1011"},
+		Items: []*uek.ScheduleItem{{
+			Start:   time.Date(2024, 10, 7, 8, 0, 0, 0, uek.Location()),
+			End:     time.Date(2024, 10, 7, 9, 30, 0, 0, uek.Location()),
+			Subject: subject,
+			Type:    "wykład",
+		}},
+	}
+}
+
+func TestArchiveFreezesMissingPeriods(t *testing.T) {
+	a := newTestArchive(t, t.TempDir())
+	winter, summer, nextWinter := testPeriod(2024, time.October, 1), testPeriod(2025, time.February, 2), testPeriod(2025, time.October, 1)
+
+	a.SaveSchedule(uek.ScheduleTypeGroup, 1, winter, testSchedule("Algebra"))
+	a.SaveSchedule(uek.ScheduleTypeGroup, 1, summer, testSchedule("Analiza"))
+	a.UpdatePeriods([]uek.SchedulePeriod{winter, summer})
+
+	if periods, _ := a.GetPeriods(t.Context()); len(periods) != 0 {
+		t.Fatalf("expected no archived periods while UEK still lists them, got %+v", periods)
+	}
+	if _, err := a.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, -1); !errors.Is(err, uek.ErrScheduleNotArchived) {
+		t.Errorf("expected ErrScheduleNotArchived, got %v", err)
+	}
+
+	// an empty list is ignored rather than freezing everything
+	a.UpdatePeriods(nil)
+	if periods, _ := a.GetPeriods(t.Context()); len(periods) != 0 {
+		t.Fatalf("expected an empty period list to be ignored, got %+v", periods)
+	}
+
+	// periods rotate, winter is no longer listed and nextWinter takes over its positional id
+	a.UpdatePeriods([]uek.SchedulePeriod{summer, nextWinter})
+	periods, err := a.GetPeriods(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 1 || periods[0].Id != -1 || !periods[0].Archived || !periods[0].Start.Equal(winter.Start) || !periods[0].End.Equal(winter.End) {
+		t.Fatalf("expected winter to be archived as -1, got %+v", periods)
+	}
+
+	schedule, err := a.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Items[0].Subject != "Algebra" {
+		t.Errorf("expected the winter schedule, got %s", schedule.Items[0].Subject)
+	}
+
+	// frozen periods no longer take snapshots
+	a.SaveSchedule(uek.ScheduleTypeGroup, 1, winter, testSchedule("Changed"))
+	if schedule, err := a.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, -1); err != nil || schedule.Items[0].Subject != "Algebra" {
+		t.Errorf("expected the frozen snapshot to stay, got %+v, %v", schedule, err)
+	}
+
+	// next freeze gets the next negative id
+	a.UpdatePeriods([]uek.SchedulePeriod{nextWinter})
+	periods, err = a.GetPeriods(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 2 || periods[0].Id != -1 || periods[1].Id != -2 {
+		t.Fatalf("expected archived ids -1 and -2, got %+v", periods)
+	}
+
+	schedule, err = a.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, -2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Items[0].Subject != "Analiza" {
+		t.Errorf("expected the summer schedule, got %s", schedule.Items[0].Subject)
+	}
+
+	for _, tc := range []struct {
+		name         string
+		scheduleType uek.ScheduleType
+		scheduleId   int
+		periodId     int
+	}{
+		{name: "unknown archived period", scheduleType: uek.ScheduleTypeGroup, scheduleId: 1, periodId: -3},
+		{name: "schedule not saved", scheduleType: uek.ScheduleTypeGroup, scheduleId: 2, periodId: -1},
+		{name: "other schedule type", scheduleType: uek.ScheduleTypeRoom, scheduleId: 1, periodId: -1},
+		{name: "positional period id", scheduleType: uek.ScheduleTypeGroup, scheduleId: 1, periodId: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := a.GetSchedule(t.Context(), tc.scheduleType, tc.scheduleId, tc.periodId); !errors.Is(err, uek.ErrScheduleNotArchived) {
+				t.Errorf("expected ErrScheduleNotArchived, got %v", err)
+			}
+		})
+	}
+}
+
+func TestArchiveIndexPersists(t *testing.T) {
+	dirPath := t.TempDir()
+	winter, summer := testPeriod(2024, time.October, 1), testPeriod(2025, time.February, 1)
+
+	a := newTestArchive(t, dirPath)
+	a.SaveSchedule(uek.ScheduleTypeLecturer, 7, winter, testSchedule("Algebra"))
+	a.UpdatePeriods([]uek.SchedulePeriod{summer})
+
+	reopened := newTestArchive(t, dirPath)
+	periods, err := reopened.GetPeriods(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 1 || periods[0].Id != -1 {
+		t.Fatalf("expected the archived period to survive a restart, got %+v", periods)
+	}
+
+	schedule, err := reopened.GetSchedule(t.Context(), uek.ScheduleTypeLecturer, 7, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Items[0].Subject != "Algebra" {
+		t.Errorf("expected the archived schedule, got %s", schedule.Items[0].Subject)
+	}
+}
+
+func TestArchiveSkipsPeriodsFrozenDuringSave(t *testing.T) {
+	a := newTestArchive(t, t.TempDir())
+	winter, summer := testPeriod(2024, time.October, 1), testPeriod(2025, time.February, 2)
+	a.SaveSchedule(uek.ScheduleTypeGroup, 1, winter, testSchedule("Algebra"))
+
+	// the save passes the first check and waits for the file lock, meanwhile the period gets frozen
+	a.fileMu.Lock()
+	saved := make(chan struct{})
+	go func() {
+		a.SaveSchedule(uek.ScheduleTypeGroup, 2, winter, testSchedule("Analiza"))
+		close(saved)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	a.mu.Lock()
+	a.index.Periods[0].ArchivedId = -1
+	a.mu.Unlock()
+	a.fileMu.Unlock()
+	<-saved
+
+	if _, err := os.Stat(a.snapshotPath(&a.index.Periods[0], uek.ScheduleTypeGroup, 2)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no snapshot in the frozen period, got %v", err)
+	}
+
+	// freezing waits for snapshots being written
+	a.fileMu.Lock()
+	updated := make(chan struct{})
+	go func() {
+		a.UpdatePeriods([]uek.SchedulePeriod{summer})
+		close(updated)
+	}()
+	select {
+	case <-updated:
+		t.Error("expected freezing to wait for the file lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	a.fileMu.Unlock()
+	<-updated
+}
@@ -0,0 +1,128 @@
+package badgercache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+type Stats struct {
+	Entries      int    `json:"entries"`
+	LSMSize      int64  `json:"lsmSize"`
+	VlogSize     int64  `json:"vlogSize"`
+	Codec        string `json:"codec"`
+	Compression  string `json:"compression"`
+	MaxDiskUsage int64  `json:"maxDiskUsage,omitempty"`
+	KeyPrefix    string `json:"keyPrefix"`
+}
+
+func (c *Cache) ListEntries(ctx context.Context, keyPrefixFilter string) ([]uek.CacheEntryInfo, error) {
+	entries := []uek.CacheEntryInfo{}
+
+	err := c.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{
+			Prefix: []byte(keyPrefix + keyPrefixFilter),
+		})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			item := it.Item()
+			entries = append(entries, uek.CacheEntryInfo{
+				Key:            strings.TrimPrefix(string(item.Key()), keyPrefix),
+				ExpirationDate: time.Unix(int64(item.ExpiresAt()), 0),
+				Size:           item.EstimatedSize(),
+			})
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+func (c *Cache) GetEntry(_ context.Context, key string) (value any, expirationDate time.Time, ok bool) {
+	err := c.db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get([]byte(keyPrefix + key))
+		if err != nil {
+			return err
+		}
+
+		expirationDate = time.Unix(int64(item.ExpiresAt()), 0)
+
+		return item.Value(func(itemValue []byte) (err error) {
+			value, err = decodeAny(key, itemValue)
+			return
+		})
+	})
+
+	if err == nil {
+		ok = true
+	} else if !errors.Is(err, badger.ErrKeyNotFound) {
+		c.logger.Error("Failed to get value", slog.String("key", key), slog.Any("err", err))
+	}
+
+	return
+}
+
+func (c *Cache) DeleteEntries(ctx context.Context, keyPattern *regexp.Regexp) (int, error) {
+	var matchingKeys [][]byte
+	if err := c.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{
+			Prefix: []byte(keyPrefix),
+		})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if keyPattern.MatchString(strings.TrimPrefix(string(it.Item().Key()), keyPrefix)) {
+				matchingKeys = append(matchingKeys, it.Item().KeyCopy(nil))
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	wb := c.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, key := range matchingKeys {
+		if err := wb.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return 0, err
+	}
+
+	return len(matchingKeys), nil
+}
+
+func (c *Cache) Stats(ctx context.Context) any {
+	stats := Stats{
+		Codec:        c.codec.String(),
+		Compression:  c.compression.String(),
+		MaxDiskUsage: c.maxDiskUsage,
+		KeyPrefix:    keyPrefix,
+	}
+	stats.LSMSize, stats.VlogSize = c.db.Size()
+
+	if entries, err := c.ListEntries(ctx, ""); err == nil {
+		stats.Entries = len(entries)
+	}
+
+	return stats
+}
@@ -0,0 +1,165 @@
+package badgercache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// Backup writes a full badger backup, loadable with Restore
+func (c *Cache) Backup(w io.Writer) error {
+	_, err := c.db.Backup(w, 0)
+	return err
+}
+
+func (c *Cache) Restore(r io.Reader) error {
+	return c.db.Load(r, 256)
+}
+
+// writes to a temporary file first so that a crash never leaves a truncated backup behind
+func (c *Cache) backupToFile(filePath string) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	bufferedWriter := bufio.NewWriter(tempFile)
+	if err := c.Backup(bufferedWriter); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := bufferedWriter.Flush(); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to sync backup: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to close backup: %w", err)
+	}
+
+	return os.Rename(tempFile.Name(), filePath)
+}
+
+func (c *Cache) restoreFromFile(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.Restore(bufio.NewReader(f))
+}
+
+func (c *Cache) backupWorker(filePath string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.cleanupWorkerCtx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		startTime := time.Now()
+		if err := c.backupToFile(filePath); err != nil {
+			c.logger.Error("Failed to back up", slog.Any("err", err))
+		} else {
+			c.logger.Debug("Backup executed successfully", slog.String("timeTaken", time.Since(startTime).String()))
+		}
+	}
+}
+
+func (c *Cache) isEmpty() bool {
+	empty := true
+	c.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{})
+		defer it.Close()
+
+		it.Rewind()
+		empty = !it.Valid()
+
+		return nil
+	})
+
+	return empty
+}
+
+// portable export line, values are plain json so they survive schema and codec changes as long as json tags do
+type exportedEntry struct {
+	Key            string          `json:"key"`
+	ExpirationDate time.Time       `json:"expirationDate"`
+	Value          json.RawMessage `json:"value"`
+}
+
+// Export writes all current entries as json lines
+func (c *Cache) Export(ctx context.Context, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	return c.Walk(ctx, func(key string, value any, expirationDate time.Time) error {
+		encodedValue, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode value of %s: %w", key, err)
+		}
+
+		return encoder.Encode(exportedEntry{
+			Key:            key,
+			ExpirationDate: expirationDate,
+			Value:          encodedValue,
+		})
+	})
+}
+
+// Import reads json lines written by Export, skipping entries that have already expired
+func (c *Cache) Import(ctx context.Context, r io.Reader) (int, error) {
+	decoder := json.NewDecoder(r)
+	importedCount := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return importedCount, err
+		}
+
+		entry := exportedEntry{}
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return importedCount, nil
+		} else if err != nil {
+			return importedCount, fmt.Errorf("failed to decode entry: %w", err)
+		}
+
+		if strings.TrimSpace(entry.Key) == "" || !entry.ExpirationDate.After(time.Now()) {
+			continue
+		}
+
+		value, err := uek.DecodeCacheValueJSON(entry.Key, entry.Value)
+		if err != nil {
+			return importedCount, fmt.Errorf("failed to decode value of %s: %w", entry.Key, err)
+		}
+
+		encodedValue, err := encodeEnvelope(c.codec, c.compression, value)
+		if err != nil {
+			return importedCount, fmt.Errorf("failed to encode value of %s: %w", entry.Key, err)
+		}
+
+		if err := c.db.Update(func(tx *badger.Txn) error {
+			return tx.SetEntry(badger.NewEntry([]byte(keyPrefix+entry.Key), encodedValue).WithTTL(time.Until(entry.ExpirationDate)))
+		}); err != nil {
+			return importedCount, fmt.Errorf("failed to upsert value of %s: %w", entry.Key, err)
+		}
+		importedCount++
+	}
+}
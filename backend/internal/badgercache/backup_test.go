@@ -0,0 +1,195 @@
+package badgercache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/y"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestBackupRestore(t *testing.T) {
+	expirationDate := time.Now().Add(time.Hour)
+
+	source := newTestCache(t, CodecMsgpack, CompressionZstd)
+	source.PutScheduleAndPeriods(expirationDate, uek.ScheduleTypeGroup, 1, 3, testSchedule(), expirationDate, testPeriods())
+
+	backup := &bytes.Buffer{}
+	if err := source.Backup(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	target := newTestCache(t, CodecGob, CompressionNone)
+	if err := target.Restore(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	schedule, cachedExpirationDate, ok := target.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, 3)
+	if !ok {
+		t.Fatal("schedule not found after restore")
+	}
+	assertSameJSON(t, testSchedule(), schedule)
+	if cachedExpirationDate.Unix() != expirationDate.Unix() {
+		t.Errorf("expected expiration date %s, got %s", expirationDate, cachedExpirationDate)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	expirationDate := time.Now().Add(time.Hour)
+	headers := []uek.ScheduleHeader{{Id: 1, Name: "KrDUIs1011"}}
+
+	source := newTestCache(t, CodecGob, CompressionSnappy)
+	source.PutScheduleAndPeriods(expirationDate, uek.ScheduleTypeGroup, 1, 3, testSchedule(), expirationDate, testPeriods())
+	source.PutHeaders(expirationDate, uek.ScheduleTypeGroup, "KrDUIs1", headers)
+
+	export := &bytes.Buffer{}
+	if err := source.Export(t.Context(), export); err != nil {
+		t.Fatal(err)
+	}
+
+	// expired entries in the export are skipped
+	expiredHeaders, err := json.Marshal(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(export).Encode(exportedEntry{
+		Key:            uek.MakeHeadersCacheKey(uek.ScheduleTypeRoom, "Paw.A"),
+		ExpirationDate: time.Now().Add(-time.Minute),
+		Value:          expiredHeaders,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := newTestCache(t, CodecMsgpack, CompressionZstd)
+	importedCount, err := target.Import(t.Context(), export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if importedCount != 3 {
+		t.Errorf("expected 3 imported entries, got %d", importedCount)
+	}
+
+	schedule, _, ok := target.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, 3)
+	if !ok {
+		t.Fatal("schedule not found after import")
+	}
+	assertSameJSON(t, testSchedule(), schedule)
+
+	periods, _, ok := target.GetPeriods(t.Context())
+	if !ok {
+		t.Fatal("periods not found after import")
+	}
+	assertSameJSON(t, testPeriods(), periods)
+
+	if _, _, ok := target.GetHeaders(t.Context(), uek.ScheduleTypeRoom, "Paw.A"); ok {
+		t.Error("expected the expired entry to be skipped")
+	}
+}
+
+func TestImportRejectsUnknownKeys(t *testing.T) {
+	c := newTestCache(t, CodecGob, CompressionNone)
+	_, err := c.Import(t.Context(), bytes.NewBufferString(fmt.Sprintf(`{"key":"unknown","expirationDate":%q,"value":{}}`, time.Now().Add(time.Hour).Format(time.RFC3339))))
+	if err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func newTestFileCache(t *testing.T, dbPath string, backupPath string) (*Cache, error) {
+	t.Helper()
+
+	return New(Config{
+		Path:       dbPath,
+		BackupPath: backupPath,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+}
+
+func TestCorruptDatabaseIsRestoredFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, backupPath := filepath.Join(dir, "db"), filepath.Join(dir, "backup")
+	expirationDate := time.Now().Add(time.Hour)
+
+	c, err := newTestFileCache(t, dbPath, backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.PutScheduleAndPeriods(expirationDate, uek.ScheduleTypeGroup, 1, 3, testSchedule(), expirationDate, testPeriods())
+	c.Close()
+
+	if err := os.WriteFile(filepath.Join(dbPath, "MANIFEST"), []byte("garbage!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = newTestFileCache(t, dbPath, backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	schedule, _, ok := c.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, 3)
+	if !ok {
+		t.Fatal("schedule not found after restoring from backup")
+	}
+	assertSameJSON(t, testSchedule(), schedule)
+
+	corruptPaths, err := filepath.Glob(dbPath + ".corrupt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptPaths) != 1 {
+		t.Errorf("expected the corrupt database to be moved aside, got %v", corruptPaths)
+	}
+}
+
+func TestLockedDatabaseIsLeftAlone(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, backupPath := filepath.Join(dir, "db"), filepath.Join(dir, "backup")
+
+	c, err := newTestFileCache(t, dbPath, backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := newTestFileCache(t, dbPath, backupPath); err == nil {
+		t.Fatal("expected an error when the database is used by another cache")
+	}
+
+	corruptPaths, err := filepath.Glob(dbPath + ".corrupt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptPaths) != 0 {
+		t.Errorf("expected the database to stay in place, got %v", corruptPaths)
+	}
+}
+
+func TestIsCorruptionError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "checksum mismatch", err: y.Wrapf(y.ErrChecksumMismatch, "value corrupted"), expected: true},
+		{name: "truncate needed", err: fmt.Errorf("open: %w", badger.ErrTruncateNeeded), expected: true},
+		{name: "bad manifest magic", err: errors.New("manifest has bad magic"), expected: true},
+		{name: "corrupt table", err: errors.New("checksum length less than zero. Data corrupted"), expected: true},
+		{name: "directory lock", err: errors.New("Cannot acquire directory lock on \"/data\".  Another process is using this Badger database."), expected: false},
+		{name: "io error", err: &os.PathError{Op: "open", Path: "/data/MANIFEST", Err: errors.New("input/output error")}, expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := isCorruptionError(tc.err); actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -3,18 +3,44 @@ package badgercache
 import (
 	"bytes"
 	"context"
-	"encoding/gob"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/y"
 	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
 )
 
+type Config struct {
+	// empty for in-memory database
+	Path string
+	// used for new entries, existing entries are decoded with the codec they were written with
+	Codec Codec
+	// applied to new entries
+	Compression Compression
+	Preset      Preset
+	// overrides preset
+	Tuning Tuning
+	// file-based database only, oldest entries are evicted when live entries or lsm and vlog sizes exceed it, 0 disables the limit
+	MaxDiskUsage int64
+	// file-based database only, restored from when the database is empty or corrupt
+	BackupPath     string
+	BackupInterval time.Duration
+	Logger         *slog.Logger
+}
+
 type Cache struct {
 	db                     *badger.DB
+	codec                  Codec
+	compression            Compression
+	maxDiskUsage           int64
+	backupPath             string
 	logger                 *slog.Logger
 	cleanupWorkerCtx       context.Context
 	cancelCleanupWorkerCtx context.CancelFunc
@@ -40,32 +66,108 @@ func (bl *badgerLogger) Debugf(format string, args ...any) {
 	bl.logger.Debug(fmt.Sprintf(format, args...))
 }
 
-func New(filePath string, logger *slog.Logger) (*Cache, error) {
-	opts := badger.DefaultOptions(filePath).WithLogger(&badgerLogger{
+func New(cfg Config) (*Cache, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	codec := cfg.Codec
+	if codec == 0 {
+		codec = CodecGob
+	}
+
+	preset := cfg.Preset
+	if preset == "" {
+		preset = PresetDefault
+	}
+	presetTuning, ok := presetTunings[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset: %s", preset)
+	}
+
+	opts := presetTuning.merge(cfg.Tuning).apply(badger.DefaultOptions(cfg.Path).WithLogger(&badgerLogger{
 		logger: logger,
-	}).WithValueLogFileSize(5 << 20 /* 5MB */).WithMemTableSize(4 << 20 /* 4MB */).WithValueThreshold(512 << 10 /* 512KB */).WithNumLevelZeroTables(2)
-	if filePath == "" {
+	}))
+	if cfg.Path == "" {
 		opts = opts.WithInMemory(true)
 	}
 
 	db, err := badger.Open(opts)
+	// a corrupt database is moved aside and restored from backup below, other errors (lock held, I/O) may be transient and leave it alone
+	if err != nil && !opts.InMemory && cfg.BackupPath != "" {
+		if isCorruptionError(err) {
+			corruptPath := fmt.Sprintf("%s.corrupt-%d", cfg.Path, time.Now().Unix())
+			logger.Error("Database is corrupt, moving it aside", slog.String("corruptPath", corruptPath), slog.Any("err", err))
+
+			if renameErr := os.Rename(cfg.Path, corruptPath); renameErr == nil {
+				db, err = badger.Open(opts)
+			} else {
+				logger.Error("Failed to move corrupt database aside", slog.Any("err", renameErr))
+			}
+		} else {
+			logger.Error("Failed to open database, not restoring from backup since it doesn't look corrupt", slog.Any("err", err))
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	c := &Cache{
-		db:     db,
-		logger: logger,
+		db:          db,
+		codec:       codec,
+		compression: cfg.Compression,
+		logger:      logger,
+	}
+	if !opts.InMemory {
+		c.maxDiskUsage = cfg.MaxDiskUsage
 	}
 	c.cleanupWorkerCtx, c.cancelCleanupWorkerCtx = context.WithCancel(context.Background())
 
+	if !opts.InMemory && cfg.BackupPath != "" {
+		c.backupPath = cfg.BackupPath
+
+		if c.isEmpty() {
+			if err := c.restoreFromFile(cfg.BackupPath); err == nil {
+				logger.Info("Restored from backup", slog.String("backupPath", cfg.BackupPath))
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				logger.Error("Failed to restore from backup", slog.Any("err", err))
+			}
+		}
+
+		if cfg.BackupInterval > 0 {
+			go c.backupWorker(cfg.BackupPath, cfg.BackupInterval)
+		}
+	}
+
 	if !opts.InMemory {
+		go c.purgeStaleEntries()
 		go c.cleanupWorker()
 	}
 
 	return c, nil
 }
 
+// badger doesn't export most of its corruption errors and wraps the exported ones without %w, so they're matched by message
+var corruptionErrorMessages = []string{
+	y.ErrChecksumMismatch.Error(),
+	"manifest has bad magic",
+	"Manifest file might be corrupted",
+	"MANIFEST invalid",
+	"MANIFEST file has invalid manifestChange op",
+	"Data corrupted",
+}
+
+func isCorruptionError(err error) bool {
+	if errors.Is(err, badger.ErrTruncateNeeded) {
+		return true
+	}
+
+	return slices.ContainsFunc(corruptionErrorMessages, func(message string) bool {
+		return strings.Contains(err.Error(), message)
+	})
+}
+
 func (c *Cache) cleanupWorker() {
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
@@ -77,6 +179,10 @@ func (c *Cache) cleanupWorker() {
 		case <-ticker.C:
 		}
 
+		if c.maxDiskUsage > 0 {
+			c.enforceMaxDiskUsage()
+		}
+
 		if err := c.db.RunValueLogGC(0.5); err != nil && !errors.Is(err, badger.ErrNoRewrite) {
 			c.logger.Error("Failed to execute cleanup", slog.Any("err", err))
 		} else {
@@ -85,22 +191,81 @@ func (c *Cache) cleanupWorker() {
 	}
 }
 
+// removes entries written by builds with a different schema or envelope format
+func (c *Cache) purgeStaleEntries() {
+	var staleKeys [][]byte
+	if err := c.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if c.cleanupWorkerCtx.Err() != nil {
+				return nil
+			}
+
+			if key := it.Item().Key(); !bytes.HasPrefix(key, []byte(keyPrefix)) {
+				staleKeys = append(staleKeys, it.Item().KeyCopy(nil))
+			}
+		}
+
+		return nil
+	}); err != nil {
+		c.logger.Error("Failed to list stale entries", slog.Any("err", err))
+		return
+	}
+
+	if len(staleKeys) == 0 {
+		return
+	}
+
+	wb := c.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, key := range staleKeys {
+		if err := wb.Delete(key); err != nil {
+			c.logger.Error("Failed to purge stale entries", slog.Any("err", err))
+			return
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		c.logger.Error("Failed to purge stale entries", slog.Any("err", err))
+		return
+	}
+
+	c.logger.Info("Purged stale entries", slog.Int("count", len(staleKeys)))
+}
+
+func (c *Cache) deleteInBackground(key string) {
+	go func() {
+		if err := c.db.Update(func(tx *badger.Txn) error {
+			return tx.Delete([]byte(keyPrefix + key))
+		}); err != nil {
+			c.logger.Error("Failed to delete value", slog.String("key", key), slog.Any("err", err))
+		}
+	}()
+}
+
 func (c *Cache) Close() {
 	c.cancelCleanupWorkerCtx()
+	if c.backupPath != "" {
+		if err := c.backupToFile(c.backupPath); err != nil {
+			c.logger.Error("Failed to back up", slog.Any("err", err))
+		}
+	}
 	c.db.Close()
 }
 
 func get[T any](c *Cache, key string) (value T, expirationDate time.Time, ok bool) {
 	err := c.db.View(func(tx *badger.Txn) error {
-		item, err := tx.Get([]byte(key))
+		item, err := tx.Get([]byte(keyPrefix + key))
 		if err != nil {
 			return err
 		}
 
 		expirationDate = time.Unix(int64(item.ExpiresAt()), 0)
 
-		return item.Value(func(itemValue []byte) error {
-			return gob.NewDecoder(bytes.NewReader(itemValue)).Decode(&value)
+		return item.Value(func(itemValue []byte) (err error) {
+			value, _, err = decodeValue[T](itemValue)
+			return
 		})
 	})
 
@@ -108,6 +273,9 @@ func get[T any](c *Cache, key string) (value T, expirationDate time.Time, ok boo
 		ok = true
 	} else if errors.Is(err, badger.ErrKeyNotFound) {
 		c.logger.Debug("Cache miss", slog.String("key", key))
+	} else if errors.Is(err, errStaleEntry) {
+		c.logger.Debug("Stale cache entry", slog.String("key", key), slog.Any("err", err))
+		c.deleteInBackground(key)
 	} else {
 		c.logger.Error("Failed to get value", slog.String("key", key), slog.Any("err", err))
 	}
@@ -115,57 +283,103 @@ func get[T any](c *Cache, key string) (value T, expirationDate time.Time, ok boo
 	return
 }
 
+func decode[T any](itemValue []byte) (any, error) {
+	value, _, err := decodeValue[T](itemValue)
+	return value, err
+}
+
+// decodes into the same type as returned by the matching Get* method
+func decodeAny(key string, itemValue []byte) (any, error) {
+	switch {
+	case key == uek.GroupingsCacheKey:
+		return decode[*uek.Groupings](itemValue)
+	case key == uek.PeriodsCacheKey:
+		return decode[[]uek.SchedulePeriod](itemValue)
+	case strings.HasPrefix(key, uek.HeadersCacheKeyPrefix):
+		return decode[[]uek.ScheduleHeader](itemValue)
+	case strings.HasPrefix(key, uek.ScheduleCacheKeyPrefix):
+		return decode[*uek.Schedule](itemValue)
+	}
+
+	return nil, fmt.Errorf("unknown key: %s", key)
+}
+
+// Walk calls fn with every non-expired entry, stopping at the first error returned by fn
+func (c *Cache) Walk(ctx context.Context, fn func(key string, value any, expirationDate time.Time) error) error {
+	return c.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{
+			PrefetchValues: true,
+			PrefetchSize:   100,
+			Prefix:         []byte(keyPrefix),
+		})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			item := it.Item()
+			key := strings.TrimPrefix(string(item.Key()), keyPrefix)
+
+			var value any
+			if err := item.Value(func(itemValue []byte) (err error) {
+				value, err = decodeAny(key, itemValue)
+				return
+			}); err != nil {
+				c.logger.Error("Failed to decode value", slog.String("key", key), slog.Any("err", err))
+				continue
+			}
+
+			if err := fn(key, value, time.Unix(int64(item.ExpiresAt()), 0)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 func put[T any](c *Cache, key string, value T, expirationDate time.Time) {
-	buff := &bytes.Buffer{}
-	if err := gob.NewEncoder(buff).Encode(value); err != nil {
+	encodedValue, err := encodeEnvelope(c.codec, c.compression, value)
+	if err != nil {
 		c.logger.Error("Failed to encode value", slog.String("key", key), slog.Any("err", err))
 		return
 	}
 
 	if err := c.db.Update(func(tx *badger.Txn) error {
-		return tx.SetEntry(badger.NewEntry([]byte(key), buff.Bytes()).WithTTL(time.Until(expirationDate)))
+		return tx.SetEntry(badger.NewEntry([]byte(keyPrefix+key), encodedValue).WithTTL(time.Until(expirationDate)))
 	}); err != nil {
 		c.logger.Error("Failed to upsert value", slog.String("key", key), slog.Any("err", err))
 	}
 }
 
-const groupingsKey = "groupings"
-const periodsKey = "periods"
-
-func makeHeadersKey(scheduleType uek.ScheduleType, groupingName string) string {
-	return fmt.Sprintf("headers-%s-%s", scheduleType, groupingName)
-}
-
-func makeScheduleKey(scheduleType uek.ScheduleType, scheduleId int, periodId int) string {
-	return fmt.Sprintf("schedule-%s-%d-%d", scheduleType, scheduleId, periodId)
-}
-
 func (c *Cache) GetGroupings(_ context.Context) (*uek.Groupings, time.Time, bool) {
-	return get[*uek.Groupings](c, groupingsKey)
+	return get[*uek.Groupings](c, uek.GroupingsCacheKey)
 }
 
 func (c *Cache) GetHeaders(_ context.Context, scheduleType uek.ScheduleType, groupingName string) ([]uek.ScheduleHeader, time.Time, bool) {
-	return get[[]uek.ScheduleHeader](c, makeHeadersKey(scheduleType, groupingName))
+	return get[[]uek.ScheduleHeader](c, uek.MakeHeadersCacheKey(scheduleType, groupingName))
 }
 
 func (c *Cache) GetSchedule(_ context.Context, scheduleType uek.ScheduleType, scheduleId int, periodId int) (*uek.Schedule, time.Time, bool) {
-	return get[*uek.Schedule](c, makeScheduleKey(scheduleType, scheduleId, periodId))
+	return get[*uek.Schedule](c, uek.MakeScheduleCacheKey(scheduleType, scheduleId, periodId))
 }
 
 func (c *Cache) GetPeriods(_ context.Context) ([]uek.SchedulePeriod, time.Time, bool) {
-	return get[[]uek.SchedulePeriod](c, periodsKey)
+	return get[[]uek.SchedulePeriod](c, uek.PeriodsCacheKey)
 }
 
 func (c *Cache) PutGroupingsAndPeriods(cacheExpirationDateGroupings time.Time, groupings *uek.Groupings, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
-	put(c, groupingsKey, groupings, cacheExpirationDateGroupings)
-	put(c, periodsKey, periods, cacheExpirationDatePeriods)
+	put(c, uek.GroupingsCacheKey, groupings, cacheExpirationDateGroupings)
+	put(c, uek.PeriodsCacheKey, periods, cacheExpirationDatePeriods)
 }
 
 func (c *Cache) PutHeaders(cacheExpirationDate time.Time, scheduleType uek.ScheduleType, groupingName string, headers []uek.ScheduleHeader) {
-	put(c, makeHeadersKey(scheduleType, groupingName), headers, cacheExpirationDate)
+	put(c, uek.MakeHeadersCacheKey(scheduleType, groupingName), headers, cacheExpirationDate)
 }
 
 func (c *Cache) PutScheduleAndPeriods(cacheExpirationDateSchedule time.Time, scheduleType uek.ScheduleType, scheduleId int, periodId int, schedule *uek.Schedule, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
-	put(c, makeScheduleKey(scheduleType, scheduleId, periodId), schedule, cacheExpirationDateSchedule)
-	put(c, periodsKey, periods, cacheExpirationDatePeriods)
+	put(c, uek.MakeScheduleCacheKey(scheduleType, scheduleId, periodId), schedule, cacheExpirationDateSchedule)
+	put(c, uek.PeriodsCacheKey, periods, cacheExpirationDatePeriods)
 }
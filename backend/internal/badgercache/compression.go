@@ -0,0 +1,73 @@
+package badgercache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression byte
+
+const (
+	CompressionNone   Compression = 0
+	CompressionZstd   Compression = 1
+	CompressionSnappy Compression = 2
+)
+
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "none":
+		return CompressionNone, nil
+	case "zstd":
+		return CompressionZstd, nil
+	case "snappy":
+		return CompressionSnappy, nil
+	}
+
+	return 0, fmt.Errorf("unknown compression: %s", name)
+}
+
+func (compression Compression) String() string {
+	switch compression {
+	case CompressionNone:
+		return "none"
+	case CompressionZstd:
+		return "zstd"
+	case CompressionSnappy:
+		return "snappy"
+	}
+
+	return fmt.Sprintf("unknown(%d)", byte(compression))
+}
+
+// EncodeAll and DecodeAll are safe for concurrent use, low concurrency keeps memory usage small
+var zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+
+func compress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	}
+
+	return nil, fmt.Errorf("unknown compression: %d", compression)
+}
+
+func decompress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	}
+
+	return nil, fmt.Errorf("%w: unknown compression %d", errStaleEntry, compression)
+}
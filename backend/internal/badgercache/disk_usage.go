@@ -0,0 +1,83 @@
+package badgercache
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// live data is evicted down to this fraction of the limit, so that garbage waiting for compaction and vlog gc
+// doesn't cause another eviction on every tick
+const diskUsageEvictionTarget = 0.75
+
+func (c *Cache) enforceMaxDiskUsage() {
+	type entryInfo struct {
+		key     []byte
+		version uint64
+		size    int64
+	}
+
+	var entries []entryInfo
+	liveSize := int64(0)
+	if err := c.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			entries = append(entries, entryInfo{
+				key:     item.KeyCopy(nil),
+				version: item.Version(),
+				size:    item.EstimatedSize(),
+			})
+			liveSize += item.EstimatedSize()
+		}
+
+		return nil
+	}); err != nil {
+		c.logger.Error("Failed to list entries for eviction", slog.Any("err", err))
+		return
+	}
+
+	// badger refreshes its size lazily and leaves out memtables, so fresh entries only show up in the live size
+	lsmSize, vlogSize := c.db.Size()
+	if max(lsmSize+vlogSize, liveSize) <= c.maxDiskUsage {
+		return
+	}
+
+	targetSize := int64(float64(c.maxDiskUsage) * diskUsageEvictionTarget)
+	if liveSize <= targetSize {
+		return
+	}
+
+	// versions grow with every write, so the lowest one is the oldest entry
+	slices.SortFunc(entries, func(a, b entryInfo) int {
+		return cmp.Compare(a.version, b.version)
+	})
+
+	wb := c.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	evictedCount := 0
+	for _, entry := range entries {
+		if liveSize <= targetSize {
+			break
+		}
+
+		if err := wb.Delete(entry.key); err != nil {
+			c.logger.Error("Failed to evict entries", slog.Any("err", err))
+			return
+		}
+		liveSize -= entry.size
+		evictedCount++
+	}
+
+	if err := wb.Flush(); err != nil {
+		c.logger.Error("Failed to evict entries", slog.Any("err", err))
+		return
+	}
+
+	c.logger.Warn("Max disk usage exceeded, evicted oldest entries", slog.Int("evicted", evictedCount), slog.Int64("lsmSize", lsmSize), slog.Int64("vlogSize", vlogSize), slog.Int64("maxDiskUsage", c.maxDiskUsage))
+}
@@ -0,0 +1,80 @@
+package badgercache
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestEnforceMaxDiskUsage(t *testing.T) {
+	const maxDiskUsage = 64 << 10
+
+	c, err := New(Config{
+		Path:         t.TempDir(),
+		MaxDiskUsage: maxDiskUsage,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	expirationDate := time.Now().Add(time.Hour)
+	headers := []uek.ScheduleHeader{{Id: 1, Name: strings.Repeat("x", 1024)}}
+	put := func(from int, to int) {
+		for i := from; i < to; i++ {
+			c.PutHeaders(expirationDate, uek.ScheduleTypeGroup, fmt.Sprint(i), headers)
+		}
+	}
+	has := func(i int) bool {
+		_, _, ok := c.GetHeaders(t.Context(), uek.ScheduleTypeGroup, fmt.Sprint(i))
+		return ok
+	}
+	liveSize := func() int64 {
+		size := int64(0)
+		c.db.View(func(tx *badger.Txn) error {
+			it := tx.NewIterator(badger.IteratorOptions{})
+			defer it.Close()
+			for it.Rewind(); it.Valid(); it.Next() {
+				size += it.Item().EstimatedSize()
+			}
+			return nil
+		})
+		return size
+	}
+
+	// under the limit nothing is evicted
+	put(0, 10)
+	c.enforceMaxDiskUsage()
+	for i := range 10 {
+		if !has(i) {
+			t.Fatalf("expected entry %d to be kept under the limit", i)
+		}
+	}
+
+	put(10, 200)
+	if size := liveSize(); size <= maxDiskUsage {
+		t.Fatalf("expected the entries to exceed the limit, got %d bytes", size)
+	}
+	c.enforceMaxDiskUsage()
+
+	if size := liveSize(); size > maxDiskUsage*diskUsageEvictionTarget {
+		t.Errorf("expected live entries to be evicted down to the target, got %d bytes", size)
+	}
+	for _, i := range []int{0, 9, 100} {
+		if has(i) {
+			t.Errorf("expected old entry %d to be evicted", i)
+		}
+	}
+	for _, i := range []int{180, 199} {
+		if !has(i) {
+			t.Errorf("expected new entry %d to be kept", i)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package badgercache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// bump when the envelope layout or payload encoding changes
+const formatVersion byte = 3
+
+// format version, codec id, compression id, created-at unix millis
+const envelopeHeaderSize = 1 + 1 + 1 + 8
+
+type Codec byte
+
+const (
+	CodecGob     Codec = 1
+	CodecMsgpack Codec = 2
+)
+
+func ParseCodec(name string) (Codec, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "gob":
+		return CodecGob, nil
+	case "msgpack":
+		return CodecMsgpack, nil
+	}
+
+	return 0, fmt.Errorf("unknown codec: %s", name)
+}
+
+func (codec Codec) String() string {
+	switch codec {
+	case CodecGob:
+		return "gob"
+	case CodecMsgpack:
+		return "msgpack"
+	}
+
+	return fmt.Sprintf("unknown(%d)", byte(codec))
+}
+
+var errStaleEntry = errors.New("stale entry")
+
+type envelope struct {
+	codec       Codec
+	compression Compression
+	createdAt   time.Time
+	payload     []byte
+}
+
+func encodeEnvelope(codec Codec, compression Compression, value any) ([]byte, error) {
+	buff := &bytes.Buffer{}
+
+	var err error
+	switch codec {
+	case CodecGob:
+		err = gob.NewEncoder(buff).Encode(value)
+	case CodecMsgpack:
+		err = msgpack.NewEncoder(buff).Encode(value)
+	default:
+		err = fmt.Errorf("unknown codec: %d", codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := compress(compression, buff.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	encodedValue := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(payload))
+	encodedValue[0] = formatVersion
+	encodedValue[1] = byte(codec)
+	encodedValue[2] = byte(compression)
+	binary.BigEndian.PutUint64(encodedValue[3:envelopeHeaderSize], uint64(time.Now().UnixMilli()))
+
+	return append(encodedValue, payload...), nil
+}
+
+func decodeEnvelope(raw []byte) (envelope, error) {
+	if len(raw) < envelopeHeaderSize {
+		return envelope{}, fmt.Errorf("%w: envelope too short", errStaleEntry)
+	}
+	if raw[0] != formatVersion {
+		return envelope{}, fmt.Errorf("%w: format version %d", errStaleEntry, raw[0])
+	}
+
+	return envelope{
+		codec:       Codec(raw[1]),
+		compression: Compression(raw[2]),
+		createdAt:   time.UnixMilli(int64(binary.BigEndian.Uint64(raw[3:envelopeHeaderSize]))),
+		payload:     raw[envelopeHeaderSize:],
+	}, nil
+}
+
+func decodeValue[T any](raw []byte) (value T, createdAt time.Time, err error) {
+	env, err := decodeEnvelope(raw)
+	if err != nil {
+		return
+	}
+	createdAt = env.createdAt
+
+	payload, err := decompress(env.compression, env.payload)
+	if err != nil {
+		return
+	}
+
+	switch env.codec {
+	case CodecGob:
+		err = gob.NewDecoder(bytes.NewReader(payload)).Decode(&value)
+	case CodecMsgpack:
+		if err = msgpack.Unmarshal(payload, &value); err == nil {
+			restoreMsgpackTimeLocations(value)
+		}
+	default:
+		err = fmt.Errorf("%w: unknown codec %d", errStaleEntry, env.codec)
+	}
+
+	return
+}
+
+// msgpack timestamps don't carry a zone and decode in time.Local, cached times are always in uek.Location().
+// msgpack extensions are registered process-wide, so this isn't done with a custom time extension
+func restoreMsgpackTimeLocations(value any) {
+	switch typedValue := value.(type) {
+	case []uek.SchedulePeriod:
+		for i := range typedValue {
+			typedValue[i].Start = typedValue[i].Start.In(uek.Location())
+			typedValue[i].End = typedValue[i].End.In(uek.Location())
+		}
+	case *uek.Schedule:
+		if typedValue == nil {
+			return
+		}
+		for _, item := range typedValue.Items {
+			item.Start = item.Start.In(uek.Location())
+			item.End = item.End.In(uek.Location())
+		}
+	}
+}
+
+// keys are namespaced by a fingerprint of the cached types, so entries written by a build with different structs
+// are never decoded and get purged in the background
+var keyPrefix = fmt.Sprintf("v%d-%08x/", formatVersion, schemaFingerprint(
+	reflect.TypeFor[uek.Groupings](),
+	reflect.TypeFor[uek.SchedulePeriod](),
+	reflect.TypeFor[uek.ScheduleHeader](),
+	reflect.TypeFor[uek.Schedule](),
+))
+
+func schemaFingerprint(types ...reflect.Type) uint32 {
+	h := fnv.New32a()
+	visited := map[reflect.Type]bool{}
+
+	var describe func(t reflect.Type)
+	describe = func(t reflect.Type) {
+		fmt.Fprintf(h, "%s:%s;", t.Kind(), t.String())
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array:
+			describe(t.Elem())
+		case reflect.Map:
+			describe(t.Key())
+			describe(t.Elem())
+		case reflect.Struct:
+			// time.Time has its own stable binary encoding
+			if t == reflect.TypeFor[time.Time]() {
+				return
+			}
+			for i := range t.NumField() {
+				field := t.Field(i)
+				fmt.Fprintf(h, "%s %s;", field.Name, field.Tag)
+				describe(field.Type)
+			}
+		}
+	}
+
+	for _, t := range types {
+		describe(t)
+	}
+
+	return h.Sum32()
+}
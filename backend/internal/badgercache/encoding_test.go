@@ -0,0 +1,255 @@
+package badgercache
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func newTestCache(t *testing.T, codec Codec, compression Compression) *Cache {
+	t.Helper()
+
+	c, err := New(Config{
+		Codec:       codec,
+		Compression: compression,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func testSchedule() *uek.Schedule {
+	return &uek.Schedule{
+		Header: uek.ScheduleHeader{Id: 1, Name: "KrDUIs1011"},
+		Items: []*uek.ScheduleItem{
+			{
+				Start:     time.Date(2025, 3, 30, 8, 0, 0, 0, uek.Location()),
+				End:       time.Date(2025, 3, 30, 9, 30, 0, 0, uek.Location()),
+				Subject:   "Algebra",
+				Type:      "wykład",
+				Groups:    []string{"KrDUIs1011", "KrDUIs1012"},
+				Lecturers: []uek.ScheduleItemLecturer{{Name: "dr Jan Kowalski", MoodleId: 123}},
+				Room:      &uek.ScheduleItemRoom{Name: "Paw.A 011"},
+			},
+			{
+				Start:   time.Date(2025, 10, 26, 11, 30, 0, 0, uek.Location()),
+				End:     time.Date(2025, 10, 26, 13, 0, 0, 0, uek.Location()),
+				Subject: "Programowanie",
+				Type:    "ćwiczenia",
+				Room:    &uek.ScheduleItemRoom{Name: "Teams", URL: "https://teams.microsoft.com/l/meetup-join/1"},
+			},
+		},
+	}
+}
+
+func testPeriods() []uek.SchedulePeriod {
+	return []uek.SchedulePeriod{
+		{Id: 3, Start: time.Date(2024, 10, 1, 0, 0, 0, 0, uek.Location()), End: time.Date(2025, 9, 30, 0, 0, 0, 0, uek.Location())},
+		{Id: -1, Start: time.Date(2023, 10, 1, 0, 0, 0, 0, uek.Location()), End: time.Date(2024, 2, 28, 0, 0, 0, 0, uek.Location()), Archived: true},
+	}
+}
+
+func assertSameJSON(t *testing.T, expected any, actual any) {
+	t.Helper()
+
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualJSON, err := json.Marshal(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expectedJSON) != string(actualJSON) {
+		t.Errorf("expected %s, got %s", expectedJSON, actualJSON)
+	}
+}
+
+var testCodecs = []Codec{CodecGob, CodecMsgpack}
+
+var testCompressions = []Compression{CompressionNone, CompressionZstd, CompressionSnappy}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	for _, codec := range testCodecs {
+		for _, compression := range testCompressions {
+			t.Run(codec.String()+"/"+compression.String(), func(t *testing.T) {
+				before := time.Now().Add(-time.Second)
+
+				raw, err := encodeEnvelope(codec, compression, testSchedule())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				env, err := decodeEnvelope(raw)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if env.codec != codec || env.compression != compression {
+					t.Errorf("expected %s/%s, got %s/%s", codec, compression, env.codec, env.compression)
+				}
+
+				schedule, createdAt, err := decodeValue[*uek.Schedule](raw)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if createdAt.Before(before) || createdAt.After(time.Now()) {
+					t.Errorf("unexpected createdAt: %s", createdAt)
+				}
+				assertSameJSON(t, testSchedule(), schedule)
+			})
+		}
+	}
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	expirationDate := time.Now().Add(time.Hour)
+	groupings := &uek.Groupings{Groups: []string{"KrDUIs1"}, Rooms: []string{"Paw.A"}}
+	headers := []uek.ScheduleHeader{{Id: 1, Name: "KrDUIs1011"}}
+
+	for _, codec := range testCodecs {
+		for _, compression := range testCompressions {
+			t.Run(codec.String()+"/"+compression.String(), func(t *testing.T) {
+				c := newTestCache(t, codec, compression)
+				c.PutGroupingsAndPeriods(expirationDate, groupings, expirationDate, testPeriods())
+				c.PutHeaders(expirationDate, uek.ScheduleTypeGroup, "KrDUIs1", headers)
+				c.PutScheduleAndPeriods(expirationDate, uek.ScheduleTypeGroup, 1, 3, testSchedule(), expirationDate, testPeriods())
+
+				cachedGroupings, _, ok := c.GetGroupings(t.Context())
+				if !ok {
+					t.Fatal("groupings not found")
+				}
+				assertSameJSON(t, groupings, cachedGroupings)
+
+				cachedHeaders, _, ok := c.GetHeaders(t.Context(), uek.ScheduleTypeGroup, "KrDUIs1")
+				if !ok {
+					t.Fatal("headers not found")
+				}
+				assertSameJSON(t, headers, cachedHeaders)
+
+				cachedPeriods, _, ok := c.GetPeriods(t.Context())
+				if !ok {
+					t.Fatal("periods not found")
+				}
+				assertSameJSON(t, testPeriods(), cachedPeriods)
+
+				cachedSchedule, cachedExpirationDate, ok := c.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 1, 3)
+				if !ok {
+					t.Fatal("schedule not found")
+				}
+				assertSameJSON(t, testSchedule(), cachedSchedule)
+				if cachedExpirationDate.Unix() != expirationDate.Unix() {
+					t.Errorf("expected expiration date %s, got %s", expirationDate, cachedExpirationDate)
+				}
+
+				if _, _, ok := c.GetSchedule(t.Context(), uek.ScheduleTypeGroup, 2, 3); ok {
+					t.Error("expected a miss for a schedule that wasn't put")
+				}
+			})
+		}
+	}
+}
+
+func TestMsgpackTimesDecodeInUEKLocation(t *testing.T) {
+	raw, err := encodeEnvelope(CodecMsgpack, CompressionNone, testSchedule())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule, _, err := decodeValue[*uek.Schedule](raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range schedule.Items {
+		if item.Start.Location() != uek.Location() || item.End.Location() != uek.Location() {
+			t.Errorf("expected times in %s, got %s and %s", uek.Location(), item.Start.Location(), item.End.Location())
+		}
+	}
+
+	raw, err = encodeEnvelope(CodecMsgpack, CompressionNone, testPeriods())
+	if err != nil {
+		t.Fatal(err)
+	}
+	periods, _, err := decodeValue[[]uek.SchedulePeriod](raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, period := range periods {
+		if period.Start.Location() != uek.Location() || period.End.Location() != uek.Location() {
+			t.Errorf("expected times in %s, got %s and %s", uek.Location(), period.Start.Location(), period.End.Location())
+		}
+	}
+}
+
+func TestMsgpackDefaultTimeExtensionUntouched(t *testing.T) {
+	encoded, err := msgpack.Marshal(time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fixext 4/8/ext 8 followed by the -1 timestamp extension type
+	if len(encoded) < 2 || (encoded[1] != 0xff && encoded[2] != 0xff) {
+		t.Errorf("expected the standard msgpack timestamp extension, got %x", encoded)
+	}
+}
+
+func TestDecodeStaleEntries(t *testing.T) {
+	valid, err := encodeEnvelope(CodecGob, CompressionNone, testPeriods())
+	if err != nil {
+		t.Fatal(err)
+	}
+	withByte := func(index int, value byte) []byte {
+		raw := append([]byte{}, valid...)
+		raw[index] = value
+		return raw
+	}
+
+	for _, tc := range []struct {
+		name string
+		raw  []byte
+	}{
+		{name: "empty", raw: nil},
+		{name: "too short", raw: valid[:envelopeHeaderSize-1]},
+		{name: "old format version", raw: withByte(0, formatVersion-1)},
+		{name: "unknown codec", raw: withByte(1, 0xee)},
+		{name: "unknown compression", raw: withByte(2, 0xee)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := decodeValue[[]uek.SchedulePeriod](tc.raw); !errors.Is(err, errStaleEntry) {
+				t.Errorf("expected errStaleEntry, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPurgeStaleEntries(t *testing.T) {
+	c := newTestCache(t, CodecGob, CompressionNone)
+	c.PutHeaders(time.Now().Add(time.Hour), uek.ScheduleTypeGroup, "KrDUIs1", []uek.ScheduleHeader{{Id: 1, Name: "KrDUIs1011"}})
+
+	staleKey := []byte("v2-00000000/" + uek.PeriodsCacheKey)
+	if err := c.db.Update(func(tx *badger.Txn) error {
+		return tx.Set(staleKey, []byte("stale"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.purgeStaleEntries()
+
+	if err := c.db.View(func(tx *badger.Txn) error {
+		_, err := tx.Get(staleKey)
+		return err
+	}); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected the stale key to be purged, got %v", err)
+	}
+	if _, _, ok := c.GetHeaders(t.Context(), uek.ScheduleTypeGroup, "KrDUIs1"); !ok {
+		t.Error("expected current entries to survive the purge")
+	}
+}
@@ -0,0 +1,107 @@
+package badgercache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+type Preset string
+
+const (
+	PresetDefault Preset = "default"
+	// for small VMs, trades write throughput and read latency for a few MB of RAM
+	PresetLowMemory Preset = "lowmemory"
+)
+
+func ParsePreset(name string) (Preset, error) {
+	switch preset := Preset(strings.ToLower(strings.TrimSpace(name))); preset {
+	case "":
+		return PresetDefault, nil
+	case PresetDefault, PresetLowMemory:
+		return preset, nil
+	}
+
+	return "", fmt.Errorf("unknown preset: %s", name)
+}
+
+// Tuning holds badger options, zero values are taken from the preset, or badger defaults if the preset doesn't set them
+type Tuning struct {
+	ValueLogFileSize        int64
+	MemTableSize            int64
+	ValueThreshold          int64
+	NumMemtables            int
+	NumLevelZeroTables      int
+	NumLevelZeroTablesStall int
+	BlockCacheSize          int64
+	IndexCacheSize          int64
+	NumCompactors           int
+}
+
+var presetTunings = map[Preset]Tuning{
+	PresetDefault: {
+		ValueLogFileSize:   5 << 20,
+		MemTableSize:       4 << 20,
+		ValueThreshold:     512 << 10,
+		NumLevelZeroTables: 2,
+	},
+	PresetLowMemory: {
+		ValueLogFileSize:        2 << 20,
+		MemTableSize:            1 << 20,
+		ValueThreshold:          64 << 10,
+		NumMemtables:            2,
+		NumLevelZeroTables:      1,
+		NumLevelZeroTablesStall: 2,
+		BlockCacheSize:          8 << 20,
+		IndexCacheSize:          4 << 20,
+		NumCompactors:           2,
+	},
+}
+
+// fields set in other override fields of t
+func (t Tuning) merge(other Tuning) Tuning {
+	pick := func(value, otherValue int64) int64 {
+		if otherValue != 0 {
+			return otherValue
+		}
+		return value
+	}
+
+	return Tuning{
+		ValueLogFileSize:        pick(t.ValueLogFileSize, other.ValueLogFileSize),
+		MemTableSize:            pick(t.MemTableSize, other.MemTableSize),
+		ValueThreshold:          pick(t.ValueThreshold, other.ValueThreshold),
+		NumMemtables:            int(pick(int64(t.NumMemtables), int64(other.NumMemtables))),
+		NumLevelZeroTables:      int(pick(int64(t.NumLevelZeroTables), int64(other.NumLevelZeroTables))),
+		NumLevelZeroTablesStall: int(pick(int64(t.NumLevelZeroTablesStall), int64(other.NumLevelZeroTablesStall))),
+		BlockCacheSize:          pick(t.BlockCacheSize, other.BlockCacheSize),
+		IndexCacheSize:          pick(t.IndexCacheSize, other.IndexCacheSize),
+		NumCompactors:           int(pick(int64(t.NumCompactors), int64(other.NumCompactors))),
+	}
+}
+
+func (t Tuning) apply(opts badger.Options) badger.Options {
+	t = Tuning{
+		ValueLogFileSize:        opts.ValueLogFileSize,
+		MemTableSize:            opts.MemTableSize,
+		ValueThreshold:          opts.ValueThreshold,
+		NumMemtables:            opts.NumMemtables,
+		NumLevelZeroTables:      opts.NumLevelZeroTables,
+		NumLevelZeroTablesStall: opts.NumLevelZeroTablesStall,
+		BlockCacheSize:          opts.BlockCacheSize,
+		IndexCacheSize:          opts.IndexCacheSize,
+		NumCompactors:           opts.NumCompactors,
+	}.merge(t)
+
+	return opts.
+		WithValueLogFileSize(t.ValueLogFileSize).
+		WithMemTableSize(t.MemTableSize).
+		WithValueThreshold(t.ValueThreshold).
+		WithNumMemtables(t.NumMemtables).
+		WithNumLevelZeroTables(t.NumLevelZeroTables).
+		WithNumLevelZeroTablesStall(t.NumLevelZeroTablesStall).
+		WithBlockCacheSize(t.BlockCacheSize).
+		WithIndexCacheSize(t.IndexCacheSize).
+		WithNumCompactors(t.NumCompactors)
+}
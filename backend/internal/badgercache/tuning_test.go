@@ -0,0 +1,57 @@
+package badgercache
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func TestParsePreset(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected Preset
+		valid    bool
+	}{
+		{name: "", expected: PresetDefault, valid: true},
+		{name: "default", expected: PresetDefault, valid: true},
+		{name: " LowMemory ", expected: PresetLowMemory, valid: true},
+		{name: "tiny"},
+	} {
+		preset, err := ParsePreset(tc.name)
+		if (err == nil) != tc.valid || preset != tc.expected {
+			t.Errorf("%q: expected %q and valid %t, got %q and %v", tc.name, tc.expected, tc.valid, preset, err)
+		}
+	}
+}
+
+func TestTuningApply(t *testing.T) {
+	defaults := badger.DefaultOptions("")
+
+	// fields set in the override win, the rest come from the preset, then badger defaults
+	opts := presetTunings[PresetDefault].merge(Tuning{MemTableSize: 8 << 20, NumCompactors: 3}).apply(defaults)
+	if opts.MemTableSize != 8<<20 || opts.NumCompactors != 3 {
+		t.Errorf("expected overridden fields, got memtable size %d and %d compactors", opts.MemTableSize, opts.NumCompactors)
+	}
+	if opts.ValueLogFileSize != 5<<20 || opts.ValueThreshold != 512<<10 || opts.NumLevelZeroTables != 2 {
+		t.Errorf("expected preset fields, got vlog file size %d, value threshold %d and %d level zero tables", opts.ValueLogFileSize, opts.ValueThreshold, opts.NumLevelZeroTables)
+	}
+	if opts.NumMemtables != defaults.NumMemtables || opts.BlockCacheSize != defaults.BlockCacheSize || opts.NumLevelZeroTablesStall != defaults.NumLevelZeroTablesStall {
+		t.Errorf("expected badger defaults for fields the preset doesn't set, got %d memtables, block cache %d and stall at %d", opts.NumMemtables, opts.BlockCacheSize, opts.NumLevelZeroTablesStall)
+	}
+
+	lowMemory := presetTunings[PresetLowMemory]
+	opts = lowMemory.merge(Tuning{}).apply(defaults)
+	if got := (Tuning{
+		ValueLogFileSize:        opts.ValueLogFileSize,
+		MemTableSize:            opts.MemTableSize,
+		ValueThreshold:          opts.ValueThreshold,
+		NumMemtables:            opts.NumMemtables,
+		NumLevelZeroTables:      opts.NumLevelZeroTables,
+		NumLevelZeroTablesStall: opts.NumLevelZeroTablesStall,
+		BlockCacheSize:          opts.BlockCacheSize,
+		IndexCacheSize:          opts.IndexCacheSize,
+		NumCompactors:           opts.NumCompactors,
+	}); got != lowMemory {
+		t.Errorf("expected every low memory field to be applied, got %+v", got)
+	}
+}
@@ -5,18 +5,32 @@ import (
 )
 
 type Config struct {
-	Debug       bool
-	Addr        string
+	Debug         bool
+	Addr          string
+	UekBaseUrl    string
+	StrictParsing bool
+	// enables admin api when set
+	AdminToken  string
 	Mock        Mock
 	CacheTimes  CacheTimes
+	MemoryCache MemoryCache
 	BadgerCache BadgerCache
+	Archive     Archive
+	Webhooks    Webhooks
+	Digest      Digest
+	SMTP        SMTP
+	WebPush     WebPush
+	Occupancy   Occupancy
 }
 
 type Mock struct {
 	Enabled       bool
 	Passthrough   bool
 	Delay         time.Duration
+	Latency       string
+	Faults        string
 	DirectoryPath string
+	ServerAddr    string
 }
 
 type CacheTimes struct {
@@ -24,32 +38,175 @@ type CacheTimes struct {
 	Headers   time.Duration
 	Schedules time.Duration
 	Periods   time.Duration
+	// json policy overriding Schedules, file takes precedence over inline
+	SchedulesPolicyFile string
+	SchedulesPolicy     string
 }
 
-type BadgerCache struct {
+type MemoryCache struct {
+	Enabled bool
+	// in bytes
+	MaxSize int64
+}
+
+type Archive struct {
+	Enabled bool
+	Path    string
+}
+
+type Webhooks struct {
+	// subscriptions are managed through the admin api
+	Enabled      bool
+	Path         string
+	PollInterval time.Duration
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	DisableAfter int
+}
+
+type Digest struct {
+	Enabled bool
+	Path    string
+	// public url of the app, links in emails point to it
+	BaseUrl string
+	// weekday and time in Warsaw time, e.g. "sunday 18:00"
+	SendAt string
+}
+
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// "starttls", "tls" or "none"
+	Security string
+	From     string
+}
+
+type WebPush struct {
+	Enabled bool
+	Path    string
+	// contact for push service operators, "mailto:" or "https:" url
+	Subject string
+	// base64url encoded P-256 key, generated on first start when empty
+	VAPIDPrivateKey string
+	ReminderLead    time.Duration
+}
+
+type Occupancy struct {
 	Enabled bool
 	Path    string
+	// how often the report for the current period is rebuilt, only on demand when 0
+	Interval time.Duration
+}
+
+type BadgerCache struct {
+	Enabled     bool
+	Path        string
+	Codec       string
+	Compression string
+	// "default" or "lowmemory"
+	Preset string
+	// in bytes, 0 disables the limit
+	MaxDiskUsage   int64
+	BackupPath     string
+	BackupInterval time.Duration
+	// 0 keeps preset value
+	ValueLogFileSize        int64
+	MemTableSize            int64
+	ValueThreshold          int64
+	NumMemtables            int
+	NumLevelZeroTables      int
+	NumLevelZeroTablesStall int
+	BlockCacheSize          int64
+	IndexCacheSize          int64
+	NumCompactors           int
 }
 
 func FromEnv() Config {
 	return Config{
-		Debug: getEnvBoolWithDefault("DEBUG", false),
-		Addr:  getEnvStringWithDefault("ADDR", ":3001"),
+		Debug:         getEnvBoolWithDefault("DEBUG", false),
+		Addr:          getEnvStringWithDefault("ADDR", ":3001"),
+		UekBaseUrl:    getEnvString("UEK_BASE_URL"),
+		StrictParsing: getEnvBoolWithDefault("STRICT_PARSING", false),
+		AdminToken:    getEnvString("ADMIN_TOKEN"),
 		Mock: Mock{
 			Enabled:       getEnvBoolWithDefault("MOCK", false),
 			Passthrough:   getEnvBoolWithDefault("MOCK_PASSTHROUGH", true),
 			Delay:         getEnvDurationWithDefault("MOCK_DELAY", time.Second),
+			Latency:       getEnvString("MOCK_LATENCY"),
+			Faults:        getEnvString("MOCK_FAULTS"),
 			DirectoryPath: getEnvStringWithDefault("MOCK_DIR", "./mock"),
+			ServerAddr:    getEnvStringWithDefault("MOCK_SERVER_ADDR", ":3002"),
 		},
 		CacheTimes: CacheTimes{
-			Groupings: getEnvDurationWithDefault("CACHETIME_GROUPINGS", time.Hour),
-			Headers:   getEnvDurationWithDefault("CACHETIME_HEADERS", time.Hour),
-			Schedules: getEnvDurationWithDefault("CACHETIME_SCHEDULES", 15*time.Minute),
-			Periods:   getEnvDurationWithDefault("CACHETIME_PERIODS", time.Hour),
+			Groupings:           getEnvDurationWithDefault("CACHETIME_GROUPINGS", time.Hour),
+			Headers:             getEnvDurationWithDefault("CACHETIME_HEADERS", time.Hour),
+			Schedules:           getEnvDurationWithDefault("CACHETIME_SCHEDULES", 15*time.Minute),
+			Periods:             getEnvDurationWithDefault("CACHETIME_PERIODS", time.Hour),
+			SchedulesPolicyFile: getEnvString("CACHETIME_SCHEDULES_POLICY_FILE"),
+			SchedulesPolicy:     getEnvString("CACHETIME_SCHEDULES_POLICY"),
+		},
+		MemoryCache: MemoryCache{
+			Enabled: getEnvBoolWithDefault("MEMORY_CACHE_ENABLED", false),
+			MaxSize: getEnvByteSizeWithDefault("MEMORY_CACHE_MAX_SIZE", 64<<20),
+		},
+		Archive: Archive{
+			Enabled: getEnvBoolWithDefault("ARCHIVE_ENABLED", false),
+			Path:    getEnvStringWithDefault("ARCHIVE_PATH", "./archive"),
+		},
+		Webhooks: Webhooks{
+			Enabled:      getEnvBoolWithDefault("WEBHOOKS_ENABLED", false),
+			Path:         getEnvStringWithDefault("WEBHOOKS_PATH", "./webhooks"),
+			PollInterval: getEnvDurationWithDefault("WEBHOOKS_POLL_INTERVAL", 15*time.Minute),
+			MaxAttempts:  getEnvIntWithDefault("WEBHOOKS_MAX_ATTEMPTS", 5),
+			RetryBackoff: getEnvDurationWithDefault("WEBHOOKS_RETRY_BACKOFF", 30*time.Second),
+			DisableAfter: getEnvIntWithDefault("WEBHOOKS_DISABLE_AFTER", 5),
+		},
+		Digest: Digest{
+			Enabled: getEnvBoolWithDefault("DIGEST_ENABLED", false),
+			Path:    getEnvStringWithDefault("DIGEST_PATH", "./digest"),
+			BaseUrl: getEnvString("DIGEST_BASE_URL"),
+			SendAt:  getEnvStringWithDefault("DIGEST_SEND_AT", "sunday 18:00"),
+		},
+		SMTP: SMTP{
+			Host:     getEnvStringWithDefault("SMTP_HOST", "localhost"),
+			Port:     getEnvIntWithDefault("SMTP_PORT", 587),
+			Username: getEnvString("SMTP_USERNAME"),
+			Password: getEnvString("SMTP_PASSWORD"),
+			Security: getEnvStringWithDefault("SMTP_SECURITY", "starttls"),
+			From:     getEnvString("SMTP_FROM"),
+		},
+		WebPush: WebPush{
+			Enabled:         getEnvBoolWithDefault("WEBPUSH_ENABLED", false),
+			Path:            getEnvStringWithDefault("WEBPUSH_PATH", "./webpush"),
+			Subject:         getEnvString("WEBPUSH_SUBJECT"),
+			VAPIDPrivateKey: getEnvString("WEBPUSH_VAPID_PRIVATE_KEY"),
+			ReminderLead:    getEnvDurationWithDefault("WEBPUSH_REMINDER_LEAD", 15*time.Minute),
+		},
+		Occupancy: Occupancy{
+			Enabled:  getEnvBoolWithDefault("OCCUPANCY_ENABLED", false),
+			Path:     getEnvStringWithDefault("OCCUPANCY_PATH", "./occupancy"),
+			Interval: getEnvDurationWithDefault("OCCUPANCY_INTERVAL", 24*time.Hour),
 		},
 		BadgerCache: BadgerCache{
-			Enabled: getEnvBoolWithDefault("BADGER_CACHE_ENABLED", false),
-			Path:    getEnvString("BADGER_CACHE_PATH"),
+			Enabled:                 getEnvBoolWithDefault("BADGER_CACHE_ENABLED", false),
+			Path:                    getEnvString("BADGER_CACHE_PATH"),
+			Codec:                   getEnvStringWithDefault("BADGER_CACHE_CODEC", "gob"),
+			Compression:             getEnvStringWithDefault("BADGER_CACHE_COMPRESSION", "none"),
+			Preset:                  getEnvStringWithDefault("BADGER_CACHE_PRESET", "default"),
+			MaxDiskUsage:            getEnvByteSizeWithDefault("BADGER_CACHE_MAX_DISK_USAGE", 0),
+			BackupPath:              getEnvString("BADGER_CACHE_BACKUP_PATH"),
+			BackupInterval:          getEnvDurationWithDefault("BADGER_CACHE_BACKUP_INTERVAL", time.Hour),
+			ValueLogFileSize:        getEnvByteSizeWithDefault("BADGER_CACHE_VALUE_LOG_FILE_SIZE", 0),
+			MemTableSize:            getEnvByteSizeWithDefault("BADGER_CACHE_MEMTABLE_SIZE", 0),
+			ValueThreshold:          getEnvByteSizeWithDefault("BADGER_CACHE_VALUE_THRESHOLD", 0),
+			NumMemtables:            getEnvIntWithDefault("BADGER_CACHE_NUM_MEMTABLES", 0),
+			NumLevelZeroTables:      getEnvIntWithDefault("BADGER_CACHE_NUM_LEVEL_ZERO_TABLES", 0),
+			NumLevelZeroTablesStall: getEnvIntWithDefault("BADGER_CACHE_NUM_LEVEL_ZERO_TABLES_STALL", 0),
+			BlockCacheSize:          getEnvByteSizeWithDefault("BADGER_CACHE_BLOCK_CACHE_SIZE", 0),
+			IndexCacheSize:          getEnvByteSizeWithDefault("BADGER_CACHE_INDEX_CACHE_SIZE", 0),
+			NumCompactors:           getEnvIntWithDefault("BADGER_CACHE_NUM_COMPACTORS", 0),
 		},
 	}
 }
@@ -28,6 +28,14 @@ func getEnvBoolWithDefault(key string, defaultValue bool) bool {
 	return value
 }
 
+func getEnvIntWithDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnvString(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
 	value, err := time.ParseDuration(getEnvString(key))
 	if err != nil {
@@ -36,3 +44,22 @@ func getEnvDurationWithDefault(key string, defaultValue time.Duration) time.Dura
 
 	return max(value, 0)
 }
+
+// accepts plain byte counts and KB/MB/GB suffixes (powers of 1024), e.g. "64MB"
+func getEnvByteSizeWithDefault(key string, defaultValue int64) int64 {
+	value := strings.ToUpper(getEnvString(key))
+	multiplier := int64(1)
+	for suffix, suffixMultiplier := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if trimmedValue, ok := strings.CutSuffix(value, suffix); ok {
+			value, multiplier = strings.TrimSpace(trimmedValue), suffixMultiplier
+			break
+		}
+	}
+
+	parsedValue, err := strconv.ParseInt(strings.TrimSuffix(value, "B"), 10, 64)
+	if err != nil || parsedValue <= 0 {
+		return defaultValue
+	}
+
+	return parsedValue * multiplier
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestGetEnvByteSizeWithDefault(t *testing.T) {
+	const defaultValue = 123
+
+	testCases := []struct {
+		value    string
+		expected int64
+	}{
+		{value: "", expected: defaultValue},
+		{value: "1024", expected: 1024},
+		{value: "1024B", expected: 1024},
+		{value: "64KB", expected: 64 << 10},
+		{value: "64MB", expected: 64 << 20},
+		{value: " 2 gb ", expected: 2 << 30},
+		{value: "1.5MB", expected: defaultValue},
+		{value: "0", expected: defaultValue},
+		{value: "-5MB", expected: defaultValue},
+		{value: "64TB", expected: defaultValue},
+		{value: "lots", expected: defaultValue},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.value, func(t *testing.T) {
+			t.Setenv("UEKPZ3_TEST_BYTE_SIZE", testCase.value)
+
+			if value := getEnvByteSizeWithDefault("TEST_BYTE_SIZE", defaultValue); value != testCase.expected {
+				t.Errorf("expected %d, got %d", testCase.expected, value)
+			}
+		})
+	}
+}
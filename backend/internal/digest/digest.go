@@ -0,0 +1,352 @@
+package digest
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	MaxScheduleIdsPerSubscription = 20
+	// confirmed and pending, per email address
+	maxSubscriptionsPerEmail = 5
+	// unconfirmed subscriptions are removed after this
+	confirmationTTL = 48 * time.Hour
+	// an address isn't sent another confirmation before this passes, whichever subscription it's for
+	confirmationResendInterval = 10 * time.Minute
+	// confirmations sent to an address across all of its subscriptions, so subscribing can't be used to flood an inbox
+	maxConfirmationsPerEmailPerDay = 5
+)
+
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+type Config struct {
+	// directory for subscriptions and schedule snapshots
+	Path string
+	Uek  *uek.Client
+	SMTP SMTPConfig
+	// public url of the app, confirmation and unsubscribe links point to it
+	BaseUrl string
+	// when digests are sent, in Warsaw time
+	SendAt SendTime
+	Logger *slog.Logger
+}
+
+type Subscription struct {
+	Id           string           `json:"id"`
+	Email        string           `json:"email"`
+	ScheduleType uek.ScheduleType `json:"scheduleType"`
+	ScheduleIds  []int            `json:"scheduleIds"`
+	// names of the schedules when subscribing, for emails
+	ScheduleNames []string `json:"scheduleNames,omitempty"`
+	// authorizes confirming and unsubscribing, only ever sent to the subscriber
+	Token              string    `json:"token,omitempty"`
+	CreatedAt          time.Time `json:"createdAt"`
+	ConfirmationSentAt time.Time `json:"confirmationSentAt"`
+	ConfirmedAt        time.Time `json:"confirmedAt,omitzero"`
+	LastSentAt         time.Time `json:"lastSentAt,omitzero"`
+}
+
+func (sub *Subscription) IsConfirmed() bool {
+	return !sub.ConfirmedAt.IsZero()
+}
+
+type SubscribeRequest struct {
+	Email        string           `json:"email"`
+	ScheduleType uek.ScheduleType `json:"scheduleType"`
+	ScheduleIds  []int            `json:"scheduleIds"`
+}
+
+type Manager struct {
+	cfg           Config
+	logger        *slog.Logger
+	mu            sync.Mutex
+	subscriptions []*Subscription
+	// when confirmations were sent in the last day, per email address
+	confirmationsSentAt map[string][]time.Time
+	snapshotMu          sync.Mutex
+	// serializes digest runs, so a manual send can't race the scheduled one
+	sendMu    sync.Mutex
+	mailWg    sync.WaitGroup
+	ctx       context.Context
+	cancelCtx context.CancelFunc
+}
+
+func New(cfg Config) (*Manager, error) {
+	if cfg.BaseUrl == "" {
+		return nil, errors.New("base url is required for links in emails")
+	}
+	if _, err := mail.ParseAddress(cfg.SMTP.From); err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	cfg.BaseUrl = strings.TrimSuffix(cfg.BaseUrl, "/")
+
+	m := &Manager{
+		cfg:                 cfg,
+		logger:              cfg.Logger,
+		subscriptions:       []*Subscription{},
+		confirmationsSentAt: map[string][]time.Time{},
+	}
+	if m.logger == nil {
+		m.logger = slog.Default()
+	}
+
+	if err := jsonfile.Read(m.subscriptionsFilePath(), &m.subscriptions); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+	// restarting doesn't reset the limit for pending confirmations
+	for _, sub := range m.subscriptions {
+		if !sub.ConfirmationSentAt.IsZero() {
+			m.confirmationsSentAt[sub.Email] = append(m.confirmationsSentAt[sub.Email], sub.ConfirmationSentAt)
+		}
+	}
+	for _, sentAt := range m.confirmationsSentAt {
+		slices.SortFunc(sentAt, time.Time.Compare)
+	}
+
+	m.ctx, m.cancelCtx = context.WithCancel(context.Background())
+	go m.scheduler()
+
+	return m, nil
+}
+
+// Close stops the scheduler and waits for emails being sent
+func (m *Manager) Close() {
+	m.cancelCtx()
+	m.mailWg.Wait()
+	m.sendMu.Lock()
+	m.sendMu.Unlock()
+}
+
+func (m *Manager) subscriptionsFilePath() string {
+	return filepath.Join(m.cfg.Path, "subscriptions.json")
+}
+
+func (m *Manager) snapshotFilePath(subscriptionId string) string {
+	return filepath.Join(m.cfg.Path, "snapshots", subscriptionId+".json")
+}
+
+// must be called with mu held
+func (m *Manager) saveSubscriptions() {
+	if err := jsonfile.Write(m.subscriptionsFilePath(), m.subscriptions); err != nil {
+		m.logger.Error("Failed to save subscriptions", slog.Any("err", err))
+	}
+}
+
+// tokens are never returned outside of emails
+func redacted(sub *Subscription) Subscription {
+	copied := *sub
+	copied.Token = ""
+	return copied
+}
+
+func (m *Manager) List() []Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriptions := make([]Subscription, 0, len(m.subscriptions))
+	for _, sub := range m.subscriptions {
+		subscriptions = append(subscriptions, redacted(sub))
+	}
+
+	return subscriptions
+}
+
+// must be called with mu held
+func (m *Manager) findBy(predicate func(sub *Subscription) bool) *Subscription {
+	index := slices.IndexFunc(m.subscriptions, predicate)
+	if index == -1 {
+		return nil
+	}
+
+	return m.subscriptions[index]
+}
+
+// Subscribe sends a confirmation email, the digest is only sent after the link in it is opened.
+// The outcome is the same whether or not the address was subscribed before, so it can't be used to probe for subscribers
+func (m *Manager) Subscribe(ctx context.Context, req SubscribeRequest) error {
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil || address.Name != "" || !strings.Contains(address.Address, ".") {
+		return fmt.Errorf("%w: invalid email address", ErrInvalidSubscription)
+	}
+	email := strings.ToLower(address.Address)
+	if !req.ScheduleType.IsValid() {
+		return fmt.Errorf("%w: invalid schedule type", ErrInvalidSubscription)
+	}
+	if len(req.ScheduleIds) == 0 || len(req.ScheduleIds) > MaxScheduleIdsPerSubscription {
+		return fmt.Errorf("%w: between 1 and %d schedule ids are required", ErrInvalidSubscription, MaxScheduleIdsPerSubscription)
+	}
+	scheduleIds := slices.Compact(slices.Sorted(slices.Values(req.ScheduleIds)))
+
+	// also checks that the schedules exist
+	now := time.Now()
+	aggregateSchedule, _, _, err := m.cfg.Uek.GetAggregateScheduleInRange(ctx, req.ScheduleType, scheduleIds, now, now.AddDate(0, 0, 7))
+	if err != nil {
+		return err
+	}
+	scheduleNames := make([]string, 0, len(aggregateSchedule.Headers))
+	for _, header := range aggregateSchedule.Headers {
+		scheduleNames = append(scheduleNames, header.Name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneUnconfirmed(now)
+
+	sub := m.findBy(func(sub *Subscription) bool {
+		return sub.Email == email && sub.ScheduleType == req.ScheduleType && slices.Equal(sub.ScheduleIds, scheduleIds)
+	})
+	if sub != nil && sub.IsConfirmed() {
+		return nil
+	}
+	if !m.canSendConfirmation(email, now) {
+		m.logger.Info("Digest confirmation not sent, too many recent confirmations for email")
+		return nil
+	}
+	if sub == nil {
+		emailSubscriptionCount := 0
+		for _, other := range m.subscriptions {
+			if other.Email == email {
+				emailSubscriptionCount++
+			}
+		}
+		if emailSubscriptionCount >= maxSubscriptionsPerEmail {
+			m.logger.Info("Digest subscription rejected, too many subscriptions for email", slog.Int("count", emailSubscriptionCount))
+			return nil
+		}
+
+		sub = &Subscription{
+			Id:           uuid.NewString(),
+			Email:        email,
+			ScheduleType: req.ScheduleType,
+			ScheduleIds:  scheduleIds,
+			Token:        rand.Text(),
+			CreatedAt:    now,
+		}
+		m.subscriptions = append(m.subscriptions, sub)
+	}
+	sub.ScheduleNames = scheduleNames
+	sub.ConfirmationSentAt = now
+	m.confirmationsSentAt[email] = append(m.confirmationsSentAt[email], now)
+	m.saveSubscriptions()
+
+	msg, err := m.renderConfirmation(sub)
+	if err != nil {
+		return err
+	}
+	m.sendInBackground(msg, slog.String("subscriptionId", sub.Id))
+	m.logger.Info("Digest subscription pending confirmation", slog.String("id", sub.Id))
+
+	return nil
+}
+
+// must be called with mu held
+func (m *Manager) canSendConfirmation(email string, now time.Time) bool {
+	sentAt := slices.DeleteFunc(m.confirmationsSentAt[email], func(sentAt time.Time) bool {
+		return now.Sub(sentAt) >= 24*time.Hour
+	})
+	if len(sentAt) == 0 {
+		delete(m.confirmationsSentAt, email)
+		return true
+	}
+	m.confirmationsSentAt[email] = sentAt
+
+	return len(sentAt) < maxConfirmationsPerEmailPerDay && now.Sub(sentAt[len(sentAt)-1]) >= confirmationResendInterval
+}
+
+// must be called with mu held
+func (m *Manager) pruneUnconfirmed(now time.Time) {
+	m.subscriptions = slices.DeleteFunc(m.subscriptions, func(sub *Subscription) bool {
+		return !sub.IsConfirmed() && now.Sub(sub.CreatedAt) > confirmationTTL
+	})
+}
+
+// Confirm returns false when the token is unknown or the confirmation expired
+func (m *Manager) Confirm(token string) (Subscription, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneUnconfirmed(time.Now())
+
+	sub := m.findBy(func(sub *Subscription) bool {
+		return token != "" && sub.Token == token
+	})
+	if sub == nil {
+		return Subscription{}, false
+	}
+
+	if !sub.IsConfirmed() {
+		sub.ConfirmedAt = time.Now()
+		m.saveSubscriptions()
+		m.logger.Info("Digest subscription confirmed", slog.String("id", sub.Id))
+	}
+
+	return redacted(sub), true
+}
+
+func (m *Manager) Unsubscribe(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := m.findBy(func(sub *Subscription) bool {
+		return token != "" && sub.Token == token
+	})
+	if sub == nil {
+		return false
+	}
+
+	m.remove(sub.Id)
+	m.logger.Info("Digest subscription removed by subscriber", slog.String("id", sub.Id))
+	return true
+}
+
+func (m *Manager) Delete(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.findBy(func(sub *Subscription) bool { return sub.Id == id }) == nil {
+		return false
+	}
+
+	m.remove(id)
+	m.logger.Info("Digest subscription deleted", slog.String("id", id))
+	return true
+}
+
+// must be called with mu held
+func (m *Manager) remove(id string) {
+	m.subscriptions = slices.DeleteFunc(m.subscriptions, func(sub *Subscription) bool {
+		return sub.Id == id
+	})
+	m.saveSubscriptions()
+
+	m.snapshotMu.Lock()
+	defer m.snapshotMu.Unlock()
+	if err := os.Remove(m.snapshotFilePath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		m.logger.Error("Failed to remove digest snapshot", slog.String("id", id), slog.Any("err", err))
+	}
+}
+
+func (m *Manager) sendInBackground(msg *message, attrs ...any) {
+	m.mailWg.Add(1)
+	go func() {
+		defer m.mailWg.Done()
+		// not bound to ctx, so confirmations requested right before shutdown still go out
+		if err := m.sendMail(context.Background(), msg); err != nil {
+			m.logger.Error("Failed to send email", append(attrs, slog.String("subject", msg.Subject), slog.Any("err", err))...)
+		}
+	}()
+}
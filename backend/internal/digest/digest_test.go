@@ -0,0 +1,280 @@
+package digest
+
+import (
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+)
+
+func TestSendTime(t *testing.T) {
+	sendAt, err := ParseSendTime("Sunday 18:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warsaw := uek.Location()
+	for _, tc := range []struct {
+		now          time.Time
+		previous     time.Time
+		next         time.Time
+		digestMonday time.Time
+	}{
+		{
+			now:          time.Date(2026, 10, 19, 12, 0, 0, 0, warsaw),
+			previous:     time.Date(2026, 10, 18, 18, 0, 0, 0, warsaw),
+			next:         time.Date(2026, 10, 25, 18, 0, 0, 0, warsaw),
+			digestMonday: time.Date(2026, 10, 19, 0, 0, 0, 0, warsaw),
+		},
+		{
+			// dst ends in the morning
+			now:          time.Date(2026, 10, 25, 18, 0, 0, 0, warsaw),
+			previous:     time.Date(2026, 10, 25, 18, 0, 0, 0, warsaw),
+			next:         time.Date(2026, 11, 1, 18, 0, 0, 0, warsaw),
+			digestMonday: time.Date(2026, 10, 26, 0, 0, 0, 0, warsaw),
+		},
+		{
+			now:          time.Date(2026, 10, 25, 17, 59, 0, 0, warsaw),
+			previous:     time.Date(2026, 10, 18, 18, 0, 0, 0, warsaw),
+			next:         time.Date(2026, 10, 25, 18, 0, 0, 0, warsaw),
+			digestMonday: time.Date(2026, 10, 19, 0, 0, 0, 0, warsaw),
+		},
+	} {
+		if previous := sendAt.previous(tc.now); !previous.Equal(tc.previous) {
+			t.Errorf("previous(%s): expected %s, got %s", tc.now, tc.previous, previous)
+		}
+		if next := sendAt.next(tc.now); !next.Equal(tc.next) {
+			t.Errorf("next(%s): expected %s, got %s", tc.now, tc.next, next)
+		}
+		if digestMonday := digestWeekStart(sendAt.previous(tc.now)); !digestMonday.Equal(tc.digestMonday) {
+			t.Errorf("digestWeekStart(%s): expected %s, got %s", tc.now, tc.digestMonday, digestMonday)
+		}
+	}
+
+	for _, invalid := range []string{"", "sunday", "someday 18:00", "sunday 25:00"} {
+		if _, err := ParseSendTime(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	from, _ := mail.ParseAddress("Plan zajęć <plan@example.com>")
+	data, err := buildMessage(from, &message{
+		To:             "student@example.com",
+		Subject:        "Plan zajęć na tydzień",
+		Text:           "Zajęć w tygodniu: 1",
+		HTML:           "<p>Zajęć w tygodniu: 1</p>",
+		UnsubscribeUrl: "https://example.com/api/digest/unsubscribe?token=abc",
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Plan zajęć na tydzień" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if msg.Header.Get("List-Unsubscribe-Post") != "List-Unsubscribe=One-Click" || msg.Header.Get("List-Unsubscribe") != "<https://example.com/api/digest/unsubscribe?token=abc>" {
+		t.Errorf("missing one-click unsubscribe headers")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q", msg.Header.Get("Content-Type"))
+	}
+	contentTypes := []string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		contentTypes = append(contentTypes, part.Header.Get("Content-Type"))
+	}
+	if strings.Join(contentTypes, ",") != "text/plain; charset=utf-8,text/html; charset=utf-8" {
+		t.Errorf("unexpected parts %v", contentTypes)
+	}
+}
+
+// accepts any message and counts them
+func newTestSMTPServer(t *testing.T) (int, *atomic.Int32) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messageCount := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				tc := textproto.NewConn(conn)
+				defer tc.Close()
+
+				tc.PrintfLine("220 localhost")
+				for {
+					line, err := tc.ReadLine()
+					if err != nil {
+						return
+					}
+
+					switch command, _, _ := strings.Cut(strings.ToUpper(line), " "); command {
+					case "DATA":
+						tc.PrintfLine("354 go ahead")
+						if _, err := tc.ReadDotLines(); err != nil {
+							return
+						}
+						messageCount.Add(1)
+						tc.PrintfLine("250 ok")
+					case "QUIT":
+						tc.PrintfLine("221 bye")
+						return
+					default:
+						tc.PrintfLine("250 ok")
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, messageCount
+}
+
+func newTestManager(t *testing.T, path string, smtpPort int) *Manager {
+	t.Helper()
+
+	mockDirectoryPath := t.TempDir()
+	if _, err := uekmock.Generate(mockDirectoryPath, uekmock.GeneratorOptions{
+		Seed:      1,
+		Groups:    2,
+		Lecturers: 4,
+		Rooms:     2,
+	}); err != nil {
+		t.Fatalf("failed to generate mock responses: %v", err)
+	}
+	mockRoundTripper, err := uekmock.NewRoundTripper(config.Mock{
+		Enabled:       true,
+		DirectoryPath: mockDirectoryPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to create mock round tripper: %v", err)
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+	m, err := New(Config{
+		Path: path,
+		Uek: uek.NewClient(uek.ClientConfig{
+			HttpClient: &http.Client{
+				Transport: mockRoundTripper,
+			},
+			Logger: logger,
+		}),
+		SMTP: SMTPConfig{
+			Host:     "127.0.0.1",
+			Port:     smtpPort,
+			Security: SMTPSecurityNone,
+			From:     "plan@example.com",
+		},
+		BaseUrl: "http://localhost",
+		Logger:  logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+func TestSubscribeConfirmationLimit(t *testing.T) {
+	path := t.TempDir()
+	smtpPort, messageCount := newTestSMTPServer(t)
+	m := newTestManager(t, path, smtpPort)
+
+	groupings, _, err := m.cfg.Uek.GetGroupings(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, _, err := m.cfg.Uek.GetHeaders(t.Context(), uek.ScheduleTypeGroup, groupings.Groups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) < 2 {
+		t.Fatalf("expected at least 2 headers, got %d", len(headers))
+	}
+
+	expectedMessageCount := int32(0)
+	subscribe := func(email string, expectSent bool, scheduleIds ...int) {
+		t.Helper()
+
+		if err := m.Subscribe(t.Context(), SubscribeRequest{
+			Email:        email,
+			ScheduleType: uek.ScheduleTypeGroup,
+			ScheduleIds:  scheduleIds,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		m.mailWg.Wait()
+
+		if expectSent {
+			expectedMessageCount++
+		}
+		if count := messageCount.Load(); count != expectedMessageCount {
+			t.Fatalf("expected %d emails to be sent, got %d", expectedMessageCount, count)
+		}
+	}
+	elapse := func(d time.Duration) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, sentAt := range m.confirmationsSentAt {
+			for i := range sentAt {
+				sentAt[i] = sentAt[i].Add(-d)
+			}
+		}
+	}
+
+	first, second := headers[0].Id, headers[1].Id
+	subscribe("student@example.com", true, first)
+	// the interval is per address, not per subscription
+	subscribe("student@example.com", false, second)
+	subscribe("Student@Example.com", false, first, second)
+	subscribe("other@example.com", true, first)
+
+	for _, scheduleIds := range [][]int{{second}, {first, second}, {first}, {second}} {
+		elapse(confirmationResendInterval)
+		subscribe("student@example.com", true, scheduleIds...)
+	}
+	// the daily limit is reached
+	elapse(confirmationResendInterval)
+	subscribe("student@example.com", false, first, second)
+
+	// restarting doesn't reset the limit
+	m.Close()
+	m = newTestManager(t, path, smtpPort)
+	subscribe("student@example.com", false, first, second)
+
+	elapse(24 * time.Hour)
+	subscribe("student@example.com", true, first, second)
+}
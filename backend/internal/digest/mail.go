@@ -0,0 +1,195 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const smtpTimeout = 30 * time.Second
+
+type SMTPSecurity string
+
+const (
+	SMTPSecurityStartTLS SMTPSecurity = "starttls"
+	// implicit tls, usually port 465
+	SMTPSecurityTLS SMTPSecurity = "tls"
+	// only for local sinks, credentials are never sent over plain connections to other hosts
+	SMTPSecurityNone SMTPSecurity = "none"
+)
+
+func ParseSMTPSecurity(s string) (SMTPSecurity, error) {
+	switch security := SMTPSecurity(strings.ToLower(s)); security {
+	case SMTPSecurityStartTLS, SMTPSecurityTLS, SMTPSecurityNone:
+		return security, nil
+	}
+
+	return "", fmt.Errorf("unknown smtp security %q, expected starttls, tls or none", s)
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Security SMTPSecurity
+	// address with an optional display name, e.g. "Plan zajęć <plan@example.com>"
+	From string
+}
+
+type message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// advertised for one-click unsubscribe (RFC 8058), empty for confirmations
+	UnsubscribeUrl string
+}
+
+func (m *Manager) sendMail(ctx context.Context, msg *message) error {
+	from, err := mail.ParseAddress(m.cfg.SMTP.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	data, err := buildMessage(from, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	return sendSMTP(ctx, m.cfg.SMTP, from.Address, msg.To, data)
+}
+
+func buildMessage(from *mail.Address, msg *message, now time.Time) ([]byte, error) {
+	body := bytes.Buffer{}
+	mw := multipart.NewWriter(&body)
+	// clients show the last alternative they support
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qpw := quotedprintable.NewWriter(pw)
+		if _, err := qpw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qpw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	_, domain, _ := strings.Cut(from.Address, "@")
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", uuid.NewString(), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+		{"Auto-Submitted", "auto-generated"},
+	}
+	if msg.UnsubscribeUrl != "" {
+		headers = append(headers,
+			[2]string{"List-Unsubscribe", "<" + msg.UnsubscribeUrl + ">"},
+			[2]string{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"},
+		)
+	}
+
+	data := bytes.Buffer{}
+	for _, header := range headers {
+		fmt.Fprintf(&data, "%s: %s\r\n", header[0], header[1])
+	}
+	data.WriteString("\r\n")
+	data.Write(body.Bytes())
+
+	return data.Bytes(), nil
+}
+
+func sendSMTP(ctx context.Context, cfg SMTPConfig, from string, to string, data []byte) error {
+	dialer := &net.Dialer{
+		Timeout: smtpTimeout,
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	if cfg.Security == SMTPSecurityTLS {
+		conn = tls.Client(conn, &tls.Config{
+			ServerName: cfg.Host,
+		})
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer c.Close()
+
+	if cfg.Security == SMTPSecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(&tls.Config{
+			ServerName: cfg.Host,
+		}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	if cfg.Username != "" {
+		// refuses to send credentials over plain connections, unless the server is local
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return fmt.Errorf("smtp server rejected sender: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("smtp server rejected recipient: %w", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp server rejected data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected message: %w", err)
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,139 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+var weekdayNames = [...]string{"niedziela", "poniedziałek", "wtorek", "środa", "czwartek", "piątek", "sobota"}
+
+var templateFuncs = map[string]any{
+	"weekday": func(t time.Time) string {
+		return weekdayNames[t.In(uek.Location()).Weekday()]
+	},
+	"date": func(t time.Time) string {
+		return t.In(uek.Location()).Format("02.01")
+	},
+	"time": func(t time.Time) string {
+		return t.In(uek.Location()).Format("15:04")
+	},
+	"room": func(room *uek.ScheduleItemRoom) string {
+		if room == nil {
+			return ""
+		}
+		return room.Name
+	},
+	"lecturers": func(lecturers []uek.ScheduleItemLecturer) string {
+		names := make([]string, 0, len(lecturers))
+		for _, lecturer := range lecturers {
+			names = append(names, lecturer.Name)
+		}
+		return strings.Join(names, ", ")
+	},
+}
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.New("").Funcs(templateFuncs).ParseFS(templatesFS, "templates/*.txt.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").Funcs(templateFuncs).ParseFS(templatesFS, "templates/*.html.tmpl"))
+)
+
+type confirmationData struct {
+	ScheduleNames string
+	ConfirmUrl    string
+	AppUrl        string
+}
+
+type digestData struct {
+	ScheduleNames string
+	WeekStart     time.Time
+	WeekEnd       time.Time
+	Days          []digestDay
+	ItemCount     int
+	// nil when there is nothing to compare with
+	Changes        *uek.ItemsDiff
+	AppUrl         string
+	UnsubscribeUrl string
+}
+
+type digestDay struct {
+	Date  time.Time
+	Items []*uek.ScheduleItem
+}
+
+func (m *Manager) tokenUrl(path string, token string) string {
+	return m.cfg.BaseUrl + path + "?token=" + url.QueryEscape(token)
+}
+
+func scheduleNames(sub *Subscription) string {
+	if len(sub.ScheduleNames) == 0 {
+		return fmt.Sprint(sub.ScheduleIds)
+	}
+
+	return strings.Join(sub.ScheduleNames, ", ")
+}
+
+func (m *Manager) renderConfirmation(sub *Subscription) (*message, error) {
+	return render(sub.Email, "Potwierdź subskrypcję planu zajęć", "confirmation", confirmationData{
+		ScheduleNames: scheduleNames(sub),
+		ConfirmUrl:    m.tokenUrl("/api/digest/confirm", sub.Token),
+		AppUrl:        m.cfg.BaseUrl,
+	}, "")
+}
+
+func (m *Manager) renderDigest(sub *Subscription, weekStart time.Time, items []*uek.ScheduleItem, changes *uek.ItemsDiff) (*message, error) {
+	data := digestData{
+		ScheduleNames:  scheduleNames(sub),
+		WeekStart:      weekStart,
+		WeekEnd:        weekStart.AddDate(0, 0, 6),
+		Days:           []digestDay{},
+		ItemCount:      len(items),
+		Changes:        changes,
+		AppUrl:         m.cfg.BaseUrl,
+		UnsubscribeUrl: m.tokenUrl("/api/digest/unsubscribe", sub.Token),
+	}
+	for _, item := range items {
+		itemDate := item.Start.In(uek.Location())
+		if len(data.Days) == 0 || data.Days[len(data.Days)-1].Date.Day() != itemDate.Day() {
+			data.Days = append(data.Days, digestDay{
+				Date: itemDate,
+			})
+		}
+		data.Days[len(data.Days)-1].Items = append(data.Days[len(data.Days)-1].Items, item)
+	}
+
+	subject := fmt.Sprintf("Plan zajęć na tydzień %s–%s", data.WeekStart.Format("02.01"), data.WeekEnd.Format("02.01"))
+	if changes != nil {
+		subject += " (zmiany w planie)"
+	}
+
+	return render(sub.Email, subject, "digest", data, data.UnsubscribeUrl)
+}
+
+func render(to string, subject string, templateName string, data any, unsubscribeUrl string) (*message, error) {
+	text, html := bytes.Buffer{}, bytes.Buffer{}
+	if err := textTemplates.ExecuteTemplate(&text, templateName+".txt.tmpl", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", templateName, err)
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, templateName+".html.tmpl", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s html: %w", templateName, err)
+	}
+
+	return &message{
+		To:             to,
+		Subject:        subject,
+		Text:           text.String(),
+		HTML:           html.String(),
+		UnsubscribeUrl: unsubscribeUrl,
+	}, nil
+}
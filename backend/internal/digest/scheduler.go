@@ -0,0 +1,254 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	// failed digests are retried this many times per run
+	maxRunAttempts   = 3
+	runRetryInterval = 15 * time.Minute
+)
+
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+type SendTime struct {
+	Weekday time.Weekday
+	Hour    int
+	Minute  int
+}
+
+var weekdaysByName = map[string]time.Weekday{}
+
+func init() {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		weekdaysByName[strings.ToLower(weekday.String())] = weekday
+	}
+}
+
+// ParseSendTime parses values like "sunday 18:00"
+func ParseSendTime(s string) (SendTime, error) {
+	weekdayName, timeOfDay, _ := strings.Cut(strings.TrimSpace(s), " ")
+	weekday, ok := weekdaysByName[strings.ToLower(weekdayName)]
+	if !ok {
+		return SendTime{}, fmt.Errorf("unknown weekday %q", weekdayName)
+	}
+
+	parsedTime, err := time.Parse("15:04", strings.TrimSpace(timeOfDay))
+	if err != nil {
+		return SendTime{}, fmt.Errorf("invalid time of day %q, expected HH:MM", timeOfDay)
+	}
+
+	return SendTime{
+		Weekday: weekday,
+		Hour:    parsedTime.Hour(),
+		Minute:  parsedTime.Minute(),
+	}, nil
+}
+
+// latest send time not after t, dates are computed in Warsaw time so DST doesn't shift them
+func (st SendTime) previous(t time.Time) time.Time {
+	t = t.In(uek.Location())
+	daysSince := (int(t.Weekday()) - int(st.Weekday) + 7) % 7
+	sendAt := time.Date(t.Year(), t.Month(), t.Day()-daysSince, st.Hour, st.Minute, 0, 0, uek.Location())
+	if sendAt.After(t) {
+		sendAt = sendAt.AddDate(0, 0, -7)
+	}
+
+	return sendAt
+}
+
+func (st SendTime) next(t time.Time) time.Time {
+	return st.previous(t).AddDate(0, 0, 7)
+}
+
+// digests cover the week starting on the first monday after they are sent
+func digestWeekStart(sendAt time.Time) time.Time {
+	sendAt = sendAt.In(uek.Location())
+	daysUntilMonday := (int(time.Monday) - int(sendAt.Weekday()) + 7) % 7
+	if daysUntilMonday == 0 {
+		daysUntilMonday = 7
+	}
+
+	return time.Date(sendAt.Year(), sendAt.Month(), sendAt.Day()+daysUntilMonday, 0, 0, 0, 0, uek.Location())
+}
+
+func (m *Manager) scheduler() {
+	// catches up after downtime, as long as the week the missed digest was for hasn't started yet
+	now := time.Now()
+	if previous := m.cfg.SendAt.previous(now); now.Before(digestWeekStart(previous)) {
+		m.runWithRetries(previous)
+	}
+
+	for {
+		sendAt := m.cfg.SendAt.next(time.Now())
+		m.logger.Debug("Next digest run scheduled", slog.Time("at", sendAt))
+
+		timer := time.NewTimer(time.Until(sendAt))
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		m.runWithRetries(sendAt)
+	}
+}
+
+func (m *Manager) runWithRetries(sendAt time.Time) {
+	for attemptNumber := 1; ; attemptNumber++ {
+		failedCount := m.sendDigests(sendAt)
+		if failedCount == 0 || attemptNumber >= maxRunAttempts {
+			return
+		}
+
+		m.logger.Warn("Some digests failed to send, retrying", slog.Int("failed", failedCount), slog.Int("attempt", attemptNumber))
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(runRetryInterval):
+		}
+	}
+}
+
+// sends digests to confirmed subscribers that didn't get one since sendAt, returns how many failed
+func (m *Manager) sendDigests(sendAt time.Time) int {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+
+	m.mu.Lock()
+	pending := []Subscription{}
+	for _, sub := range m.subscriptions {
+		if sub.IsConfirmed() && sub.LastSentAt.Before(sendAt) {
+			pending = append(pending, *sub)
+		}
+	}
+	m.mu.Unlock()
+	if len(pending) == 0 {
+		return 0
+	}
+
+	weekStart := digestWeekStart(sendAt)
+	m.logger.Info("Sending digests", slog.Int("count", len(pending)), slog.Time("weekStart", weekStart))
+
+	failedCount := 0
+	for i, sub := range pending {
+		if m.ctx.Err() != nil {
+			return failedCount + len(pending) - i
+		}
+
+		if err := m.sendDigest(m.ctx, &sub, weekStart); err != nil {
+			failedCount++
+			if !errors.Is(err, context.Canceled) {
+				m.logger.Error("Failed to send digest", slog.String("subscriptionId", sub.Id), slog.Any("err", err))
+			}
+		}
+	}
+	m.logger.Info("Digests sent", slog.Int("sent", len(pending)-failedCount), slog.Int("failed", failedCount))
+
+	return failedCount
+}
+
+// SendNow sends the digest for the upcoming week to one subscriber, regardless of when they got the last one
+func (m *Manager) SendNow(ctx context.Context, id string) error {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+
+	m.mu.Lock()
+	sub := m.findBy(func(sub *Subscription) bool { return sub.Id == id })
+	var copied Subscription
+	if sub != nil {
+		copied = *sub
+	}
+	m.mu.Unlock()
+	if sub == nil {
+		return ErrSubscriptionNotFound
+	}
+
+	return m.sendDigest(ctx, &copied, digestWeekStart(m.cfg.SendAt.next(time.Now())))
+}
+
+type digestSnapshot struct {
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	Items     []*uek.ScheduleItem `json:"items"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+// the snapshot taken with each digest also covers the following week, the next digest lists changes against it
+func (m *Manager) sendDigest(ctx context.Context, sub *Subscription, weekStart time.Time) error {
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	snapshotEnd := weekStart.AddDate(0, 0, 14)
+
+	aggregateSchedule, _, _, err := m.cfg.Uek.GetAggregateScheduleInRange(ctx, sub.ScheduleType, sub.ScheduleIds, weekStart, snapshotEnd)
+	if err != nil {
+		return fmt.Errorf("failed to get schedule: %w", err)
+	}
+	weekItems := itemsInRange(aggregateSchedule.Items, weekStart, weekEnd)
+
+	var changes *uek.ItemsDiff
+	m.snapshotMu.Lock()
+	previous := digestSnapshot{}
+	err = jsonfile.Read(m.snapshotFilePath(sub.Id), &previous)
+	m.snapshotMu.Unlock()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		m.logger.Warn("Failed to read digest snapshot", slog.String("subscriptionId", sub.Id), slog.Any("err", err))
+	}
+	if err == nil && !previous.From.After(weekStart) && !previous.To.Before(weekEnd) {
+		if d := uek.DiffItems(itemsInRange(previous.Items, weekStart, weekEnd), weekItems); !d.IsEmpty() {
+			changes = d
+		}
+	}
+
+	// nothing worth an email, e.g. during holidays
+	if len(weekItems) > 0 || changes != nil {
+		msg, err := m.renderDigest(sub, weekStart, weekItems, changes)
+		if err != nil {
+			return err
+		}
+		if err := m.sendMail(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	m.snapshotMu.Lock()
+	if err := jsonfile.Write(m.snapshotFilePath(sub.Id), digestSnapshot{
+		From:      weekStart,
+		To:        snapshotEnd,
+		Items:     aggregateSchedule.Items,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		m.logger.Error("Failed to write digest snapshot", slog.String("subscriptionId", sub.Id), slog.Any("err", err))
+	}
+	m.snapshotMu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if live := m.findBy(func(other *Subscription) bool { return other.Id == sub.Id }); live != nil {
+		live.LastSentAt = time.Now()
+		m.saveSubscriptions()
+	}
+
+	return nil
+}
+
+func itemsInRange(items []*uek.ScheduleItem, from time.Time, to time.Time) []*uek.ScheduleItem {
+	inRange := []*uek.ScheduleItem{}
+	for _, item := range items {
+		if item.Start.Before(to) && item.End.After(from) {
+			inRange = append(inRange, item)
+		}
+	}
+
+	return inRange
+}
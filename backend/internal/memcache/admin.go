@@ -0,0 +1,65 @@
+package memcache
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+type Stats struct {
+	Entries   int   `json:"entries"`
+	UsedBytes int64 `json:"usedBytes"`
+	MaxBytes  int64 `json:"maxBytes"`
+}
+
+func (c *Cache) ListEntries(_ context.Context, keyPrefix string) ([]uek.CacheEntryInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entries := []uek.CacheEntryInfo{}
+	for element := c.lru.Front(); element != nil; element = element.Next() {
+		if e := element.Value.(*entry); strings.HasPrefix(e.key, keyPrefix) && e.expirationDate.After(now) {
+			entries = append(entries, uek.CacheEntryInfo{
+				Key:            e.key,
+				ExpirationDate: e.expirationDate,
+				Size:           e.size,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+func (c *Cache) GetEntry(_ context.Context, key string) (any, time.Time, bool) {
+	return get[any](c, key)
+}
+
+func (c *Cache) DeleteEntries(_ context.Context, keyPattern *regexp.Regexp) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deletedCount := 0
+	for key, element := range c.elementsByKey {
+		if keyPattern.MatchString(key) {
+			c.removeElement(element)
+			deletedCount++
+		}
+	}
+
+	return deletedCount, nil
+}
+
+func (c *Cache) Stats(_ context.Context) any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Entries:   c.lru.Len(),
+		UsedBytes: c.usedBytes,
+		MaxBytes:  c.maxBytes,
+	}
+}
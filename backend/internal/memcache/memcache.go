@@ -0,0 +1,194 @@
+package memcache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// Cache is a size-bounded in-process LRU cache. Values are stored and returned as-is, without copying,
+// so callers must treat them as immutable
+type Cache struct {
+	mu                     sync.Mutex
+	maxBytes               int64
+	usedBytes              int64
+	elementsByKey          map[string]*list.Element
+	lru                    *list.List
+	logger                 *slog.Logger
+	cleanupWorkerCtx       context.Context
+	cancelCleanupWorkerCtx context.CancelFunc
+}
+
+type entry struct {
+	key            string
+	value          any
+	expirationDate time.Time
+	size           int64
+}
+
+func New(maxBytes int64, logger *slog.Logger) *Cache {
+	c := &Cache{
+		maxBytes:      maxBytes,
+		elementsByKey: map[string]*list.Element{},
+		lru:           list.New(),
+		logger:        logger,
+	}
+	c.cleanupWorkerCtx, c.cancelCleanupWorkerCtx = context.WithCancel(context.Background())
+
+	go c.cleanupWorker()
+
+	return c
+}
+
+func (c *Cache) cleanupWorker() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.cleanupWorkerCtx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		removedCount, entryCount, usedBytes := c.removeExpired(time.Now())
+		c.logger.Debug("Cleanup executed successfully", slog.Int("removed", removedCount), slog.Int("entries", entryCount), slog.Int64("usedBytes", usedBytes))
+	}
+}
+
+// returns the number of removed entries, and entries and bytes left
+func (c *Cache) removeExpired(now time.Time) (int, int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removedCount := 0
+	for element := c.lru.Back(); element != nil; {
+		previousElement := element.Prev()
+		if e := element.Value.(*entry); !e.expirationDate.After(now) {
+			c.removeElement(element)
+			removedCount++
+		}
+		element = previousElement
+	}
+
+	return removedCount, c.lru.Len(), c.usedBytes
+}
+
+func (c *Cache) Close() {
+	c.cancelCleanupWorkerCtx()
+}
+
+// must be called with mu locked
+func (c *Cache) removeElement(element *list.Element) {
+	e := c.lru.Remove(element).(*entry)
+	delete(c.elementsByKey, e.key)
+	c.usedBytes -= e.size
+}
+
+func get[T any](c *Cache, key string) (value T, expirationDate time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.elementsByKey[key]
+	if !found {
+		c.logger.Debug("Cache miss", slog.String("key", key))
+		return
+	}
+
+	e := element.Value.(*entry)
+	if !e.expirationDate.After(time.Now()) {
+		c.removeElement(element)
+		c.logger.Debug("Cache miss", slog.String("key", key))
+		return
+	}
+
+	if value, ok = e.value.(T); !ok {
+		c.logger.Error("Failed to get value", slog.String("key", key), slog.Any("err", fmt.Errorf("unexpected value type %T", e.value)))
+		return
+	}
+	c.lru.MoveToFront(element)
+
+	return value, e.expirationDate, true
+}
+
+// PutEntry stores a value under a key made with uek cache key functions
+func (c *Cache) PutEntry(key string, value any, expirationDate time.Time) {
+	switch typedValue := value.(type) {
+	case *uek.Groupings:
+		put(c, key, typedValue, estimateGroupingsSize(typedValue), expirationDate)
+	case []uek.SchedulePeriod:
+		put(c, key, typedValue, estimatePeriodsSize(typedValue), expirationDate)
+	case []uek.ScheduleHeader:
+		put(c, key, typedValue, estimateHeadersSize(typedValue), expirationDate)
+	case *uek.Schedule:
+		put(c, key, typedValue, estimateScheduleSize(typedValue), expirationDate)
+	default:
+		c.logger.Error("Failed to put value", slog.String("key", key), slog.Any("err", fmt.Errorf("unsupported value type %T", value)))
+	}
+}
+
+func put(c *Cache, key string, value any, size int64, expirationDate time.Time) {
+	if !expirationDate.After(time.Now()) {
+		return
+	}
+
+	size += int64(len(key)) + entryOverhead
+	if size > c.maxBytes {
+		c.logger.Warn("Value too large to be cached", slog.String("key", key), slog.Int64("size", size), slog.Int64("maxBytes", c.maxBytes))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existingElement, found := c.elementsByKey[key]; found {
+		c.removeElement(existingElement)
+	}
+
+	c.elementsByKey[key] = c.lru.PushFront(&entry{
+		key:            key,
+		value:          value,
+		expirationDate: expirationDate,
+		size:           size,
+	})
+	c.usedBytes += size
+
+	for c.usedBytes > c.maxBytes {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+func (c *Cache) GetGroupings(_ context.Context) (*uek.Groupings, time.Time, bool) {
+	return get[*uek.Groupings](c, uek.GroupingsCacheKey)
+}
+
+func (c *Cache) GetHeaders(_ context.Context, scheduleType uek.ScheduleType, groupingName string) ([]uek.ScheduleHeader, time.Time, bool) {
+	return get[[]uek.ScheduleHeader](c, uek.MakeHeadersCacheKey(scheduleType, groupingName))
+}
+
+func (c *Cache) GetSchedule(_ context.Context, scheduleType uek.ScheduleType, scheduleId int, periodId int) (*uek.Schedule, time.Time, bool) {
+	return get[*uek.Schedule](c, uek.MakeScheduleCacheKey(scheduleType, scheduleId, periodId))
+}
+
+func (c *Cache) GetPeriods(_ context.Context) ([]uek.SchedulePeriod, time.Time, bool) {
+	return get[[]uek.SchedulePeriod](c, uek.PeriodsCacheKey)
+}
+
+func (c *Cache) PutGroupingsAndPeriods(cacheExpirationDateGroupings time.Time, groupings *uek.Groupings, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
+	put(c, uek.GroupingsCacheKey, groupings, estimateGroupingsSize(groupings), cacheExpirationDateGroupings)
+	put(c, uek.PeriodsCacheKey, periods, estimatePeriodsSize(periods), cacheExpirationDatePeriods)
+}
+
+func (c *Cache) PutHeaders(cacheExpirationDate time.Time, scheduleType uek.ScheduleType, groupingName string, headers []uek.ScheduleHeader) {
+	put(c, uek.MakeHeadersCacheKey(scheduleType, groupingName), headers, estimateHeadersSize(headers), cacheExpirationDate)
+}
+
+func (c *Cache) PutScheduleAndPeriods(cacheExpirationDateSchedule time.Time, scheduleType uek.ScheduleType, scheduleId int, periodId int, schedule *uek.Schedule, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
+	put(c, uek.MakeScheduleCacheKey(scheduleType, scheduleId, periodId), schedule, estimateScheduleSize(schedule), cacheExpirationDateSchedule)
+	put(c, uek.PeriodsCacheKey, periods, estimatePeriodsSize(periods), cacheExpirationDatePeriods)
+}
@@ -0,0 +1,150 @@
+package memcache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func newTestCache(t *testing.T, maxBytes int64) *Cache {
+	t.Helper()
+
+	c := New(maxBytes, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// headers of the same name length have the same estimated size
+func testHeaders(name string) []uek.ScheduleHeader {
+	return []uek.ScheduleHeader{{Id: 1, Name: name}}
+}
+
+func testEntrySize(groupingName string) int64 {
+	return estimateHeadersSize(testHeaders(groupingName)) + int64(len(uek.MakeHeadersCacheKey(uek.ScheduleTypeGroup, groupingName))) + entryOverhead
+}
+
+func cachedGroupingNames(c *Cache) []string {
+	names := []string{}
+	for element := c.lru.Front(); element != nil; element = element.Next() {
+		names = append(names, strings.TrimPrefix(element.Value.(*entry).key, uek.MakeHeadersCacheKey(uek.ScheduleTypeGroup, "")))
+	}
+	return names
+}
+
+func TestCacheEvictionOrder(t *testing.T) {
+	expirationDate := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name string
+		// "+x" puts x, "?x" gets x
+		ops           []string
+		capacity      int
+		expectedNames []string
+	}{
+		{name: "fills up", ops: []string{"+a", "+b", "+c"}, capacity: 3, expectedNames: []string{"c", "b", "a"}},
+		{name: "evicts least recently put", ops: []string{"+a", "+b", "+c", "+d"}, capacity: 3, expectedNames: []string{"d", "c", "b"}},
+		{name: "get refreshes recency", ops: []string{"+a", "+b", "+c", "?a", "+d"}, capacity: 3, expectedNames: []string{"d", "a", "c"}},
+		{name: "put replaces and refreshes", ops: []string{"+a", "+b", "+c", "+a", "+d"}, capacity: 3, expectedNames: []string{"d", "a", "c"}},
+		{name: "missing get changes nothing", ops: []string{"+a", "+b", "?x", "+c"}, capacity: 2, expectedNames: []string{"c", "b"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := newTestCache(t, int64(testCase.capacity)*testEntrySize("a"))
+
+			for _, op := range testCase.ops {
+				switch name := op[1:]; op[0] {
+				case '+':
+					c.PutHeaders(expirationDate, uek.ScheduleTypeGroup, name, testHeaders(name))
+				case '?':
+					c.GetHeaders(context.Background(), uek.ScheduleTypeGroup, name)
+				}
+			}
+
+			if names := cachedGroupingNames(c); !slices.Equal(names, testCase.expectedNames) {
+				t.Errorf("expected %v, got %v", testCase.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	expirationDate := time.Now().Add(time.Hour)
+	maxBytes := 10 * testEntrySize("a")
+	c := newTestCache(t, maxBytes)
+
+	for _, name := range []string{"a", "bb", "ccc", "dddd", "e", "ffffff", "g", "hhhhhhhhh", "i", "j", "kk", "l"} {
+		c.PutHeaders(expirationDate, uek.ScheduleTypeGroup, name, testHeaders(name))
+
+		if c.usedBytes > maxBytes {
+			t.Fatalf("used %d bytes out of %d", c.usedBytes, maxBytes)
+		}
+	}
+
+	var usedBytes int64
+	for element := c.lru.Front(); element != nil; element = element.Next() {
+		usedBytes += element.Value.(*entry).size
+	}
+	if usedBytes != c.usedBytes || len(c.elementsByKey) != c.lru.Len() {
+		t.Errorf("bookkeeping is off: %d bytes in entries, %d counted, %d keys, %d elements", usedBytes, c.usedBytes, len(c.elementsByKey), c.lru.Len())
+	}
+
+	// too large for the whole cache, must not evict anything
+	entryCount := c.lru.Len()
+	c.PutHeaders(expirationDate, uek.ScheduleTypeGroup, "huge", testHeaders(strings.Repeat("x", int(maxBytes))))
+	if _, _, ok := c.GetHeaders(context.Background(), uek.ScheduleTypeGroup, "huge"); ok || c.lru.Len() != entryCount {
+		t.Errorf("oversized value was cached")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := newTestCache(t, 1<<20)
+	now := time.Now()
+
+	c.PutHeaders(now.Add(-time.Second), uek.ScheduleTypeGroup, "expired", testHeaders("expired"))
+	if c.lru.Len() != 0 {
+		t.Fatal("already expired value was cached")
+	}
+
+	c.PutHeaders(now.Add(time.Hour), uek.ScheduleTypeGroup, "fresh", testHeaders("fresh"))
+	c.PutHeaders(now.Add(2*time.Hour), uek.ScheduleTypeGroup, "stale", testHeaders("stale"))
+	c.PutHeaders(now.Add(3*time.Hour), uek.ScheduleTypeGroup, "later", testHeaders("later"))
+
+	headers, expirationDate, ok := c.GetHeaders(context.Background(), uek.ScheduleTypeGroup, "fresh")
+	if !ok || headers[0].Name != "fresh" || !expirationDate.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected fresh entry: %v %s %t", headers, expirationDate, ok)
+	}
+
+	// expired entries are dropped on read
+	c.elementsByKey[uek.MakeHeadersCacheKey(uek.ScheduleTypeGroup, "stale")].Value.(*entry).expirationDate = now.Add(-time.Second)
+	if _, _, ok := c.GetHeaders(context.Background(), uek.ScheduleTypeGroup, "stale"); ok {
+		t.Error("expired entry was returned")
+	}
+	if names := cachedGroupingNames(c); !slices.Equal(names, []string{"fresh", "later"}) {
+		t.Errorf("expected expired entry to be removed on read, got %v", names)
+	}
+
+	// and by the cleanup worker
+	removedCount, entryCount, usedBytes := c.removeExpired(now.Add(90 * time.Minute))
+	if removedCount != 1 || entryCount != 1 || usedBytes != testEntrySize("later") {
+		t.Errorf("unexpected cleanup result: removed %d, left %d entries and %d bytes", removedCount, entryCount, usedBytes)
+	}
+	if names := cachedGroupingNames(c); !slices.Equal(names, []string{"later"}) {
+		t.Errorf("expected only the later entry, got %v", names)
+	}
+}
+
+func TestCacheWrongType(t *testing.T) {
+	c := newTestCache(t, 1<<20)
+	c.PutEntry(uek.GroupingsCacheKey, testHeaders("a"), time.Now().Add(time.Hour))
+
+	if _, _, ok := c.GetGroupings(context.Background()); ok {
+		t.Error("value of another type was returned")
+	}
+}
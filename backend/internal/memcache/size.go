@@ -0,0 +1,77 @@
+package memcache
+
+import (
+	"unsafe"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// rough per-entry cost of map/list bookkeeping
+const entryOverhead = 160
+
+const (
+	stringHeaderSize     = int64(unsafe.Sizeof(""))
+	sliceHeaderSize      = int64(unsafe.Sizeof([]string{}))
+	pointerSize          = int64(unsafe.Sizeof(uintptr(0)))
+	scheduleItemSize     = int64(unsafe.Sizeof(uek.ScheduleItem{}))
+	scheduleItemRoomSize = int64(unsafe.Sizeof(uek.ScheduleItemRoom{}))
+	lecturerSize         = int64(unsafe.Sizeof(uek.ScheduleItemLecturer{}))
+	scheduleHeaderSize   = int64(unsafe.Sizeof(uek.ScheduleHeader{}))
+	schedulePeriodSize   = int64(unsafe.Sizeof(uek.SchedulePeriod{}))
+	parseWarningSize     = int64(unsafe.Sizeof(uek.ParseWarning{}))
+	scheduleStructSize   = int64(unsafe.Sizeof(uek.Schedule{}))
+	groupingsStructSize  = int64(unsafe.Sizeof(uek.Groupings{}))
+)
+
+func estimateStringsSize(values []string) int64 {
+	size := sliceHeaderSize
+	for _, value := range values {
+		size += stringHeaderSize + int64(len(value))
+	}
+	return size
+}
+
+func estimateGroupingsSize(groupings *uek.Groupings) int64 {
+	if groupings == nil {
+		return 0
+	}
+
+	return groupingsStructSize + estimateStringsSize(groupings.Groups) + estimateStringsSize(groupings.Rooms)
+}
+
+func estimatePeriodsSize(periods []uek.SchedulePeriod) int64 {
+	return sliceHeaderSize + int64(len(periods))*schedulePeriodSize
+}
+
+func estimateHeadersSize(headers []uek.ScheduleHeader) int64 {
+	size := sliceHeaderSize
+	for _, header := range headers {
+		size += scheduleHeaderSize + int64(len(header.Name))
+	}
+	return size
+}
+
+// overestimates a bit, as group/lecturer/room values coming from the schedule itself are shared by all its items
+func estimateScheduleSize(schedule *uek.Schedule) int64 {
+	if schedule == nil {
+		return 0
+	}
+
+	size := scheduleStructSize + int64(len(schedule.Header.Name)) + sliceHeaderSize + int64(len(schedule.Warnings))*parseWarningSize
+	for _, warning := range schedule.Warnings {
+		size += int64(len(warning.Message))
+	}
+
+	for _, item := range schedule.Items {
+		size += pointerSize + scheduleItemSize + int64(len(item.Subject)+len(item.Type)+len(item.Extra))
+		size += estimateStringsSize(item.Groups)
+		for _, lecturer := range item.Lecturers {
+			size += lecturerSize + int64(len(lecturer.Name))
+		}
+		if item.Room != nil {
+			size += scheduleItemRoomSize + int64(len(item.Room.Name)+len(item.Room.URL))
+		}
+	}
+
+	return size
+}
@@ -0,0 +1,82 @@
+package occupancy
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// building rows have empty room columns
+var roomsCSVHeader = []string{"building", "room_id", "room", "utilisation_percent", "occupied_hours", "double_booked_hours", "peak_hours"}
+
+var heatmapCSVHeader = []string{"building", "room_id", "room", "weekday", "hour", "occupancy_percent"}
+
+// WriteRoomsCSV writes a row per building, followed by rows of its rooms
+func (r *Report) WriteRoomsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(roomsCSVHeader)
+
+	writeRow := func(building string, roomId string, roomName string, occupancy *Occupancy) {
+		peakHours := make([]string, 0, len(occupancy.PeakHours))
+		for _, peakHour := range occupancy.PeakHours {
+			peakHours = append(peakHours, fmt.Sprintf("%d %02d:00", peakHour.Weekday, peakHour.Hour))
+		}
+
+		cw.Write([]string{
+			building,
+			roomId,
+			roomName,
+			formatFloat(occupancy.Utilisation),
+			formatFloat(occupancy.OccupiedHours),
+			formatFloat(occupancy.DoubleBookedHours),
+			strings.Join(peakHours, "; "),
+		})
+	}
+
+	for _, building := range r.Buildings {
+		writeRow(building.Name, "", "", &building.Occupancy)
+		for _, room := range building.Rooms {
+			writeRow(building.Name, strconv.Itoa(room.Id), room.Name, &room.Occupancy)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteHeatmapCSV writes a row per hour of every building and room, in long format for pivot tables
+func (r *Report) WriteHeatmapCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(heatmapCSVHeader)
+
+	writeRows := func(building string, roomId string, roomName string, occupancy *Occupancy) {
+		for weekday, hours := range occupancy.Heatmap {
+			for hour, value := range hours {
+				cw.Write([]string{
+					building,
+					roomId,
+					roomName,
+					strconv.Itoa(weekday + 1),
+					strconv.Itoa(r.OpeningHour + hour),
+					formatFloat(value),
+				})
+			}
+		}
+	}
+
+	for _, building := range r.Buildings {
+		writeRows(building.Name, "", "", &building.Occupancy)
+		for _, room := range building.Rooms {
+			writeRows(building.Name, strconv.Itoa(room.Id), room.Name, &room.Occupancy)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,225 @@
+package occupancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// room schedules are fetched through the client's cache, but a fresh report still means a request per room
+	maxConcurrentScheduleRequests = 2
+	failedReportRetryInterval     = time.Hour
+)
+
+var ErrNoCurrentPeriod = errors.New("no period covers the current date")
+
+type Config struct {
+	// directory for the last report
+	Path string
+	Uek  *uek.Client
+	// how often the report for the current period is rebuilt, only on demand when 0
+	Interval time.Duration
+	Logger   *slog.Logger
+}
+
+type Manager struct {
+	cfg    Config
+	logger *slog.Logger
+	mu     sync.Mutex
+	report *Report
+	// held while a report is being generated
+	generateMu sync.Mutex
+	generateWg sync.WaitGroup
+	ctx        context.Context
+	cancelCtx  context.CancelFunc
+}
+
+func New(cfg Config) (*Manager, error) {
+	m := &Manager{
+		cfg:    cfg,
+		logger: cfg.Logger,
+	}
+	if m.logger == nil {
+		m.logger = slog.Default()
+	}
+
+	report := &Report{}
+	if err := jsonfile.Read(m.reportFilePath(), report); err == nil {
+		m.report = report
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	m.ctx, m.cancelCtx = context.WithCancel(context.Background())
+	if cfg.Interval > 0 {
+		m.generateWg.Add(1)
+		go m.scheduler()
+	}
+
+	return m, nil
+}
+
+// Close stops the scheduler and waits for a report in progress to be canceled
+func (m *Manager) Close() {
+	m.cancelCtx()
+	m.generateWg.Wait()
+}
+
+func (m *Manager) reportFilePath() string {
+	return filepath.Join(m.cfg.Path, "report.json")
+}
+
+// Report returns the last generated report, nil when there is none yet
+func (m *Manager) Report() *Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.report
+}
+
+// GenerateInBackground starts generating a report for the period, 0 means the current academic year.
+// Returns false if a report is already being generated
+func (m *Manager) GenerateInBackground(periodId int) bool {
+	if !m.generateMu.TryLock() {
+		return false
+	}
+
+	m.generateWg.Add(1)
+	go func() {
+		defer m.generateWg.Done()
+		defer m.generateMu.Unlock()
+
+		m.generateAndSave(periodId)
+	}()
+
+	return true
+}
+
+// a saved report younger than the interval postpones the first run
+func (m *Manager) scheduler() {
+	defer m.generateWg.Done()
+
+	wait := time.Duration(0)
+	if report := m.Report(); report != nil {
+		wait = max(time.Until(report.GeneratedAt.Add(m.cfg.Interval)), 0)
+	}
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		m.generateMu.Lock()
+		ok := m.generateAndSave(0)
+		m.generateMu.Unlock()
+
+		wait = m.cfg.Interval
+		if !ok {
+			wait = min(wait, failedReportRetryInterval)
+		}
+	}
+}
+
+// must be called with generateMu held
+func (m *Manager) generateAndSave(periodId int) bool {
+	startedAt := time.Now()
+	report, err := m.generate(m.ctx, periodId)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			m.logger.Error("Failed to generate occupancy report", slog.Int("periodId", periodId), slog.Any("err", err))
+		}
+		return false
+	}
+
+	m.mu.Lock()
+	m.report = report
+	m.mu.Unlock()
+
+	if err := jsonfile.Write(m.reportFilePath(), report); err != nil {
+		m.logger.Error("Failed to save occupancy report", slog.Any("err", err))
+	}
+
+	m.logger.Info("Occupancy report generated",
+		slog.Int("periodId", report.PeriodId),
+		slog.Int("buildings", len(report.Buildings)),
+		slog.Int("missingRooms", len(report.MissingRooms)),
+		slog.Duration("took", time.Since(startedAt)),
+	)
+
+	return true
+}
+
+func (m *Manager) generate(ctx context.Context, periodId int) (*Report, error) {
+	if periodId == 0 {
+		periods, _, err := m.cfg.Uek.GetSchedulePeriods(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get periods: %w", err)
+		}
+
+		var ok bool
+		if periodId, ok = uek.CurrentYearPeriodId(periods, time.Now()); !ok {
+			return nil, ErrNoCurrentPeriod
+		}
+	}
+
+	roomHeadersByGrouping, _, err := m.cfg.Uek.GetRoomHeadersByGrouping(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room headers: %w", err)
+	}
+
+	rooms := []roomSchedule{}
+	for groupingName, headers := range roomHeadersByGrouping {
+		for _, header := range headers {
+			rooms = append(rooms, roomSchedule{
+				building: groupingName,
+				header:   header,
+			})
+		}
+	}
+
+	// a room that can't be fetched shouldn't take the whole report down
+	eg := errgroup.Group{}
+	eg.SetLimit(maxConcurrentScheduleRequests)
+	for i := range rooms {
+		eg.Go(func() error {
+			schedule, _, err := m.cfg.Uek.GetSchedule(ctx, uek.ScheduleTypeRoom, rooms[i].header.Id, periodId)
+			if err != nil {
+				if ctx.Err() == nil {
+					m.logger.Warn("Failed to get room schedule for occupancy report", slog.Int("roomId", rooms[i].header.Id), slog.Int("periodId", periodId), slog.Any("err", err))
+				}
+				return nil
+			}
+
+			rooms[i].items = schedule.Items
+			rooms[i].fetched = true
+			return nil
+		})
+	}
+	eg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// keeps the previous report when UEK is down
+	if len(rooms) > 0 && !slices.ContainsFunc(rooms, func(room roomSchedule) bool { return room.fetched }) {
+		return nil, errors.New("no room schedule could be fetched")
+	}
+
+	report := computeReport(rooms)
+	report.PeriodId = periodId
+	report.GeneratedAt = time.Now()
+
+	return report, nil
+}
@@ -0,0 +1,268 @@
+package occupancy
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	// heatmaps and utilisation only cover these hours
+	openingHour   = 7
+	closingHour   = 21
+	openHours     = closingHour - openingHour
+	peakHourCount = 3
+)
+
+type Report struct {
+	PeriodId    int       `json:"periodId"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// dates with a class in any room, so holidays and exam breaks don't count as free time
+	TeachingDays int `json:"teachingDays"`
+	OpeningHour  int `json:"openingHour"`
+	ClosingHour  int `json:"closingHour"`
+	// of all rooms together
+	Occupancy Occupancy  `json:"occupancy"`
+	Buildings []Building `json:"buildings"`
+	// rooms whose schedules couldn't be fetched, left out of the report
+	MissingRooms []uek.ScheduleHeader `json:"missingRooms"`
+}
+
+// Building is a room grouping
+type Building struct {
+	Name      string    `json:"name"`
+	Occupancy Occupancy `json:"occupancy"`
+	// most utilised first
+	Rooms []Room `json:"rooms"`
+}
+
+type Room struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Occupancy Occupancy `json:"occupancy"`
+}
+
+type Occupancy struct {
+	// percent of opening hours on teaching days
+	Utilisation float64 `json:"utilisation"`
+	// clock hours, including time outside opening hours
+	OccupiedHours float64 `json:"occupiedHours"`
+	// time with two or more different classes in a room at once
+	DoubleBookedHours float64 `json:"doubleBookedHours"`
+	// a row per weekday from monday, a column per hour from the opening hour,
+	// percent of the hour the rooms were occupied on an average teaching day
+	Heatmap [][]float64 `json:"heatmap"`
+	// busiest heatmap cells, most occupied first
+	PeakHours []PeakHour `json:"peakHours"`
+}
+
+type PeakHour struct {
+	// iso weekday, 1 is monday
+	Weekday   int     `json:"weekday"`
+	Hour      int     `json:"hour"`
+	Occupancy float64 `json:"occupancy"`
+}
+
+type roomSchedule struct {
+	building string
+	header   uek.ScheduleHeader
+	items    []*uek.ScheduleItem
+	fetched  bool
+}
+
+// minutes summed over rooms, turned into percentages once the number of teaching days is known
+type occupancyMinutes struct {
+	rooms        int
+	occupied     float64
+	occupiedOpen float64
+	doubleBooked float64
+	heatmap      [7][openHours]float64
+}
+
+func (acc *occupancyMinutes) add(other *occupancyMinutes) {
+	acc.rooms += other.rooms
+	acc.occupied += other.occupied
+	acc.occupiedOpen += other.occupiedOpen
+	acc.doubleBooked += other.doubleBooked
+	for weekday := range acc.heatmap {
+		for hour := range acc.heatmap[weekday] {
+			acc.heatmap[weekday][hour] += other.heatmap[weekday][hour]
+		}
+	}
+}
+
+func computeReport(rooms []roomSchedule) *Report {
+	report := &Report{
+		OpeningHour:  openingHour,
+		ClosingHour:  closingHour,
+		Buildings:    []Building{},
+		MissingRooms: []uek.ScheduleHeader{},
+	}
+
+	teachingDates := map[string]time.Weekday{}
+	for _, room := range rooms {
+		for _, item := range room.items {
+			start := item.Start.In(uek.Location())
+			teachingDates[start.Format(time.DateOnly)] = start.Weekday()
+		}
+	}
+	report.TeachingDays = len(teachingDates)
+	teachingDaysByWeekday := [7]int{}
+	for _, weekday := range teachingDates {
+		teachingDaysByWeekday[weekdayIndex(weekday)]++
+	}
+
+	buildingIndexes := map[string]int{}
+	buildingMinutes := []*occupancyMinutes{}
+	totalMinutes := &occupancyMinutes{}
+	for _, room := range rooms {
+		if !room.fetched {
+			report.MissingRooms = append(report.MissingRooms, room.header)
+			continue
+		}
+
+		i, ok := buildingIndexes[room.building]
+		if !ok {
+			i = len(report.Buildings)
+			buildingIndexes[room.building] = i
+			report.Buildings = append(report.Buildings, Building{Name: room.building, Rooms: []Room{}})
+			buildingMinutes = append(buildingMinutes, &occupancyMinutes{})
+		}
+
+		minutes := roomMinutes(room.items)
+		buildingMinutes[i].add(minutes)
+		totalMinutes.add(minutes)
+		report.Buildings[i].Rooms = append(report.Buildings[i].Rooms, Room{
+			Id:        room.header.Id,
+			Name:      room.header.Name,
+			Occupancy: minutes.occupancy(report.TeachingDays, teachingDaysByWeekday),
+		})
+	}
+
+	for i := range report.Buildings {
+		report.Buildings[i].Occupancy = buildingMinutes[i].occupancy(report.TeachingDays, teachingDaysByWeekday)
+		slices.SortFunc(report.Buildings[i].Rooms, func(a Room, b Room) int {
+			return cmp.Or(cmp.Compare(b.Occupancy.Utilisation, a.Occupancy.Utilisation), cmp.Compare(a.Name, b.Name))
+		})
+	}
+	slices.SortFunc(report.Buildings, func(a Building, b Building) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(report.MissingRooms, func(a uek.ScheduleHeader, b uek.ScheduleHeader) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	report.Occupancy = totalMinutes.occupancy(report.TeachingDays, teachingDaysByWeekday)
+
+	return report
+}
+
+// sweeps over the room's classes, counting time covered by at least one and by two or more of them.
+// Cancelled slots free the room, reservations still block it
+func roomMinutes(items []*uek.ScheduleItem) *occupancyMinutes {
+	type event struct {
+		at    time.Time
+		delta int
+	}
+
+	minutes := &occupancyMinutes{rooms: 1}
+	events := make([]event, 0, len(items)*2)
+	var previous *uek.ScheduleItem
+	for _, item := range items {
+		// the same class listed twice isn't a double booking
+		if item.IsCancelled() || (previous != nil && item.Compare(previous) == 0) {
+			continue
+		}
+		events = append(events, event{item.Start, 1}, event{item.End, -1})
+		previous = item
+	}
+	// ends first, back to back classes don't overlap
+	slices.SortFunc(events, func(a event, b event) int {
+		return cmp.Or(a.at.Compare(b.at), cmp.Compare(a.delta, b.delta))
+	})
+
+	depth := 0
+	for i, e := range events {
+		if i > 0 && depth > 0 {
+			segmentStart, segmentEnd := events[i-1].at, e.at
+			minutes.occupied += segmentEnd.Sub(segmentStart).Minutes()
+			if depth > 1 {
+				minutes.doubleBooked += segmentEnd.Sub(segmentStart).Minutes()
+			}
+			minutes.addToHeatmap(segmentStart, segmentEnd)
+		}
+		depth += e.delta
+	}
+
+	return minutes
+}
+
+func (acc *occupancyMinutes) addToHeatmap(start time.Time, end time.Time) {
+	for cursor := start.In(uek.Location()); cursor.Before(end); {
+		hourEnd := time.Date(cursor.Year(), cursor.Month(), cursor.Day(), cursor.Hour()+1, 0, 0, 0, cursor.Location())
+		if hour := cursor.Hour(); hour >= openingHour && hour < closingHour {
+			segment := minTime(hourEnd, end).Sub(cursor).Minutes()
+			acc.heatmap[weekdayIndex(cursor.Weekday())][hour-openingHour] += segment
+			acc.occupiedOpen += segment
+		}
+		cursor = hourEnd
+	}
+}
+
+func (acc *occupancyMinutes) occupancy(teachingDays int, teachingDaysByWeekday [7]int) Occupancy {
+	occupancy := Occupancy{
+		OccupiedHours:     roundToHundredths(acc.occupied / 60),
+		DoubleBookedHours: roundToHundredths(acc.doubleBooked / 60),
+		Heatmap:           make([][]float64, 7),
+		PeakHours:         []PeakHour{},
+	}
+	if teachingDays > 0 && acc.rooms > 0 {
+		occupancy.Utilisation = percent(acc.occupiedOpen, float64(acc.rooms*teachingDays*openHours*60))
+	}
+
+	for weekday := range acc.heatmap {
+		occupancy.Heatmap[weekday] = make([]float64, openHours)
+		for hour, minutes := range acc.heatmap[weekday] {
+			if teachingDaysByWeekday[weekday] == 0 || minutes == 0 {
+				continue
+			}
+
+			occupancy.Heatmap[weekday][hour] = percent(minutes, float64(acc.rooms*teachingDaysByWeekday[weekday]*60))
+			occupancy.PeakHours = append(occupancy.PeakHours, PeakHour{
+				Weekday:   weekday + 1,
+				Hour:      openingHour + hour,
+				Occupancy: occupancy.Heatmap[weekday][hour],
+			})
+		}
+	}
+
+	slices.SortStableFunc(occupancy.PeakHours, func(a PeakHour, b PeakHour) int {
+		return cmp.Compare(b.Occupancy, a.Occupancy)
+	})
+	occupancy.PeakHours = occupancy.PeakHours[:min(len(occupancy.PeakHours), peakHourCount)]
+
+	return occupancy
+}
+
+// monday is 0
+func weekdayIndex(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+func percent(part float64, whole float64) float64 {
+	return math.Round(part/whole*1000) / 10
+}
+
+func roundToHundredths(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
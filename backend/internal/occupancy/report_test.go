@@ -0,0 +1,102 @@
+package occupancy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestComputeReport(t *testing.T) {
+	at := func(date string, clock string) time.Time {
+		parsed, _ := time.ParseInLocation(time.DateOnly+" 15:04", date+" "+clock, uek.Location())
+		return parsed
+	}
+	item := func(date string, start string, end string, subject string) *uek.ScheduleItem {
+		return &uek.ScheduleItem{Start: at(date, start), End: at(date, end), Subject: subject, Type: "wykład"}
+	}
+
+	rooms := []roomSchedule{
+		{
+			building: "Paw.A",
+			header:   uek.ScheduleHeader{Id: 1, Name: "Paw.A 011"},
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "08:00", "09:30", "Ekonomia"),
+				// listed twice, still one class
+				item("2026-10-19", "08:00", "09:30", "Ekonomia"),
+				// overlaps the previous one by 30 minutes
+				item("2026-10-19", "09:00", "10:00", "Statystyka"),
+				// back to back isn't a double booking
+				item("2026-10-19", "10:00", "11:00", "Finanse"),
+				// moved away, the room is free for the class above
+				{Start: at("2026-10-19", "10:00"), End: at("2026-10-19", "11:30"), Subject: "Rachunkowość", Type: "przeniesienie zajęć"},
+			},
+			fetched: true,
+		},
+		{
+			building: "Paw.A",
+			header:   uek.ScheduleHeader{Id: 2, Name: "Paw.A 012"},
+			items: []*uek.ScheduleItem{
+				item("2026-10-20", "08:00", "09:00", "Ekonomia"),
+			},
+			fetched: true,
+		},
+		{
+			building: "Paw.B",
+			header:   uek.ScheduleHeader{Id: 3, Name: "Paw.B 101"},
+		},
+	}
+
+	report := computeReport(rooms)
+
+	if report.TeachingDays != 2 {
+		t.Errorf("expected 2 teaching days, got %d", report.TeachingDays)
+	}
+	if len(report.MissingRooms) != 1 || report.MissingRooms[0].Id != 3 {
+		t.Errorf("expected the unfetched room to be missing, got %+v", report.MissingRooms)
+	}
+	if len(report.Buildings) != 1 || len(report.Buildings[0].Rooms) != 2 {
+		t.Fatalf("unexpected buildings: %+v", report.Buildings)
+	}
+
+	room := report.Buildings[0].Rooms[0]
+	if room.Id != 1 || room.Occupancy.OccupiedHours != 3 || room.Occupancy.DoubleBookedHours != 0.5 {
+		t.Errorf("unexpected most utilised room: %+v", room)
+	}
+	// 3 of 2 days * 14 opening hours
+	if room.Occupancy.Utilisation != 10.7 {
+		t.Errorf("expected 10.7%% utilisation, got %v", room.Occupancy.Utilisation)
+	}
+	// monday 08:00-09:00 is the only full hour with one monday in the period
+	if room.Occupancy.Heatmap[0][8-openingHour] != 100 || room.Occupancy.Heatmap[0][11-openingHour] != 0 {
+		t.Errorf("unexpected heatmap row: %v", room.Occupancy.Heatmap[0])
+	}
+	if len(room.Occupancy.PeakHours) != peakHourCount || room.Occupancy.PeakHours[0] != (PeakHour{Weekday: 1, Hour: 8, Occupancy: 100}) {
+		t.Errorf("unexpected peak hours: %+v", room.Occupancy.PeakHours)
+	}
+
+	building := report.Buildings[0].Occupancy
+	// both rooms at 08:00, the second one only on tuesday
+	if building.Heatmap[0][8-openingHour] != 50 || building.Heatmap[1][8-openingHour] != 50 {
+		t.Errorf("unexpected building heatmap: %v", building.Heatmap[:2])
+	}
+
+	csv := bytes.Buffer{}
+	if err := report.WriteRoomsCSV(&csv); err != nil {
+		t.Fatalf("failed to write rooms csv: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 4 || lines[2] != "Paw.A,1,Paw.A 011,10.7,3,0.5,1 08:00; 1 09:00; 1 10:00" {
+		t.Errorf("unexpected rooms csv:\n%s", csv.String())
+	}
+
+	csv.Reset()
+	if err := report.WriteHeatmapCSV(&csv); err != nil {
+		t.Fatalf("failed to write heatmap csv: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(csv.String()), "\n"); len(lines) != 1+3*7*openHours {
+		t.Errorf("expected a row per hour of every building and room, got %d lines", len(lines))
+	}
+}
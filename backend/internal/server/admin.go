@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func (srv *Server) registerAdminRoutes() {
+	if srv.adminToken == "" {
+		return
+	}
+
+	srv.registerAdminWebhookRoutes()
+	srv.registerAdminDigestRoutes()
+	srv.registerAdminPushRoutes()
+	srv.registerAdminOccupancyRoutes()
+
+	mux := srv.httpServer.Handler.(*http.ServeMux)
+	// parse warning counts are reported even without a cache
+	mux.HandleFunc("GET /api/admin/cache/stats", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminCacheStats))))
+	if srv.cacheAdmin == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /api/admin/cache/entries", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminListCacheEntries))))
+	mux.HandleFunc("GET /api/admin/cache/entries/{key}", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminGetCacheEntry))))
+	mux.HandleFunc("DELETE /api/admin/cache/entries", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminDeleteCacheEntries))))
+	mux.HandleFunc("POST /api/admin/cache/refresh", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminRefreshCacheEntry))))
+
+	if _, ok := srv.cacheAdmin.(uek.CacheSnapshotter); ok {
+		mux.HandleFunc("GET /api/admin/cache/export", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminExportCache))))
+		mux.HandleFunc("POST /api/admin/cache/import", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminImportCache))))
+	}
+}
+
+func (srv *Server) adminAuthMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(srv.adminToken)) != 1 {
+			respondError(w, r, http.StatusUnauthorized, errorCodeUnauthorized, "Missing or invalid admin token", nil)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		handler(w, r)
+	}
+}
+
+func (srv *Server) handleAdminListCacheEntries(w http.ResponseWriter, r *http.Request) {
+	entries, err := srv.cacheAdmin.ListEntries(r.Context(), r.URL.Query().Get("prefix"))
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			srv.logger.Error("Failed to list cache entries", slog.String("requestId", requestIdFromContext(r.Context())), slog.Any("err", err))
+		}
+		respondError(w, r, http.StatusInternalServerError, errorCodeInternal, "Failed to list cache entries", nil)
+		return
+	}
+
+	respondJSON(w, entries)
+}
+
+func (srv *Server) handleAdminGetCacheEntry(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	value, expirationDate, ok := srv.cacheAdmin.GetEntry(r.Context(), key)
+	if !ok {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Cache entry not found", map[string]string{"key": key})
+		return
+	}
+
+	respondJSON(w, struct {
+		Key            string    `json:"key"`
+		ExpirationDate time.Time `json:"expirationDate"`
+		Value          any       `json:"value"`
+	}{
+		Key:            key,
+		ExpirationDate: expirationDate,
+		Value:          value,
+	})
+}
+
+// accepts either an exact key or a glob pattern
+func (srv *Server) handleAdminDeleteCacheEntries(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	key, pattern := queryParams.Get("key"), queryParams.Get("pattern")
+	if (key == "") == (pattern == "") {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidParameter, "Exactly one of key or pattern is required", nil)
+		return
+	}
+
+	keyPattern, err := uek.CompileCacheKeyPattern(pattern)
+	if key != "" {
+		keyPattern, err = uek.CompileCacheKeyPattern(strings.NewReplacer("*", "\\*", "?", "\\?").Replace(key))
+	}
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidParameter, "Invalid key pattern", nil)
+		return
+	}
+
+	deletedCount, err := srv.cacheAdmin.DeleteEntries(r.Context(), keyPattern)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			srv.logger.Error("Failed to delete cache entries", slog.String("pattern", keyPattern.String()), slog.String("requestId", requestIdFromContext(r.Context())), slog.Any("err", err))
+		}
+		respondError(w, r, http.StatusInternalServerError, errorCodeInternal, "Failed to delete cache entries", nil)
+		return
+	}
+	srv.logger.Info("Cache entries invalidated", slog.String("pattern", keyPattern.String()), slog.Int("count", deletedCount))
+
+	respondJSON(w, struct {
+		Deleted int `json:"deleted"`
+	}{
+		Deleted: deletedCount,
+	})
+}
+
+func (srv *Server) handleAdminRefreshCacheEntry(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidParameter, "Key is required", nil)
+		return
+	}
+
+	expirationDate, err := srv.uek.RefreshCacheEntry(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, uek.ErrInvalidCacheKey) {
+			respondError(w, r, http.StatusBadRequest, errorCodeInvalidParameter, "Key does not name a refreshable cache entry", map[string]string{"key": key})
+			return
+		}
+		srv.respondUpstreamError(w, r, "Failed to refresh cache entry", err, slog.String("key", key))
+		return
+	}
+	srv.logger.Info("Cache entry refreshed", slog.String("key", key))
+
+	respondJSON(w, struct {
+		Key            string    `json:"key"`
+		ExpirationDate time.Time `json:"expirationDate"`
+	}{
+		Key:            key,
+		ExpirationDate: expirationDate,
+	})
+}
+
+func (srv *Server) handleAdminCacheStats(w http.ResponseWriter, r *http.Request) {
+	res := struct {
+		Cache         any                            `json:"cache,omitempty"`
+		ParseWarnings map[uek.ParseWarningReason]int `json:"parseWarnings"`
+	}{
+		ParseWarnings: srv.uek.ParseWarningCounts(),
+	}
+	if srv.cacheAdmin != nil {
+		res.Cache = srv.cacheAdmin.Stats(r.Context())
+	}
+
+	respondJSON(w, res)
+}
+
+func (srv *Server) handleAdminExportCache(w http.ResponseWriter, r *http.Request) {
+	// exports can outlive the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"cache-%s.jsonl\"", time.Now().UTC().Format("20060102T150405Z")))
+
+	if err := srv.cacheAdmin.(uek.CacheSnapshotter).Export(r.Context(), w); err != nil && !errors.Is(err, context.Canceled) {
+		srv.logger.Error("Failed to export cache", slog.Any("err", err))
+		// headers are already sent
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (srv *Server) handleAdminImportCache(w http.ResponseWriter, r *http.Request) {
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	importedCount, err := srv.cacheAdmin.(uek.CacheSnapshotter).Import(r.Context(), r.Body)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			srv.logger.Error("Failed to import cache", slog.Int("imported", importedCount), slog.String("requestId", requestIdFromContext(r.Context())), slog.Any("err", err))
+		}
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, "Failed to import cache", map[string]int{"imported": importedCount})
+		return
+	}
+	srv.logger.Info("Cache imported", slog.Int("imported", importedCount))
+
+	respondJSON(w, struct {
+		Imported int `json:"imported"`
+	}{
+		Imported: importedCount,
+	})
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/digest"
+)
+
+func (srv *Server) registerAdminDigestRoutes() {
+	if srv.digests == nil {
+		return
+	}
+
+	mux := srv.httpServer.Handler.(*http.ServeMux)
+
+	mux.HandleFunc("GET /api/admin/digest/subscriptions", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminListDigestSubscriptions))))
+	mux.HandleFunc("DELETE /api/admin/digest/subscriptions/{id}", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminDeleteDigestSubscription))))
+	mux.HandleFunc("POST /api/admin/digest/subscriptions/{id}/send", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminSendDigest))))
+}
+
+func (srv *Server) handleAdminListDigestSubscriptions(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, srv.digests.List())
+}
+
+func (srv *Server) handleAdminDeleteDigestSubscription(w http.ResponseWriter, r *http.Request) {
+	if !srv.digests.Delete(r.PathValue("id")) {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sends the upcoming week's digest right away, for previewing emails
+func (srv *Server) handleAdminSendDigest(w http.ResponseWriter, r *http.Request) {
+	if err := srv.digests.SendNow(r.Context(), r.PathValue("id")); err != nil {
+		if errors.Is(err, digest.ErrSubscriptionNotFound) {
+			respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+			return
+		}
+		if !errors.Is(err, context.Canceled) {
+			srv.logger.Error("Failed to send digest", slog.String("id", r.PathValue("id")), slog.String("requestId", requestIdFromContext(r.Context())), slog.Any("err", err))
+		}
+		respondError(w, r, http.StatusInternalServerError, errorCodeInternal, "Failed to send digest", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func (srv *Server) registerAdminOccupancyRoutes() {
+	if srv.occupancy == nil {
+		return
+	}
+
+	mux := srv.httpServer.Handler.(*http.ServeMux)
+
+	mux.HandleFunc("POST /api/admin/occupancy/generate", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminGenerateOccupancy))))
+}
+
+// periodId defaults to the current academic year, the report replaces the previous one
+func (srv *Server) handleAdminGenerateOccupancy(w http.ResponseWriter, r *http.Request) {
+	periodId := 0
+	if rawPeriodId := r.URL.Query().Get("periodId"); rawPeriodId != "" {
+		var err error
+		if periodId, err = strconv.Atoi(rawPeriodId); err != nil || periodId == 0 {
+			respondError(w, r, http.StatusBadRequest, errorCodeInvalidPeriodId, "periodId must be a nonzero integer", nil)
+			return
+		}
+	}
+
+	if !srv.occupancy.GenerateInBackground(periodId) {
+		respondError(w, r, http.StatusConflict, errorCodeReportInProgress, "A report is already being generated", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+)
+
+func (srv *Server) registerAdminPushRoutes() {
+	if srv.webPush == nil {
+		return
+	}
+
+	mux := srv.httpServer.Handler.(*http.ServeMux)
+
+	mux.HandleFunc("GET /api/admin/push/subscriptions", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminListPushSubscriptions))))
+	mux.HandleFunc("POST /api/admin/push/subscriptions/{id}/test", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminTestPush))))
+}
+
+func (srv *Server) handleAdminListPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, srv.webPush.List())
+}
+
+func (srv *Server) handleAdminTestPush(w http.ResponseWriter, r *http.Request) {
+	if !srv.webPush.SendTest(r.PathValue("id")) {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
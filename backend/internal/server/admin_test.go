@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/admincli"
+	"github.com/szczursonn/uek-planzajec-v3/internal/memcache"
+)
+
+const testAdminToken = "test-admin-token"
+
+func TestAdminCacheRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cache := memcache.New(16*1024*1024, logger)
+	t.Cleanup(cache.Close)
+
+	srv := New(Config{
+		Uek:        newTestUekClient(t, cache),
+		CacheAdmin: cache,
+		AdminToken: testAdminToken,
+		Logger:     logger,
+	})
+	testServer := httptest.NewServer(srv.httpServer.Handler)
+	t.Cleanup(testServer.Close)
+
+	run := func(token string, args ...string) (string, error) {
+		output := &bytes.Buffer{}
+		err := admincli.Run(t.Context(), admincli.Config{
+			BaseUrl: testServer.URL,
+			Token:   token,
+			Output:  output,
+		}, args)
+		return output.String(), err
+	}
+	mustRun := func(args ...string) string {
+		t.Helper()
+		output, err := run(testAdminToken, args...)
+		if err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		return output
+	}
+
+	for _, token := range []string{"", "wrong-token"} {
+		if _, err := run(token, "stats"); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("expected token %q to be rejected, got %v", token, err)
+		}
+	}
+	if _, err := run("", "refresh", "groupings"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected a refresh without a token to be rejected, got %v", err)
+	}
+	if entries, _ := cache.ListEntries(t.Context(), ""); len(entries) != 0 {
+		t.Fatalf("expected rejected requests not to touch the cache, got %+v", entries)
+	}
+
+	// lecturer headers are requested without a grouping
+	for _, key := range []string{"groupings", "headers-lecturer-", "headers-room-Paw.A"} {
+		refreshed := struct {
+			Key string `json:"key"`
+		}{}
+		if err := json.Unmarshal([]byte(mustRun("refresh", key)), &refreshed); err != nil || refreshed.Key != key {
+			t.Errorf("unexpected refresh response for %s: %+v, err: %v", key, refreshed, err)
+		}
+	}
+	if _, err := run(testAdminToken, "refresh", "schedule-group-1"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected an invalid key to be rejected, got %v", err)
+	}
+
+	list := mustRun("list")
+	for _, key := range []string{"groupings", "periods", "headers-lecturer-", "headers-room-Paw.A"} {
+		if !strings.Contains(list, key+" ") {
+			t.Errorf("expected %s to be listed, got:\n%s", key, list)
+		}
+	}
+	entry := struct {
+		Key   string `json:"key"`
+		Value []any  `json:"value"`
+	}{}
+	if err := json.Unmarshal([]byte(mustRun("get", "headers-lecturer-")), &entry); err != nil || len(entry.Value) == 0 {
+		t.Errorf("expected lecturer headers, got %+v, err: %v", entry, err)
+	}
+
+	deleted := struct {
+		Deleted int `json:"deleted"`
+	}{}
+	if err := json.Unmarshal([]byte(mustRun("invalidate", "-pattern", "headers-*")), &deleted); err != nil || deleted.Deleted != 2 {
+		t.Errorf("expected both headers to be deleted, got %+v, err: %v", deleted, err)
+	}
+	// keys are matched literally
+	if err := json.Unmarshal([]byte(mustRun("invalidate", "period?")), &deleted); err != nil || deleted.Deleted != 0 {
+		t.Errorf("expected nothing to be deleted, got %+v, err: %v", deleted, err)
+	}
+	if err := json.Unmarshal([]byte(mustRun("invalidate", "periods")), &deleted); err != nil || deleted.Deleted != 1 {
+		t.Errorf("expected periods to be deleted, got %+v, err: %v", deleted, err)
+	}
+
+	if list := mustRun("list"); !strings.Contains(list, "groupings ") || strings.Contains(list, "headers-") || strings.Contains(list, "periods ") {
+		t.Errorf("expected only groupings to be left, got:\n%s", list)
+	}
+	if _, err := run(testAdminToken, "get", "headers-lecturer-"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the deleted entry to be gone, got %v", err)
+	}
+}
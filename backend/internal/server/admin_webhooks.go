@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/webhook"
+)
+
+func (srv *Server) registerAdminWebhookRoutes() {
+	if srv.webhooks == nil {
+		return
+	}
+
+	mux := srv.httpServer.Handler.(*http.ServeMux)
+
+	mux.HandleFunc("GET /api/admin/webhooks", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminListWebhooks))))
+	mux.HandleFunc("POST /api/admin/webhooks", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminCreateWebhook))))
+	mux.HandleFunc("GET /api/admin/webhooks/{id}", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminGetWebhook))))
+	mux.HandleFunc("DELETE /api/admin/webhooks/{id}", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminDeleteWebhook))))
+	mux.HandleFunc("POST /api/admin/webhooks/{id}/enable", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminEnableWebhook))))
+	mux.HandleFunc("POST /api/admin/webhooks/{id}/ping", srv.requestIdMiddleware(srv.debugLoggingMiddleware(srv.adminAuthMiddleware(srv.handleAdminPingWebhook))))
+}
+
+func (srv *Server) handleAdminListWebhooks(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, srv.webhooks.List())
+}
+
+// the secret is only included in this response
+func (srv *Server) handleAdminCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	req := webhook.CreateSubscriptionRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, "Body must be a json subscription", nil)
+		return
+	}
+
+	sub, err := srv.webhooks.Create(req)
+	if err != nil {
+		if errors.Is(err, webhook.ErrInvalidSubscription) {
+			respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, err.Error(), map[string]any{"events": webhook.EventTypes})
+			return
+		}
+		respondError(w, r, http.StatusInternalServerError, errorCodeInternal, "Failed to create subscription", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (srv *Server) handleAdminGetWebhook(w http.ResponseWriter, r *http.Request) {
+	sub, ok := srv.webhooks.Get(r.PathValue("id"))
+	if !ok {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+		return
+	}
+
+	respondJSON(w, sub)
+}
+
+func (srv *Server) handleAdminDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !srv.webhooks.Delete(r.PathValue("id")) {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *Server) handleAdminEnableWebhook(w http.ResponseWriter, r *http.Request) {
+	sub, ok := srv.webhooks.Enable(r.PathValue("id"))
+	if !ok {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+		return
+	}
+
+	respondJSON(w, sub)
+}
+
+func (srv *Server) handleAdminPingWebhook(w http.ResponseWriter, r *http.Request) {
+	deliveryId, ok := srv.webhooks.Ping(r.PathValue("id"))
+	if !ok {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(struct {
+		DeliveryId string `json:"deliveryId"`
+	}{
+		DeliveryId: deliveryId,
+	})
+}
@@ -1,15 +1,10 @@
 package server
 
 import (
-	"context"
-	"encoding/base64"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
@@ -22,22 +17,39 @@ const maxSchedulesPerRequest = 4
 func (srv *Server) registerAPIRoutes() {
 	mux := srv.httpServer.Handler.(*http.ServeMux)
 
-	mux.HandleFunc("GET /api/", srv.debugLoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		respondNotFound(w)
-	}))
-	mux.HandleFunc("GET /api/groupings", srv.debugLoggingMiddleware(srv.handleGroupings))
-	mux.HandleFunc("GET /api/headers", srv.debugLoggingMiddleware(srv.handleHeaders))
-	mux.HandleFunc("GET /api/aggregateSchedule", srv.debugLoggingMiddleware(srv.handleAggregateSchedule))
-	mux.HandleFunc("GET /api/ical/{payload}", srv.debugLoggingMiddleware(srv.handleICal))
+	mux.HandleFunc("GET /api/", srv.requestIdMiddleware(srv.debugLoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Unknown endpoint", nil)
+	})))
+	srv.registerAPIEndpoint(groupingsEndpoint, srv.handleGroupings)
+	srv.registerAPIEndpoint(headersEndpoint, srv.handleHeaders)
+	srv.registerAPIEndpoint(aggregateScheduleEndpoint, srv.handleAggregateSchedule)
+	srv.registerAPIEndpoint(icalEndpoint, srv.handleICal)
+	srv.registerAPIEndpoint(scheduleRangeEndpoint, srv.handleScheduleRange)
+	srv.registerAPIEndpoint(nowEndpoint, srv.handleNow)
+	srv.registerAPIEndpoint(statsEndpoint, srv.handleStats)
+	srv.registerAPIEndpoint(lecturerProfileEndpoint, srv.handleLecturerProfile)
+	srv.registerAPIEndpoint(conflictsEndpoint, srv.handleConflicts)
+	srv.registerAPIEndpoint(streamEndpoint, srv.handleStream)
+	srv.registerAPIEndpoint(openAPIDocumentEndpoint, srv.handleOpenAPIDocument)
 }
 
-func (srv *Server) handleGroupings(w http.ResponseWriter, r *http.Request) {
+// params are validated against the endpoint definition before the handler runs
+func (srv *Server) registerAPIEndpoint(ep *apiEndpoint, handler func(w http.ResponseWriter, r *http.Request, params requestParams)) {
+	srv.httpServer.Handler.(*http.ServeMux).HandleFunc(ep.pattern(), srv.requestIdMiddleware(srv.debugLoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		params, err := parseRequestParams(r, ep)
+		if err != nil {
+			respondValidationError(w, r, err)
+			return
+		}
+
+		handler(w, r, params)
+	})))
+}
+
+func (srv *Server) handleGroupings(w http.ResponseWriter, r *http.Request, _ requestParams) {
 	groupings, cacheExpirationDate, err := srv.uek.GetGroupings(r.Context())
 	if err != nil {
-		if !errors.Is(err, context.Canceled) {
-			srv.logger.Error("Failed to get groupings", slog.Any("err", err))
-		}
-		respondServiceUnavailable(w)
+		srv.respondUpstreamError(w, r, "Failed to get groupings", err)
 		return
 	}
 
@@ -45,21 +57,12 @@ func (srv *Server) handleGroupings(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, groupings)
 }
 
-func (srv *Server) handleHeaders(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	scheduleType, groupingName := uek.ScheduleType(q.Get("type")), q.Get("grouping")
-
-	if !scheduleType.IsValid() {
-		respondBadRequest(w)
-		return
-	}
+func (srv *Server) handleHeaders(w http.ResponseWriter, r *http.Request, params requestParams) {
+	scheduleType, groupingName := uek.ScheduleType(params.string("type")), params.string("grouping")
 
 	headers, cacheExpirationDate, err := srv.uek.GetHeaders(r.Context(), scheduleType, groupingName)
 	if err != nil {
-		if !errors.Is(err, context.Canceled) {
-			srv.logger.Error("Failed to get headers", slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.String("groupingName", groupingName)), slog.Any("err", err))
-		}
-		respondServiceUnavailable(w)
+		srv.respondUpstreamError(w, r, "Failed to get headers", err, slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.String("groupingName", groupingName)))
 		return
 	}
 
@@ -67,70 +70,23 @@ func (srv *Server) handleHeaders(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, headers)
 }
 
-func (srv *Server) handleAggregateSchedule(w http.ResponseWriter, r *http.Request) {
-	queryParams := r.URL.Query()
-	scheduleType := uek.ScheduleType(queryParams.Get("type"))
-	if !scheduleType.IsValid() {
-		respondBadRequest(w)
-		return
-	}
-
-	scheduleIds := []int{}
-	for _, rawScheduleId := range queryParams["id"] {
-		scheduleId, err := strconv.Atoi(rawScheduleId)
-		if err != nil || slices.Contains(scheduleIds, scheduleId) {
-			respondBadRequest(w)
-			return
-		}
-
-		scheduleIds = append(scheduleIds, scheduleId)
-	}
-	if len(scheduleIds) == 0 || len(scheduleIds) > maxSchedulesPerRequest {
-		respondBadRequest(w)
-		return
-	}
+func (srv *Server) handleAggregateSchedule(w http.ResponseWriter, r *http.Request, params requestParams) {
+	scheduleType, scheduleIds := uek.ScheduleType(params.string("type")), params.ints("id")
 
 	periods, periodsCacheExpirationDate, err := srv.uek.GetSchedulePeriods(r.Context())
 	if err != nil {
-		respondServiceUnavailable(w)
+		srv.respondUpstreamError(w, r, "Failed to get periods", err)
 		return
 	}
 
-	requestPeriodIdString := strings.TrimSpace(queryParams.Get("periodId"))
-	var requestPeriodId int
-	if requestPeriodIdString == "" {
-		var ok bool
-		if requestPeriodId, ok = pickCurrentYearPeriodId(periods); !ok {
-			respondServiceUnavailable(w)
-			return
-		}
-	} else {
-		requestPeriodId, err = strconv.Atoi(requestPeriodIdString)
-		if err != nil {
-			respondBadRequest(w)
-			return
-		}
-
-		idFound := false
-		for _, period := range periods {
-			if period.Id == requestPeriodId {
-				idFound = true
-				break
-			}
-		}
-
-		if !idFound {
-			respondBadRequest(w)
-			return
-		}
+	requestPeriodId, archived, ok := resolveRequestPeriodId(w, r, params, periods)
+	if !ok {
+		return
 	}
 
 	aggregateSchedule, aggregateScheduleCacheExpirationDate, err := srv.uek.GetAggregateSchedule(r.Context(), scheduleType, scheduleIds, requestPeriodId)
 	if err != nil {
-		if !errors.Is(err, context.Canceled) {
-			srv.logger.Error("Failed to get schedule", slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.Any("scheduleIds", scheduleIds), slog.Int("periodId", requestPeriodId)), slog.Any("err", err))
-		}
-		respondServiceUnavailable(w)
+		srv.respondUpstreamError(w, r, "Failed to get schedule", err, slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.Any("scheduleIds", scheduleIds), slog.Int("periodId", requestPeriodId)))
 		return
 	}
 
@@ -140,44 +96,56 @@ func (srv *Server) handleAggregateSchedule(w http.ResponseWriter, r *http.Reques
 		setCacheHeader(w, periodsCacheExpirationDate)
 	}
 
-	respondJSON(w, struct {
+	res := struct {
 		Schedule *uek.AggregateSchedule `json:"schedule"`
 		Periods  []uek.SchedulePeriod   `json:"periods"`
+		Warnings []uek.ParseWarning     `json:"warnings,omitempty"`
+		Archived bool                   `json:"archived,omitempty"`
+		// indexes point into schedule.items
+		Conflicts []scheduleConflict `json:"conflicts"`
 	}{
-		Schedule: aggregateSchedule,
-		Periods:  periods,
-	})
+		Schedule:  aggregateSchedule,
+		Periods:   periods,
+		Archived:  archived,
+		Conflicts: detectConflicts(scheduleType, aggregateSchedule.Headers, aggregateSchedule.Items),
+	}
+	if params.bool("warnings") {
+		res.Warnings = aggregateSchedule.Warnings
+	}
+
+	respondJSON(w, res)
 }
 
-func (srv *Server) handleICal(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) handleICal(w http.ResponseWriter, r *http.Request, params requestParams) {
 	const icalTimestampFormat = "20060102T150405Z"
 	type icalPayload struct {
 		ScheduleType   uek.ScheduleType `json:"scheduleType"`
 		ScheduleIds    []int            `json:"scheduleIds"`
 		HiddenSubjects []string         `json:"hiddenSubjects"`
+		MarkConflicts  bool             `json:"markConflicts"`
 	}
 
 	payload := icalPayload{}
-	if err := json.NewDecoder(base64.NewDecoder(base64.StdEncoding, strings.NewReader(r.PathValue("payload")))).Decode(&payload); err != nil || len(payload.ScheduleIds) == 0 || len(payload.ScheduleIds) > maxSchedulesPerRequest {
-		respondBadRequest(w)
+	if err := decodeBase64JSONParam(schemaRef("ICalPayload"), "payload", params.string("payload"), &payload); err != nil {
+		respondValidationError(w, r, err)
 		return
 	}
 
 	periods, periodsCacheExpirationDate, err := srv.uek.GetSchedulePeriods(r.Context())
 	if err != nil {
-		respondServiceUnavailable(w)
+		srv.respondUpstreamError(w, r, "Failed to get periods", err)
 		return
 	}
 
 	currentYearPeriodId, ok := pickCurrentYearPeriodId(periods)
 	if !ok {
-		respondServiceUnavailable(w)
+		respondError(w, r, http.StatusServiceUnavailable, errorCodeNoCurrentPeriod, "No period covers the current date", nil)
 		return
 	}
 
 	aggregateSchedule, aggregateScheduleCacheExpirationDate, err := srv.uek.GetAggregateSchedule(r.Context(), payload.ScheduleType, payload.ScheduleIds, currentYearPeriodId)
 	if err != nil {
-		respondServiceUnavailable(w)
+		srv.respondUpstreamError(w, r, "Failed to get schedule", err, slog.Group("params", slog.String("scheduleType", string(payload.ScheduleType)), slog.Any("scheduleIds", payload.ScheduleIds), slog.Int("periodId", currentYearPeriodId)))
 		return
 	}
 
@@ -207,18 +175,30 @@ func (srv *Server) handleICal(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "NAME: %s\nX-WR-CALNAME: %s\n", calendarName, calendarName)
 	dtStamp := time.Now().UTC().Format(icalTimestampFormat)
 
-	for _, item := range aggregateSchedule.Items {
-		if slices.Contains(payload.HiddenSubjects, item.Subject) {
-			continue
-		}
+	// hidden subjects can't conflict with anything
+	items := slices.DeleteFunc(slices.Clone(aggregateSchedule.Items), func(item *uek.ScheduleItem) bool {
+		return slices.Contains(payload.HiddenSubjects, item.Subject)
+	})
 
+	conflictNotes := map[int][]string{}
+	if payload.MarkConflicts {
+		conflictNotes = describeConflicts(items, detectConflicts(payload.ScheduleType, aggregateSchedule.Headers, items))
+	}
+
+	for i, item := range items {
 		fmt.Fprintf(w, "BEGIN:VEVENT\nUID:%s\nSEQUENCE:0\nDTSTAMP:%s\nDTSTART:%s\nDTEND:%s\nSUMMARY:", uuid.NewString(), dtStamp, item.Start.UTC().Format(icalTimestampFormat), item.End.UTC().Format(icalTimestampFormat))
+		if len(conflictNotes[i]) > 0 {
+			fmt.Fprint(w, "[konflikt] ")
+		}
 		if item.Extra != "" {
 			fmt.Fprint(w, "[!] ")
 		}
 		fmt.Fprintf(w, "[%s] %s\n", item.Type, item.Subject)
 
 		fmt.Fprint(w, "DESCRIPTION:")
+		for _, note := range conflictNotes[i] {
+			fmt.Fprint(w, note, "\\n\\n")
+		}
 		if item.Extra != "" {
 			fmt.Fprint(w, item.Extra, "\\n\\n")
 		}
@@ -233,7 +213,7 @@ func (srv *Server) handleICal(w http.ResponseWriter, r *http.Request) {
 				}
 				fmt.Fprint(w, lecturer.Name)
 				if lecturer.MoodleId != 0 {
-					fmt.Fprintf(w, " (https://e-uczelnia.uek.krakow.pl/course/view.php?id=%d)", lecturer.MoodleId)
+					fmt.Fprintf(w, " (%s)", moodleUrl(lecturer.MoodleId))
 				}
 				fmt.Fprint(w, "\\n\\n")
 			}
@@ -269,27 +249,27 @@ func (srv *Server) handleICal(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "END:VCALENDAR")
 }
 
-func pickCurrentYearPeriodId(periods []uek.SchedulePeriod) (int, bool) {
-	now := time.Now()
-
-	var longestPeriodContainingNowId *int
-	longestPeriodContainingNowDuration := time.Duration(0)
-
-	for _, period := range periods {
-		if period.Start.After(now) || period.End.Before(now) {
-			continue
+// periodId param or the current academic year, responds with an error when neither can be used
+func resolveRequestPeriodId(w http.ResponseWriter, r *http.Request, params requestParams, periods []uek.SchedulePeriod) (int, bool, bool) {
+	requestPeriodId, hasRequestPeriodId := params.int("periodId")
+	if !hasRequestPeriodId {
+		periodId, ok := pickCurrentYearPeriodId(periods)
+		if !ok {
+			respondError(w, r, http.StatusServiceUnavailable, errorCodeNoCurrentPeriod, "No period covers the current date, periodId is required", nil)
 		}
+		return periodId, false, ok
+	}
 
-		periodDuration := period.End.Sub(period.Start)
-		if periodDuration > longestPeriodContainingNowDuration {
-			longestPeriodContainingNowId = &period.Id
-			longestPeriodContainingNowDuration = periodDuration
+	for _, period := range periods {
+		if period.Id == requestPeriodId {
+			return requestPeriodId, period.Archived, true
 		}
 	}
 
-	if longestPeriodContainingNowId == nil {
-		return 0, false
-	}
+	respondError(w, r, http.StatusBadRequest, errorCodeUnknownPeriodId, "Unknown period id", map[string]any{"periodId": requestPeriodId})
+	return 0, false, false
+}
 
-	return *longestPeriodContainingNowId, true
+func pickCurrentYearPeriodId(periods []uek.SchedulePeriod) (int, bool) {
+	return uek.CurrentYearPeriodId(periods, time.Now())
 }
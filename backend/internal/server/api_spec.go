@@ -0,0 +1,794 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/digest"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/webpush"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func schemaRef(name string) *openAPISchema {
+	return &openAPISchema{
+		Ref: "#/components/schemas/" + name,
+	}
+}
+
+func closedObjectSchema(description string, properties map[string]*openAPISchema, required ...string) *openAPISchema {
+	return &openAPISchema{
+		Type:                 "object",
+		Description:          description,
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: ptr(false),
+	}
+}
+
+var scheduleIdsSchema = &openAPISchema{
+	Type: "array",
+	Items: &openAPISchema{
+		Type: "integer",
+		errorCodes: map[string]errorCode{
+			"": errorCodeInvalidScheduleId,
+		},
+	},
+	MinItems:    ptr(1),
+	MaxItems:    ptr(maxSchedulesPerRequest),
+	UniqueItems: true,
+	errorCodes: map[string]errorCode{
+		"":            errorCodeInvalidScheduleId,
+		"required":    errorCodeMissingScheduleId,
+		"minItems":    errorCodeMissingScheduleId,
+		"maxItems":    errorCodeTooManyScheduleIds,
+		"uniqueItems": errorCodeDuplicateScheduleId,
+	},
+}
+
+// subscriptions drop duplicate ids themselves and allow more of them than reads
+func subscriptionScheduleIdsSchema(maxItems int) *openAPISchema {
+	schema := *scheduleIdsSchema
+	schema.MaxItems = ptr(maxItems)
+	schema.UniqueItems = false
+	return &schema
+}
+
+var apiSchemas = map[string]*openAPISchema{
+	"ScheduleType": {
+		Type: "string",
+		Enum: []string{string(uek.ScheduleTypeGroup), string(uek.ScheduleTypeLecturer), string(uek.ScheduleTypeRoom)},
+		errorCodes: map[string]errorCode{
+			"": errorCodeInvalidScheduleType,
+		},
+	},
+	"Groupings": closedObjectSchema("Names of groupings that can be passed to /api/headers", map[string]*openAPISchema{
+		"groups": {Type: "array", Nullable: true, Items: &openAPISchema{Type: "string"}},
+		"rooms":  {Type: "array", Nullable: true, Items: &openAPISchema{Type: "string"}},
+	}, "groups", "rooms"),
+	"ScheduleHeader": closedObjectSchema("", map[string]*openAPISchema{
+		"id":   {Type: "integer"},
+		"name": {Type: "string"},
+	}, "id", "name"),
+	"SchedulePeriod": closedObjectSchema("", map[string]*openAPISchema{
+		"id":       {Type: "integer", Description: "Negative for periods only available in the archive"},
+		"start":    {Type: "string", Format: "date-time"},
+		"end":      {Type: "string", Format: "date-time"},
+		"archived": {Type: "boolean"},
+	}, "id", "start", "end"),
+	"ScheduleItemLecturer": closedObjectSchema("", map[string]*openAPISchema{
+		"name":     {Type: "string"},
+		"moodleId": {Type: "integer"},
+	}, "name"),
+	"ScheduleItemRoom": closedObjectSchema("", map[string]*openAPISchema{
+		"name": {Type: "string"},
+		"url":  {Type: "string", Description: "Present for online classes"},
+	}, "name"),
+	"ScheduleItem": closedObjectSchema("", map[string]*openAPISchema{
+		"start":     {Type: "string", Format: "date-time"},
+		"end":       {Type: "string", Format: "date-time"},
+		"subject":   {Type: "string"},
+		"type":      {Type: "string"},
+		"groups":    {Type: "array", Items: &openAPISchema{Type: "string"}},
+		"lecturers": {Type: "array", Items: schemaRef("ScheduleItemLecturer")},
+		"room":      schemaRef("ScheduleItemRoom"),
+		"extra":     {Type: "string"},
+	}, "start", "end", "subject", "type"),
+	"AggregateSchedule": closedObjectSchema("Items of all requested schedules merged and sorted by start", map[string]*openAPISchema{
+		"headers": {Type: "array", Items: schemaRef("ScheduleHeader")},
+		"items":   {Type: "array", Items: schemaRef("ScheduleItem")},
+	}, "headers", "items"),
+	"ParseWarning": closedObjectSchema("", map[string]*openAPISchema{
+		"scheduleId": {Type: "integer"},
+		"itemIndex":  {Type: "integer", Description: "-1 for problems with the schedule itself"},
+		"reason":     {Type: "string", Enum: []string{string(uek.ParseWarningReasonInvalidDate), string(uek.ParseWarningReasonStartAfterEnd), string(uek.ParseWarningReasonInvalidMoodleId)}},
+		"action":     {Type: "string", Enum: []string{string(uek.ParseWarningActionDropped), string(uek.ParseWarningActionRepaired)}},
+		"message":    {Type: "string"},
+	}, "scheduleId", "itemIndex", "reason", "action", "message"),
+	"AggregateScheduleResponse": closedObjectSchema("", map[string]*openAPISchema{
+		"schedule":  schemaRef("AggregateSchedule"),
+		"periods":   {Type: "array", Items: schemaRef("SchedulePeriod")},
+		"warnings":  {Type: "array", Items: schemaRef("ParseWarning")},
+		"archived":  {Type: "boolean", Description: "Schedule was served from the archive"},
+		"conflicts": {Type: "array", Items: schemaRef("ScheduleConflict"), Description: "Conflicts between items of different schedules, itemIndexes point into schedule.items"},
+	}, "schedule", "periods", "conflicts"),
+	"ScheduleConflict": closedObjectSchema("Two items of different schedules that overlap, or are in different buildings with too short a break in between", map[string]*openAPISchema{
+		"kind":        schemaRef("ScheduleConflictKind"),
+		"itemIndexes": {Type: "array", Items: &openAPISchema{Type: "integer"}, MinItems: ptr(2), MaxItems: ptr(2), Description: "Earlier item first"},
+		"minutes":     {Type: "integer", Description: "How long the items overlap, or the break between them for room transitions"},
+	}, "kind", "itemIndexes", "minutes"),
+	"ScheduleConflictKind": {
+		Type: "string",
+		Enum: []string{string(conflictKindOverlap), string(conflictKindRoomTransition)},
+	},
+	"ConflictsResponse": closedObjectSchema("", map[string]*openAPISchema{
+		"periodId": {Type: "integer"},
+		"headers":  {Type: "array", Items: schemaRef("ScheduleHeader")},
+		"conflicts": {Type: "array", Description: "Earliest first", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"kind":    schemaRef("ScheduleConflictKind"),
+			"minutes": {Type: "integer", Description: "How long the items overlap, or the break between them for room transitions"},
+			"items":   {Type: "array", Items: schemaRef("ScheduleItem"), MinItems: ptr(2), MaxItems: ptr(2), Description: "Earlier item first"},
+		}, "kind", "minutes", "items")},
+	}, "periodId", "headers", "conflicts"),
+	"ICalPayload": {
+		Type:        "object",
+		Description: "Sent base64 encoded in the path",
+		Properties: map[string]*openAPISchema{
+			"scheduleType":   schemaRef("ScheduleType"),
+			"scheduleIds":    scheduleIdsSchema,
+			"hiddenSubjects": {Type: "array", Nullable: true, Items: &openAPISchema{Type: "string"}},
+			"markConflicts":  {Type: "boolean", Description: "Prefix conflicting events with [konflikt] and describe the conflict"},
+		},
+		Required: []string{"scheduleType", "scheduleIds"},
+		errorCodes: map[string]errorCode{
+			"":         errorCodeInvalidPayload,
+			"required": errorCodeInvalidPayload,
+		},
+	},
+	"ScheduleRangeResponse": closedObjectSchema("One page of items overlapping the date range", map[string]*openAPISchema{
+		"from":       {Type: "string", Format: "date"},
+		"to":         {Type: "string", Format: "date"},
+		"headers":    {Type: "array", Items: schemaRef("ScheduleHeader")},
+		"items":      {Type: "array", Items: schemaRef("ScheduleItem")},
+		"periods":    {Type: "array", Items: schemaRef("SchedulePeriod"), Description: "Periods the items were taken from"},
+		"total":      {Type: "integer", Description: "Item count across all pages"},
+		"offset":     {Type: "integer"},
+		"limit":      {Type: "integer"},
+		"nextOffset": {Type: "integer", Description: "Absent on the last page"},
+	}, "from", "to", "headers", "items", "periods", "total", "offset", "limit"),
+	"NowResponse": closedObjectSchema("Computed in Europe/Warsaw time", map[string]*openAPISchema{
+		"now":                     {Type: "string", Format: "date-time"},
+		"current":                 schemaRef("ScheduleItem"),
+		"minutesUntilCurrentEnds": {Type: "integer"},
+		"overlappingCurrent":      {Type: "array", Items: schemaRef("ScheduleItem"), Description: "Other items in progress at the same time as current, absent when there are none"},
+		"next":                    schemaRef("ScheduleItem"),
+		"minutesUntilNext":        {Type: "integer"},
+		"breakMinutes":            {Type: "integer", Description: "Gap before the next class, only when it is today and something happened earlier today"},
+		"remainingToday":          {Type: "array", Items: schemaRef("ScheduleItem"), Description: "Items after the current one that start today"},
+		"restOfDayOnline":         {Type: "boolean", Description: "Current and remaining items are all online, false when there are none"},
+	}, "now", "remainingToday", "restOfDayOnline"),
+	"StatsCounts": closedObjectSchema("Hours are 45 minute teaching hours", map[string]*openAPISchema{
+		"count":          {Type: "integer"},
+		"hours":          {Type: "number"},
+		"completedCount": {Type: "integer"},
+		"completedHours": {Type: "number"},
+		"remainingCount": {Type: "integer", Description: "Includes classes in progress"},
+		"remainingHours": {Type: "number"},
+		"onlineCount":    {Type: "integer"},
+		"onlineHours":    {Type: "number"},
+	}, "count", "hours", "completedCount", "completedHours", "remainingCount", "remainingHours", "onlineCount", "onlineHours"),
+	"TypeStats": closedObjectSchema("", map[string]*openAPISchema{
+		"type":   {Type: "string"},
+		"counts": schemaRef("StatsCounts"),
+	}, "type", "counts"),
+	"StatsResponse": closedObjectSchema("Computed in Europe/Warsaw time, hours are 45 minute teaching hours, cancelled slots and reservations are left out", map[string]*openAPISchema{
+		"now":         {Type: "string", Format: "date-time"},
+		"periodId":    {Type: "integer"},
+		"total":       schemaRef("StatsCounts"),
+		"onlineShare": {Type: "number", Description: "Share of hours held online, from 0 to 1"},
+		"bySubject": {Type: "array", Description: "Most hours first", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"subject": {Type: "string"},
+			"counts":  schemaRef("StatsCounts"),
+			"byType":  {Type: "array", Items: schemaRef("TypeStats")},
+		}, "subject", "counts", "byType")},
+		"byType": {Type: "array", Description: "Most hours first", Items: schemaRef("TypeStats")},
+		"weeklyLoad": {Type: "array", Description: "Every week from the first to the last item, including empty ones", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"weekStart": {Type: "string", Format: "date", Description: "Monday"},
+			"count":     {Type: "integer"},
+			"hours":     {Type: "number"},
+		}, "weekStart", "count", "hours")},
+		"busiestDays": {Type: "array", Description: "Weekdays with classes, most hours first", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"weekday": {Type: "integer", Minimum: ptr(1), Maximum: ptr(7), Description: "ISO weekday, 1 is monday"},
+			"count":   {Type: "integer"},
+			"hours":   {Type: "number"},
+			"days":    {Type: "integer", Description: "Distinct dates with classes on this weekday"},
+		}, "weekday", "count", "hours", "days")},
+		"longestDay": closedObjectSchema("Most time between the first start and the last end", map[string]*openAPISchema{
+			"date":  {Type: "string", Format: "date"},
+			"start": {Type: "string", Format: "date-time"},
+			"end":   {Type: "string", Format: "date-time"},
+			"count": {Type: "integer"},
+			"hours": {Type: "number"},
+		}, "date", "start", "end", "count", "hours"),
+	}, "now", "periodId", "total", "onlineShare", "bySubject", "byType", "weeklyLoad", "busiestDays"),
+	"LecturerProfileResponse": closedObjectSchema("Lists are sorted by count, most common first", map[string]*openAPISchema{
+		"header":    schemaRef("ScheduleHeader"),
+		"periodId":  {Type: "integer"},
+		"moodleId":  {Type: "integer"},
+		"moodleUrl": {Type: "string"},
+		"subjects": {Type: "array", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"subject": {Type: "string"},
+			"types":   {Type: "array", Items: &openAPISchema{Type: "string"}},
+			"groups":  {Type: "array", Items: &openAPISchema{Type: "string"}},
+			"count":   {Type: "integer"},
+			"hours":   {Type: "number", Description: "45 minute teaching hours"},
+		}, "subject", "types", "groups", "count", "hours")},
+		"groups": {Type: "array", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"name":  {Type: "string"},
+			"count": {Type: "integer"},
+		}, "name", "count")},
+		"rooms": {Type: "array", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"name":   {Type: "string"},
+			"online": {Type: "boolean"},
+			"count":  {Type: "integer"},
+		}, "name", "count")},
+		"days": {Type: "array", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"weekday": {Type: "integer", Minimum: ptr(1), Maximum: ptr(7), Description: "ISO weekday, 1 is monday"},
+			"count":   {Type: "integer"},
+		}, "weekday", "count")},
+		"hours": {Type: "array", Description: "Start and end times of classes in Europe/Warsaw", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"start": {Type: "string", Description: "HH:MM"},
+			"end":   {Type: "string", Description: "HH:MM"},
+			"count": {Type: "integer"},
+		}, "start", "end", "count")},
+		"coTeachers": {Type: "array", Description: "Other lecturers of the same classes, found in schedules of the rooms the lecturer uses. Online classes and rooms that failed to load aren't covered", Items: closedObjectSchema("", map[string]*openAPISchema{
+			"name":      {Type: "string"},
+			"moodleId":  {Type: "integer"},
+			"moodleUrl": {Type: "string"},
+			"subjects":  {Type: "array", Items: &openAPISchema{Type: "string"}},
+			"count":     {Type: "integer", Description: "Classes taught together"},
+		}, "name", "subjects", "count")},
+	}, "header", "periodId", "subjects", "groups", "rooms", "days", "hours", "coTeachers"),
+	"OccupancyReport": closedObjectSchema("Hours are clock hours in Europe/Warsaw", map[string]*openAPISchema{
+		"periodId":     {Type: "integer"},
+		"generatedAt":  {Type: "string", Format: "date-time"},
+		"teachingDays": {Type: "integer", Description: "Dates with a class in any room, so holidays and exam breaks don't count as free time"},
+		"openingHour":  {Type: "integer", Description: "Heatmaps and utilisation only cover hours from openingHour to closingHour"},
+		"closingHour":  {Type: "integer"},
+		"occupancy":    schemaRef("Occupancy"),
+		"buildings":    {Type: "array", Items: schemaRef("OccupancyBuilding")},
+		"missingRooms": {Type: "array", Items: schemaRef("ScheduleHeader"), Description: "Rooms whose schedules couldn't be fetched, left out of the report"},
+	}, "periodId", "generatedAt", "teachingDays", "openingHour", "closingHour", "occupancy", "buildings", "missingRooms"),
+	"OccupancyBuilding": closedObjectSchema("A room grouping", map[string]*openAPISchema{
+		"name":      {Type: "string"},
+		"occupancy": schemaRef("Occupancy"),
+		"rooms":     {Type: "array", Items: schemaRef("OccupancyRoom"), Description: "Most utilised first"},
+	}, "name", "occupancy", "rooms"),
+	"OccupancyRoom": closedObjectSchema("", map[string]*openAPISchema{
+		"id":        {Type: "integer"},
+		"name":      {Type: "string"},
+		"occupancy": schemaRef("Occupancy"),
+	}, "id", "name", "occupancy"),
+	"Occupancy": closedObjectSchema("", map[string]*openAPISchema{
+		"utilisation":       {Type: "number", Description: "Percent of opening hours on teaching days"},
+		"occupiedHours":     {Type: "number", Description: "Including time outside opening hours"},
+		"doubleBookedHours": {Type: "number", Description: "Time with two or more different classes in a room at once"},
+		"heatmap":           {Type: "array", Items: &openAPISchema{Type: "array", Items: &openAPISchema{Type: "number"}}, Description: "A row per weekday from monday, a column per hour from openingHour, percent of the hour the rooms were occupied on an average teaching day"},
+		"peakHours":         {Type: "array", Items: schemaRef("OccupancyPeakHour"), Description: "Busiest heatmap cells, most occupied first"},
+	}, "utilisation", "occupiedHours", "doubleBookedHours", "heatmap", "peakHours"),
+	"OccupancyPeakHour": closedObjectSchema("", map[string]*openAPISchema{
+		"weekday":   {Type: "integer", Minimum: ptr(1), Maximum: ptr(7), Description: "ISO weekday, 1 is monday"},
+		"hour":      {Type: "integer"},
+		"occupancy": {Type: "number"},
+	}, "weekday", "hour", "occupancy"),
+	"DigestSubscribeRequest": {
+		Type:        "object",
+		Description: "A confirmation email is sent first, the digest only after the link in it is followed",
+		Properties: map[string]*openAPISchema{
+			"email":        {Type: "string", Format: "email"},
+			"scheduleType": schemaRef("ScheduleType"),
+			"scheduleIds":  subscriptionScheduleIdsSchema(digest.MaxScheduleIdsPerSubscription),
+		},
+		Required: []string{"email", "scheduleType", "scheduleIds"},
+		errorCodes: map[string]errorCode{
+			"":         errorCodeInvalidPayload,
+			"required": errorCodeInvalidPayload,
+		},
+	},
+	"PushSubscribeRequest": {
+		Type:        "object",
+		Description: "Subscribing an already subscribed endpoint replaces its schedule selection",
+		Properties: map[string]*openAPISchema{
+			"subscription": {
+				Type:        "object",
+				Description: "As serialized by PushSubscription.toJSON",
+				Properties: map[string]*openAPISchema{
+					"endpoint":       {Type: "string", Format: "uri"},
+					"expirationTime": {Type: "integer", Nullable: true, Description: "Unix milliseconds"},
+					"keys": {
+						Type: "object",
+						Properties: map[string]*openAPISchema{
+							"p256dh": {Type: "string", Description: "Base64url encoded"},
+							"auth":   {Type: "string", Description: "Base64url encoded"},
+						},
+						Required: []string{"p256dh", "auth"},
+						errorCodes: map[string]errorCode{
+							"": errorCodeInvalidPayload,
+						},
+					},
+				},
+				Required: []string{"endpoint", "keys"},
+				errorCodes: map[string]errorCode{
+					"": errorCodeInvalidPayload,
+				},
+			},
+			"scheduleType":   schemaRef("ScheduleType"),
+			"scheduleIds":    subscriptionScheduleIdsSchema(webpush.MaxScheduleIdsPerSelection),
+			"hiddenSubjects": {Type: "array", Nullable: true, Items: &openAPISchema{Type: "string"}, MaxItems: ptr(webpush.MaxHiddenSubjects)},
+			"reminders":      {Type: "boolean", Description: "Notify before classes, defaults to true"},
+			"changeAlerts":   {Type: "boolean", Description: "Notify about changes to upcoming classes, defaults to true"},
+		},
+		Required: []string{"subscription", "scheduleType", "scheduleIds"},
+		errorCodes: map[string]errorCode{
+			"":         errorCodeInvalidPayload,
+			"required": errorCodeInvalidPayload,
+		},
+	},
+	"Error": closedObjectSchema("", map[string]*openAPISchema{
+		"error": closedObjectSchema("", map[string]*openAPISchema{
+			"code":       {Type: "string"},
+			"message":    {Type: "string"},
+			"details":    {Type: "object"},
+			"requestId":  {Type: "string"},
+			"retryAfter": {Type: "integer", Description: "Seconds, also sent in the Retry-After header"},
+		}, "code", "message"),
+	}, "error"),
+}
+
+var (
+	scheduleTypeParam = &apiParam{
+		Name:     "type",
+		In:       "query",
+		Required: true,
+		Schema:   schemaRef("ScheduleType"),
+	}
+
+	scheduleIdsParam = &apiParam{
+		Name:     "id",
+		In:       "query",
+		Required: true,
+		Schema:   scheduleIdsSchema,
+	}
+
+	periodIdParam = &apiParam{
+		Name:        "periodId",
+		In:          "query",
+		Description: "Defaults to the period of the current academic year",
+		Schema: &openAPISchema{
+			Type: "integer",
+			errorCodes: map[string]errorCode{
+				"": errorCodeInvalidPeriodId,
+			},
+		},
+	}
+
+	digestTokenParam = &apiParam{
+		Name:        "token",
+		In:          "query",
+		Description: "From the link in the email",
+		Required:    true,
+		Schema:      &openAPISchema{Type: "string"},
+	}
+
+	errorResponses = []apiResponse{
+		{StatusCode: http.StatusBadRequest, Description: "Invalid parameters", ContentType: "application/json", Schema: schemaRef("Error")},
+		{Description: "Upstream or internal failure", ContentType: "application/json", Schema: schemaRef("Error")},
+	}
+)
+
+var (
+	groupingsEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/groupings",
+		OperationId: "getGroupings",
+		Summary:     "List group and room groupings",
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "Groupings", ContentType: "application/json", Schema: schemaRef("Groupings")},
+			errorResponses[1],
+		},
+	}
+
+	headersEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/headers",
+		OperationId: "getHeaders",
+		Summary:     "List schedules of a type in a grouping",
+		Params: []*apiParam{
+			scheduleTypeParam,
+			{
+				Name:        "grouping",
+				In:          "query",
+				Description: "Grouping name from /api/groupings, ignored for lecturers",
+				Schema:      &openAPISchema{Type: "string"},
+			},
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Schedule headers", ContentType: "application/json", Schema: &openAPISchema{Type: "array", Items: schemaRef("ScheduleHeader")}},
+		}, errorResponses...),
+	}
+
+	aggregateScheduleEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/aggregateSchedule",
+		OperationId: "getAggregateSchedule",
+		Summary:     "Get schedules of the same type merged together",
+		Params: []*apiParam{
+			scheduleTypeParam,
+			scheduleIdsParam,
+			periodIdParam,
+			{
+				Name:        "warnings",
+				In:          "query",
+				Description: "Include parse warnings",
+				Schema:      &openAPISchema{Type: "boolean"},
+			},
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Aggregate schedule", ContentType: "application/json", Schema: schemaRef("AggregateScheduleResponse")},
+			{StatusCode: http.StatusNotFound, Description: "Schedule was not archived for the period", ContentType: "application/json", Schema: schemaRef("Error")},
+		}, errorResponses...),
+	}
+
+	icalEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/ical/{payload}",
+		OperationId: "getICal",
+		Summary:     "Export the current period of an aggregate schedule as iCalendar",
+		Params: []*apiParam{
+			{
+				Name:        "payload",
+				In:          "path",
+				Description: "Base64 encoded ICalPayload json",
+				Required:    true,
+				Schema: &openAPISchema{
+					Type:   "string",
+					Format: "byte",
+					errorCodes: map[string]errorCode{
+						"": errorCodeInvalidPayload,
+					},
+				},
+			},
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "iCalendar file", ContentType: "text/calendar", Schema: &openAPISchema{Type: "string"}},
+		}, errorResponses...),
+	}
+
+	scheduleRangeEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/v2/schedule",
+		OperationId: "getScheduleRange",
+		Summary:     "Get merged schedules between two dates, regardless of UEK periods",
+		Params: []*apiParam{
+			scheduleTypeParam,
+			scheduleIdsParam,
+			{
+				Name:        "from",
+				In:          "query",
+				Description: "First day, inclusive, in Europe/Warsaw",
+				Required:    true,
+				Schema:      &openAPISchema{Type: "string", Format: "date"},
+			},
+			{
+				Name:        "to",
+				In:          "query",
+				Description: fmt.Sprintf("Last day, inclusive, in Europe/Warsaw. At most %d days after from", maxScheduleRangeDays),
+				Required:    true,
+				Schema:      &openAPISchema{Type: "string", Format: "date"},
+			},
+			{
+				Name:        "limit",
+				In:          "query",
+				Description: fmt.Sprintf("Items per page, defaults to %d", defaultScheduleRangeLimit),
+				Schema:      &openAPISchema{Type: "integer", Minimum: ptr(1), Maximum: ptr(maxScheduleRangeLimit)},
+			},
+			{
+				Name:        "offset",
+				In:          "query",
+				Description: "Taken from nextOffset of the previous page",
+				Schema:      &openAPISchema{Type: "integer", Minimum: ptr(0)},
+			},
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Page of the schedule", ContentType: "application/json", Schema: schemaRef("ScheduleRangeResponse")},
+		}, errorResponses...),
+	}
+
+	nowEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/now",
+		OperationId: "getNow",
+		Summary:     "Get the current and next class, for widgets and displays",
+		Params: []*apiParam{
+			scheduleTypeParam,
+			scheduleIdsParam,
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Current state", ContentType: "application/json", Schema: schemaRef("NowResponse")},
+		}, errorResponses...),
+	}
+
+	statsEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/stats",
+		OperationId: "getStats",
+		Summary:     "Get hours per subject and type, semester progress and weekly load of an aggregate schedule",
+		Params: []*apiParam{
+			scheduleTypeParam,
+			scheduleIdsParam,
+			periodIdParam,
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Statistics", ContentType: "application/json", Schema: schemaRef("StatsResponse")},
+			{StatusCode: http.StatusNotFound, Description: "Schedule was not archived for the period", ContentType: "application/json", Schema: schemaRef("Error")},
+		}, errorResponses...),
+	}
+
+	lecturerProfileEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/lecturerProfile",
+		OperationId: "getLecturerProfile",
+		Summary:     "Get subjects, groups, rooms, usual hours and co-teachers of a lecturer",
+		Params: []*apiParam{
+			{
+				Name:        "id",
+				In:          "query",
+				Description: "Id of a lecturer header",
+				Required:    true,
+				Schema: &openAPISchema{
+					Type: "integer",
+					errorCodes: map[string]errorCode{
+						"":         errorCodeInvalidScheduleId,
+						"required": errorCodeMissingScheduleId,
+					},
+				},
+			},
+			periodIdParam,
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Lecturer profile", ContentType: "application/json", Schema: schemaRef("LecturerProfileResponse")},
+			{StatusCode: http.StatusNotFound, Description: "Schedule was not archived for the period", ContentType: "application/json", Schema: schemaRef("Error")},
+		}, errorResponses...),
+	}
+
+	conflictsEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/conflicts",
+		OperationId: "getConflicts",
+		Summary:     fmt.Sprintf("List overlapping classes of different schedules and room changes to another building in under %d minutes", int(minBuildingChangeGap.Minutes())),
+		Params: []*apiParam{
+			scheduleTypeParam,
+			scheduleIdsParam,
+			periodIdParam,
+			{
+				Name:        "hiddenSubject",
+				In:          "query",
+				Description: "Subjects to leave out, may be repeated",
+				Schema:      &openAPISchema{Type: "array", Items: &openAPISchema{Type: "string"}},
+			},
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Conflicts", ContentType: "application/json", Schema: schemaRef("ConflictsResponse")},
+			{StatusCode: http.StatusNotFound, Description: "Schedule was not archived for the period", ContentType: "application/json", Schema: schemaRef("Error")},
+		}, errorResponses...),
+	}
+
+	streamEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/stream",
+		OperationId: "getStream",
+		Summary:     "Server-sent events for the current period: \"schedule\" when the schedule changes, \"now\" when the current or next class changes",
+		Params: []*apiParam{
+			scheduleTypeParam,
+			scheduleIdsParam,
+			{
+				Name:        "Last-Event-ID",
+				In:          "header",
+				Description: "Sent by EventSource on reconnect, events the client has already seen are skipped",
+				Schema:      &openAPISchema{Type: "string"},
+			},
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusOK, Description: "Event stream, event data is json", ContentType: "text/event-stream", Schema: &openAPISchema{Type: "string"}},
+		}, errorResponses...),
+	}
+
+	// occupancy endpoints are only registered when reports are enabled
+	occupancyEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/occupancy",
+		OperationId: "getOccupancy",
+		Summary:     "Get the last report of room utilisation per building and hour",
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "Occupancy report", ContentType: "application/json", Schema: schemaRef("OccupancyReport")},
+			{StatusCode: http.StatusNotFound, Description: "No report has been generated yet", ContentType: "application/json", Schema: schemaRef("Error")},
+		},
+	}
+
+	occupancyCSVEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/occupancy.csv",
+		OperationId: "getOccupancyCSV",
+		Summary:     "Export the last occupancy report as CSV",
+		Params: []*apiParam{
+			{
+				Name:        "view",
+				In:          "query",
+				Description: "A row per room, or the heatmap of every building. Defaults to rooms",
+				Schema:      &openAPISchema{Type: "string", Enum: []string{"rooms", "heatmap"}},
+			},
+		},
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "CSV file", ContentType: "text/csv", Schema: &openAPISchema{Type: "string"}},
+			{StatusCode: http.StatusNotFound, Description: "No report has been generated yet", ContentType: "application/json", Schema: schemaRef("Error")},
+			errorResponses[0],
+		},
+	}
+
+	// digest endpoints are only registered when email is configured, the pages are opened from links in emails
+	digestSubscribeEndpoint = &apiEndpoint{
+		Method:      http.MethodPost,
+		Path:        "/api/digest/subscriptions",
+		OperationId: "subscribeDigest",
+		Summary:     "Subscribe an email address to a weekly digest of the upcoming week's classes",
+		RequestBody: &apiRequestBody{
+			Schema: schemaRef("DigestSubscribeRequest"),
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusAccepted, Description: "Sent for every valid request, whether the address was subscribed before is not revealed", ContentType: "application/json", Schema: closedObjectSchema("", map[string]*openAPISchema{
+				"status": {Type: "string", Enum: []string{"confirmationSent"}},
+			}, "status")},
+		}, errorResponses...),
+	}
+
+	digestConfirmPageEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/digest/confirm",
+		OperationId: "getDigestConfirmPage",
+		Summary:     "Page with a button that confirms a digest subscription",
+		Params:      []*apiParam{digestTokenParam},
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "Confirmation page", ContentType: "text/html", Schema: &openAPISchema{Type: "string"}},
+			errorResponses[0],
+		},
+	}
+
+	digestConfirmEndpoint = &apiEndpoint{
+		Method:      http.MethodPost,
+		Path:        "/api/digest/confirm",
+		OperationId: "confirmDigest",
+		Summary:     "Confirm a digest subscription",
+		Params:      []*apiParam{digestTokenParam},
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "Subscription confirmed", ContentType: "text/html", Schema: &openAPISchema{Type: "string"}},
+			{StatusCode: http.StatusNotFound, Description: "Token is unknown or the confirmation expired", ContentType: "text/html", Schema: &openAPISchema{Type: "string"}},
+			errorResponses[0],
+		},
+	}
+
+	digestUnsubscribePageEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/digest/unsubscribe",
+		OperationId: "getDigestUnsubscribePage",
+		Summary:     "Page with a button that cancels a digest subscription",
+		Params:      []*apiParam{digestTokenParam},
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "Unsubscribe page", ContentType: "text/html", Schema: &openAPISchema{Type: "string"}},
+			errorResponses[0],
+		},
+	}
+
+	digestUnsubscribeEndpoint = &apiEndpoint{
+		Method:      http.MethodPost,
+		Path:        "/api/digest/unsubscribe",
+		OperationId: "unsubscribeDigest",
+		Summary:     "Cancel a digest subscription, also the one-click unsubscribe (RFC 8058) target",
+		Params:      []*apiParam{digestTokenParam},
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "Subscription cancelled", ContentType: "text/html", Schema: &openAPISchema{Type: "string"}},
+			{StatusCode: http.StatusNotFound, Description: "Token is unknown", ContentType: "text/html", Schema: &openAPISchema{Type: "string"}},
+			errorResponses[0],
+		},
+	}
+
+	// push endpoints are only registered when web push is configured
+	pushPublicKeyEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/push/public-key",
+		OperationId: "getPushPublicKey",
+		Summary:     "Get the VAPID public key browsers subscribe with",
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "Public key", ContentType: "application/json", Schema: closedObjectSchema("", map[string]*openAPISchema{
+				"publicKey": {Type: "string", Description: "Base64url encoded, the applicationServerKey"},
+			}, "publicKey")},
+		},
+	}
+
+	pushSubscribeEndpoint = &apiEndpoint{
+		Method:      http.MethodPost,
+		Path:        "/api/push/subscriptions",
+		OperationId: "subscribePush",
+		Summary:     "Subscribe a browser to reminders before classes and alerts about schedule changes",
+		RequestBody: &apiRequestBody{
+			Schema: schemaRef("PushSubscribeRequest"),
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusCreated, Description: "Subscription saved, the id is kept when an endpoint subscribes again", ContentType: "application/json", Schema: closedObjectSchema("", map[string]*openAPISchema{
+				"id": {Type: "string"},
+			}, "id")},
+			{StatusCode: http.StatusServiceUnavailable, Description: "No more subscriptions are accepted", ContentType: "application/json", Schema: schemaRef("Error")},
+		}, errorResponses...),
+	}
+
+	pushUnsubscribeEndpoint = &apiEndpoint{
+		Method:      http.MethodDelete,
+		Path:        "/api/push/subscriptions",
+		OperationId: "unsubscribePush",
+		Summary:     "Unsubscribe a browser, the endpoint is only known to the browser and its push service, so it authorizes the request",
+		RequestBody: &apiRequestBody{
+			Schema: &openAPISchema{
+				Type: "object",
+				Properties: map[string]*openAPISchema{
+					"endpoint": {Type: "string", Format: "uri"},
+				},
+				Required: []string{"endpoint"},
+				errorCodes: map[string]errorCode{
+					"": errorCodeInvalidPayload,
+				},
+			},
+		},
+		Responses: append([]apiResponse{
+			{StatusCode: http.StatusNoContent, Description: "Unsubscribed"},
+			{StatusCode: http.StatusNotFound, Description: "Endpoint is not subscribed", ContentType: "application/json", Schema: schemaRef("Error")},
+		}, errorResponses...),
+	}
+
+	openAPIDocumentEndpoint = &apiEndpoint{
+		Method:      http.MethodGet,
+		Path:        "/api/openapi.json",
+		OperationId: "getOpenAPIDocument",
+		Summary:     "This document",
+		Responses: []apiResponse{
+			{StatusCode: http.StatusOK, Description: "OpenAPI document", ContentType: "application/json", Schema: &openAPISchema{Type: "object"}},
+		},
+	}
+)
+
+var apiEndpoints = []*apiEndpoint{
+	groupingsEndpoint,
+	headersEndpoint,
+	aggregateScheduleEndpoint,
+	icalEndpoint,
+	scheduleRangeEndpoint,
+	nowEndpoint,
+	statsEndpoint,
+	lecturerProfileEndpoint,
+	conflictsEndpoint,
+	streamEndpoint,
+	occupancyEndpoint,
+	occupancyCSVEndpoint,
+	digestSubscribeEndpoint,
+	digestConfirmPageEndpoint,
+	digestConfirmEndpoint,
+	digestUnsubscribePageEndpoint,
+	digestUnsubscribeEndpoint,
+	pushPublicKeyEndpoint,
+	pushSubscribeEndpoint,
+	pushUnsubscribeEndpoint,
+	openAPIDocumentEndpoint,
+}
+
+var apiDocument = buildOpenAPIDocument(apiEndpoints, apiSchemas)
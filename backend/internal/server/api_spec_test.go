@@ -0,0 +1,531 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+	"github.com/szczursonn/uek-planzajec-v3/internal/digest"
+	"github.com/szczursonn/uek-planzajec-v3/internal/occupancy"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+	"github.com/szczursonn/uek-planzajec-v3/internal/webpush"
+)
+
+// go types behind component schemas, properties and required lists must match their json tags
+var apiSchemaTypes = map[string]reflect.Type{
+	"Groupings":               reflect.TypeFor[uek.Groupings](),
+	"ScheduleHeader":          reflect.TypeFor[uek.ScheduleHeader](),
+	"SchedulePeriod":          reflect.TypeFor[uek.SchedulePeriod](),
+	"ScheduleItemLecturer":    reflect.TypeFor[uek.ScheduleItemLecturer](),
+	"ScheduleItemRoom":        reflect.TypeFor[uek.ScheduleItemRoom](),
+	"ScheduleItem":            reflect.TypeFor[uek.ScheduleItem](),
+	"AggregateSchedule":       reflect.TypeFor[uek.AggregateSchedule](),
+	"ParseWarning":            reflect.TypeFor[uek.ParseWarning](),
+	"ScheduleRangeResponse":   reflect.TypeFor[scheduleRangeResponse](),
+	"NowResponse":             reflect.TypeFor[nowResponse](),
+	"StatsResponse":           reflect.TypeFor[statsResponse](),
+	"StatsCounts":             reflect.TypeFor[statsCounts](),
+	"TypeStats":               reflect.TypeFor[typeStats](),
+	"LecturerProfileResponse": reflect.TypeFor[lecturerProfileResponse](),
+	"ScheduleConflict":        reflect.TypeFor[scheduleConflict](),
+	"ConflictsResponse":       reflect.TypeFor[conflictsResponse](),
+	"OccupancyReport":         reflect.TypeFor[occupancy.Report](),
+	"OccupancyBuilding":       reflect.TypeFor[occupancy.Building](),
+	"OccupancyRoom":           reflect.TypeFor[occupancy.Room](),
+	"Occupancy":               reflect.TypeFor[occupancy.Occupancy](),
+	"OccupancyPeakHour":       reflect.TypeFor[occupancy.PeakHour](),
+	"DigestSubscribeRequest":  reflect.TypeFor[digest.SubscribeRequest](),
+	"PushSubscribeRequest":    reflect.TypeFor[webpush.SubscribeRequest](),
+	"Error":                   reflect.TypeFor[errorResponse](),
+}
+
+func TestComponentSchemasMatchTypes(t *testing.T) {
+	for name, typ := range apiSchemaTypes {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := apiSchemas[name]
+			if !ok {
+				t.Fatalf("schema %s is not defined", name)
+			}
+
+			assertSchemaMatchesType(t, name, schema, typ)
+		})
+	}
+}
+
+func assertSchemaMatchesType(t *testing.T, path string, schema *openAPISchema, typ reflect.Type) {
+	t.Helper()
+	schema = resolveSchema(schema)
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	var expectedType string
+	switch {
+	case typ == reflect.TypeFor[time.Time]():
+		expectedType = "string"
+		if schema.Format != "date-time" {
+			t.Errorf("%s: expected date-time format, got %q", path, schema.Format)
+		}
+	case typ.Kind() == reflect.Interface:
+		// free-form
+		return
+	case typ.Kind() == reflect.String:
+		expectedType = "string"
+	case typ.Kind() == reflect.Bool:
+		expectedType = "boolean"
+	case typ.Kind() == reflect.Int || typ.Kind() == reflect.Int64:
+		expectedType = "integer"
+	case typ.Kind() == reflect.Float64:
+		expectedType = "number"
+	case typ.Kind() == reflect.Slice:
+		expectedType = "array"
+	case typ.Kind() == reflect.Struct:
+		expectedType = "object"
+	default:
+		t.Fatalf("%s: unsupported kind %s", path, typ.Kind())
+	}
+	if schema.Type != expectedType {
+		t.Errorf("%s: expected type %s, got %q", path, expectedType, schema.Type)
+		return
+	}
+
+	switch expectedType {
+	case "array":
+		assertSchemaMatchesType(t, path+"[]", schema.Items, typ.Elem())
+	case "object":
+		if typ == reflect.TypeFor[time.Time]() {
+			return
+		}
+
+		fieldNames := []string{}
+		for _, field := range reflect.VisibleFields(typ) {
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			fieldNames = append(fieldNames, name)
+
+			propertyPath := path + "." + name
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				t.Errorf("%s: missing from schema", propertyPath)
+				continue
+			}
+
+			omitted := slices.Contains(strings.Split(opts, ","), "omitempty") || slices.Contains(strings.Split(opts, ","), "omitzero")
+			if required := slices.Contains(schema.Required, name); required == omitted {
+				t.Errorf("%s: required is %t, but field is omitted when empty: %t", propertyPath, required, omitted)
+			}
+
+			assertSchemaMatchesType(t, propertyPath, propertySchema, field.Type)
+		}
+
+		for name := range schema.Properties {
+			if !slices.Contains(fieldNames, name) {
+				t.Errorf("%s.%s: not present in %s", path, name, typ)
+			}
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	srv := newTestServer(t)
+	res := serveTestRequest(srv, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+
+	doc := map[string]any{}
+	if err := json.Unmarshal(res.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if doc["openapi"] != openAPIVersion {
+		t.Errorf("expected openapi %s, got %v", openAPIVersion, doc["openapi"])
+	}
+
+	// every $ref must point at a component
+	refRegex := regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`)
+	for _, match := range refRegex.FindAllStringSubmatch(res.Body.String(), -1) {
+		if _, ok := apiSchemas[match[1]]; !ok {
+			t.Errorf("unresolved $ref to %s", match[1])
+		}
+	}
+
+	operationIds := []string{}
+	pathParamRegex := regexp.MustCompile(`\{([^}]+)\}`)
+	for _, ep := range apiEndpoints {
+		if slices.Contains(operationIds, ep.OperationId) {
+			t.Errorf("%s: duplicate operationId %s", ep.pattern(), ep.OperationId)
+		}
+		operationIds = append(operationIds, ep.OperationId)
+
+		if _, ok := ep.response(http.StatusOK); !ok {
+			t.Errorf("%s: no 200 response", ep.pattern())
+		}
+
+		pathParamNames := []string{}
+		for _, match := range pathParamRegex.FindAllStringSubmatch(ep.Path, -1) {
+			pathParamNames = append(pathParamNames, match[1])
+		}
+		for _, param := range ep.Params {
+			if param.In != "query" && param.In != "path" && param.In != "header" {
+				t.Errorf("%s: param %s has unsupported location %q", ep.pattern(), param.Name, param.In)
+			}
+			if param.In == "path" && (!param.Required || !slices.Contains(pathParamNames, param.Name)) {
+				t.Errorf("%s: path param %s must be required and present in the path", ep.pattern(), param.Name)
+			}
+			pathParamNames = slices.DeleteFunc(pathParamNames, func(name string) bool { return name == param.Name && param.In == "path" })
+		}
+		if len(pathParamNames) > 0 {
+			t.Errorf("%s: path params %v are not declared", ep.pattern(), pathParamNames)
+		}
+	}
+}
+
+type apiTestCase struct {
+	name string
+	url  string
+	// json, for endpoints with a request body
+	body string
+	// 0 means any status declared by the endpoint
+	expectedStatus int
+	expectedCode   errorCode
+	// for streams, the request is canceled after it
+	timeout time.Duration
+}
+
+func TestAPIMatchesSpec(t *testing.T) {
+	srv := newTestServer(t)
+
+	periods, _, err := srv.uek.GetSchedulePeriods(context.Background())
+	if err != nil || len(periods) == 0 {
+		t.Fatalf("failed to get periods: %v", err)
+	}
+	groupings, _, err := srv.uek.GetGroupings(context.Background())
+	if err != nil || len(groupings.Groups) == 0 {
+		t.Fatalf("failed to get groupings: %v", err)
+	}
+	headers, _, err := srv.uek.GetHeaders(context.Background(), uek.ScheduleTypeGroup, groupings.Groups[0])
+	if err != nil || len(headers) < 2 {
+		t.Fatalf("failed to get headers: %v", err)
+	}
+	groupId, otherGroupId, periodId := headers[0].Id, headers[1].Id, periods[0].Id
+	lecturerHeaders, _, err := srv.uek.GetHeaders(context.Background(), uek.ScheduleTypeLecturer, "")
+	if err != nil || len(lecturerHeaders) == 0 {
+		t.Fatalf("failed to get lecturer headers: %v", err)
+	}
+	lecturerId := lecturerHeaders[0].Id
+
+	// not found cases are covered before the report is generated
+	for _, url := range []string{"/api/occupancy", "/api/occupancy.csv"} {
+		if res := serveTestRequest(srv, httptest.NewRequest(http.MethodGet, url, nil)); res.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404 without a report, got %d", url, res.Code)
+		}
+	}
+	if !srv.occupancy.GenerateInBackground(0) {
+		t.Fatal("occupancy report is already being generated")
+	}
+	for deadline := time.Now().Add(10 * time.Second); srv.occupancy.Report() == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("occupancy report was not generated")
+		}
+	}
+
+	uaPrivateKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushKeys := fmt.Sprintf(`{"p256dh":"%s","auth":"%s"}`, base64.RawURLEncoding.EncodeToString(uaPrivateKey.PublicKey().Bytes()), base64.RawURLEncoding.EncodeToString(make([]byte, 16)))
+	pushSubscription := func(endpoint string, scheduleIds string) string {
+		return fmt.Sprintf(`{"subscription":{"endpoint":"%s","expirationTime":null,"keys":%s},"scheduleType":"group","scheduleIds":%s}`, endpoint, pushKeys, scheduleIds)
+	}
+
+	icalPayload := func(payload string) string {
+		return base64.StdEncoding.EncodeToString([]byte(payload))
+	}
+
+	// every endpoint needs at least one case, so new endpoints can't skip being checked against the spec
+	testCasesByOperationId := map[string][]apiTestCase{
+		"getGroupings": {
+			{name: "ok", url: "/api/groupings", expectedStatus: http.StatusOK},
+		},
+		"getHeaders": {
+			{name: "ok", url: "/api/headers?type=group&grouping=" + url.QueryEscape(groupings.Groups[0]), expectedStatus: http.StatusOK},
+			{name: "lecturers", url: "/api/headers?type=lecturer", expectedStatus: http.StatusOK},
+			{name: "missing type", url: "/api/headers", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleType},
+			{name: "invalid type", url: "/api/headers?type=x", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleType},
+		},
+		"getAggregateSchedule": {
+			{name: "ok", url: fmt.Sprintf("/api/aggregateSchedule?type=group&id=%d&id=%d&periodId=%d&warnings=true", groupId, otherGroupId, periodId), expectedStatus: http.StatusOK},
+			{name: "current period", url: fmt.Sprintf("/api/aggregateSchedule?type=group&id=%d", groupId)},
+			{name: "invalid type", url: fmt.Sprintf("/api/aggregateSchedule?type=x&id=%d", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleType},
+			{name: "missing id", url: "/api/aggregateSchedule?type=group", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeMissingScheduleId},
+			{name: "invalid id", url: "/api/aggregateSchedule?type=group&id=a", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleId},
+			{name: "duplicate id", url: fmt.Sprintf("/api/aggregateSchedule?type=group&id=%d&id=%d", groupId, groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeDuplicateScheduleId},
+			{name: "too many ids", url: "/api/aggregateSchedule?type=group&id=1&id=2&id=3&id=4&id=5", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeTooManyScheduleIds},
+			{name: "invalid period id", url: fmt.Sprintf("/api/aggregateSchedule?type=group&id=%d&periodId=a", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPeriodId},
+			{name: "unknown period id", url: fmt.Sprintf("/api/aggregateSchedule?type=group&id=%d&periodId=999999", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeUnknownPeriodId},
+			{name: "invalid warnings", url: fmt.Sprintf("/api/aggregateSchedule?type=group&id=%d&warnings=maybe", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidParameter},
+		},
+		"getICal": {
+			{name: "current period", url: "/api/ical/" + icalPayload(fmt.Sprintf(`{"scheduleType":"group","scheduleIds":[%d],"hiddenSubjects":[]}`, groupId))},
+			{name: "conflicts", url: "/api/ical/" + icalPayload(fmt.Sprintf(`{"scheduleType":"group","scheduleIds":[%d,%d],"markConflicts":true}`, groupId, otherGroupId))},
+			{name: "not base64", url: "/api/ical/%21%21", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "not json", url: "/api/ical/" + icalPayload("{"), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "missing ids", url: "/api/ical/" + icalPayload(`{"scheduleType":"group"}`), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "empty ids", url: "/api/ical/" + icalPayload(`{"scheduleType":"group","scheduleIds":[]}`), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeMissingScheduleId},
+			{name: "invalid type", url: "/api/ical/" + icalPayload(`{"scheduleType":"x","scheduleIds":[1]}`), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleType},
+		},
+		"getScheduleRange": {
+			{name: "ok", url: fmt.Sprintf("/api/v2/schedule?type=group&id=%d&id=%d&from=%s&to=%s&limit=5", groupId, otherGroupId, periods[0].Start.Format(time.DateOnly), periods[0].Start.AddDate(0, 1, 0).Format(time.DateOnly)), expectedStatus: http.StatusOK},
+			{name: "outside periods", url: fmt.Sprintf("/api/v2/schedule?type=group&id=%d&from=2000-01-01&to=2000-01-31", groupId), expectedStatus: http.StatusOK},
+			{name: "invalid date", url: fmt.Sprintf("/api/v2/schedule?type=group&id=%d&from=2026-13-01&to=2027-01-01", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidParameter},
+			{name: "missing to", url: fmt.Sprintf("/api/v2/schedule?type=group&id=%d&from=2026-10-01", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidParameter},
+			{name: "reversed", url: fmt.Sprintf("/api/v2/schedule?type=group&id=%d&from=2026-10-02&to=2026-10-01", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidDateRange},
+			{name: "too long", url: fmt.Sprintf("/api/v2/schedule?type=group&id=%d&from=2026-01-01&to=2027-06-01", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidDateRange},
+			{name: "limit too high", url: fmt.Sprintf("/api/v2/schedule?type=group&id=%d&from=2026-10-01&to=2026-10-02&limit=100000", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidParameter},
+		},
+		"getNow": {
+			{name: "ok", url: fmt.Sprintf("/api/now?type=group&id=%d", groupId), expectedStatus: http.StatusOK},
+			{name: "missing id", url: "/api/now?type=group", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeMissingScheduleId},
+		},
+		"getStats": {
+			{name: "ok", url: fmt.Sprintf("/api/stats?type=group&id=%d&id=%d&periodId=%d", groupId, otherGroupId, periodId), expectedStatus: http.StatusOK},
+			{name: "current period", url: fmt.Sprintf("/api/stats?type=group&id=%d", groupId)},
+			{name: "unknown period id", url: fmt.Sprintf("/api/stats?type=group&id=%d&periodId=999999", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeUnknownPeriodId},
+			{name: "missing id", url: "/api/stats?type=group", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeMissingScheduleId},
+		},
+		"getLecturerProfile": {
+			{name: "ok", url: fmt.Sprintf("/api/lecturerProfile?id=%d&periodId=%d", lecturerId, periodId), expectedStatus: http.StatusOK},
+			{name: "current period", url: fmt.Sprintf("/api/lecturerProfile?id=%d", lecturerId)},
+			{name: "missing id", url: "/api/lecturerProfile", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeMissingScheduleId},
+			{name: "invalid id", url: "/api/lecturerProfile?id=a", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleId},
+		},
+		"getConflicts": {
+			{name: "ok", url: fmt.Sprintf("/api/conflicts?type=group&id=%d&id=%d&periodId=%d", groupId, otherGroupId, periodId), expectedStatus: http.StatusOK},
+			{name: "current period", url: fmt.Sprintf("/api/conflicts?type=group&id=%d&id=%d&hiddenSubject=x&hiddenSubject=y", groupId, otherGroupId)},
+			{name: "missing id", url: "/api/conflicts?type=group", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeMissingScheduleId},
+		},
+		"getStream": {
+			{name: "ok", url: fmt.Sprintf("/api/stream?type=group&id=%d&id=%d", groupId, otherGroupId), expectedStatus: http.StatusOK, timeout: 100 * time.Millisecond},
+			{name: "invalid type", url: fmt.Sprintf("/api/stream?type=x&id=%d", groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleType},
+		},
+		"getOccupancy": {
+			{name: "ok", url: "/api/occupancy", expectedStatus: http.StatusOK},
+		},
+		"getOccupancyCSV": {
+			{name: "rooms", url: "/api/occupancy.csv", expectedStatus: http.StatusOK},
+			{name: "heatmap", url: "/api/occupancy.csv?view=heatmap", expectedStatus: http.StatusOK},
+			{name: "invalid view", url: "/api/occupancy.csv?view=x", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidParameter},
+		},
+		"subscribeDigest": {
+			{name: "ok", url: "/api/digest/subscriptions", body: fmt.Sprintf(`{"email":"student@example.com","scheduleType":"group","scheduleIds":[%d,%d]}`, groupId, otherGroupId), expectedStatus: http.StatusAccepted},
+			{name: "not json", url: "/api/digest/subscriptions", body: "{", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "missing email", url: "/api/digest/subscriptions", body: fmt.Sprintf(`{"scheduleType":"group","scheduleIds":[%d]}`, groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "invalid email", url: "/api/digest/subscriptions", body: fmt.Sprintf(`{"email":"student","scheduleType":"group","scheduleIds":[%d]}`, groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "invalid type", url: "/api/digest/subscriptions", body: fmt.Sprintf(`{"email":"student@example.com","scheduleType":"x","scheduleIds":[%d]}`, groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidScheduleType},
+			{name: "empty ids", url: "/api/digest/subscriptions", body: `{"email":"student@example.com","scheduleType":"group","scheduleIds":[]}`, expectedStatus: http.StatusBadRequest, expectedCode: errorCodeMissingScheduleId},
+		},
+		"getDigestConfirmPage": {
+			{name: "ok", url: "/api/digest/confirm?token=abc", expectedStatus: http.StatusOK},
+			{name: "missing token", url: "/api/digest/confirm", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidParameter},
+		},
+		"confirmDigest": {
+			{name: "unknown token", url: "/api/digest/confirm?token=abc", expectedStatus: http.StatusNotFound},
+		},
+		"getDigestUnsubscribePage": {
+			{name: "ok", url: "/api/digest/unsubscribe?token=abc", expectedStatus: http.StatusOK},
+		},
+		"unsubscribeDigest": {
+			{name: "unknown token", url: "/api/digest/unsubscribe?token=abc", expectedStatus: http.StatusNotFound},
+			{name: "missing token", url: "/api/digest/unsubscribe", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidParameter},
+		},
+		"getPushPublicKey": {
+			{name: "ok", url: "/api/push/public-key", expectedStatus: http.StatusOK},
+		},
+		"subscribePush": {
+			{name: "ok", url: "/api/push/subscriptions", body: pushSubscription("https://push.example.com/abc", fmt.Sprintf("[%d,%d]", groupId, otherGroupId)), expectedStatus: http.StatusCreated},
+			{name: "not json", url: "/api/push/subscriptions", body: "{", expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "missing keys", url: "/api/push/subscriptions", body: fmt.Sprintf(`{"subscription":{"endpoint":"https://push.example.com/abc"},"scheduleType":"group","scheduleIds":[%d]}`, groupId), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "private endpoint", url: "/api/push/subscriptions", body: pushSubscription("http://localhost/abc", fmt.Sprintf("[%d]", groupId)), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+			{name: "too many ids", url: "/api/push/subscriptions", body: pushSubscription("https://push.example.com/abc", "[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21]"), expectedStatus: http.StatusBadRequest, expectedCode: errorCodeTooManyScheduleIds},
+			{name: "unknown schedule", url: "/api/push/subscriptions", body: pushSubscription("https://push.example.com/abc", "[999999]")},
+		},
+		"unsubscribePush": {
+			{name: "ok", url: "/api/push/subscriptions", body: `{"endpoint":"https://push.example.com/abc"}`, expectedStatus: http.StatusNoContent},
+			{name: "unknown endpoint", url: "/api/push/subscriptions", body: `{"endpoint":"https://push.example.com/abc"}`, expectedStatus: http.StatusNotFound, expectedCode: errorCodeNotFound},
+			{name: "missing endpoint", url: "/api/push/subscriptions", body: `{}`, expectedStatus: http.StatusBadRequest, expectedCode: errorCodeInvalidPayload},
+		},
+		"getOpenAPIDocument": {
+			{name: "ok", url: "/api/openapi.json", expectedStatus: http.StatusOK},
+		},
+	}
+
+	for _, ep := range apiEndpoints {
+		testCases, ok := testCasesByOperationId[ep.OperationId]
+		if !ok {
+			t.Errorf("%s: no test cases", ep.OperationId)
+			continue
+		}
+
+		for _, testCase := range testCases {
+			t.Run(ep.OperationId+"/"+testCase.name, func(t *testing.T) {
+				req := httptest.NewRequest(ep.Method, testCase.url, strings.NewReader(testCase.body))
+				if testCase.timeout != 0 {
+					ctx, cancelCtx := context.WithTimeout(req.Context(), testCase.timeout)
+					defer cancelCtx()
+					req = req.WithContext(ctx)
+				}
+
+				res := serveTestRequest(srv, req)
+				if testCase.expectedStatus != 0 && res.Code != testCase.expectedStatus {
+					t.Fatalf("expected status %d, got %d: %s", testCase.expectedStatus, res.Code, res.Body)
+				}
+
+				specResponse, ok := ep.response(res.Code)
+				if !ok {
+					t.Fatalf("status %d is not declared in the spec", res.Code)
+				}
+				if contentType := res.Header().Get("Content-Type"); !strings.HasPrefix(contentType, specResponse.ContentType) {
+					t.Fatalf("expected content type %s, got %s", specResponse.ContentType, contentType)
+				}
+				if specResponse.ContentType != "application/json" {
+					return
+				}
+
+				var body any
+				if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+					t.Fatalf("invalid json: %v", err)
+				}
+				if err := validateValue(specResponse.Schema, "", body); err != nil {
+					t.Fatalf("response doesn't match the spec: %v", err)
+				}
+
+				if testCase.expectedCode != "" {
+					if code := body.(map[string]any)["error"].(map[string]any)["code"]; code != string(testCase.expectedCode) {
+						t.Fatalf("expected error code %s, got %v", testCase.expectedCode, code)
+					}
+				}
+			})
+		}
+	}
+}
+
+// cache is optional
+func newTestUekClient(t *testing.T, cache uek.Cache) *uek.Client {
+	t.Helper()
+
+	mockDirectoryPath := t.TempDir()
+	if _, err := uekmock.Generate(mockDirectoryPath, uekmock.GeneratorOptions{
+		Seed:      1,
+		Groups:    4,
+		Lecturers: 8,
+		Rooms:     4,
+	}); err != nil {
+		t.Fatalf("failed to generate mock responses: %v", err)
+	}
+
+	mockRoundTripper, err := uekmock.NewRoundTripper(config.Mock{
+		Enabled:       true,
+		DirectoryPath: mockDirectoryPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to create mock round tripper: %v", err)
+	}
+
+	cfg := uek.ClientConfig{
+		HttpClient: &http.Client{
+			Transport: mockRoundTripper,
+		},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	if cache != nil {
+		cfg.Cache = cache
+		// entries would expire right away
+		cfg.CacheTimes = config.CacheTimes{
+			Groupings: time.Hour,
+			Headers:   time.Hour,
+			Schedules: time.Hour,
+			Periods:   time.Hour,
+		}
+	}
+
+	return uek.NewClient(cfg)
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	uekClient := newTestUekClient(t, nil)
+
+	// nothing listens on the port, so confirmation emails fail right away
+	digests, err := digest.New(digest.Config{
+		Path: t.TempDir(),
+		Uek:  uekClient,
+		SMTP: digest.SMTPConfig{
+			Host:     "127.0.0.1",
+			Port:     1,
+			Security: digest.SMTPSecurityNone,
+			From:     "plan@example.com",
+		},
+		BaseUrl: "http://localhost",
+		Logger:  logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create digest manager: %v", err)
+	}
+	t.Cleanup(digests.Close)
+
+	webPush, err := webpush.New(webpush.Config{
+		Path:    t.TempDir(),
+		Uek:     uekClient,
+		Subject: "mailto:admin@example.com",
+		Logger:  logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create web push manager: %v", err)
+	}
+	t.Cleanup(webPush.Close)
+
+	occupancyReports, err := occupancy.New(occupancy.Config{
+		Path:   t.TempDir(),
+		Uek:    uekClient,
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatalf("failed to create occupancy manager: %v", err)
+	}
+	t.Cleanup(occupancyReports.Close)
+
+	return New(Config{
+		Uek:       uekClient,
+		Digests:   digests,
+		WebPush:   webPush,
+		Occupancy: occupancyReports,
+		Logger:    logger,
+	})
+}
+
+func serveTestRequest(srv *Server, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(res, req)
+	return res
+}
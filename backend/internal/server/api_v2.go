@@ -0,0 +1,74 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	maxScheduleRangeDays      = 366
+	defaultScheduleRangeLimit = 100
+	maxScheduleRangeLimit     = 500
+)
+
+type scheduleRangeResponse struct {
+	From    string               `json:"from"`
+	To      string               `json:"to"`
+	Headers []uek.ScheduleHeader `json:"headers"`
+	Items   []*uek.ScheduleItem  `json:"items"`
+	// periods the items were taken from
+	Periods    []uek.SchedulePeriod `json:"periods"`
+	Total      int                  `json:"total"`
+	Offset     int                  `json:"offset"`
+	Limit      int                  `json:"limit"`
+	NextOffset int                  `json:"nextOffset,omitzero"`
+}
+
+func (srv *Server) handleScheduleRange(w http.ResponseWriter, r *http.Request, params requestParams) {
+	scheduleType, scheduleIds := uek.ScheduleType(params.string("type")), params.ints("id")
+
+	// both dates are inclusive and in UEK's time zone
+	from, _ := time.ParseInLocation(time.DateOnly, params.string("from"), uek.Location())
+	to, _ := time.ParseInLocation(time.DateOnly, params.string("to"), uek.Location())
+	to = to.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidDateRange, "from must not be after to", nil)
+		return
+	}
+	if to.Sub(from) > maxScheduleRangeDays*24*time.Hour+time.Hour {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidDateRange, "Date range is too long", map[string]int{"maxDays": maxScheduleRangeDays})
+		return
+	}
+
+	limit, ok := params.int("limit")
+	if !ok {
+		limit = defaultScheduleRangeLimit
+	}
+	offset, _ := params.int("offset")
+
+	aggregateSchedule, periods, cacheExpirationDate, err := srv.uek.GetAggregateScheduleInRange(r.Context(), scheduleType, scheduleIds, from, to)
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get schedule range", err, slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.Any("scheduleIds", scheduleIds), slog.Time("from", from), slog.Time("to", to)))
+		return
+	}
+
+	res := scheduleRangeResponse{
+		From:    params.string("from"),
+		To:      params.string("to"),
+		Headers: aggregateSchedule.Headers,
+		Items:   aggregateSchedule.Items[min(offset, len(aggregateSchedule.Items)):min(offset+limit, len(aggregateSchedule.Items))],
+		Periods: periods,
+		Total:   len(aggregateSchedule.Items),
+		Offset:  offset,
+		Limit:   limit,
+	}
+	if offset+limit < res.Total {
+		res.NextOffset = offset + limit
+	}
+
+	setCacheHeader(w, cacheExpirationDate)
+	respondJSON(w, res)
+}
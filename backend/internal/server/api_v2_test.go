@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestScheduleRange(t *testing.T) {
+	srv := newTestServer(t)
+
+	groupings, _, err := srv.uek.GetGroupings(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, _, err := srv.uek.GetHeaders(t.Context(), uek.ScheduleTypeGroup, groupings.Groups[0])
+	if err != nil || len(headers) < 2 {
+		t.Fatalf("failed to get headers: %v", err)
+	}
+	scheduleIds := []int{headers[0].Id, headers[1].Id}
+	periods, _, err := srv.uek.GetSchedulePeriods(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	periodIndex := slices.IndexFunc(periods, func(period uek.SchedulePeriod) bool {
+		return !period.Archived
+	})
+	if periodIndex == -1 {
+		t.Fatal("no current period")
+	}
+
+	// the window starts and ends inside the period
+	period := periods[periodIndex]
+	from := period.Start.AddDate(0, 0, 10)
+	to := from.AddDate(0, 0, 20)
+
+	fetch := func(offset int, limit int) (scheduleRangeResponse, string) {
+		t.Helper()
+
+		res := serveTestRequest(srv, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v2/schedule?type=group&id=%d&id=%d&from=%s&to=%s&offset=%d&limit=%d", scheduleIds[0], scheduleIds[1], from.Format(time.DateOnly), to.Format(time.DateOnly), offset, limit), nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+		}
+
+		body := scheduleRangeResponse{}
+		if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body, res.Body.String()
+	}
+	itemKeys := func(items []*uek.ScheduleItem) []string {
+		keys := []string{}
+		for _, item := range items {
+			keys = append(keys, fmt.Sprintf("%s %s %s %s", item.Start.Format(time.RFC3339), item.End.Format(time.RFC3339), item.Subject, strings.Join(item.Groups, ",")))
+		}
+		return keys
+	}
+
+	full, _ := fetch(0, maxScheduleRangeLimit)
+	if len(full.Periods) != 1 {
+		t.Fatalf("expected a single covering period, got %+v", full.Periods)
+	}
+	if full.From != from.Format(time.DateOnly) || full.To != to.Format(time.DateOnly) || len(full.Headers) != 2 {
+		t.Errorf("unexpected range or headers: %s - %s, %+v", full.From, full.To, full.Headers)
+	}
+
+	// the whole period, trimmed by hand. to is inclusive
+	periodSchedule, _, err := srv.uek.GetAggregateSchedule(t.Context(), uek.ScheduleTypeGroup, scheduleIds, full.Periods[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedItems := []*uek.ScheduleItem{}
+	sawItemsOutside := false
+	for _, item := range periodSchedule.Items {
+		if item.Start.Before(to.AddDate(0, 0, 1)) && item.End.After(from) {
+			expectedItems = append(expectedItems, item)
+		} else {
+			sawItemsOutside = true
+		}
+	}
+	if len(expectedItems) < 4 || !sawItemsOutside {
+		t.Fatalf("expected items both inside and outside of the window, got %d of %d", len(expectedItems), len(periodSchedule.Items))
+	}
+	if !slices.Equal(itemKeys(full.Items), itemKeys(expectedItems)) {
+		t.Errorf("expected items:\n%v\ngot:\n%v", itemKeys(expectedItems), itemKeys(full.Items))
+	}
+	if full.Total != len(expectedItems) || full.Offset != 0 || full.Limit != maxScheduleRangeLimit || full.NextOffset != 0 {
+		t.Errorf("unexpected paging of the full page: total %d, offset %d, limit %d, next offset %d", full.Total, full.Offset, full.Limit, full.NextOffset)
+	}
+
+	// following nextOffset visits every item once
+	limit := 3
+	pagedItems := []*uek.ScheduleItem{}
+	for offset := 0; ; {
+		page, _ := fetch(offset, limit)
+		if page.Total != full.Total || page.Offset != offset || page.Limit != limit {
+			t.Fatalf("unexpected paging at offset %d: %+v", offset, page)
+		}
+		pagedItems = append(pagedItems, page.Items...)
+
+		if page.NextOffset == 0 {
+			if len(page.Items) == 0 || len(page.Items) > limit || offset+len(page.Items) != full.Total {
+				t.Errorf("expected the last page to end at the total, got %d items at offset %d", len(page.Items), offset)
+			}
+			break
+		}
+		if len(page.Items) != limit || page.NextOffset != offset+limit {
+			t.Fatalf("expected a full page followed by offset %d, got %d items and %d", offset+limit, len(page.Items), page.NextOffset)
+		}
+		offset = page.NextOffset
+	}
+	if !slices.Equal(itemKeys(pagedItems), itemKeys(full.Items)) {
+		t.Errorf("expected the pages to add up to the full list")
+	}
+
+	// a page ending exactly at the total has no next one
+	if page, _ := fetch(full.Total-limit, limit); len(page.Items) != limit || page.NextOffset != 0 {
+		t.Errorf("expected %d items without a next offset, got %d items and %d", limit, len(page.Items), page.NextOffset)
+	}
+
+	for _, offset := range []int{full.Total, full.Total + 10} {
+		page, body := fetch(offset, limit)
+		if !strings.Contains(body, `"items":[]`) || page.Total != full.Total || page.Offset != offset || page.NextOffset != 0 {
+			t.Errorf("expected an empty page at offset %d, got %s", offset, body)
+		}
+	}
+}
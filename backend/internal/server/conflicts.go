@@ -0,0 +1,184 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// less than this between rooms in different buildings isn't enough to get there
+const minBuildingChangeGap = 10 * time.Minute
+
+type conflictKind string
+
+const (
+	conflictKindOverlap        conflictKind = "overlap"
+	conflictKindRoomTransition conflictKind = "roomTransition"
+)
+
+type scheduleConflict struct {
+	Kind conflictKind `json:"kind"`
+	// indexes of the two items in the schedule, earlier first
+	ItemIndexes []int `json:"itemIndexes"`
+	// how long the items overlap, or the break between them for room transitions
+	Minutes int `json:"minutes"`
+}
+
+type conflictsResponse struct {
+	PeriodId  int                  `json:"periodId"`
+	Headers   []uek.ScheduleHeader `json:"headers"`
+	Conflicts []conflictWithItems  `json:"conflicts"`
+}
+
+type conflictWithItems struct {
+	Kind    conflictKind        `json:"kind"`
+	Minutes int                 `json:"minutes"`
+	Items   []*uek.ScheduleItem `json:"items"`
+}
+
+func (srv *Server) handleConflicts(w http.ResponseWriter, r *http.Request, params requestParams) {
+	scheduleType, scheduleIds := uek.ScheduleType(params.string("type")), params.ints("id")
+	hiddenSubjects := params.strings("hiddenSubject")
+
+	periods, periodsCacheExpirationDate, err := srv.uek.GetSchedulePeriods(r.Context())
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get periods", err)
+		return
+	}
+
+	periodId, _, ok := resolveRequestPeriodId(w, r, params, periods)
+	if !ok {
+		return
+	}
+
+	aggregateSchedule, cacheExpirationDate, err := srv.uek.GetAggregateSchedule(r.Context(), scheduleType, scheduleIds, periodId)
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get schedule", err, slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.Any("scheduleIds", scheduleIds), slog.Int("periodId", periodId)))
+		return
+	}
+
+	items := slices.DeleteFunc(slices.Clone(aggregateSchedule.Items), func(item *uek.ScheduleItem) bool {
+		return slices.Contains(hiddenSubjects, item.Subject)
+	})
+
+	res := conflictsResponse{
+		PeriodId:  periodId,
+		Headers:   aggregateSchedule.Headers,
+		Conflicts: []conflictWithItems{},
+	}
+	for _, conflict := range detectConflicts(scheduleType, aggregateSchedule.Headers, items) {
+		res.Conflicts = append(res.Conflicts, conflictWithItems{
+			Kind:    conflict.Kind,
+			Minutes: conflict.Minutes,
+			Items:   []*uek.ScheduleItem{items[conflict.ItemIndexes[0]], items[conflict.ItemIndexes[1]]},
+		})
+	}
+
+	setCacheHeader(w, minTime(cacheExpirationDate, periodsCacheExpirationDate))
+	respondJSON(w, res)
+}
+
+// items must be sorted. Only items that don't share a schedule conflict, overlaps within one schedule
+// are usually subgroups and UEK's business. Cancelled slots never conflict, the replacement often takes their place
+func detectConflicts(scheduleType uek.ScheduleType, headers []uek.ScheduleHeader, items []*uek.ScheduleItem) []scheduleConflict {
+	conflicts := []scheduleConflict{}
+	if len(headers) < 2 {
+		return conflicts
+	}
+
+	sources := make([][]int, len(items))
+	for i, item := range items {
+		sources[i] = itemSourceHeaders(scheduleType, headers, item)
+	}
+
+	for i, item := range items {
+		if item.IsCancelled() {
+			continue
+		}
+
+		for j := i + 1; j < len(items) && items[j].Start.Before(item.End.Add(minBuildingChangeGap)); j++ {
+			other := items[j]
+			if other.IsCancelled() || slices.ContainsFunc(sources[i], func(headerIndex int) bool { return slices.Contains(sources[j], headerIndex) }) {
+				continue
+			}
+
+			if other.Start.Before(item.End) {
+				conflicts = append(conflicts, scheduleConflict{
+					Kind:        conflictKindOverlap,
+					ItemIndexes: []int{i, j},
+					Minutes:     int(minTime(item.End, other.End).Sub(other.Start).Minutes()),
+				})
+			} else if isImpossibleRoomTransition(item, other) {
+				conflicts = append(conflicts, scheduleConflict{
+					Kind:        conflictKindRoomTransition,
+					ItemIndexes: []int{i, j},
+					Minutes:     int(other.Start.Sub(item.End).Minutes()),
+				})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// indexes of the headers an item of a merged schedule came from, merged items only differ in groups
+func itemSourceHeaders(scheduleType uek.ScheduleType, headers []uek.ScheduleHeader, item *uek.ScheduleItem) []int {
+	sources := []int{}
+	for i, header := range headers {
+		var fromHeader bool
+		switch scheduleType {
+		case uek.ScheduleTypeGroup:
+			fromHeader = slices.Contains(item.Groups, header.Name)
+		case uek.ScheduleTypeLecturer:
+			fromHeader = slices.ContainsFunc(item.Lecturers, func(lecturer uek.ScheduleItemLecturer) bool { return lecturer.Name == header.Name })
+		case uek.ScheduleTypeRoom:
+			fromHeader = item.Room != nil && item.Room.Name == header.Name
+		}
+		if fromHeader {
+			sources = append(sources, i)
+		}
+	}
+
+	return sources
+}
+
+func isImpossibleRoomTransition(before *uek.ScheduleItem, after *uek.ScheduleItem) bool {
+	if before.Room == nil || after.Room == nil || before.Room.URL != "" || after.Room.URL != "" {
+		return false
+	}
+
+	return after.Start.Sub(before.End) < minBuildingChangeGap && roomBuilding(before.Room.Name) != roomBuilding(after.Room.Name)
+}
+
+// room names start with the building, like "Paw.A 011"
+func roomBuilding(roomName string) string {
+	building, _, _ := strings.Cut(roomName, " ")
+	return building
+}
+
+// human readable notes for the ical feed, keyed by item index
+func describeConflicts(items []*uek.ScheduleItem, conflicts []scheduleConflict) map[int][]string {
+	notes := map[int][]string{}
+	for _, conflict := range conflicts {
+		first, second := conflict.ItemIndexes[0], conflict.ItemIndexes[1]
+		switch conflict.Kind {
+		case conflictKindOverlap:
+			notes[first] = append(notes[first], fmt.Sprintf("Konflikt: %s (%d min)", describeConflictingItem(items[second]), conflict.Minutes))
+			notes[second] = append(notes[second], fmt.Sprintf("Konflikt: %s (%d min)", describeConflictingItem(items[first]), conflict.Minutes))
+		case conflictKindRoomTransition:
+			notes[first] = append(notes[first], fmt.Sprintf("Za mało czasu na przejście do %s, %d min przerwy przed %s", items[second].Room.Name, conflict.Minutes, describeConflictingItem(items[second])))
+			notes[second] = append(notes[second], fmt.Sprintf("Za mało czasu na przejście z %s, %d min przerwy po %s", items[first].Room.Name, conflict.Minutes, describeConflictingItem(items[first])))
+		}
+	}
+
+	return notes
+}
+
+func describeConflictingItem(item *uek.ScheduleItem) string {
+	return fmt.Sprintf("[%s] %s %s-%s", item.Type, item.Subject, item.Start.In(uek.Location()).Format("15:04"), item.End.In(uek.Location()).Format("15:04"))
+}
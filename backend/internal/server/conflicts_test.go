@@ -0,0 +1,50 @@
+package server
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestDetectConflicts(t *testing.T) {
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, uek.Location())
+	at := func(hour int, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	headers := []uek.ScheduleHeader{{Id: 1, Name: "KrDZEa1011"}, {Id: 2, Name: "CJ-ANG-B2"}}
+
+	items := []*uek.ScheduleItem{
+		{Start: at(8, 0), End: at(9, 30), Subject: "Ekonomia", Groups: []string{"KrDZEa1011"}, Room: &uek.ScheduleItemRoom{Name: "Paw.A 011"}},
+		// subgroups of the same group overlapping isn't a conflict
+		{Start: at(8, 0), End: at(9, 30), Subject: "Informatyka", Groups: []string{"KrDZEa1011"}, Room: &uek.ScheduleItemRoom{Name: "Paw.A 012"}},
+		{Start: at(9, 0), End: at(10, 30), Subject: "Angielski", Groups: []string{"CJ-ANG-B2"}, Room: &uek.ScheduleItemRoom{Name: "Paw.B 101"}},
+		{Start: at(9, 35), End: at(11, 5), Subject: "Statystyka", Groups: []string{"KrDZEa1011"}, Room: &uek.ScheduleItemRoom{Name: "Paw.C 1"}},
+		{Start: at(11, 10), End: at(12, 40), Subject: "Angielski", Groups: []string{"CJ-ANG-B2"}, Room: &uek.ScheduleItemRoom{Name: "Paw.D 1"}},
+		// online classes don't need walking
+		{Start: at(12, 45), End: at(14, 15), Subject: "Finanse", Groups: []string{"KrDZEa1011"}, Room: &uek.ScheduleItemRoom{Name: "Platforma Moodle", URL: "https://e-uczelnia.uek.krakow.pl"}},
+		// shared by both groups
+		{Start: at(14, 0), End: at(15, 30), Subject: "Wykład", Groups: []string{"CJ-ANG-B2", "KrDZEa1011"}, Room: &uek.ScheduleItemRoom{Name: "Paw.E aula 1"}},
+		// a class moved away doesn't conflict with the one moved in
+		{Start: at(16, 0), End: at(17, 30), Subject: "Ekonomia", Type: "przeniesienie zajęć", Groups: []string{"KrDZEa1011"}, Room: &uek.ScheduleItemRoom{Name: "Paw.A 011"}},
+		{Start: at(16, 0), End: at(17, 30), Subject: "Angielski", Type: "lektorat", Groups: []string{"CJ-ANG-B2"}, Room: &uek.ScheduleItemRoom{Name: "Paw.A 011"}},
+	}
+
+	conflicts := detectConflicts(uek.ScheduleTypeGroup, headers, items)
+	expected := []scheduleConflict{
+		{Kind: conflictKindOverlap, ItemIndexes: []int{0, 2}, Minutes: 30},
+		{Kind: conflictKindOverlap, ItemIndexes: []int{1, 2}, Minutes: 30},
+		{Kind: conflictKindOverlap, ItemIndexes: []int{2, 3}, Minutes: 55},
+		{Kind: conflictKindRoomTransition, ItemIndexes: []int{3, 4}, Minutes: 5},
+	}
+	if !slices.EqualFunc(conflicts, expected, func(a scheduleConflict, b scheduleConflict) bool {
+		return a.Kind == b.Kind && slices.Equal(a.ItemIndexes, b.ItemIndexes) && a.Minutes == b.Minutes
+	}) {
+		t.Errorf("expected %+v, got %+v", expected, conflicts)
+	}
+
+	if conflicts := detectConflicts(uek.ScheduleTypeGroup, headers[:1], items); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts within a single schedule, got %+v", conflicts)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/digest"
+)
+
+// links from emails are opened in a browser, so these routes answer with a page instead of json
+var digestPageTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html lang="pl">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<title>{{.Title}} - Plan zajęć UEK</title>
+</head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 48px auto; padding: 0 16px; color: #1f2937;">
+	<h1 style="font-size: 22px;">{{.Title}}</h1>
+	<p>{{.Message}}</p>
+	{{- if .FormAction}}
+	<form method="post" action="{{.FormAction}}">
+		<button type="submit" style="padding: 10px 16px; font-size: 15px;">{{.FormButton}}</button>
+	</form>
+	{{- end}}
+	<p><a href="/">Przejdź do planu zajęć</a></p>
+</body>
+</html>
+`))
+
+type digestPage struct {
+	Title      string
+	Message    string
+	FormAction string
+	FormButton string
+}
+
+func (srv *Server) registerDigestRoutes() {
+	if srv.digests == nil {
+		return
+	}
+
+	srv.registerAPIEndpoint(digestSubscribeEndpoint, srv.handleDigestSubscribe)
+	srv.registerAPIEndpoint(digestConfirmPageEndpoint, srv.handleDigestConfirmPage)
+	srv.registerAPIEndpoint(digestConfirmEndpoint, srv.handleDigestConfirm)
+	srv.registerAPIEndpoint(digestUnsubscribePageEndpoint, srv.handleDigestUnsubscribePage)
+	srv.registerAPIEndpoint(digestUnsubscribeEndpoint, srv.handleDigestUnsubscribe)
+}
+
+func respondDigestPage(w http.ResponseWriter, statusCode int, page digestPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(statusCode)
+	digestPageTemplate.Execute(w, page)
+}
+
+// always accepted for valid requests, whether the address was subscribed before is not revealed
+func (srv *Server) handleDigestSubscribe(w http.ResponseWriter, r *http.Request, params requestParams) {
+	req := digest.SubscribeRequest{}
+	if err := params.decodeBody(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, "Body must be a json subscription", nil)
+		return
+	}
+
+	if err := srv.digests.Subscribe(r.Context(), req); err != nil {
+		if errors.Is(err, digest.ErrInvalidSubscription) {
+			respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, err.Error(), nil)
+			return
+		}
+		srv.respondUpstreamError(w, r, "Failed to subscribe to digest", err, slog.String("scheduleType", string(req.ScheduleType)), slog.Any("scheduleIds", req.ScheduleIds))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(struct {
+		Status string `json:"status"`
+	}{
+		Status: "confirmationSent",
+	})
+}
+
+// link scanners would confirm subscriptions nobody asked for, so confirming also requires a button press
+func (srv *Server) handleDigestConfirmPage(w http.ResponseWriter, r *http.Request, _ requestParams) {
+	respondDigestPage(w, http.StatusOK, digestPage{
+		Title:      "Potwierdzenie subskrypcji",
+		Message:    "Potwierdź, że chcesz dostawać cotygodniowe podsumowanie planu zajęć na ten adres.",
+		FormAction: "/api/digest/confirm?" + r.URL.RawQuery,
+		FormButton: "Potwierdź",
+	})
+}
+
+func (srv *Server) handleDigestConfirm(w http.ResponseWriter, r *http.Request, params requestParams) {
+	if _, ok := srv.digests.Confirm(params.string("token")); !ok {
+		respondDigestPage(w, http.StatusNotFound, digestPage{
+			Title:   "Link wygasł",
+			Message: "Ten link potwierdzający jest nieprawidłowy lub wygasł. Zapisz się ponownie, aby otrzymać nowy.",
+		})
+		return
+	}
+
+	respondDigestPage(w, http.StatusOK, digestPage{
+		Title:   "Subskrypcja potwierdzona",
+		Message: "Podsumowanie planu zajęć na nadchodzący tydzień będzie przychodzić co tydzień. Z subskrypcji możesz zrezygnować linkiem w każdej wiadomości.",
+	})
+}
+
+// link scanners in mail clients open links on their own, so unsubscribing from the link requires a button press
+func (srv *Server) handleDigestUnsubscribePage(w http.ResponseWriter, r *http.Request, _ requestParams) {
+	respondDigestPage(w, http.StatusOK, digestPage{
+		Title:      "Rezygnacja z subskrypcji",
+		Message:    "Czy na pewno nie chcesz dostawać cotygodniowego podsumowania planu zajęć?",
+		FormAction: "/api/digest/unsubscribe?" + r.URL.RawQuery,
+		FormButton: "Zrezygnuj",
+	})
+}
+
+// also the target of one-click unsubscribe (RFC 8058) from mail clients
+func (srv *Server) handleDigestUnsubscribe(w http.ResponseWriter, r *http.Request, params requestParams) {
+	if !srv.digests.Unsubscribe(params.string("token")) {
+		respondDigestPage(w, http.StatusNotFound, digestPage{
+			Title:   "Nie znaleziono subskrypcji",
+			Message: "Ta subskrypcja nie istnieje lub została już anulowana.",
+		})
+		return
+	}
+
+	respondDigestPage(w, http.StatusOK, digestPage{
+		Title:   "Zrezygnowano z subskrypcji",
+		Message: "Nie wyślemy już więcej podsumowań na ten adres.",
+	})
+}
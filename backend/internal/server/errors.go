@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+type errorCode string
+
+const (
+	errorCodeNotFound                 errorCode = "notFound"
+	errorCodeUnauthorized             errorCode = "unauthorized"
+	errorCodeInternal                 errorCode = "internal"
+	errorCodeCanceled                 errorCode = "canceled"
+	errorCodeInvalidScheduleType      errorCode = "invalidScheduleType"
+	errorCodeInvalidScheduleId        errorCode = "invalidScheduleId"
+	errorCodeDuplicateScheduleId      errorCode = "duplicateScheduleId"
+	errorCodeMissingScheduleId        errorCode = "missingScheduleId"
+	errorCodeTooManyScheduleIds       errorCode = "tooManyScheduleIds"
+	errorCodeInvalidPeriodId          errorCode = "invalidPeriodId"
+	errorCodeUnknownPeriodId          errorCode = "unknownPeriodId"
+	errorCodeNoCurrentPeriod          errorCode = "noCurrentPeriod"
+	errorCodeInvalidDateRange         errorCode = "invalidDateRange"
+	errorCodeInvalidPayload           errorCode = "invalidPayload"
+	errorCodeInvalidParameter         errorCode = "invalidParameter"
+	errorCodeScheduleNotArchived      errorCode = "scheduleNotArchived"
+	errorCodeReportInProgress         errorCode = "reportInProgress"
+	errorCodeSubscriptionLimitReached errorCode = "subscriptionLimitReached"
+	errorCodeUpstreamTimeout          errorCode = "upstreamTimeout"
+	errorCodeUpstreamUnreachable      errorCode = "upstreamUnreachable"
+	errorCodeUpstreamRateLimited      errorCode = "upstreamRateLimited"
+	errorCodeUpstreamError            errorCode = "upstreamError"
+	errorCodeUpstreamInvalidResult    errorCode = "upstreamInvalidResponse"
+)
+
+// used when UEK doesn't say how long to wait
+var defaultUpstreamRetryAfter = map[uek.UpstreamErrorKind]time.Duration{
+	uek.UpstreamErrorKindTimeout:         10 * time.Second,
+	uek.UpstreamErrorKindUnreachable:     30 * time.Second,
+	uek.UpstreamErrorKindRateLimited:     30 * time.Second,
+	uek.UpstreamErrorKindBadStatus:       30 * time.Second,
+	uek.UpstreamErrorKindInvalidResponse: time.Minute,
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code      errorCode `json:"code"`
+	Message   string    `json:"message"`
+	Details   any       `json:"details,omitempty"`
+	RequestId string    `json:"requestId,omitempty"`
+	// in seconds
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, statusCode int, code errorCode, message string, details any) {
+	respondErrorWithRetryAfter(w, r, statusCode, code, message, details, 0)
+}
+
+func respondErrorWithRetryAfter(w http.ResponseWriter, r *http.Request, statusCode int, code errorCode, message string, details any, retryAfter time.Duration) {
+	body := errorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestId: requestIdFromContext(r.Context()),
+	}
+
+	if retryAfter > 0 {
+		body.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(body.RetryAfter))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(errorResponse{
+		Error: body,
+	})
+}
+
+// logs unexpected failures and maps uek client errors to responses
+func (srv *Server) respondUpstreamError(w http.ResponseWriter, r *http.Request, logMessage string, err error, logAttrs ...any) {
+	if errors.Is(err, context.Canceled) {
+		// client is gone, the response doesn't matter
+		respondError(w, r, http.StatusServiceUnavailable, errorCodeCanceled, "Request canceled", nil)
+		return
+	}
+
+	if errors.Is(err, uek.ErrScheduleNotArchived) {
+		respondError(w, r, http.StatusNotFound, errorCodeScheduleNotArchived, "Schedule was not archived for this period", nil)
+		return
+	}
+
+	srv.logger.Error(logMessage, append(logAttrs, slog.String("requestId", requestIdFromContext(r.Context())), slog.Any("err", err))...)
+
+	upstreamErr := (*uek.UpstreamError)(nil)
+	if !errors.As(err, &upstreamErr) {
+		respondError(w, r, http.StatusInternalServerError, errorCodeInternal, "Internal error", nil)
+		return
+	}
+
+	retryAfter := upstreamErr.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultUpstreamRetryAfter[upstreamErr.Kind]
+	}
+
+	switch upstreamErr.Kind {
+	case uek.UpstreamErrorKindTimeout:
+		respondErrorWithRetryAfter(w, r, http.StatusGatewayTimeout, errorCodeUpstreamTimeout, "UEK took too long to respond", nil, retryAfter)
+	case uek.UpstreamErrorKindRateLimited:
+		respondErrorWithRetryAfter(w, r, http.StatusServiceUnavailable, errorCodeUpstreamRateLimited, "UEK is rate limiting requests", nil, retryAfter)
+	case uek.UpstreamErrorKindBadStatus:
+		respondErrorWithRetryAfter(w, r, http.StatusBadGateway, errorCodeUpstreamError, "UEK responded with an error", map[string]int{"upstreamStatusCode": upstreamErr.StatusCode}, retryAfter)
+	case uek.UpstreamErrorKindInvalidResponse:
+		respondErrorWithRetryAfter(w, r, http.StatusBadGateway, errorCodeUpstreamInvalidResult, "UEK responded with malformed data", nil, retryAfter)
+	default:
+		respondErrorWithRetryAfter(w, r, http.StatusServiceUnavailable, errorCodeUpstreamUnreachable, "UEK is unreachable", nil, retryAfter)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestRespondUpstreamError(t *testing.T) {
+	srv := &Server{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	for _, tc := range []struct {
+		name               string
+		err                error
+		expectedStatus     int
+		expectedCode       errorCode
+		expectedRetryAfter string
+	}{
+		{name: "canceled", err: fmt.Errorf("get: %w", context.Canceled), expectedStatus: http.StatusServiceUnavailable, expectedCode: errorCodeCanceled},
+		{name: "not archived", err: uek.ErrScheduleNotArchived, expectedStatus: http.StatusNotFound, expectedCode: errorCodeScheduleNotArchived},
+		{name: "internal", err: errors.New("disk on fire"), expectedStatus: http.StatusInternalServerError, expectedCode: errorCodeInternal},
+		{name: "timeout", err: &uek.UpstreamError{Kind: uek.UpstreamErrorKindTimeout, Err: context.DeadlineExceeded}, expectedStatus: http.StatusGatewayTimeout, expectedCode: errorCodeUpstreamTimeout, expectedRetryAfter: "10"},
+		{name: "unreachable", err: &uek.UpstreamError{Kind: uek.UpstreamErrorKindUnreachable, Err: errors.New("connection refused")}, expectedStatus: http.StatusServiceUnavailable, expectedCode: errorCodeUpstreamUnreachable, expectedRetryAfter: "30"},
+		{name: "rate limited with upstream retry after", err: &uek.UpstreamError{Kind: uek.UpstreamErrorKindRateLimited, StatusCode: 429, RetryAfter: 90 * time.Second, Err: errors.New("429")}, expectedStatus: http.StatusServiceUnavailable, expectedCode: errorCodeUpstreamRateLimited, expectedRetryAfter: "90"},
+		{name: "fractional retry after is rounded up", err: &uek.UpstreamError{Kind: uek.UpstreamErrorKindRateLimited, StatusCode: 429, RetryAfter: 1500 * time.Millisecond, Err: errors.New("429")}, expectedStatus: http.StatusServiceUnavailable, expectedCode: errorCodeUpstreamRateLimited, expectedRetryAfter: "2"},
+		{name: "bad status", err: &uek.UpstreamError{Kind: uek.UpstreamErrorKindBadStatus, StatusCode: 500, Err: errors.New("500")}, expectedStatus: http.StatusBadGateway, expectedCode: errorCodeUpstreamError, expectedRetryAfter: "30"},
+		{name: "invalid response", err: fmt.Errorf("get schedule: %w", &uek.UpstreamError{Kind: uek.UpstreamErrorKindInvalidResponse, Err: errors.New("XML syntax error")}), expectedStatus: http.StatusBadGateway, expectedCode: errorCodeUpstreamInvalidResult, expectedRetryAfter: "60"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.respondUpstreamError(w, httptest.NewRequest(http.MethodGet, "/api/schedule", nil), "Failed to get schedule", tc.err)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, w.Code)
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != tc.expectedRetryAfter {
+				t.Errorf("expected Retry-After %q, got %q", tc.expectedRetryAfter, retryAfter)
+			}
+
+			res := errorResponse{}
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Error.Code != tc.expectedCode {
+				t.Errorf("expected code %s, got %s", tc.expectedCode, res.Error.Code)
+			}
+			if tc.expectedRetryAfter != "" && fmt.Sprint(res.Error.RetryAfter) != tc.expectedRetryAfter {
+				t.Errorf("expected retryAfter %s in the body, got %d", tc.expectedRetryAfter, res.Error.RetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	maxConcurrentRoomScheduleRequests = 4
+	// profiles missing co-teachers from rooms that failed to load, so they get completed soon
+	partialLecturerProfileMaxAge = time.Minute
+)
+
+type lecturerProfileResponse struct {
+	Header    uek.ScheduleHeader `json:"header"`
+	PeriodId  int                `json:"periodId"`
+	MoodleId  int                `json:"moodleId,omitempty"`
+	MoodleUrl string             `json:"moodleUrl,omitempty"`
+	// most classes first
+	Subjects []profileSubject `json:"subjects"`
+	Groups   []profileCount   `json:"groups"`
+	Rooms    []profileRoom    `json:"rooms"`
+	Days     []profileWeekday `json:"days"`
+	Hours    []profileSlot    `json:"hours"`
+	// found in schedules of the rooms the lecturer uses, online classes aren't covered
+	CoTeachers []profileCoTeacher `json:"coTeachers"`
+}
+
+type profileSubject struct {
+	Subject string   `json:"subject"`
+	Types   []string `json:"types"`
+	Groups  []string `json:"groups"`
+	Count   int      `json:"count"`
+	Hours   float64  `json:"hours"`
+}
+
+type profileCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type profileRoom struct {
+	Name   string `json:"name"`
+	Online bool   `json:"online,omitempty"`
+	Count  int    `json:"count"`
+}
+
+type profileWeekday struct {
+	// iso weekday, 1 is monday
+	Weekday int `json:"weekday"`
+	Count   int `json:"count"`
+}
+
+type profileSlot struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Count int    `json:"count"`
+}
+
+type profileCoTeacher struct {
+	Name      string   `json:"name"`
+	MoodleId  int      `json:"moodleId,omitempty"`
+	MoodleUrl string   `json:"moodleUrl,omitempty"`
+	Subjects  []string `json:"subjects"`
+	Count     int      `json:"count"`
+}
+
+func (srv *Server) handleLecturerProfile(w http.ResponseWriter, r *http.Request, params requestParams) {
+	lecturerId, _ := params.int("id")
+
+	periods, periodsCacheExpirationDate, err := srv.uek.GetSchedulePeriods(r.Context())
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get periods", err)
+		return
+	}
+
+	periodId, _, ok := resolveRequestPeriodId(w, r, params, periods)
+	if !ok {
+		return
+	}
+
+	schedule, scheduleCacheExpirationDate, err := srv.uek.GetSchedule(r.Context(), uek.ScheduleTypeLecturer, lecturerId, periodId)
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get schedule", err, slog.Group("params", slog.Int("lecturerId", lecturerId), slog.Int("periodId", periodId)))
+		return
+	}
+
+	roomSchedules, roomsCacheExpirationDate := srv.getLecturerRoomSchedules(r.Context(), schedule.Items, periodId)
+
+	res := computeLecturerProfile(schedule, roomSchedules)
+	res.PeriodId = periodId
+
+	cacheExpirationDate := minTime(periodsCacheExpirationDate, scheduleCacheExpirationDate)
+	if !roomsCacheExpirationDate.IsZero() {
+		cacheExpirationDate = minTime(cacheExpirationDate, roomsCacheExpirationDate)
+	}
+	setCacheHeader(w, cacheExpirationDate)
+	respondJSON(w, res)
+}
+
+// schedules of the physical rooms used by the items, for finding co-teachers. The expiration date is zero when nothing was fetched.
+// Rooms that can't be fetched are logged and left out, co-teachers are the least important part of the profile
+func (srv *Server) getLecturerRoomSchedules(ctx context.Context, items []*uek.ScheduleItem, periodId int) ([]*uek.Schedule, time.Time) {
+	roomNames := []string{}
+	for _, item := range items {
+		if item.Room != nil && item.Room.URL == "" && !slices.Contains(roomNames, item.Room.Name) {
+			roomNames = append(roomNames, item.Room.Name)
+		}
+	}
+	if len(roomNames) == 0 {
+		return nil, time.Time{}
+	}
+
+	roomHeadersByGrouping, cacheExpirationDate, err := srv.uek.GetRoomHeadersByGrouping(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			srv.logger.Warn("Failed to get room headers for lecturer profile", slog.String("requestId", requestIdFromContext(ctx)), slog.Any("err", err))
+		}
+		return nil, time.Now().Add(partialLecturerProfileMaxAge)
+	}
+	roomIds := []int{}
+	for _, headers := range roomHeadersByGrouping {
+		for _, header := range headers {
+			if slices.Contains(roomNames, header.Name) && !slices.Contains(roomIds, header.Id) {
+				roomIds = append(roomIds, header.Id)
+			}
+		}
+	}
+
+	eg := errgroup.Group{}
+	eg.SetLimit(maxConcurrentRoomScheduleRequests)
+	schedules := make([]*uek.Schedule, len(roomIds))
+	cacheExpirationDates := make([]time.Time, len(roomIds))
+	for i, roomId := range roomIds {
+		eg.Go(func() error {
+			schedule, scheduleCacheExpirationDate, err := srv.uek.GetSchedule(ctx, uek.ScheduleTypeRoom, roomId, periodId)
+			if err != nil {
+				if ctx.Err() == nil {
+					srv.logger.Warn("Failed to get room schedule for lecturer profile", slog.Int("roomId", roomId), slog.Int("periodId", periodId), slog.String("requestId", requestIdFromContext(ctx)), slog.Any("err", err))
+				}
+				cacheExpirationDates[i] = time.Now().Add(partialLecturerProfileMaxAge)
+				return nil
+			}
+
+			schedules[i], cacheExpirationDates[i] = schedule, scheduleCacheExpirationDate
+			return nil
+		})
+	}
+	eg.Wait()
+
+	for _, date := range cacheExpirationDates {
+		cacheExpirationDate = minTime(cacheExpirationDate, date)
+	}
+
+	return slices.DeleteFunc(schedules, func(schedule *uek.Schedule) bool {
+		return schedule == nil
+	}), cacheExpirationDate
+}
+
+func computeLecturerProfile(schedule *uek.Schedule, roomSchedules []*uek.Schedule) *lecturerProfileResponse {
+	res := &lecturerProfileResponse{
+		Header:     schedule.Header,
+		Subjects:   []profileSubject{},
+		Groups:     []profileCount{},
+		Rooms:      []profileRoom{},
+		Days:       []profileWeekday{},
+		Hours:      []profileSlot{},
+		CoTeachers: []profileCoTeacher{},
+	}
+
+	// every item of a lecturer schedule lists the lecturer, with the moodle id of the schedule
+	if len(schedule.Items) > 0 && len(schedule.Items[0].Lecturers) > 0 {
+		res.MoodleId = schedule.Items[0].Lecturers[0].MoodleId
+	}
+	if res.MoodleId != 0 {
+		res.MoodleUrl = moodleUrl(res.MoodleId)
+	}
+
+	for _, item := range schedule.Items {
+		i := slices.IndexFunc(res.Subjects, func(subject profileSubject) bool { return subject.Subject == item.Subject })
+		if i == -1 {
+			i = len(res.Subjects)
+			res.Subjects = append(res.Subjects, profileSubject{Subject: item.Subject, Types: []string{}, Groups: []string{}})
+		}
+		subject := &res.Subjects[i]
+		subject.Count++
+		subject.Hours += teachingHours(item)
+		if !slices.Contains(subject.Types, item.Type) {
+			subject.Types = append(subject.Types, item.Type)
+		}
+
+		for _, group := range item.Groups {
+			if !slices.Contains(subject.Groups, group) {
+				subject.Groups = append(subject.Groups, group)
+			}
+			res.Groups = incrementProfileCount(res.Groups, group)
+		}
+
+		if item.Room != nil {
+			online := item.Room.URL != ""
+			i := slices.IndexFunc(res.Rooms, func(room profileRoom) bool { return room.Name == item.Room.Name && room.Online == online })
+			if i == -1 {
+				i = len(res.Rooms)
+				res.Rooms = append(res.Rooms, profileRoom{Name: item.Room.Name, Online: online})
+			}
+			res.Rooms[i].Count++
+		}
+
+		start, end := item.Start.In(uek.Location()), item.End.In(uek.Location())
+		weekday := isoWeekday(start.Weekday())
+		if i := slices.IndexFunc(res.Days, func(day profileWeekday) bool { return day.Weekday == weekday }); i != -1 {
+			res.Days[i].Count++
+		} else {
+			res.Days = append(res.Days, profileWeekday{Weekday: weekday, Count: 1})
+		}
+
+		slot := profileSlot{Start: start.Format("15:04"), End: end.Format("15:04")}
+		if i := slices.IndexFunc(res.Hours, func(s profileSlot) bool { return s.Start == slot.Start && s.End == slot.End }); i != -1 {
+			res.Hours[i].Count++
+		} else {
+			slot.Count = 1
+			res.Hours = append(res.Hours, slot)
+		}
+
+		for _, lecturer := range findCoTeachers(item, schedule.Header.Name, roomSchedules) {
+			i := slices.IndexFunc(res.CoTeachers, func(coTeacher profileCoTeacher) bool { return coTeacher.Name == lecturer.Name })
+			if i == -1 {
+				i = len(res.CoTeachers)
+				res.CoTeachers = append(res.CoTeachers, profileCoTeacher{Name: lecturer.Name, Subjects: []string{}})
+			}
+			coTeacher := &res.CoTeachers[i]
+			coTeacher.Count++
+			if coTeacher.MoodleId == 0 && lecturer.MoodleId != 0 {
+				coTeacher.MoodleId = lecturer.MoodleId
+				coTeacher.MoodleUrl = moodleUrl(lecturer.MoodleId)
+			}
+			if !slices.Contains(coTeacher.Subjects, item.Subject) {
+				coTeacher.Subjects = append(coTeacher.Subjects, item.Subject)
+			}
+		}
+	}
+
+	for i := range res.Subjects {
+		res.Subjects[i].Hours = roundToHundredths(res.Subjects[i].Hours)
+		slices.Sort(res.Subjects[i].Groups)
+	}
+
+	slices.SortStableFunc(res.Subjects, func(a profileSubject, b profileSubject) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Subject, b.Subject))
+	})
+	slices.SortStableFunc(res.Groups, func(a profileCount, b profileCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	slices.SortStableFunc(res.Rooms, func(a profileRoom, b profileRoom) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	slices.SortStableFunc(res.Days, func(a profileWeekday, b profileWeekday) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Weekday, b.Weekday))
+	})
+	slices.SortStableFunc(res.Hours, func(a profileSlot, b profileSlot) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Start, b.Start), cmp.Compare(a.End, b.End))
+	})
+	slices.SortStableFunc(res.CoTeachers, func(a profileCoTeacher, b profileCoTeacher) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+
+	return res
+}
+
+func incrementProfileCount(counts []profileCount, name string) []profileCount {
+	if i := slices.IndexFunc(counts, func(count profileCount) bool { return count.Name == name }); i != -1 {
+		counts[i].Count++
+		return counts
+	}
+
+	return append(counts, profileCount{Name: name, Count: 1})
+}
+
+// other lecturers of the same class in the room's schedule
+func findCoTeachers(item *uek.ScheduleItem, lecturerName string, roomSchedules []*uek.Schedule) []uek.ScheduleItemLecturer {
+	if item.Room == nil || item.Room.URL != "" {
+		return nil
+	}
+
+	for _, roomSchedule := range roomSchedules {
+		if roomSchedule.Header.Name != item.Room.Name {
+			continue
+		}
+
+		for _, roomItem := range roomSchedule.Items {
+			if !roomItem.Start.Equal(item.Start) || !roomItem.End.Equal(item.End) || roomItem.Subject != item.Subject || roomItem.Type != item.Type {
+				continue
+			}
+
+			coTeachers := []uek.ScheduleItemLecturer{}
+			for _, lecturer := range roomItem.Lecturers {
+				if lecturer.Name != lecturerName {
+					coTeachers = append(coTeachers, lecturer)
+				}
+			}
+			return coTeachers
+		}
+	}
+
+	return nil
+}
+
+func moodleUrl(moodleId int) string {
+	return fmt.Sprintf("https://e-uczelnia.uek.krakow.pl/course/view.php?id=%d", moodleId)
+}
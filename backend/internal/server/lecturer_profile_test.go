@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+)
+
+func TestComputeLecturerProfile(t *testing.T) {
+	// the next monday is after the dst change
+	monday := time.Date(2026, 10, 19, 8, 0, 0, 0, uek.Location())
+	tuesday, nextMonday := monday.AddDate(0, 0, 1), monday.AddDate(0, 0, 7)
+	lecturer := uek.ScheduleItemLecturer{Name: "dr Jan Nowak", MoodleId: 123}
+	coTeacher := uek.ScheduleItemLecturer{Name: "dr Anna Kowalska", MoodleId: 456}
+	room := &uek.ScheduleItemRoom{Name: "Paw.A 011"}
+
+	schedule := &uek.Schedule{
+		Header: uek.ScheduleHeader{Id: 1, Name: lecturer.Name},
+		Items: []*uek.ScheduleItem{
+			{Start: monday, End: monday.Add(90 * time.Minute), Subject: "Ekonomia", Type: "wykład", Groups: []string{"A", "B"}, Lecturers: []uek.ScheduleItemLecturer{lecturer}, Room: room},
+			{Start: tuesday, End: tuesday.Add(90 * time.Minute), Subject: "Statystyka", Type: "ćwiczenia", Groups: []string{"A"}, Lecturers: []uek.ScheduleItemLecturer{lecturer}, Room: &uek.ScheduleItemRoom{Name: "Platforma Moodle", URL: "https://e-uczelnia.uek.krakow.pl"}},
+			{Start: nextMonday, End: nextMonday.Add(90 * time.Minute), Subject: "Ekonomia", Type: "wykład", Groups: []string{"A", "B"}, Lecturers: []uek.ScheduleItemLecturer{lecturer}, Room: room},
+		},
+	}
+	roomSchedules := []*uek.Schedule{{
+		Header: uek.ScheduleHeader{Id: 2, Name: room.Name},
+		Items: []*uek.ScheduleItem{
+			{Start: monday, End: monday.Add(90 * time.Minute), Subject: "Ekonomia", Type: "wykład", Lecturers: []uek.ScheduleItemLecturer{lecturer, coTeacher}, Room: room},
+			// only the lecturer in the second week
+			{Start: nextMonday, End: nextMonday.Add(90 * time.Minute), Subject: "Ekonomia", Type: "wykład", Lecturers: []uek.ScheduleItemLecturer{lecturer}, Room: room},
+		},
+	}}
+
+	res := computeLecturerProfile(schedule, roomSchedules)
+
+	if res.MoodleUrl != "https://e-uczelnia.uek.krakow.pl/course/view.php?id=123" {
+		t.Errorf("unexpected moodle url %q", res.MoodleUrl)
+	}
+	if len(res.Subjects) != 2 || res.Subjects[0].Subject != "Ekonomia" || res.Subjects[0].Count != 2 || res.Subjects[0].Hours != 4 || len(res.Subjects[0].Groups) != 2 {
+		t.Errorf("unexpected subjects: %+v", res.Subjects)
+	}
+	if len(res.Groups) != 2 || res.Groups[0] != (profileCount{Name: "A", Count: 3}) {
+		t.Errorf("unexpected groups: %+v", res.Groups)
+	}
+	if len(res.Rooms) != 2 || res.Rooms[0] != (profileRoom{Name: room.Name, Count: 2}) || !res.Rooms[1].Online {
+		t.Errorf("unexpected rooms: %+v", res.Rooms)
+	}
+	if len(res.Days) != 2 || res.Days[0] != (profileWeekday{Weekday: 1, Count: 2}) {
+		t.Errorf("unexpected days: %+v", res.Days)
+	}
+	if len(res.Hours) != 1 || res.Hours[0] != (profileSlot{Start: "08:00", End: "09:30", Count: 3}) {
+		t.Errorf("unexpected hours: %+v", res.Hours)
+	}
+	if len(res.CoTeachers) != 1 || res.CoTeachers[0].Name != coTeacher.Name || res.CoTeachers[0].Count != 1 || res.CoTeachers[0].MoodleId != coTeacher.MoodleId {
+		t.Errorf("unexpected co-teachers: %+v", res.CoTeachers)
+	}
+}
+
+func TestLecturerProfileWithoutFailedRooms(t *testing.T) {
+	srv := newTestServer(t)
+	lecturerHeaders, _, err := srv.uek.GetHeaders(t.Context(), uek.ScheduleTypeLecturer, "")
+	if err != nil || len(lecturerHeaders) == 0 {
+		t.Fatalf("failed to get lecturer headers: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/lecturerProfile?id=%d", lecturerHeaders[0].Id), nil)
+	// every room schedule fails, everything else works
+	ctx, err := uekmock.WithRequestOverrides(req.Context(), http.Header{uekmock.FaultsHeader: {"500@typ=S&id="}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := serveTestRequest(srv, req.WithContext(ctx))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body)
+	}
+
+	profile := lecturerProfileResponse{}
+	if err := json.Unmarshal(res.Body.Bytes(), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if len(profile.Subjects) == 0 || len(profile.Rooms) == 0 {
+		t.Errorf("expected the profile to be filled in, got %+v", profile)
+	}
+	if len(profile.CoTeachers) != 0 {
+		t.Errorf("expected no co-teachers without room schedules, got %+v", profile.CoTeachers)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
 )
 
 func (srv *Server) debugLoggingMiddleware(handler http.HandlerFunc) http.HandlerFunc {
@@ -14,6 +16,14 @@ func (srv *Server) debugLoggingMiddleware(handler http.HandlerFunc) http.Handler
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
+
+		// mock fault/latency overrides are only honored in debug mode
+		if ctx, err := uekmock.WithRequestOverrides(r.Context(), r.Header); err != nil {
+			srv.logger.DebugContext(r.Context(), "Ignoring invalid mock overrides", slog.Any("err", err))
+		} else {
+			r = r.WithContext(ctx)
+		}
+
 		handler(w, r)
 		srv.logger.DebugContext(r.Context(), "Request handled", slog.String("url", r.URL.String()), slog.String("proto", r.Proto), slog.String("sourceIp", r.RemoteAddr), slog.String("timeTaken", time.Since(startTime).String()))
 	}
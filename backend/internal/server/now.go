@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	// how far ahead to look for the next class, covers holiday breaks
+	nowLookahead = 14 * 24 * time.Hour
+	nowMaxAge    = time.Minute
+)
+
+type nowResponse struct {
+	Now                     time.Time         `json:"now"`
+	Current                 *uek.ScheduleItem `json:"current,omitempty"`
+	MinutesUntilCurrentEnds *int              `json:"minutesUntilCurrentEnds,omitempty"`
+	// other items in progress at the same time as current, happens with merged schedules
+	OverlappingCurrent []*uek.ScheduleItem `json:"overlappingCurrent,omitempty"`
+	Next               *uek.ScheduleItem   `json:"next,omitempty"`
+	MinutesUntilNext   *int                `json:"minutesUntilNext,omitempty"`
+	// gap before the next class, only when it is today and something happened earlier today
+	BreakMinutes *int `json:"breakMinutes,omitempty"`
+	// items after the current one that start today
+	RemainingToday  []*uek.ScheduleItem `json:"remainingToday"`
+	RestOfDayOnline bool                `json:"restOfDayOnline"`
+}
+
+func (srv *Server) handleNow(w http.ResponseWriter, r *http.Request, params requestParams) {
+	scheduleType, scheduleIds := uek.ScheduleType(params.string("type")), params.ints("id")
+
+	now := time.Now().In(uek.Location()).Truncate(time.Second)
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	aggregateSchedule, _, cacheExpirationDate, err := srv.uek.GetAggregateScheduleInRange(r.Context(), scheduleType, scheduleIds, startOfToday, now.Add(nowLookahead))
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get schedule", err, slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.Any("scheduleIds", scheduleIds)))
+		return
+	}
+
+	res, nextChange := computeNow(aggregateSchedule.Items, now)
+
+	maxAge := min(time.Until(cacheExpirationDate), nextChange.Sub(now), nowMaxAge)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", max(int(maxAge.Seconds()), 0)))
+	respondJSON(w, res)
+}
+
+// items must be sorted, returns the response and when it will change next
+// cancelled slots are skipped, the classes don't take place
+func computeNow(items []*uek.ScheduleItem, now time.Time) (*nowResponse, time.Time) {
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	startOfTomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	res := &nowResponse{
+		Now:            now,
+		RemainingToday: []*uek.ScheduleItem{},
+	}
+	nextChange := startOfTomorrow
+
+	var lastEndedToday *uek.ScheduleItem
+	for _, item := range items {
+		if item.IsCancelled() {
+			continue
+		}
+
+		switch {
+		case !item.End.After(now):
+			if item.End.After(startOfToday) && (lastEndedToday == nil || item.End.After(lastEndedToday.End)) {
+				lastEndedToday = item
+			}
+		case !item.Start.After(now):
+			if res.Current == nil {
+				res.Current = item
+				res.MinutesUntilCurrentEnds = ptr(minutesUntil(now, item.End))
+			} else {
+				res.OverlappingCurrent = append(res.OverlappingCurrent, item)
+			}
+			nextChange = minTime(nextChange, item.End)
+		default:
+			if res.Next == nil {
+				res.Next = item
+				res.MinutesUntilNext = ptr(minutesUntil(now, item.Start))
+				nextChange = minTime(nextChange, item.Start)
+			}
+			if item.Start.Before(startOfTomorrow) {
+				res.RemainingToday = append(res.RemainingToday, item)
+			}
+		}
+	}
+
+	if res.Next != nil && res.Next.Start.Before(startOfTomorrow) {
+		breakStart := time.Time{}
+		if res.Current != nil {
+			breakStart = res.Current.End
+			for _, item := range res.OverlappingCurrent {
+				if item.End.After(breakStart) {
+					breakStart = item.End
+				}
+			}
+		} else if lastEndedToday != nil {
+			breakStart = lastEndedToday.End
+		}
+		if !breakStart.IsZero() && breakStart.Before(res.Next.Start) {
+			res.BreakMinutes = ptr(int(res.Next.Start.Sub(breakStart).Minutes()))
+		}
+	}
+
+	restOfDay := res.RemainingToday
+	if res.Current != nil {
+		restOfDay = slices.Concat([]*uek.ScheduleItem{res.Current}, res.OverlappingCurrent, restOfDay)
+	}
+	res.RestOfDayOnline = len(restOfDay) > 0
+	for _, item := range restOfDay {
+		if item.Room == nil || item.Room.URL == "" {
+			res.RestOfDayOnline = false
+			break
+		}
+	}
+
+	return res, nextChange
+}
+
+func minutesUntil(now time.Time, t time.Time) int {
+	return int(math.Ceil(t.Sub(now).Minutes()))
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
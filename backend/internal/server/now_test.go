@@ -0,0 +1,214 @@
+package server
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestComputeNow(t *testing.T) {
+	at := func(date string, clock string) time.Time {
+		parsed, _ := time.ParseInLocation(time.DateOnly+" 15:04", date+" "+clock, uek.Location())
+		return parsed
+	}
+	item := func(date string, start string, end string, subject string, online bool) *uek.ScheduleItem {
+		room := &uek.ScheduleItemRoom{Name: "Paw.A 011"}
+		if online {
+			room = &uek.ScheduleItemRoom{Name: "Platforma Moodle", URL: "https://e-uczelnia.uek.krakow.pl"}
+		}
+		return &uek.ScheduleItem{Start: at(date, start), End: at(date, end), Subject: subject, Type: "wykład", Room: room}
+	}
+	cancelled := func(item *uek.ScheduleItem) *uek.ScheduleItem {
+		item.Type = "przeniesienie zajęć"
+		return item
+	}
+	subjects := func(items []*uek.ScheduleItem) []string {
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Subject)
+		}
+		return names
+	}
+	subject := func(item *uek.ScheduleItem) string {
+		if item == nil {
+			return ""
+		}
+		return item.Subject
+	}
+
+	for _, tc := range []struct {
+		name               string
+		items              []*uek.ScheduleItem
+		now                time.Time
+		current            string
+		overlappingCurrent []string
+		next               string
+		minutesUntilNext   *int
+		breakMinutes       *int
+		remainingToday     []string
+		restOfDayOnline    bool
+		nextChange         time.Time
+	}{
+		{
+			name:           "nothing",
+			now:            at("2026-10-19", "10:00"),
+			remainingToday: []string{},
+			nextChange:     at("2026-10-20", "00:00"),
+		},
+		{
+			name: "break after an earlier class",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "08:00", "09:30", "Ekonomia", false),
+				item("2026-10-19", "11:00", "12:30", "Statystyka", false),
+			},
+			now:              at("2026-10-19", "10:00"),
+			next:             "Statystyka",
+			minutesUntilNext: ptr(60),
+			breakMinutes:     ptr(90),
+			remainingToday:   []string{"Statystyka"},
+			nextChange:       at("2026-10-19", "11:00"),
+		},
+		{
+			name: "break after the current class",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "08:00", "09:30", "Ekonomia", false),
+				item("2026-10-19", "09:45", "11:15", "Statystyka", false),
+				item("2026-10-19", "11:30", "13:00", "Finanse", false),
+			},
+			now:              at("2026-10-19", "08:30"),
+			current:          "Ekonomia",
+			next:             "Statystyka",
+			minutesUntilNext: ptr(75),
+			breakMinutes:     ptr(15),
+			remainingToday:   []string{"Statystyka", "Finanse"},
+			nextChange:       at("2026-10-19", "09:30"),
+		},
+		{
+			name: "overlapping classes in progress",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "08:00", "09:30", "Ekonomia", false),
+				item("2026-10-19", "08:15", "10:00", "Statystyka", false),
+				item("2026-10-19", "10:15", "11:45", "Finanse", false),
+			},
+			now:                at("2026-10-19", "09:00"),
+			current:            "Ekonomia",
+			overlappingCurrent: []string{"Statystyka"},
+			next:               "Finanse",
+			minutesUntilNext:   ptr(75),
+			// counted from the later of the overlapping classes
+			breakMinutes:   ptr(15),
+			remainingToday: []string{"Finanse"},
+			nextChange:     at("2026-10-19", "09:30"),
+		},
+		{
+			name: "no break across the day boundary",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "18:00", "19:30", "Ekonomia", false),
+				item("2026-10-20", "08:00", "09:30", "Statystyka", false),
+			},
+			now:              at("2026-10-19", "20:00"),
+			next:             "Statystyka",
+			minutesUntilNext: ptr(720),
+			remainingToday:   []string{},
+			nextChange:       at("2026-10-20", "00:00"),
+		},
+		{
+			name: "yesterday's class doesn't start a break",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "18:00", "19:30", "Ekonomia", false),
+				item("2026-10-20", "08:00", "09:30", "Statystyka", false),
+			},
+			now:              at("2026-10-20", "07:00"),
+			next:             "Statystyka",
+			minutesUntilNext: ptr(60),
+			remainingToday:   []string{"Statystyka"},
+			nextChange:       at("2026-10-20", "08:00"),
+		},
+		{
+			name: "cancelled classes are skipped",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "08:00", "09:30", "Ekonomia", false),
+				cancelled(item("2026-10-19", "09:45", "11:15", "Statystyka", false)),
+				cancelled(item("2026-10-19", "11:30", "13:00", "Statystyka", false)),
+				item("2026-10-19", "13:15", "14:45", "Finanse", false),
+			},
+			now:              at("2026-10-19", "10:00"),
+			next:             "Finanse",
+			minutesUntilNext: ptr(195),
+			breakMinutes:     ptr(225),
+			remainingToday:   []string{"Finanse"},
+			nextChange:       at("2026-10-19", "13:15"),
+		},
+		{
+			name: "rest of day online",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "08:00", "09:30", "Ekonomia", false),
+				item("2026-10-19", "09:45", "11:15", "Statystyka", true),
+				item("2026-10-19", "11:30", "13:00", "Finanse", true),
+				item("2026-10-20", "08:00", "09:30", "Ekonomia", false),
+			},
+			now:              at("2026-10-19", "10:00"),
+			current:          "Statystyka",
+			next:             "Finanse",
+			minutesUntilNext: ptr(90),
+			breakMinutes:     ptr(15),
+			remainingToday:   []string{"Finanse"},
+			restOfDayOnline:  true,
+			nextChange:       at("2026-10-19", "11:15"),
+		},
+		{
+			name: "rest of day not online when an overlapping class is in a room",
+			items: []*uek.ScheduleItem{
+				item("2026-10-19", "09:45", "11:15", "Statystyka", true),
+				item("2026-10-19", "10:00", "11:30", "Finanse", false),
+			},
+			now:                at("2026-10-19", "10:30"),
+			current:            "Statystyka",
+			overlappingCurrent: []string{"Finanse"},
+			remainingToday:     []string{},
+			nextChange:         at("2026-10-19", "11:15"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, nextChange := computeNow(tc.items, tc.now)
+
+			if subject(res.Current) != tc.current {
+				t.Errorf("expected current %q, got %q", tc.current, subject(res.Current))
+			}
+			if !slices.Equal(subjects(res.OverlappingCurrent), tc.overlappingCurrent) {
+				t.Errorf("expected overlapping current %v, got %v", tc.overlappingCurrent, subjects(res.OverlappingCurrent))
+			}
+			if subject(res.Next) != tc.next {
+				t.Errorf("expected next %q, got %q", tc.next, subject(res.Next))
+			}
+			if !equalIntPtr(res.MinutesUntilNext, tc.minutesUntilNext) {
+				t.Errorf("expected minutes until next %v, got %v", formatIntPtr(tc.minutesUntilNext), formatIntPtr(res.MinutesUntilNext))
+			}
+			if !equalIntPtr(res.BreakMinutes, tc.breakMinutes) {
+				t.Errorf("expected break %v, got %v", formatIntPtr(tc.breakMinutes), formatIntPtr(res.BreakMinutes))
+			}
+			if !slices.Equal(subjects(res.RemainingToday), tc.remainingToday) {
+				t.Errorf("expected remaining today %v, got %v", tc.remainingToday, subjects(res.RemainingToday))
+			}
+			if res.RestOfDayOnline != tc.restOfDayOnline {
+				t.Errorf("expected rest of day online %t, got %t", tc.restOfDayOnline, res.RestOfDayOnline)
+			}
+			if !nextChange.Equal(tc.nextChange) {
+				t.Errorf("expected next change %s, got %s", tc.nextChange, nextChange)
+			}
+		})
+	}
+}
+
+func equalIntPtr(a *int, b *int) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func formatIntPtr(v *int) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
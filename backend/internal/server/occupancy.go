@@ -0,0 +1,52 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+func (srv *Server) registerOccupancyRoutes() {
+	if srv.occupancy == nil {
+		return
+	}
+
+	srv.registerAPIEndpoint(occupancyEndpoint, srv.handleOccupancy)
+	srv.registerAPIEndpoint(occupancyCSVEndpoint, srv.handleOccupancyCSV)
+}
+
+func (srv *Server) handleOccupancy(w http.ResponseWriter, r *http.Request, _ requestParams) {
+	report := srv.occupancy.Report()
+	if report == nil {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "No occupancy report has been generated yet", nil)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	respondJSON(w, report)
+}
+
+func (srv *Server) handleOccupancyCSV(w http.ResponseWriter, r *http.Request, params requestParams) {
+	view := params.string("view")
+	report := srv.occupancy.Report()
+	if report == nil {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "No occupancy report has been generated yet", nil)
+		return
+	}
+
+	if view == "" {
+		view = "rooms"
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="occupancy-`+view+`.csv"`)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+
+	var err error
+	if view == "heatmap" {
+		err = report.WriteHeatmapCSV(w)
+	} else {
+		err = report.WriteRoomsCSV(w)
+	}
+	if err != nil {
+		srv.logger.Debug("Failed to write occupancy csv", slog.String("requestId", requestIdFromContext(r.Context())), slog.Any("err", err))
+	}
+}
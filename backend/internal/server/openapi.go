@@ -0,0 +1,386 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const openAPIVersion = "3.0.3"
+
+// subset of the OpenAPI 3.0 schema object, also used to validate requests
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Minimum              *int                      `json:"minimum,omitempty"`
+	Maximum              *int                      `json:"maximum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	UniqueItems          bool                      `json:"uniqueItems,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *bool                     `json:"additionalProperties,omitempty"`
+	// codes returned when a value fails the keyword, "" is the fallback
+	errorCodes map[string]errorCode
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationId string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Parameters  []*apiParam                 `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIRequestBody struct {
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required"`
+	Content     map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+// describes a route registered in registerAPIRoutes, handlers get their params already validated
+type apiEndpoint struct {
+	Method      string
+	Path        string
+	OperationId string
+	Summary     string
+	Params      []*apiParam
+	// json, validated together with params
+	RequestBody *apiRequestBody
+	Responses   []apiResponse
+}
+
+type apiRequestBody struct {
+	Description string
+	Schema      *openAPISchema
+}
+
+type apiResponse struct {
+	// 0 means "default"
+	StatusCode  int
+	Description string
+	ContentType string
+	Schema      *openAPISchema
+}
+
+func (ep *apiEndpoint) pattern() string {
+	return ep.Method + " " + ep.Path
+}
+
+// falls back to the default response
+func (ep *apiEndpoint) response(statusCode int) (apiResponse, bool) {
+	defaultIndex := -1
+	for i, res := range ep.Responses {
+		if res.StatusCode == statusCode {
+			return res, true
+		}
+		if res.StatusCode == 0 {
+			defaultIndex = i
+		}
+	}
+
+	if defaultIndex == -1 {
+		return apiResponse{}, false
+	}
+
+	return ep.Responses[defaultIndex], true
+}
+
+func buildOpenAPIDocument(endpoints []*apiEndpoint, schemas map[string]*openAPISchema) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:   "UEK Plan Zajęć API",
+			Version: "1",
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			Schemas: schemas,
+		},
+	}
+
+	for _, ep := range endpoints {
+		op := &openAPIOperation{
+			OperationId: ep.OperationId,
+			Summary:     ep.Summary,
+			Parameters:  ep.Params,
+			Responses:   map[string]*openAPIResponse{},
+		}
+		if ep.RequestBody != nil {
+			op.RequestBody = &openAPIRequestBody{
+				Description: ep.RequestBody.Description,
+				Required:    true,
+				Content: map[string]*openAPIMediaType{
+					"application/json": {
+						Schema: ep.RequestBody.Schema,
+					},
+				},
+			}
+		}
+
+		for _, res := range ep.Responses {
+			statusCode := "default"
+			if res.StatusCode != 0 {
+				statusCode = fmt.Sprint(res.StatusCode)
+			}
+
+			op.Responses[statusCode] = &openAPIResponse{
+				Description: res.Description,
+			}
+			if res.ContentType != "" {
+				op.Responses[statusCode].Content = map[string]*openAPIMediaType{
+					res.ContentType: {
+						Schema: res.Schema,
+					},
+				}
+			}
+		}
+
+		if doc.Paths[ep.Path] == nil {
+			doc.Paths[ep.Path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[ep.Path][strings.ToLower(ep.Method)] = op
+	}
+
+	return doc
+}
+
+func (srv *Server) handleOpenAPIDocument(w http.ResponseWriter, r *http.Request, _ requestParams) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	respondJSON(w, apiDocument)
+}
+
+type schemaValidationError struct {
+	Path    string
+	Keyword string
+	Message string
+	schema  *openAPISchema
+}
+
+func (err *schemaValidationError) Error() string {
+	if err.Path == "" {
+		return err.Message
+	}
+
+	return fmt.Sprintf("%s: %s", err.Path, err.Message)
+}
+
+func (err *schemaValidationError) code() errorCode {
+	if code, ok := err.schema.errorCodes[err.Keyword]; ok {
+		return code
+	}
+	if code, ok := err.schema.errorCodes[""]; ok {
+		return code
+	}
+
+	return errorCodeInvalidParameter
+}
+
+func (err *schemaValidationError) details() map[string]any {
+	details := map[string]any{
+		"parameter": err.Path,
+		"keyword":   err.Keyword,
+	}
+
+	switch err.Keyword {
+	case "enum":
+		details["allowed"] = err.schema.Enum
+	case "maxItems":
+		details["max"] = *err.schema.MaxItems
+	case "minItems":
+		details["min"] = *err.schema.MinItems
+	case "minimum":
+		details["min"] = *err.schema.Minimum
+	case "maximum":
+		details["max"] = *err.schema.Maximum
+	}
+
+	return details
+}
+
+func resolveSchema(schema *openAPISchema) *openAPISchema {
+	for schema.Ref != "" {
+		schema = apiSchemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+
+	return schema
+}
+
+// validates a value decoded by encoding/json, or a query param converted by parseQueryValue
+func validateValue(schema *openAPISchema, path string, value any) *schemaValidationError {
+	schema = resolveSchema(schema)
+	fail := func(keyword string, format string, args ...any) *schemaValidationError {
+		return &schemaValidationError{
+			Path:    path,
+			Keyword: keyword,
+			Message: fmt.Sprintf(format, args...),
+			schema:  schema,
+		}
+	}
+
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fail("type", "must not be null")
+	}
+
+	switch schema.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fail("type", "must be a string")
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			return fail("enum", "must be one of %s", strings.Join(schema.Enum, ", "))
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fail("format", "must be a RFC 3339 date-time")
+			}
+		}
+		if schema.Format == "date" {
+			if _, err := time.Parse(time.DateOnly, s); err != nil {
+				return fail("format", "must be a date in YYYY-MM-DD format")
+			}
+		}
+
+	case "integer":
+		var n int
+		switch v := value.(type) {
+		case int:
+			n = v
+		case float64:
+			if v != math.Trunc(v) {
+				return fail("type", "must be an integer")
+			}
+			n = int(v)
+		default:
+			return fail("type", "must be an integer")
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			return fail("minimum", "must be at least %d", *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			return fail("maximum", "must be at most %d", *schema.Maximum)
+		}
+
+	case "number":
+		switch value.(type) {
+		case int, float64:
+		default:
+			return fail("type", "must be a number")
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("type", "must be a boolean")
+		}
+
+	case "array":
+		items, ok := toAnySlice(value)
+		if !ok {
+			return fail("type", "must be an array")
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			return fail("minItems", "must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			return fail("maxItems", "must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range items {
+			if err := validateValue(schema.Items, fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+			if schema.UniqueItems && slices.Contains(items[:i], item) {
+				return fail("uniqueItems", "must not contain duplicates")
+			}
+		}
+
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fail("type", "must be an object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fail("required", "missing property %s", name)
+			}
+		}
+		// sorted to report the same error for the same input
+		for _, name := range slices.Sorted(maps.Keys(object)) {
+			propertyPath := name
+			if path != "" {
+				propertyPath = path + "." + name
+			}
+
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fail("additionalProperties", "unknown property %s", name)
+				}
+				continue
+			}
+			if err := validateValue(propertySchema, propertyPath, object[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func toAnySlice(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case []int:
+		items := make([]any, len(v))
+		for i, n := range v {
+			items[i] = n
+		}
+		return items, true
+	case []string:
+		items := make([]any, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI parameter object, query params of array type may be repeated
+type apiParam struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+const maxRequestBodySize = 64 * 1024
+
+// param values converted to go types, missing optional params are absent
+type requestParams struct {
+	endpoint *apiEndpoint
+	values   map[string]any
+	// validated json request body
+	body []byte
+}
+
+func parseRequestParams(r *http.Request, ep *apiEndpoint) (requestParams, *schemaValidationError) {
+	params := requestParams{
+		endpoint: ep,
+		values:   map[string]any{},
+	}
+	query := r.URL.Query()
+
+	for _, param := range ep.Params {
+		schema := resolveSchema(param.Schema)
+
+		var rawValues []string
+		switch param.In {
+		case "path":
+			rawValues = []string{r.PathValue(param.Name)}
+		case "header":
+			rawValues = r.Header.Values(param.Name)
+		case "query":
+			for _, rawValue := range query[param.Name] {
+				if rawValue = strings.TrimSpace(rawValue); rawValue != "" {
+					rawValues = append(rawValues, rawValue)
+				}
+			}
+		}
+
+		if len(rawValues) == 0 || rawValues[0] == "" {
+			if param.Required {
+				keyword := "required"
+				if schema.Type == "array" {
+					keyword = "minItems"
+				}
+				return requestParams{}, &schemaValidationError{
+					Path:    param.Name,
+					Keyword: keyword,
+					Message: "is required",
+					schema:  schema,
+				}
+			}
+			continue
+		}
+
+		var value any
+		if schema.Type == "array" {
+			itemSchema := resolveSchema(schema.Items)
+			values := make([]any, 0, len(rawValues))
+			for i, rawValue := range rawValues {
+				itemValue, err := parseQueryValue(itemSchema, fmt.Sprintf("%s[%d]", param.Name, i), rawValue)
+				if err != nil {
+					return requestParams{}, err
+				}
+				values = append(values, itemValue)
+			}
+			value = values
+		} else {
+			var err *schemaValidationError
+			if value, err = parseQueryValue(schema, param.Name, rawValues[len(rawValues)-1]); err != nil {
+				return requestParams{}, err
+			}
+		}
+
+		if err := validateValue(schema, param.Name, value); err != nil {
+			return requestParams{}, err
+		}
+		params.values[param.Name] = value
+	}
+
+	if ep.RequestBody != nil {
+		var err *schemaValidationError
+		if params.body, err = readJSONBody(r, ep.RequestBody.Schema); err != nil {
+			return requestParams{}, err
+		}
+	}
+
+	return params, nil
+}
+
+func readJSONBody(r *http.Request, schema *openAPISchema) ([]byte, *schemaValidationError) {
+	invalidBodyErr := &schemaValidationError{
+		Keyword: "type",
+		Message: fmt.Sprintf("body must be json of at most %d bytes", maxRequestBodySize),
+		schema:  resolveSchema(schema),
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+	if err != nil || len(data) > maxRequestBodySize {
+		return nil, invalidBodyErr
+	}
+
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, invalidBodyErr
+	}
+
+	if err := validateValue(schema, "", raw); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func parseQueryValue(schema *openAPISchema, path string, rawValue string) (any, *schemaValidationError) {
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.Atoi(rawValue)
+		if err != nil {
+			return nil, &schemaValidationError{
+				Path:    path,
+				Keyword: "type",
+				Message: "must be an integer",
+				schema:  schema,
+			}
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(rawValue)
+		if err != nil {
+			return nil, &schemaValidationError{
+				Path:    path,
+				Keyword: "type",
+				Message: "must be a boolean",
+				schema:  schema,
+			}
+		}
+		return b, nil
+	}
+
+	return rawValue, nil
+}
+
+// handlers may only read params declared in their endpoint definition
+func (params requestParams) lookup(name string) (any, bool) {
+	for _, param := range params.endpoint.Params {
+		if param.Name == name {
+			value, ok := params.values[name]
+			return value, ok
+		}
+	}
+
+	panic(fmt.Sprintf("param %q is not declared for %s", name, params.endpoint.pattern()))
+}
+
+// panics for endpoints without a request body
+func (params requestParams) decodeBody(v any) error {
+	if params.endpoint.RequestBody == nil {
+		panic(fmt.Sprintf("no request body is declared for %s", params.endpoint.pattern()))
+	}
+
+	return json.Unmarshal(params.body, v)
+}
+
+func (params requestParams) string(name string) string {
+	value, _ := params.lookup(name)
+	s, _ := value.(string)
+	return s
+}
+
+func (params requestParams) int(name string) (int, bool) {
+	value, ok := params.lookup(name)
+	n, _ := value.(int)
+	return n, ok
+}
+
+func (params requestParams) bool(name string) bool {
+	value, _ := params.lookup(name)
+	b, _ := value.(bool)
+	return b
+}
+
+func (params requestParams) ints(name string) []int {
+	value, _ := params.lookup(name)
+	values, _ := value.([]any)
+	ints := make([]int, 0, len(values))
+	for _, value := range values {
+		ints = append(ints, value.(int))
+	}
+	return ints
+}
+
+func (params requestParams) strings(name string) []string {
+	value, _ := params.lookup(name)
+	values, _ := value.([]any)
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		strs = append(strs, value.(string))
+	}
+	return strs
+}
+
+// validates the decoded json against the schema before unmarshalling it into v
+func decodeBase64JSONParam(schema *openAPISchema, path string, rawValue string, v any) *schemaValidationError {
+	invalidPayloadErr := &schemaValidationError{
+		Path:    path,
+		Keyword: "format",
+		Message: "must be base64 encoded json",
+		schema:  resolveSchema(schema),
+	}
+
+	data, err := base64.StdEncoding.DecodeString(rawValue)
+	if err != nil {
+		return invalidPayloadErr
+	}
+
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return invalidPayloadErr
+	}
+
+	if err := validateValue(schema, path, raw); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return invalidPayloadErr
+	}
+
+	return nil
+}
+
+func respondValidationError(w http.ResponseWriter, r *http.Request, err *schemaValidationError) {
+	respondError(w, r, http.StatusBadRequest, err.code(), fmt.Sprintf("Invalid %s", err.Error()), err.details())
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/webpush"
+)
+
+func (srv *Server) registerPushRoutes() {
+	if srv.webPush == nil {
+		return
+	}
+
+	srv.registerAPIEndpoint(pushPublicKeyEndpoint, srv.handlePushPublicKey)
+	srv.registerAPIEndpoint(pushSubscribeEndpoint, srv.handlePushSubscribe)
+	srv.registerAPIEndpoint(pushUnsubscribeEndpoint, srv.handlePushUnsubscribe)
+}
+
+func (srv *Server) handlePushPublicKey(w http.ResponseWriter, r *http.Request, _ requestParams) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	respondJSON(w, struct {
+		PublicKey string `json:"publicKey"`
+	}{
+		PublicKey: srv.webPush.PublicKey(),
+	})
+}
+
+// subscribing an already subscribed endpoint replaces its schedule selection
+func (srv *Server) handlePushSubscribe(w http.ResponseWriter, r *http.Request, params requestParams) {
+	req := webpush.SubscribeRequest{}
+	if err := params.decodeBody(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, "Body must be a json push subscription", nil)
+		return
+	}
+
+	id, err := srv.webPush.Subscribe(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, webpush.ErrInvalidSubscription):
+			respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, err.Error(), nil)
+		case errors.Is(err, webpush.ErrSubscriptionLimitReached):
+			respondError(w, r, http.StatusServiceUnavailable, errorCodeSubscriptionLimitReached, "No more push subscriptions are accepted", nil)
+		default:
+			srv.respondUpstreamError(w, r, "Failed to subscribe to push notifications", err, slog.String("scheduleType", string(req.ScheduleType)), slog.Any("scheduleIds", req.ScheduleIds))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		Id string `json:"id"`
+	}{
+		Id: id,
+	})
+}
+
+// the endpoint is only known to the browser and its push service, so it authorizes the request
+func (srv *Server) handlePushUnsubscribe(w http.ResponseWriter, r *http.Request, params requestParams) {
+	req := struct {
+		Endpoint string `json:"endpoint"`
+	}{}
+	if err := params.decodeBody(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, errorCodeInvalidPayload, "Body must be a json object with the endpoint", nil)
+		return
+	}
+
+	if !srv.webPush.Unsubscribe(req.Endpoint) {
+		respondError(w, r, http.StatusNotFound, errorCodeNotFound, "Subscription not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
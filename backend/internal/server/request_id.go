@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+type requestIdContextKey struct{}
+
+// ids from proxies are reused as long as they are safe to echo back and log
+var validRequestIdRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func (srv *Server) requestIdMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-Id")
+		if !validRequestIdRegex.MatchString(requestId) {
+			requestId = uuid.NewString()
+		}
+
+		w.Header().Set("X-Request-Id", requestId)
+		handler(w, r.WithContext(context.WithValue(r.Context(), requestIdContextKey{}, requestId)))
+	}
+}
+
+func requestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdContextKey{}).(string)
+	return requestId
+}
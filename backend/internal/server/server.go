@@ -8,19 +8,51 @@ import (
 	"sync"
 	"time"
 
+	"github.com/szczursonn/uek-planzajec-v3/internal/digest"
+	"github.com/szczursonn/uek-planzajec-v3/internal/occupancy"
 	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/webhook"
+	"github.com/szczursonn/uek-planzajec-v3/internal/webpush"
 )
 
+type Config struct {
+	Addr string
+	Uek  *uek.Client
+	// admin routes are registered only if AdminToken is set
+	CacheAdmin uek.CacheAdmin
+	Webhooks   *webhook.Manager
+	// public subscribe, confirm and unsubscribe routes are registered only if set
+	Digests    *digest.Manager
+	WebPush    *webpush.Manager
+	Occupancy  *occupancy.Manager
+	AdminToken string
+	Logger     *slog.Logger
+}
+
 type Server struct {
 	httpServer                  http.Server
 	uek                         *uek.Client
+	cacheAdmin                  uek.CacheAdmin
+	webhooks                    *webhook.Manager
+	digests                     *digest.Manager
+	webPush                     *webpush.Manager
+	occupancy                   *occupancy.Manager
+	adminToken                  string
 	logger                      *slog.Logger
 	bufferPool                  sync.Pool
 	staticAssetPathToMetadata   map[string]staticAssetMetadata
 	staticAssetPathToMetadataMu sync.RWMutex
+	streamHub                   *streamHub
+	// canceled when shutdown starts, long-lived responses must end on it
+	shutdownCtx context.Context
 }
 
-func New(addr string, uek *uek.Client, logger *slog.Logger) *Server {
+func New(cfg Config) *Server {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	// enable h2c
 	protocols := &http.Protocols{}
 	protocols.SetHTTP1(true)
@@ -28,7 +60,7 @@ func New(addr string, uek *uek.Client, logger *slog.Logger) *Server {
 
 	srv := &Server{
 		httpServer: http.Server{
-			Addr:              addr,
+			Addr:              cfg.Addr,
 			ReadHeaderTimeout: 15 * time.Second,
 			WriteTimeout:      45 * time.Second,
 			IdleTimeout:       time.Minute,
@@ -36,8 +68,14 @@ func New(addr string, uek *uek.Client, logger *slog.Logger) *Server {
 			Protocols:         protocols,
 			ErrorLog:          slog.NewLogLogger(logger.With(slog.String("source", "http.Server")).Handler(), slog.LevelError),
 		},
-		uek:    uek,
-		logger: logger,
+		uek:        cfg.Uek,
+		cacheAdmin: cfg.CacheAdmin,
+		webhooks:   cfg.Webhooks,
+		digests:    cfg.Digests,
+		webPush:    cfg.WebPush,
+		occupancy:  cfg.Occupancy,
+		adminToken: cfg.AdminToken,
+		logger:     logger,
 		bufferPool: sync.Pool{
 			New: func() any {
 				buff := make([]byte, 32*1024)
@@ -45,10 +83,20 @@ func New(addr string, uek *uek.Client, logger *slog.Logger) *Server {
 			},
 		},
 		staticAssetPathToMetadata: map[string]staticAssetMetadata{},
+		streamHub:                 newStreamHub(),
 	}
 
+	var cancelShutdownCtx context.CancelFunc
+	srv.shutdownCtx, cancelShutdownCtx = context.WithCancel(context.Background())
+	srv.httpServer.RegisterOnShutdown(cancelShutdownCtx)
+	srv.uek.OnScheduleFetched(srv.streamHub.publish)
+
 	srv.registerStaticRoutes()
 	srv.registerAPIRoutes()
+	srv.registerDigestRoutes()
+	srv.registerPushRoutes()
+	srv.registerOccupancyRoutes()
+	srv.registerAdminRoutes()
 
 	return srv
 }
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// study programmes count hours in 45 minute teaching hours
+const teachingHour = 45 * time.Minute
+
+type statsResponse struct {
+	Now      time.Time   `json:"now"`
+	PeriodId int         `json:"periodId"`
+	Total    statsCounts `json:"total"`
+	// share of hours held online, 0 when there are no items
+	OnlineShare float64        `json:"onlineShare"`
+	BySubject   []subjectStats `json:"bySubject"`
+	ByType      []typeStats    `json:"byType"`
+	// every week from the first to the last item, including empty ones
+	WeeklyLoad []weekLoad `json:"weeklyLoad"`
+	// weekdays with classes, most hours first
+	BusiestDays []weekdayLoad `json:"busiestDays"`
+	// most time between the first start and the last end
+	LongestDay *dayLoad `json:"longestDay,omitempty"`
+}
+
+type statsCounts struct {
+	Count          int     `json:"count"`
+	Hours          float64 `json:"hours"`
+	CompletedCount int     `json:"completedCount"`
+	CompletedHours float64 `json:"completedHours"`
+	// includes classes in progress
+	RemainingCount int     `json:"remainingCount"`
+	RemainingHours float64 `json:"remainingHours"`
+	OnlineCount    int     `json:"onlineCount"`
+	OnlineHours    float64 `json:"onlineHours"`
+}
+
+type subjectStats struct {
+	Subject string      `json:"subject"`
+	Counts  statsCounts `json:"counts"`
+	ByType  []typeStats `json:"byType"`
+}
+
+type typeStats struct {
+	Type   string      `json:"type"`
+	Counts statsCounts `json:"counts"`
+}
+
+type weekLoad struct {
+	// monday
+	WeekStart string  `json:"weekStart"`
+	Count     int     `json:"count"`
+	Hours     float64 `json:"hours"`
+}
+
+type weekdayLoad struct {
+	// iso weekday, 1 is monday
+	Weekday int     `json:"weekday"`
+	Count   int     `json:"count"`
+	Hours   float64 `json:"hours"`
+	// distinct dates with classes on this weekday
+	Days int `json:"days"`
+}
+
+type dayLoad struct {
+	Date  string    `json:"date"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Count int       `json:"count"`
+	Hours float64   `json:"hours"`
+}
+
+func (srv *Server) handleStats(w http.ResponseWriter, r *http.Request, params requestParams) {
+	scheduleType, scheduleIds := uek.ScheduleType(params.string("type")), params.ints("id")
+
+	periods, periodsCacheExpirationDate, err := srv.uek.GetSchedulePeriods(r.Context())
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get periods", err)
+		return
+	}
+
+	periodId, _, ok := resolveRequestPeriodId(w, r, params, periods)
+	if !ok {
+		return
+	}
+
+	aggregateSchedule, cacheExpirationDate, err := srv.uek.GetAggregateSchedule(r.Context(), scheduleType, scheduleIds, periodId)
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get schedule", err, slog.Group("params", slog.String("scheduleType", string(scheduleType)), slog.Any("scheduleIds", scheduleIds), slog.Int("periodId", periodId)))
+		return
+	}
+
+	now := time.Now().In(uek.Location()).Truncate(time.Second)
+	res, nextChange := computeStats(aggregateSchedule.Items, now)
+	res.PeriodId = periodId
+
+	// completed and remaining shift whenever a class ends
+	maxAge := min(time.Until(cacheExpirationDate), time.Until(periodsCacheExpirationDate), nextChange.Sub(now))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", max(int(maxAge.Seconds()), 0)))
+	respondJSON(w, res)
+}
+
+// items must be sorted, returns the response and when it will change next
+// cancelled slots and reservations are left out like in the app
+func computeStats(items []*uek.ScheduleItem, now time.Time) (*statsResponse, time.Time) {
+	res := &statsResponse{
+		Now:         now,
+		BySubject:   []subjectStats{},
+		ByType:      []typeStats{},
+		WeeklyLoad:  []weekLoad{},
+		BusiestDays: []weekdayLoad{},
+	}
+	nextChange := time.Time{}
+
+	subjectIndexes := map[string]int{}
+	typeIndexes := map[string]int{}
+	weekdayIndexes := map[time.Weekday]int{}
+	var lastWeekStart time.Time
+	var currentDay *dayLoad
+	for _, item := range items {
+		if !item.IsClass() {
+			continue
+		}
+
+		if item.End.After(now) && (nextChange.IsZero() || item.End.Before(nextChange)) {
+			nextChange = item.End
+		}
+
+		res.Total.add(item, now)
+
+		subjectIndex, ok := subjectIndexes[item.Subject]
+		if !ok {
+			subjectIndex = len(res.BySubject)
+			subjectIndexes[item.Subject] = subjectIndex
+			res.BySubject = append(res.BySubject, subjectStats{Subject: item.Subject})
+		}
+		subject := &res.BySubject[subjectIndex]
+		subject.Counts.add(item, now)
+		subjectTypeIndex := slices.IndexFunc(subject.ByType, func(stats typeStats) bool { return stats.Type == item.Type })
+		if subjectTypeIndex == -1 {
+			subjectTypeIndex = len(subject.ByType)
+			subject.ByType = append(subject.ByType, typeStats{Type: item.Type})
+		}
+		subject.ByType[subjectTypeIndex].Counts.add(item, now)
+
+		typeIndex, ok := typeIndexes[item.Type]
+		if !ok {
+			typeIndex = len(res.ByType)
+			typeIndexes[item.Type] = typeIndex
+			res.ByType = append(res.ByType, typeStats{Type: item.Type})
+		}
+		res.ByType[typeIndex].Counts.add(item, now)
+
+		start := item.Start.In(uek.Location())
+		date := start.Format(time.DateOnly)
+		hours := teachingHours(item)
+
+		weekStart := startOfWeek(start)
+		if len(res.WeeklyLoad) == 0 {
+			lastWeekStart = weekStart
+			res.WeeklyLoad = append(res.WeeklyLoad, weekLoad{WeekStart: weekStart.Format(time.DateOnly)})
+		}
+		for lastWeekStart.Before(weekStart) {
+			lastWeekStart = lastWeekStart.AddDate(0, 0, 7)
+			res.WeeklyLoad = append(res.WeeklyLoad, weekLoad{WeekStart: lastWeekStart.Format(time.DateOnly)})
+		}
+		week := &res.WeeklyLoad[len(res.WeeklyLoad)-1]
+		week.Count++
+		week.Hours += hours
+
+		newDay := currentDay == nil || currentDay.Date != date
+		weekdayIndex, ok := weekdayIndexes[start.Weekday()]
+		if !ok {
+			weekdayIndex = len(res.BusiestDays)
+			weekdayIndexes[start.Weekday()] = weekdayIndex
+			res.BusiestDays = append(res.BusiestDays, weekdayLoad{Weekday: isoWeekday(start.Weekday())})
+		}
+		weekday := &res.BusiestDays[weekdayIndex]
+		weekday.Count++
+		weekday.Hours += hours
+		if newDay {
+			weekday.Days++
+			if currentDay != nil && isLongerDay(currentDay, res.LongestDay) {
+				res.LongestDay = currentDay
+			}
+			currentDay = &dayLoad{
+				Date:  date,
+				Start: item.Start,
+			}
+		}
+		if item.End.After(currentDay.End) {
+			currentDay.End = item.End
+		}
+		currentDay.Count++
+		currentDay.Hours += hours
+	}
+	if currentDay != nil && isLongerDay(currentDay, res.LongestDay) {
+		res.LongestDay = currentDay
+	}
+
+	if res.Total.Hours > 0 {
+		res.OnlineShare = roundToHundredths(res.Total.OnlineHours / res.Total.Hours)
+	}
+
+	res.Total.round()
+	for i := range res.BySubject {
+		res.BySubject[i].Counts.round()
+		for j := range res.BySubject[i].ByType {
+			res.BySubject[i].ByType[j].Counts.round()
+		}
+		slices.SortStableFunc(res.BySubject[i].ByType, compareTypeStats)
+	}
+	for i := range res.ByType {
+		res.ByType[i].Counts.round()
+	}
+	for i := range res.WeeklyLoad {
+		res.WeeklyLoad[i].Hours = roundToHundredths(res.WeeklyLoad[i].Hours)
+	}
+	for i := range res.BusiestDays {
+		res.BusiestDays[i].Hours = roundToHundredths(res.BusiestDays[i].Hours)
+	}
+	if res.LongestDay != nil {
+		res.LongestDay.Hours = roundToHundredths(res.LongestDay.Hours)
+	}
+
+	slices.SortStableFunc(res.BySubject, func(a subjectStats, b subjectStats) int {
+		return cmp.Or(cmp.Compare(b.Counts.Hours, a.Counts.Hours), cmp.Compare(a.Subject, b.Subject))
+	})
+	slices.SortStableFunc(res.ByType, compareTypeStats)
+	slices.SortStableFunc(res.BusiestDays, func(a weekdayLoad, b weekdayLoad) int {
+		return cmp.Or(cmp.Compare(b.Hours, a.Hours), cmp.Compare(a.Weekday, b.Weekday))
+	})
+
+	if nextChange.IsZero() {
+		nextChange = now.Add(24 * time.Hour)
+	}
+
+	return res, nextChange
+}
+
+func (c *statsCounts) add(item *uek.ScheduleItem, now time.Time) {
+	hours := teachingHours(item)
+
+	c.Count++
+	c.Hours += hours
+	if item.End.After(now) {
+		c.RemainingCount++
+		c.RemainingHours += hours
+	} else {
+		c.CompletedCount++
+		c.CompletedHours += hours
+	}
+	if item.Room != nil && item.Room.URL != "" {
+		c.OnlineCount++
+		c.OnlineHours += hours
+	}
+}
+
+func (c *statsCounts) round() {
+	c.Hours = roundToHundredths(c.Hours)
+	c.CompletedHours = roundToHundredths(c.CompletedHours)
+	c.RemainingHours = roundToHundredths(c.RemainingHours)
+	c.OnlineHours = roundToHundredths(c.OnlineHours)
+}
+
+func compareTypeStats(a typeStats, b typeStats) int {
+	return cmp.Or(cmp.Compare(b.Counts.Hours, a.Counts.Hours), cmp.Compare(a.Type, b.Type))
+}
+
+func teachingHours(item *uek.ScheduleItem) float64 {
+	return float64(item.End.Sub(item.Start)) / float64(teachingHour)
+}
+
+func roundToHundredths(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
+
+func isLongerDay(day *dayLoad, longest *dayLoad) bool {
+	return longest == nil || day.End.Sub(day.Start) > longest.End.Sub(longest.Start)
+}
+
+// midnight of the monday, in the time zone of t
+func startOfWeek(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()-(isoWeekday(t.Weekday())-1), 0, 0, 0, 0, t.Location())
+}
+
+func isoWeekday(weekday time.Weekday) int {
+	if weekday == time.Sunday {
+		return 7
+	}
+
+	return int(weekday)
+}
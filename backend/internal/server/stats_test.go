@@ -0,0 +1,67 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestComputeStats(t *testing.T) {
+	at := func(date string, clock string) time.Time {
+		parsed, _ := time.ParseInLocation(time.DateOnly+" 15:04", date+" "+clock, uek.Location())
+		return parsed
+	}
+	item := func(date string, start string, end string, subject string, typ string, online bool) *uek.ScheduleItem {
+		room := &uek.ScheduleItemRoom{Name: "Paw.A 011"}
+		if online {
+			room = &uek.ScheduleItemRoom{Name: "Platforma Moodle", URL: "https://e-uczelnia.uek.krakow.pl"}
+		}
+		return &uek.ScheduleItem{Start: at(date, start), End: at(date, end), Subject: subject, Type: typ, Room: room}
+	}
+
+	// spans the dst change on 2026-10-25 and an empty week
+	items := []*uek.ScheduleItem{
+		item("2026-10-19", "08:00", "09:30", "Ekonomia", "wykład", false),
+		item("2026-10-19", "15:00", "18:00", "Ekonomia", "ćwiczenia", false),
+		item("2026-10-21", "09:45", "11:15", "Statystyka", "wykład", true),
+		item("2026-10-23", "08:00", "09:30", "Ekonomia", "przeniesienie zajęć", false),
+		item("2026-10-23", "11:30", "13:00", "Rada wydziału", "rezerwacja", false),
+		item("2026-11-04", "09:45", "11:15", "Ekonomia", "wykład", false),
+	}
+	res, nextChange := computeStats(items, at("2026-10-21", "10:00"))
+
+	if res.Total.Count != 4 || res.Total.Hours != 10 || res.Total.CompletedCount != 2 || res.Total.CompletedHours != 6 || res.Total.RemainingHours != 4 || res.Total.OnlineHours != 2 {
+		t.Errorf("unexpected total: %+v", res.Total)
+	}
+	if res.OnlineShare != 0.2 {
+		t.Errorf("expected online share 0.2, got %v", res.OnlineShare)
+	}
+	if !nextChange.Equal(at("2026-10-21", "11:15")) {
+		t.Errorf("expected next change when the class in progress ends, got %v", nextChange)
+	}
+
+	if len(res.BySubject) != 2 || res.BySubject[0].Subject != "Ekonomia" || res.BySubject[0].Counts.Hours != 8 {
+		t.Fatalf("unexpected subjects: %+v", res.BySubject)
+	}
+	if byType := res.BySubject[0].ByType; len(byType) != 2 || byType[0].Type != "wykład" || byType[0].Counts.Count != 2 || byType[1].Type != "ćwiczenia" {
+		t.Errorf("unexpected types of the first subject: %+v", byType)
+	}
+
+	expectedWeeks := []weekLoad{{"2026-10-19", 3, 8}, {"2026-10-26", 0, 0}, {"2026-11-02", 1, 2}}
+	if len(res.WeeklyLoad) != len(expectedWeeks) {
+		t.Fatalf("expected %d weeks, got %+v", len(expectedWeeks), res.WeeklyLoad)
+	}
+	for i, week := range res.WeeklyLoad {
+		if week != expectedWeeks[i] {
+			t.Errorf("week %d: expected %+v, got %+v", i, expectedWeeks[i], week)
+		}
+	}
+
+	if len(res.BusiestDays) != 2 || res.BusiestDays[0] != (weekdayLoad{Weekday: 1, Count: 2, Hours: 6, Days: 1}) || res.BusiestDays[1] != (weekdayLoad{Weekday: 3, Count: 2, Hours: 4, Days: 2}) {
+		t.Errorf("unexpected busiest days: %+v", res.BusiestDays)
+	}
+	if res.LongestDay == nil || res.LongestDay.Date != "2026-10-19" || res.LongestDay.Count != 2 || res.LongestDay.Hours != 6 {
+		t.Errorf("unexpected longest day: %+v", res.LongestDay)
+	}
+}
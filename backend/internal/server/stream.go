@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	streamHeartbeatInterval  = 15 * time.Second
+	streamMinRefreshInterval = 30 * time.Second
+	// each write pushes the deadline forward, so streams outlive the server write timeout
+	streamWriteTimeout = 30 * time.Second
+	streamRetry        = 5 * time.Second
+)
+
+type streamKey struct {
+	scheduleType uek.ScheduleType
+	scheduleId   int
+	periodId     int
+}
+
+// fans out fresh schedule fetches to open streams
+type streamHub struct {
+	mu          sync.Mutex
+	subscribers map[streamKey]map[chan<- uek.ScheduleFetch]struct{}
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{
+		subscribers: map[streamKey]map[chan<- uek.ScheduleFetch]struct{}{},
+	}
+}
+
+func (hub *streamHub) subscribe(keys []streamKey, ch chan<- uek.ScheduleFetch) (unsubscribe func()) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for _, key := range keys {
+		if hub.subscribers[key] == nil {
+			hub.subscribers[key] = map[chan<- uek.ScheduleFetch]struct{}{}
+		}
+		hub.subscribers[key][ch] = struct{}{}
+	}
+
+	return func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+
+		for _, key := range keys {
+			delete(hub.subscribers[key], ch)
+			if len(hub.subscribers[key]) == 0 {
+				delete(hub.subscribers, key)
+			}
+		}
+	}
+}
+
+func (hub *streamHub) publish(fetch uek.ScheduleFetch) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for ch := range hub.subscribers[streamKey{fetch.ScheduleType, fetch.ScheduleId, fetch.PeriodId}] {
+		// slow streams catch up on their next refresh
+		select {
+		case ch <- fetch:
+		default:
+		}
+	}
+}
+
+type streamScheduleEvent struct {
+	PeriodId int                  `json:"periodId"`
+	Version  string               `json:"version"`
+	Headers  []uek.ScheduleHeader `json:"headers"`
+}
+
+type streamState struct {
+	scheduleType            uek.ScheduleType
+	scheduleIds             []int
+	periodId                int
+	schedules               []*uek.Schedule
+	expirationDates         []time.Time
+	scheduleVersion         string
+	now                     *nowResponse
+	nowVersion              string
+	nextNowChange           time.Time
+	lastSentScheduleVersion string
+	lastSentNowVersion      string
+}
+
+func (srv *Server) handleStream(w http.ResponseWriter, r *http.Request, params requestParams) {
+	state := &streamState{
+		scheduleType: uek.ScheduleType(params.string("type")),
+		scheduleIds:  params.ints("id"),
+	}
+
+	periods, _, err := srv.uek.GetSchedulePeriods(r.Context())
+	if err != nil {
+		srv.respondUpstreamError(w, r, "Failed to get periods", err)
+		return
+	}
+	var ok bool
+	if state.periodId, ok = pickCurrentYearPeriodId(periods); !ok {
+		respondError(w, r, http.StatusServiceUnavailable, errorCodeNoCurrentPeriod, "No period covers the current date", nil)
+		return
+	}
+
+	state.schedules = make([]*uek.Schedule, len(state.scheduleIds))
+	state.expirationDates = make([]time.Time, len(state.scheduleIds))
+	for i := range state.scheduleIds {
+		if err := state.refreshSchedule(r.Context(), srv.uek, i); err != nil {
+			srv.respondUpstreamError(w, r, "Failed to get schedule", err, slog.Group("params", slog.String("scheduleType", string(state.scheduleType)), slog.Any("scheduleIds", state.scheduleIds), slog.Int("periodId", state.periodId)))
+			return
+		}
+	}
+	state.update(time.Now())
+
+	fetches := make(chan uek.ScheduleFetch, len(state.scheduleIds))
+	keys := make([]streamKey, 0, len(state.scheduleIds))
+	for _, scheduleId := range state.scheduleIds {
+		keys = append(keys, streamKey{state.scheduleType, scheduleId, state.periodId})
+	}
+	defer srv.streamHub.subscribe(keys, fetches)()
+
+	// events the client has already seen are not sent again after a reconnect
+	state.lastSentScheduleVersion, state.lastSentNowVersion, _ = strings.Cut(params.string("Last-Event-ID"), ".")
+
+	rc := http.NewResponseController(w)
+	flush := func() bool {
+		if err := rc.Flush(); err != nil {
+			srv.logger.DebugContext(r.Context(), "Stream closed", slog.Any("err", err))
+			return false
+		}
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return true
+	}
+
+	headers := w.Header()
+	headers.Set("Content-Type", "text/event-stream")
+	headers.Set("Cache-Control", "no-store")
+	// disables response buffering in nginx
+	headers.Set("X-Accel-Buffering", "no")
+	rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	state.writePendingEvents(w)
+	if !flush() {
+		return
+	}
+
+	heartbeatTicker := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeatTicker.Stop()
+	refreshTimer := time.NewTimer(state.nextRefresh(time.Now()))
+	defer refreshTimer.Stop()
+	nowTimer := time.NewTimer(time.Until(state.nextNowChange))
+	defer nowTimer.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-srv.shutdownCtx.Done():
+			return
+		case now := <-heartbeatTicker.C:
+			fmt.Fprintf(w, ": heartbeat %s\n\n", now.UTC().Format(time.RFC3339))
+		case fetch := <-fetches:
+			i := slices.Index(state.scheduleIds, fetch.ScheduleId)
+			state.schedules[i], state.expirationDates[i] = fetch.Schedule, fetch.ExpirationDate
+			state.update(time.Now())
+			refreshTimer.Reset(state.nextRefresh(time.Now()))
+		case <-refreshTimer.C:
+			now := time.Now()
+			for i, expirationDate := range state.expirationDates {
+				if expirationDate.After(now) {
+					continue
+				}
+				if err := state.refreshSchedule(r.Context(), srv.uek, i); err != nil {
+					srv.logger.DebugContext(r.Context(), "Failed to refresh streamed schedule", slog.Int("scheduleId", state.scheduleIds[i]), slog.Any("err", err))
+					state.expirationDates[i] = now.Add(streamMinRefreshInterval)
+				}
+			}
+			state.update(now)
+			refreshTimer.Reset(state.nextRefresh(now))
+		case <-nowTimer.C:
+			state.update(time.Now())
+		}
+
+		nowTimer.Reset(time.Until(state.nextNowChange))
+		state.writePendingEvents(w)
+		if !flush() {
+			return
+		}
+	}
+}
+
+func (state *streamState) refreshSchedule(ctx context.Context, client *uek.Client, i int) error {
+	schedule, expirationDate, err := client.GetSchedule(ctx, state.scheduleType, state.scheduleIds[i], state.periodId)
+	if err != nil {
+		return err
+	}
+
+	state.schedules[i], state.expirationDates[i] = schedule, expirationDate
+	return nil
+}
+
+func (state *streamState) nextRefresh(now time.Time) time.Duration {
+	return max(slices.MinFunc(state.expirationDates, time.Time.Compare).Sub(now), streamMinRefreshInterval)
+}
+
+func (state *streamState) update(now time.Time) {
+	headers := make([]uek.ScheduleHeader, 0, len(state.schedules))
+	items := []*uek.ScheduleItem{}
+	for _, schedule := range state.schedules {
+		headers = append(headers, schedule.Header)
+		items = append(items, schedule.Items...)
+	}
+	slices.SortStableFunc(items, (*uek.ScheduleItem).Compare)
+
+	state.scheduleVersion = hashJSON(struct {
+		Headers []uek.ScheduleHeader `json:"headers"`
+		Items   []*uek.ScheduleItem  `json:"items"`
+	}{headers, items})
+
+	state.now, state.nextNowChange = computeNow(items, now.In(uek.Location()).Truncate(time.Second))
+	// minutes change all the time, only the items themselves matter
+	state.nowVersion = hashJSON(append([]*uek.ScheduleItem{state.now.Current, state.now.Next}, state.now.OverlappingCurrent...))
+	state.nextNowChange = state.nextNowChange.Add(time.Second)
+}
+
+func (state *streamState) writePendingEvents(w io.Writer) {
+	eventId := state.scheduleVersion + "." + state.nowVersion
+
+	if state.scheduleVersion != state.lastSentScheduleVersion {
+		headers := make([]uek.ScheduleHeader, 0, len(state.schedules))
+		for _, schedule := range state.schedules {
+			headers = append(headers, schedule.Header)
+		}
+
+		writeStreamEvent(w, eventId, "schedule", streamScheduleEvent{
+			PeriodId: state.periodId,
+			Version:  state.scheduleVersion,
+			Headers:  headers,
+		})
+		state.lastSentScheduleVersion = state.scheduleVersion
+	}
+
+	if state.nowVersion != state.lastSentNowVersion {
+		writeStreamEvent(w, eventId, "now", state.now)
+		state.lastSentNowVersion = state.nowVersion
+	}
+}
+
+func writeStreamEvent(w io.Writer, id string, event string, data any) {
+	encodedData, _ := json.Marshal(data)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, encodedData)
+}
+
+func hashJSON(v any) string {
+	hash := fnv.New64a()
+	json.NewEncoder(hash).Encode(v)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestStreamResumesFromLastEventId(t *testing.T) {
+	srv := newTestServer(t)
+
+	groupings, _, err := srv.uek.GetGroupings(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get groupings: %v", err)
+	}
+	headers, _, err := srv.uek.GetHeaders(context.Background(), uek.ScheduleTypeGroup, groupings.Groups[0])
+	if err != nil {
+		t.Fatalf("failed to get headers: %v", err)
+	}
+	streamUrl := fmt.Sprintf("/api/stream?type=group&id=%d", headers[0].Id)
+
+	openStream := func(lastEventId string) string {
+		ctx, cancelCtx := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancelCtx()
+
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, streamUrl, nil)
+		if lastEventId != "" {
+			req.Header.Set("Last-Event-ID", lastEventId)
+		}
+
+		res := serveTestRequest(srv, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", res.Code, res.Body)
+		}
+		return res.Body.String()
+	}
+
+	body := openStream("")
+	for _, event := range []string{"schedule", "now"} {
+		if !regexp.MustCompile(`(?m)^event: ` + event + `$`).MatchString(body) {
+			t.Fatalf("expected %s event in the first response, got %q", event, body)
+		}
+	}
+
+	eventIds := regexp.MustCompile(`(?m)^id: (.+)$`).FindAllStringSubmatch(body, -1)
+	lastEventId := eventIds[len(eventIds)-1][1]
+
+	if body := openStream(lastEventId); regexp.MustCompile(`(?m)^event:`).MatchString(body) {
+		t.Fatalf("expected no events after resuming, got %q", body)
+	}
+
+	if body := openStream("stale.stale"); !regexp.MustCompile(`(?m)^event: schedule$`).MatchString(body) {
+		t.Fatalf("expected schedule event after resuming from a stale id, got %q", body)
+	}
+}
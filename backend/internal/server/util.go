@@ -18,18 +18,10 @@ func respondNotFound(w http.ResponseWriter) {
 	http.Error(w, "Not Found", http.StatusNotFound)
 }
 
-func respondBadRequest(w http.ResponseWriter) {
-	http.Error(w, "Bad Request", http.StatusBadRequest)
-}
-
 func respondInternalServerError(w http.ResponseWriter) {
 	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 }
 
-func respondServiceUnavailable(w http.ResponseWriter) {
-	http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
-}
-
 func setCacheHeader(w http.ResponseWriter, expirationDate time.Time) {
 	maxAge := int(math.Ceil(time.Until(expirationDate).Seconds()))
 	if maxAge > 0 {
@@ -0,0 +1,119 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+type Config struct {
+	// directory for the snapshot files
+	Path   string
+	Uek    *uek.Client
+	Logger *slog.Logger
+}
+
+// Store keeps the last seen version of schedules of the current year, so changes are noticed across restarts
+type Store struct {
+	cfg    Config
+	logger *slog.Logger
+	mu     sync.Mutex
+}
+
+type scheduleSnapshot struct {
+	Header    uek.ScheduleHeader  `json:"header"`
+	Items     []*uek.ScheduleItem `json:"items"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+func New(cfg Config) *Store {
+	s := &Store{
+		cfg:    cfg,
+		logger: cfg.Logger,
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
+	}
+
+	return s
+}
+
+func (s *Store) filePath(scheduleType uek.ScheduleType, scheduleId int, periodId int) string {
+	return filepath.Join(s.cfg.Path, fmt.Sprintf("%s-%d-%d.json", scheduleType, scheduleId, periodId))
+}
+
+// CurrentYearPeriodId is the only period snapshots are kept for
+func (s *Store) CurrentYearPeriodId(ctx context.Context) (int, bool) {
+	periods, _, err := s.cfg.Uek.GetSchedulePeriods(ctx)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			s.logger.Warn("Failed to get periods for schedule snapshots", slog.Any("err", err))
+		}
+		return 0, false
+	}
+
+	return uek.CurrentYearPeriodId(periods, time.Now())
+}
+
+// Update stores the fetched schedule and returns how it changed since the previous snapshot.
+// Returns nil when there was no snapshot to compare with, or the fetch isn't of the current year:
+// periods overlap, the same change would be reported once for the year and once for the semester
+func (s *Store) Update(ctx context.Context, fetch uek.ScheduleFetch) *uek.ItemsDiff {
+	if uek.IsArchivedPeriodId(fetch.PeriodId) {
+		return nil
+	}
+	if periodId, ok := s.CurrentYearPeriodId(ctx); !ok || periodId != fetch.PeriodId {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := s.filePath(fetch.ScheduleType, fetch.ScheduleId, fetch.PeriodId)
+	previous := scheduleSnapshot{}
+	if err := jsonfile.Read(filePath, &previous); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.logger.Error("Failed to read schedule snapshot", slog.String("path", filePath), slog.Any("err", err))
+		}
+
+		s.write(filePath, fetch.Schedule)
+		return nil
+	}
+
+	d := uek.DiffItems(previous.Items, fetch.Schedule.Items)
+	if !d.IsEmpty() {
+		s.write(filePath, fetch.Schedule)
+	}
+
+	return d
+}
+
+// Ensure stores the schedule unless there already is a snapshot of it.
+// Cached schedules are not reported by the client, but they still make a good baseline
+func (s *Store) Ensure(scheduleType uek.ScheduleType, scheduleId int, periodId int, schedule *uek.Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := s.filePath(scheduleType, scheduleId, periodId)
+	if err := jsonfile.Read(filePath, &scheduleSnapshot{}); errors.Is(err, fs.ErrNotExist) {
+		s.write(filePath, schedule)
+	}
+}
+
+func (s *Store) write(filePath string, schedule *uek.Schedule) {
+	if err := jsonfile.Write(filePath, scheduleSnapshot{
+		Header:    schedule.Header,
+		Items:     schedule.Items,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write schedule snapshot", slog.String("path", filePath), slog.Any("err", err))
+	}
+}
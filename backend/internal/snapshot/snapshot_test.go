@@ -0,0 +1,98 @@
+package snapshot
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	mockDirectoryPath := t.TempDir()
+	if _, err := uekmock.Generate(mockDirectoryPath, uekmock.GeneratorOptions{
+		Seed:      1,
+		Groups:    2,
+		Lecturers: 4,
+		Rooms:     2,
+	}); err != nil {
+		t.Fatalf("failed to generate mock responses: %v", err)
+	}
+
+	mockRoundTripper, err := uekmock.NewRoundTripper(config.Mock{
+		Enabled:       true,
+		DirectoryPath: mockDirectoryPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to create mock round tripper: %v", err)
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+	return New(Config{
+		Path: t.TempDir(),
+		Uek: uek.NewClient(uek.ClientConfig{
+			HttpClient: &http.Client{
+				Transport: mockRoundTripper,
+			},
+			Logger: logger,
+		}),
+		Logger: logger,
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	s := newTestStore(t)
+
+	periods, _, err := s.cfg.Uek.GetSchedulePeriods(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	yearPeriodId, ok := s.CurrentYearPeriodId(t.Context())
+	if !ok {
+		t.Fatal("no current year period")
+	}
+	now := time.Now()
+	semesterPeriodIndex := slices.IndexFunc(periods, func(period uek.SchedulePeriod) bool {
+		return period.Id != yearPeriodId && !period.Start.After(now) && !period.End.Before(now)
+	})
+	if semesterPeriodIndex == -1 {
+		t.Fatal("no current semester period")
+	}
+
+	item := &uek.ScheduleItem{Start: now, End: now.Add(90 * time.Minute), Subject: "Ekonomia", Type: "wykład"}
+	schedule := &uek.Schedule{Header: uek.ScheduleHeader{Id: 1, Name: "KrDZEa1011"}, Items: []*uek.ScheduleItem{item}}
+	changedSchedule := &uek.Schedule{Header: schedule.Header, Items: []*uek.ScheduleItem{}}
+	fetch := func(periodId int, schedule *uek.Schedule) uek.ScheduleFetch {
+		return uek.ScheduleFetch{ScheduleType: uek.ScheduleTypeGroup, ScheduleId: 1, PeriodId: periodId, Schedule: schedule}
+	}
+
+	if d := s.Update(t.Context(), fetch(yearPeriodId, schedule)); d != nil {
+		t.Errorf("expected no diff without a previous snapshot, got %+v", d)
+	}
+	if d := s.Update(t.Context(), fetch(yearPeriodId, schedule)); d == nil || !d.IsEmpty() {
+		t.Errorf("expected an empty diff for the same schedule, got %+v", d)
+	}
+
+	// other periods overlap the year, they are ignored
+	for _, periodId := range []int{periods[semesterPeriodIndex].Id, -1} {
+		s.Update(t.Context(), fetch(periodId, schedule))
+		if d := s.Update(t.Context(), fetch(periodId, changedSchedule)); d != nil {
+			t.Errorf("expected period %d to be ignored, got %+v", periodId, d)
+		}
+	}
+
+	// a baseline doesn't replace an existing snapshot
+	s.Ensure(uek.ScheduleTypeGroup, 1, yearPeriodId, changedSchedule)
+	if d := s.Update(t.Context(), fetch(yearPeriodId, changedSchedule)); d == nil || len(d.Removed) != 1 {
+		t.Errorf("expected the item to be removed, got %+v", d)
+	}
+	if d := s.Update(t.Context(), fetch(yearPeriodId, changedSchedule)); d == nil || !d.IsEmpty() {
+		t.Errorf("expected the changed schedule to be the new snapshot, got %+v", d)
+	}
+}
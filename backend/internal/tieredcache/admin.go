@@ -0,0 +1,87 @@
+package tieredcache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+var matchAllKeys = regexp.MustCompile(".*")
+
+// lists the cold layer, as it is a superset of the hot one
+func (c *Cache) ListEntries(ctx context.Context, keyPrefix string) ([]uek.CacheEntryInfo, error) {
+	if coldAdmin, ok := c.cold.(uek.CacheAdmin); ok {
+		return coldAdmin.ListEntries(ctx, keyPrefix)
+	}
+	if hotAdmin, ok := c.hot.(uek.CacheAdmin); ok {
+		return hotAdmin.ListEntries(ctx, keyPrefix)
+	}
+
+	return []uek.CacheEntryInfo{}, nil
+}
+
+func (c *Cache) GetEntry(ctx context.Context, key string) (any, time.Time, bool) {
+	for _, layer := range []uek.Cache{c.hot, c.cold} {
+		if layerAdmin, ok := layer.(uek.CacheAdmin); ok {
+			if value, expirationDate, ok := layerAdmin.GetEntry(ctx, key); ok {
+				return value, expirationDate, true
+			}
+		}
+	}
+
+	return nil, time.Time{}, false
+}
+
+// the hot layer must be cleared too, otherwise invalidated entries would still be served from it
+func (c *Cache) DeleteEntries(ctx context.Context, keyPattern *regexp.Regexp) (int, error) {
+	deletedCount := 0
+	for _, layer := range []uek.Cache{c.hot, c.cold} {
+		if layerAdmin, ok := layer.(uek.CacheAdmin); ok {
+			layerDeletedCount, err := layerAdmin.DeleteEntries(ctx, keyPattern)
+			if err != nil {
+				return 0, err
+			}
+			deletedCount = max(deletedCount, layerDeletedCount)
+		}
+	}
+
+	return deletedCount, nil
+}
+
+func (c *Cache) Stats(ctx context.Context) any {
+	stats := map[string]any{}
+	if hotAdmin, ok := c.hot.(uek.CacheAdmin); ok {
+		stats["hot"] = hotAdmin.Stats(ctx)
+	}
+	if coldAdmin, ok := c.cold.(uek.CacheAdmin); ok {
+		stats["cold"] = coldAdmin.Stats(ctx)
+	}
+
+	return stats
+}
+
+func (c *Cache) Export(ctx context.Context, w io.Writer) error {
+	if coldSnapshotter, ok := c.cold.(uek.CacheSnapshotter); ok {
+		return coldSnapshotter.Export(ctx, w)
+	}
+
+	return errors.New("cold cache does not support exporting")
+}
+
+// the hot layer is cleared, so that imported entries reach it on first read
+func (c *Cache) Import(ctx context.Context, r io.Reader) (int, error) {
+	if coldSnapshotter, ok := c.cold.(uek.CacheSnapshotter); ok {
+		importedCount, err := coldSnapshotter.Import(ctx, r)
+		if hotAdmin, ok := c.hot.(uek.CacheAdmin); ok {
+			hotAdmin.DeleteEntries(ctx, matchAllKeys)
+		}
+
+		return importedCount, err
+	}
+
+	return 0, errors.New("cold cache does not support importing")
+}
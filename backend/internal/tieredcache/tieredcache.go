@@ -0,0 +1,124 @@
+package tieredcache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// HotCache is a fast in-process layer, e.g. memcache
+type HotCache interface {
+	uek.Cache
+	PutEntry(key string, value any, expirationDate time.Time)
+}
+
+// Walker is implemented by persistent caches that can list their contents, e.g. badgercache
+type Walker interface {
+	Walk(ctx context.Context, fn func(key string, value any, expirationDate time.Time) error) error
+}
+
+// Cache reads through the hot layer to the cold one and writes through to both, values read from the cold layer
+// are copied into the hot layer with their original expiration date
+type Cache struct {
+	hot    HotCache
+	cold   uek.Cache
+	logger *slog.Logger
+}
+
+func New(hot HotCache, cold uek.Cache, logger *slog.Logger) *Cache {
+	return &Cache{
+		hot:    hot,
+		cold:   cold,
+		logger: logger,
+	}
+}
+
+// WarmUp fills the hot layer with contents of the cold one, if it supports listing them
+func (c *Cache) WarmUp(ctx context.Context) {
+	walker, ok := c.cold.(Walker)
+	if !ok {
+		return
+	}
+
+	startTime := time.Now()
+	entryCount := 0
+	if err := walker.Walk(ctx, func(key string, value any, expirationDate time.Time) error {
+		c.hot.PutEntry(key, value, expirationDate)
+		entryCount++
+		return nil
+	}); err != nil {
+		c.logger.Error("Failed to warm up hot cache", slog.Any("err", err))
+		return
+	}
+
+	c.logger.Info("Hot cache warmed up", slog.Int("entries", entryCount), slog.String("timeTaken", time.Since(startTime).String()))
+}
+
+func (c *Cache) GetGroupings(ctx context.Context) (*uek.Groupings, time.Time, bool) {
+	if groupings, expirationDate, ok := c.hot.GetGroupings(ctx); ok {
+		return groupings, expirationDate, true
+	}
+
+	groupings, expirationDate, ok := c.cold.GetGroupings(ctx)
+	if ok {
+		c.hot.PutEntry(uek.GroupingsCacheKey, groupings, expirationDate)
+	}
+
+	return groupings, expirationDate, ok
+}
+
+func (c *Cache) GetHeaders(ctx context.Context, scheduleType uek.ScheduleType, groupingName string) ([]uek.ScheduleHeader, time.Time, bool) {
+	if headers, expirationDate, ok := c.hot.GetHeaders(ctx, scheduleType, groupingName); ok {
+		return headers, expirationDate, true
+	}
+
+	headers, expirationDate, ok := c.cold.GetHeaders(ctx, scheduleType, groupingName)
+	if ok {
+		c.hot.PutEntry(uek.MakeHeadersCacheKey(scheduleType, groupingName), headers, expirationDate)
+	}
+
+	return headers, expirationDate, ok
+}
+
+func (c *Cache) GetSchedule(ctx context.Context, scheduleType uek.ScheduleType, scheduleId int, periodId int) (*uek.Schedule, time.Time, bool) {
+	if schedule, expirationDate, ok := c.hot.GetSchedule(ctx, scheduleType, scheduleId, periodId); ok {
+		return schedule, expirationDate, true
+	}
+
+	schedule, expirationDate, ok := c.cold.GetSchedule(ctx, scheduleType, scheduleId, periodId)
+	if ok {
+		c.hot.PutEntry(uek.MakeScheduleCacheKey(scheduleType, scheduleId, periodId), schedule, expirationDate)
+	}
+
+	return schedule, expirationDate, ok
+}
+
+func (c *Cache) GetPeriods(ctx context.Context) ([]uek.SchedulePeriod, time.Time, bool) {
+	if periods, expirationDate, ok := c.hot.GetPeriods(ctx); ok {
+		return periods, expirationDate, true
+	}
+
+	periods, expirationDate, ok := c.cold.GetPeriods(ctx)
+	if ok {
+		c.hot.PutEntry(uek.PeriodsCacheKey, periods, expirationDate)
+	}
+
+	return periods, expirationDate, ok
+}
+
+func (c *Cache) PutGroupingsAndPeriods(cacheExpirationDateGroupings time.Time, groupings *uek.Groupings, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
+	c.hot.PutGroupingsAndPeriods(cacheExpirationDateGroupings, groupings, cacheExpirationDatePeriods, periods)
+	c.cold.PutGroupingsAndPeriods(cacheExpirationDateGroupings, groupings, cacheExpirationDatePeriods, periods)
+}
+
+func (c *Cache) PutHeaders(cacheExpirationDate time.Time, scheduleType uek.ScheduleType, groupingName string, headers []uek.ScheduleHeader) {
+	c.hot.PutHeaders(cacheExpirationDate, scheduleType, groupingName, headers)
+	c.cold.PutHeaders(cacheExpirationDate, scheduleType, groupingName, headers)
+}
+
+func (c *Cache) PutScheduleAndPeriods(cacheExpirationDateSchedule time.Time, scheduleType uek.ScheduleType, scheduleId int, periodId int, schedule *uek.Schedule, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
+	c.hot.PutScheduleAndPeriods(cacheExpirationDateSchedule, scheduleType, scheduleId, periodId, schedule, cacheExpirationDatePeriods, periods)
+	c.cold.PutScheduleAndPeriods(cacheExpirationDateSchedule, scheduleType, scheduleId, periodId, schedule, cacheExpirationDatePeriods, periods)
+}
@@ -0,0 +1,178 @@
+package tieredcache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+type fakeEntry struct {
+	value          any
+	expirationDate time.Time
+}
+
+// map backed cache layer, counting reads
+type fakeCache struct {
+	entries map[string]fakeEntry
+	gets    int
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{entries: map[string]fakeEntry{}}
+}
+
+func fakeGet[T any](c *fakeCache, key string) (T, time.Time, bool) {
+	c.gets++
+	e, ok := c.entries[key]
+	if !ok || !e.expirationDate.After(time.Now()) {
+		var zero T
+		return zero, time.Time{}, false
+	}
+
+	return e.value.(T), e.expirationDate, true
+}
+
+func (c *fakeCache) PutEntry(key string, value any, expirationDate time.Time) {
+	c.entries[key] = fakeEntry{value, expirationDate}
+}
+
+func (c *fakeCache) GetGroupings(_ context.Context) (*uek.Groupings, time.Time, bool) {
+	return fakeGet[*uek.Groupings](c, uek.GroupingsCacheKey)
+}
+
+func (c *fakeCache) GetHeaders(_ context.Context, scheduleType uek.ScheduleType, groupingName string) ([]uek.ScheduleHeader, time.Time, bool) {
+	return fakeGet[[]uek.ScheduleHeader](c, uek.MakeHeadersCacheKey(scheduleType, groupingName))
+}
+
+func (c *fakeCache) GetSchedule(_ context.Context, scheduleType uek.ScheduleType, scheduleId int, periodId int) (*uek.Schedule, time.Time, bool) {
+	return fakeGet[*uek.Schedule](c, uek.MakeScheduleCacheKey(scheduleType, scheduleId, periodId))
+}
+
+func (c *fakeCache) GetPeriods(_ context.Context) ([]uek.SchedulePeriod, time.Time, bool) {
+	return fakeGet[[]uek.SchedulePeriod](c, uek.PeriodsCacheKey)
+}
+
+func (c *fakeCache) PutGroupingsAndPeriods(cacheExpirationDateGroupings time.Time, groupings *uek.Groupings, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
+	c.PutEntry(uek.GroupingsCacheKey, groupings, cacheExpirationDateGroupings)
+	c.PutEntry(uek.PeriodsCacheKey, periods, cacheExpirationDatePeriods)
+}
+
+func (c *fakeCache) PutHeaders(cacheExpirationDate time.Time, scheduleType uek.ScheduleType, groupingName string, headers []uek.ScheduleHeader) {
+	c.PutEntry(uek.MakeHeadersCacheKey(scheduleType, groupingName), headers, cacheExpirationDate)
+}
+
+func (c *fakeCache) PutScheduleAndPeriods(cacheExpirationDateSchedule time.Time, scheduleType uek.ScheduleType, scheduleId int, periodId int, schedule *uek.Schedule, cacheExpirationDatePeriods time.Time, periods []uek.SchedulePeriod) {
+	c.PutEntry(uek.MakeScheduleCacheKey(scheduleType, scheduleId, periodId), schedule, cacheExpirationDateSchedule)
+	c.PutEntry(uek.PeriodsCacheKey, periods, cacheExpirationDatePeriods)
+}
+
+type fakeWalkingCache struct {
+	*fakeCache
+}
+
+func (c fakeWalkingCache) Walk(_ context.Context, fn func(key string, value any, expirationDate time.Time) error) error {
+	for key, e := range c.entries {
+		if err := fn(key, e.value, e.expirationDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestCache(cold uek.Cache) (*Cache, *fakeCache) {
+	hot := newFakeCache()
+	return New(hot, cold, slog.New(slog.NewTextHandler(io.Discard, nil))), hot
+}
+
+func TestColdHitFillsHotLayer(t *testing.T) {
+	cold := newFakeCache()
+	c, hot := newTestCache(cold)
+	ctx := context.Background()
+	expirationDate := time.Now().Add(time.Hour)
+	schedule := &uek.Schedule{Header: uek.ScheduleHeader{Id: 1, Name: "KrDZEa1011"}}
+	cold.PutScheduleAndPeriods(expirationDate, uek.ScheduleTypeGroup, 1, 3, schedule, expirationDate.Add(time.Hour), []uek.SchedulePeriod{{Id: 3}})
+
+	got, gotExpirationDate, ok := c.GetSchedule(ctx, uek.ScheduleTypeGroup, 1, 3)
+	if !ok || got != schedule || !gotExpirationDate.Equal(expirationDate) {
+		t.Fatalf("unexpected cold read: %v %s %t", got, gotExpirationDate, ok)
+	}
+
+	hotEntry, ok := hot.entries[uek.MakeScheduleCacheKey(uek.ScheduleTypeGroup, 1, 3)]
+	if !ok || hotEntry.value != schedule || !hotEntry.expirationDate.Equal(expirationDate) {
+		t.Fatalf("hot layer wasn't filled with the cold entry and its expiration date: %+v", hotEntry)
+	}
+
+	coldGets := cold.gets
+	if got, _, ok := c.GetSchedule(ctx, uek.ScheduleTypeGroup, 1, 3); !ok || got != schedule {
+		t.Fatal("hot read failed")
+	}
+	if cold.gets != coldGets {
+		t.Error("hot hit still read the cold layer")
+	}
+
+	periods, periodsExpirationDate, ok := c.GetPeriods(ctx)
+	if !ok || len(periods) != 1 || !periodsExpirationDate.Equal(expirationDate.Add(time.Hour)) || !hot.entries[uek.PeriodsCacheKey].expirationDate.Equal(periodsExpirationDate) {
+		t.Errorf("unexpected periods read through: %v %s %t", periods, periodsExpirationDate, ok)
+	}
+}
+
+func TestMissInBothLayers(t *testing.T) {
+	cold := newFakeCache()
+	c, hot := newTestCache(cold)
+
+	if _, _, ok := c.GetHeaders(context.Background(), uek.ScheduleTypeGroup, "x"); ok {
+		t.Fatal("expected a miss")
+	}
+	if len(hot.entries) != 0 || hot.gets != 1 || cold.gets != 1 {
+		t.Errorf("expected one read of each layer and nothing stored, got %d hot and %d cold reads, %d hot entries", hot.gets, cold.gets, len(hot.entries))
+	}
+}
+
+func TestWritesGoToBothLayers(t *testing.T) {
+	cold := newFakeCache()
+	c, hot := newTestCache(cold)
+	expirationDate := time.Now().Add(time.Hour)
+
+	c.PutGroupingsAndPeriods(expirationDate, &uek.Groupings{}, expirationDate, []uek.SchedulePeriod{})
+	c.PutHeaders(expirationDate, uek.ScheduleTypeRoom, "Paw.A", []uek.ScheduleHeader{})
+	c.PutScheduleAndPeriods(expirationDate, uek.ScheduleTypeRoom, 2, 1, &uek.Schedule{}, expirationDate, []uek.SchedulePeriod{})
+
+	for _, key := range []string{uek.GroupingsCacheKey, uek.PeriodsCacheKey, uek.MakeHeadersCacheKey(uek.ScheduleTypeRoom, "Paw.A"), uek.MakeScheduleCacheKey(uek.ScheduleTypeRoom, 2, 1)} {
+		if _, ok := hot.entries[key]; !ok {
+			t.Errorf("%s is missing from the hot layer", key)
+		}
+		if _, ok := cold.entries[key]; !ok {
+			t.Errorf("%s is missing from the cold layer", key)
+		}
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	cold := newFakeCache()
+	expirationDate := time.Now().Add(time.Hour)
+	cold.PutHeaders(expirationDate, uek.ScheduleTypeGroup, "a", []uek.ScheduleHeader{{Id: 1, Name: "a"}})
+	cold.PutScheduleAndPeriods(expirationDate.Add(time.Minute), uek.ScheduleTypeGroup, 1, 3, &uek.Schedule{}, expirationDate, []uek.SchedulePeriod{})
+
+	c, hot := newTestCache(fakeWalkingCache{cold})
+	c.WarmUp(context.Background())
+
+	if len(hot.entries) != len(cold.entries) {
+		t.Fatalf("expected %d warmed up entries, got %d", len(cold.entries), len(hot.entries))
+	}
+	for key, coldEntry := range cold.entries {
+		if hotEntry := hot.entries[key]; !hotEntry.expirationDate.Equal(coldEntry.expirationDate) {
+			t.Errorf("%s: expected expiration date %s, got %s", key, coldEntry.expirationDate, hotEntry.expirationDate)
+		}
+	}
+
+	// cold layers that can't be listed are skipped
+	c, hot = newTestCache(cold)
+	c.WarmUp(context.Background())
+	if len(hot.entries) != 0 {
+		t.Errorf("expected nothing to be warmed up, got %d entries", len(hot.entries))
+	}
+}
@@ -11,6 +11,8 @@ import (
 type AggregateSchedule struct {
 	Headers []ScheduleHeader `json:"headers"`
 	Items   []*ScheduleItem  `json:"items"`
+	// exposed only on request
+	Warnings []ParseWarning `json:"-"`
 }
 
 func (c *Client) GetAggregateSchedule(ctx context.Context, scheduleType ScheduleType, scheduleIds []int, periodId int) (*AggregateSchedule, time.Time, error) {
@@ -66,9 +68,11 @@ func (item *ScheduleItem) ShallowCopy() *ScheduleItem {
 // sorted lists merge + deduping without additional sorting
 func mergeSchedules(singleSchedules []*Schedule) *AggregateSchedule {
 	headers := make([]ScheduleHeader, 0, len(singleSchedules))
+	var warnings []ParseWarning
 	totalItemCount := 0
 	for _, schedule := range singleSchedules {
 		headers = append(headers, schedule.Header)
+		warnings = append(warnings, schedule.Warnings...)
 		totalItemCount += len(schedule.Items)
 	}
 
@@ -112,8 +116,9 @@ func mergeSchedules(singleSchedules []*Schedule) *AggregateSchedule {
 	}
 
 	return &AggregateSchedule{
-		Headers: headers,
-		Items:   items,
+		Headers:  headers,
+		Items:    items,
+		Warnings: warnings,
 	}
 }
 
@@ -0,0 +1,74 @@
+package uek
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Archive keeps schedules of periods that UEK no longer serves. Archived periods have negative ids,
+// so they never collide with the positional ids used by UEK
+type Archive interface {
+	// called with every freshly fetched schedule
+	SaveSchedule(scheduleType ScheduleType, scheduleId int, period SchedulePeriod, schedule *Schedule)
+	// called with every freshly fetched period list, periods missing from it get archived
+	UpdatePeriods(periods []SchedulePeriod)
+
+	GetPeriods(ctx context.Context) ([]SchedulePeriod, error)
+	GetSchedule(ctx context.Context, scheduleType ScheduleType, scheduleId int, periodId int) (*Schedule, error)
+}
+
+var ErrScheduleNotArchived = errors.New("schedule not archived")
+
+// archived schedules never change, this only bounds http caching
+const archivedScheduleCacheTime = 7 * 24 * time.Hour
+
+func IsArchivedPeriodId(periodId int) bool {
+	return periodId < 0
+}
+
+func (c *Client) archiveSchedule(scheduleType ScheduleType, scheduleId int, periodId int, periods []SchedulePeriod, schedule *Schedule) {
+	if c.cfg.Archive == nil {
+		return
+	}
+
+	periodIndex := slices.IndexFunc(periods, func(period SchedulePeriod) bool {
+		return period.Id == periodId
+	})
+	if periodIndex == -1 {
+		return
+	}
+
+	go c.cfg.Archive.SaveSchedule(scheduleType, scheduleId, periods[periodIndex], schedule)
+	go c.cfg.Archive.UpdatePeriods(periods)
+}
+
+func (c *Client) withArchivedPeriods(ctx context.Context, periods []SchedulePeriod) []SchedulePeriod {
+	if c.cfg.Archive == nil {
+		return periods
+	}
+
+	archivedPeriods, err := c.cfg.Archive.GetPeriods(ctx)
+	if err != nil {
+		c.logger.Error("Failed to get archived periods", slog.Any("err", err))
+		return periods
+	}
+
+	// periods may be shared with the cache, so they must not be appended to in place
+	return slices.Concat(periods, archivedPeriods)
+}
+
+func (c *Client) getArchivedSchedule(ctx context.Context, scheduleType ScheduleType, scheduleId int, periodId int) (*Schedule, time.Time, error) {
+	if c.cfg.Archive == nil {
+		return nil, time.Time{}, ErrScheduleNotArchived
+	}
+
+	schedule, err := c.cfg.Archive.GetSchedule(ctx, scheduleType, scheduleId, periodId)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return schedule, time.Now().Add(archivedScheduleCacheTime), nil
+}
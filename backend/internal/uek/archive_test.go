@@ -0,0 +1,106 @@
+package uek
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type fakeArchive struct {
+	periods    []SchedulePeriod
+	periodsErr error
+	schedules  map[int]*Schedule
+}
+
+func (a *fakeArchive) SaveSchedule(ScheduleType, int, SchedulePeriod, *Schedule) {}
+
+func (a *fakeArchive) UpdatePeriods([]SchedulePeriod) {}
+
+func (a *fakeArchive) GetPeriods(context.Context) ([]SchedulePeriod, error) {
+	return a.periods, a.periodsErr
+}
+
+func (a *fakeArchive) GetSchedule(_ context.Context, _ ScheduleType, _ int, periodId int) (*Schedule, error) {
+	if schedule, ok := a.schedules[periodId]; ok {
+		return schedule, nil
+	}
+	return nil, ErrScheduleNotArchived
+}
+
+func TestWithArchivedPeriods(t *testing.T) {
+	current := make([]SchedulePeriod, 1, 4)
+	current[0] = SchedulePeriod{Id: 1, Start: time.Date(2025, 10, 1, 0, 0, 0, 0, uekLocation), End: time.Date(2026, 2, 19, 0, 0, 0, 0, uekLocation)}
+	archived := []SchedulePeriod{{Id: -1, Start: time.Date(2025, 2, 20, 0, 0, 0, 0, uekLocation), End: time.Date(2025, 9, 19, 0, 0, 0, 0, uekLocation), Archived: true}}
+
+	for _, tc := range []struct {
+		name     string
+		archive  Archive
+		expected []int
+	}{
+		{name: "no archive", archive: nil, expected: []int{1}},
+		{name: "archived periods are appended", archive: &fakeArchive{periods: archived}, expected: []int{1, -1}},
+		{name: "archive error keeps upstream periods", archive: &fakeArchive{periods: archived, periodsErr: errors.New("disk on fire")}, expected: []int{1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(ClientConfig{Archive: tc.archive})
+
+			periods := client.withArchivedPeriods(t.Context(), current)
+			ids := []int{}
+			for _, period := range periods {
+				ids = append(ids, period.Id)
+			}
+			if !slices.Equal(ids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ids)
+			}
+
+			// spare capacity of a cached slice must not be written to
+			if extended := current[:cap(current)]; extended[1].Id != 0 {
+				t.Errorf("expected the input periods to be left alone, got %+v", extended[1])
+			}
+		})
+	}
+}
+
+func TestGetArchivedSchedule(t *testing.T) {
+	archivedSchedule := &Schedule{Header: ScheduleHeader{Id: 1, Name: "KrDUIs1011"}}
+
+	for _, tc := range []struct {
+		name     string
+		archive  Archive
+		periodId int
+		wantErr  error
+	}{
+		{name: "no archive", archive: nil, periodId: -1, wantErr: ErrScheduleNotArchived},
+		{name: "archived", archive: &fakeArchive{schedules: map[int]*Schedule{-1: archivedSchedule}}, periodId: -1},
+		{name: "not archived", archive: &fakeArchive{schedules: map[int]*Schedule{-1: archivedSchedule}}, periodId: -2, wantErr: ErrScheduleNotArchived},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// negative period ids are never sent upstream, so the client has no http client
+			client := NewClient(ClientConfig{Archive: tc.archive})
+
+			schedule, cacheExpirationDate, err := client.GetSchedule(t.Context(), ScheduleTypeGroup, 1, tc.periodId)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if schedule != archivedSchedule {
+				t.Errorf("expected the archived schedule, got %+v", schedule)
+			}
+			if !cacheExpirationDate.After(time.Now().Add(archivedScheduleCacheTime - time.Minute)) {
+				t.Errorf("expected archived schedules to be cached for long, got %s", cacheExpirationDate)
+			}
+		})
+	}
+}
+
+func TestIsArchivedPeriodId(t *testing.T) {
+	for periodId, expected := range map[int]bool{-2: true, -1: true, 0: false, 1: false} {
+		if actual := IsArchivedPeriodId(periodId); actual != expected {
+			t.Errorf("%d: expected %t, got %t", periodId, expected, actual)
+		}
+	}
+}
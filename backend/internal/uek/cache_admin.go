@@ -0,0 +1,129 @@
+package uek
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheAdmin is optionally implemented by caches to allow inspecting and invalidating their contents
+type CacheAdmin interface {
+	ListEntries(ctx context.Context, keyPrefix string) ([]CacheEntryInfo, error)
+	// value has the same type as returned by the matching Get* method
+	GetEntry(ctx context.Context, key string) (value any, expirationDate time.Time, ok bool)
+	DeleteEntries(ctx context.Context, keyPattern *regexp.Regexp) (int, error)
+	// implementation specific, must be json serializable
+	Stats(ctx context.Context) any
+}
+
+// CacheSnapshotter is optionally implemented by caches to allow moving their contents between instances
+type CacheSnapshotter interface {
+	// writes json lines
+	Export(ctx context.Context, w io.Writer) error
+	Import(ctx context.Context, r io.Reader) (int, error)
+}
+
+var ErrInvalidCacheKey = errors.New("invalid cache key")
+
+type CacheEntryInfo struct {
+	Key            string    `json:"key"`
+	ExpirationDate time.Time `json:"expirationDate"`
+	// in bytes, as stored by the cache
+	Size int64 `json:"size"`
+}
+
+// CompileCacheKeyPattern turns a glob pattern, where * matches any sequence and ? any single character, into a regexp matching whole keys
+func CompileCacheKeyPattern(pattern string) (*regexp.Regexp, error) {
+	quotedPattern := regexp.QuoteMeta(pattern)
+	quotedPattern = strings.ReplaceAll(quotedPattern, `\*`, ".*")
+	quotedPattern = strings.ReplaceAll(quotedPattern, `\?`, ".")
+
+	return regexp.Compile("^" + quotedPattern + "$")
+}
+
+// RefreshCacheEntry fetches fresh data for a cache key, bypassing the cache, and waits until it is stored
+func (c *Client) RefreshCacheEntry(ctx context.Context, key string) (time.Time, error) {
+	switch {
+	case key == GroupingsCacheKey || key == PeriodsCacheKey:
+		groupings, groupingsExpirationDate, periods, periodsExpirationDate, err := c.getFreshGroupingsAndPeriods(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if c.cfg.Cache != nil {
+			c.cfg.Cache.PutGroupingsAndPeriods(groupingsExpirationDate, groupings, periodsExpirationDate, periods)
+		}
+
+		if key == PeriodsCacheKey {
+			return periodsExpirationDate, nil
+		}
+		return groupingsExpirationDate, nil
+	case strings.HasPrefix(key, HeadersCacheKeyPrefix):
+		// lecturer headers are requested without a grouping
+		rawScheduleType, groupingName, found := strings.Cut(strings.TrimPrefix(key, HeadersCacheKeyPrefix), "-")
+		scheduleType := ScheduleType(rawScheduleType)
+		if !scheduleType.IsValid() || !found {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidCacheKey, key)
+		}
+
+		headers, expirationDate, err := c.getFreshHeaders(ctx, scheduleType, groupingName)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if c.cfg.Cache != nil {
+			c.cfg.Cache.PutHeaders(expirationDate, scheduleType, groupingName, headers)
+		}
+
+		return expirationDate, nil
+	case strings.HasPrefix(key, ScheduleCacheKeyPrefix):
+		keyParts := strings.Split(strings.TrimPrefix(key, ScheduleCacheKeyPrefix), "-")
+		if len(keyParts) != 3 {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidCacheKey, key)
+		}
+		scheduleType := ScheduleType(keyParts[0])
+		scheduleId, scheduleIdErr := strconv.Atoi(keyParts[1])
+		periodId, periodIdErr := strconv.Atoi(keyParts[2])
+		if !scheduleType.IsValid() || scheduleIdErr != nil || periodIdErr != nil {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidCacheKey, key)
+		}
+
+		schedule, scheduleExpirationDate, periods, periodsExpirationDate, err := c.getFreshSchedule(ctx, scheduleType, scheduleId, periodId)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if c.cfg.Cache != nil {
+			c.cfg.Cache.PutScheduleAndPeriods(scheduleExpirationDate, scheduleType, scheduleId, periodId, schedule, periodsExpirationDate, periods)
+		}
+
+		return scheduleExpirationDate, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidCacheKey, key)
+}
+
+// DecodeCacheValueJSON decodes a json encoded value into the same type as returned by the Get* method matching the key
+func DecodeCacheValueJSON(key string, data []byte) (any, error) {
+	switch {
+	case key == GroupingsCacheKey:
+		return decodeJSON[*Groupings](data)
+	case key == PeriodsCacheKey:
+		return decodeJSON[[]SchedulePeriod](data)
+	case strings.HasPrefix(key, HeadersCacheKeyPrefix):
+		return decodeJSON[[]ScheduleHeader](data)
+	case strings.HasPrefix(key, ScheduleCacheKeyPrefix):
+		return decodeJSON[*Schedule](data)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInvalidCacheKey, key)
+}
+
+func decodeJSON[T any](data []byte) (any, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
@@ -0,0 +1,89 @@
+package uek
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCompileCacheKeyPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		key     string
+		matches bool
+	}{
+		{pattern: "schedule-*-*-3", key: "schedule-group-123-3", matches: true},
+		{pattern: "schedule-*-*-3", key: "schedule-group-123-33", matches: false},
+		{pattern: "headers-*", key: "headers-lecturer-", matches: true},
+		{pattern: "headers-room-Paw.?", key: "headers-room-Paw.A", matches: true},
+		{pattern: "headers-room-Paw.?", key: "headers-room-PawXA", matches: false},
+		{pattern: "headers-room-Paw.?", key: "headers-room-Paw.AB", matches: false},
+		// whole keys only
+		{pattern: "periods", key: "periods", matches: true},
+		{pattern: "periods", key: "old-periods", matches: false},
+		{pattern: "groupings", key: "groupings-2", matches: false},
+		// regexp syntax is literal
+		{pattern: "schedule-group-(1|2)-3", key: "schedule-group-1-3", matches: false},
+		{pattern: "schedule-group-(1|2)-3", key: "schedule-group-(1|2)-3", matches: true},
+	} {
+		keyPattern, err := CompileCacheKeyPattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("failed to compile %q: %v", tc.pattern, err)
+		}
+		if matches := keyPattern.MatchString(tc.key); matches != tc.matches {
+			t.Errorf("expected %q matching %q to be %t", tc.pattern, tc.key, tc.matches)
+		}
+	}
+}
+
+type recordingRoundTripper struct {
+	rawQueries []string
+}
+
+// every request fails, only the request urls matter
+func (rt *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.rawQueries = append(rt.rawQueries, req.URL.RawQuery)
+	return nil, errors.New("offline")
+}
+
+func TestRefreshCacheEntryKeys(t *testing.T) {
+	for _, tc := range []struct {
+		key string
+		// empty when the key is invalid
+		expectedRawQuery string
+	}{
+		{key: GroupingsCacheKey, expectedRawQuery: "okres=1&xml"},
+		{key: PeriodsCacheKey, expectedRawQuery: "okres=1&xml"},
+		{key: "headers-group-Ekonomia, I stopień", expectedRawQuery: "typ=G&grupa=Ekonomia%2C+I+stopie%C5%84&xml"},
+		{key: "headers-room-Paw.A", expectedRawQuery: "typ=S&grupa=Paw.A&xml"},
+		{key: "headers-lecturer-", expectedRawQuery: "typ=N&grupa=&xml"},
+		{key: "headers-lecturer"},
+		{key: "headers-teacher-"},
+		{key: "schedule-group-123-45", expectedRawQuery: "typ=G&id=123&okres=45&xml"},
+		{key: "schedule-group-123"},
+		{key: "schedule-group-abc-45"},
+		{key: "schedule-group-123-45-6"},
+		{key: "schedule-class-123-45"},
+		{key: "unknown"},
+	} {
+		t.Run(tc.key, func(t *testing.T) {
+			rt := &recordingRoundTripper{}
+			client := NewClient(ClientConfig{HttpClient: &http.Client{Transport: rt}})
+
+			_, err := client.RefreshCacheEntry(t.Context(), tc.key)
+			if tc.expectedRawQuery == "" {
+				if !errors.Is(err, ErrInvalidCacheKey) || len(rt.rawQueries) != 0 {
+					t.Errorf("expected an invalid key without requests, got %v and %v", err, rt.rawQueries)
+				}
+				return
+			}
+
+			if err == nil || errors.Is(err, ErrInvalidCacheKey) {
+				t.Errorf("expected the request error, got %v", err)
+			}
+			if len(rt.rawQueries) != 1 || rt.rawQueries[0] != tc.expectedRawQuery {
+				t.Errorf("expected a request with %q, got %v", tc.expectedRawQuery, rt.rawQueries)
+			}
+		})
+	}
+}
@@ -4,24 +4,40 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/szczursonn/uek-planzajec-v3/internal/config"
 )
 
-const baseUrl = "https://planzajec.uek.krakow.pl/index.php"
+const DefaultBaseUrl = "https://planzajec.uek.krakow.pl/index.php"
 const UserAgent = "uek-planzajec-v3/1.0 (+https://uek-planzajec-v3.fly.dev)"
 
 type ClientConfig struct {
 	HttpClient *http.Client
+	// defaults to DefaultBaseUrl
+	BaseUrl    string
 	Cache      Cache
 	CacheTimes config.CacheTimes
+	// overrides CacheTimes.Schedules when a rule matches
+	TTLPolicy *TTLPolicy
+	Archive   Archive
+	// fail whole schedule on malformed items instead of skipping them
+	StrictParsing bool
+	Logger        *slog.Logger
 }
 
 type Client struct {
 	cfg                    ClientConfig
+	logger                 *slog.Logger
 	selfRateLimitSemaphore chan struct{}
+	listenersMu            sync.RWMutex
+	scheduleFetchListeners map[int]func(ScheduleFetch)
+	nextListenerId         int
+	parseWarningsMu        sync.Mutex
+	parseWarningCounts     map[ParseWarningReason]int
 }
 
 type Cache interface {
@@ -37,12 +53,34 @@ type Cache interface {
 	GetPeriods(ctx context.Context) ([]SchedulePeriod, time.Time, bool)
 }
 
+// keys shared by cache implementations
+const GroupingsCacheKey = "groupings"
+const PeriodsCacheKey = "periods"
+const HeadersCacheKeyPrefix = "headers-"
+const ScheduleCacheKeyPrefix = "schedule-"
+
+func MakeHeadersCacheKey(scheduleType ScheduleType, groupingName string) string {
+	return fmt.Sprintf("%s%s-%s", HeadersCacheKeyPrefix, scheduleType, groupingName)
+}
+
+func MakeScheduleCacheKey(scheduleType ScheduleType, scheduleId int, periodId int) string {
+	return fmt.Sprintf("%s%s-%d-%d", ScheduleCacheKeyPrefix, scheduleType, scheduleId, periodId)
+}
+
 func NewClient(cfg ClientConfig) *Client {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	return &Client{
-		cfg: cfg,
+		cfg:    cfg,
+		logger: logger,
 		// when there are 2+ concurrent requests response times get extremely long - waterfalls are faster
 		// 2+1 requests - 300-400ms, 3 requests - 1000+ms
 		selfRateLimitSemaphore: make(chan struct{}, 2),
+		scheduleFetchListeners: map[int]func(ScheduleFetch){},
+		parseWarningCounts:     map[ParseWarningReason]int{},
 	}
 }
 
@@ -65,20 +103,32 @@ type responseBody struct {
 		Id    string               `xml:"id,attr"`
 		Nazwa string               `xml:"nazwa,attr"`
 	} `xml:"zasob"`
-	Zajecia []struct {
-		Termin     string `xml:"termin"`
-		OdGodz     string `xml:"od-godz"`
-		DoGodz     string `xml:"do-godz"`
-		Przedmiot  string `xml:"przedmiot"`
-		Typ        string `xml:"typ"`
-		Nauczyciel []struct {
-			Moodle string `xml:"moodle,attr"`
-			Nazwa  string `xml:",chardata"`
-		} `xml:"nauczyciel"`
-		Sala  string `xml:"sala"`
-		Grupa string `xml:"grupa"`
-		Uwagi string `xml:"uwagi"`
-	} `xml:"zajecia"`
+	Zajecia []responseBodyItem `xml:"zajecia"`
+}
+
+type responseBodyItem struct {
+	Termin     string                     `xml:"termin"`
+	OdGodz     string                     `xml:"od-godz"`
+	DoGodz     string                     `xml:"do-godz"`
+	Przedmiot  string                     `xml:"przedmiot"`
+	Typ        string                     `xml:"typ"`
+	Nauczyciel []responseBodyItemLecturer `xml:"nauczyciel"`
+	Sala       string                     `xml:"sala"`
+	Grupa      string                     `xml:"grupa"`
+	Uwagi      string                     `xml:"uwagi"`
+}
+
+type responseBodyItemLecturer struct {
+	Moodle string `xml:"moodle,attr"`
+	Nazwa  string `xml:",chardata"`
+}
+
+func (c *Client) baseUrl() string {
+	if c.cfg.BaseUrl != "" {
+		return c.cfg.BaseUrl
+	}
+
+	return DefaultBaseUrl
 }
 
 func (c *Client) fetchAndUnmarshalXML(ctx context.Context, targetUrl string) (*responseBody, error) {
@@ -102,17 +152,17 @@ func (c *Client) fetchAndUnmarshalXML(ctx context.Context, targetUrl string) (*r
 
 	res, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to do request: %w", err)
+		return nil, newRequestError(fmt.Errorf("failed to do request: %w", err))
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+		return nil, newStatusError(res)
 	}
 
 	resBody := &responseBody{}
 	if err = xml.NewDecoder(res.Body).Decode(resBody); err != nil {
-		return nil, fmt.Errorf("failed to decode xml response: %w", err)
+		return nil, newInvalidResponseError(fmt.Errorf("failed to decode xml response: %w", err))
 	}
 
 	return resBody, nil
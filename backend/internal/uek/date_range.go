@@ -0,0 +1,117 @@
+package uek
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// GetAggregateScheduleInRange merges schedules of non-overlapping periods covering [from, to) and keeps only items that overlap it.
+// Periods the schedules were taken from are returned as well
+func (c *Client) GetAggregateScheduleInRange(ctx context.Context, scheduleType ScheduleType, scheduleIds []int, from time.Time, to time.Time) (*AggregateSchedule, []SchedulePeriod, time.Time, error) {
+	periods, periodsCacheExpirationDate, err := c.GetSchedulePeriods(ctx)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	overlappingPeriods := coveringPeriods(periods, from, to)
+	if len(overlappingPeriods) == 0 {
+		return &AggregateSchedule{
+			Headers: []ScheduleHeader{},
+			Items:   []*ScheduleItem{},
+		}, overlappingPeriods, periodsCacheExpirationDate, nil
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	singleSchedules := make([]*Schedule, len(overlappingPeriods)*len(scheduleIds))
+	cacheExpirationDates := make([]time.Time, len(singleSchedules)+1)
+	cacheExpirationDates[len(singleSchedules)] = periodsCacheExpirationDate
+
+	for periodIndex, period := range overlappingPeriods {
+		for scheduleIndex, scheduleId := range scheduleIds {
+			i := periodIndex*len(scheduleIds) + scheduleIndex
+			eg.Go(func() error {
+				schedule, cacheExpirationDate, err := c.getSchedule(egCtx, scheduleType, scheduleId, period.Id)
+				if err != nil {
+					// the archive only has schedules someone asked for before the period was rotated out
+					if IsArchivedPeriodId(period.Id) && errors.Is(err, ErrScheduleNotArchived) {
+						cacheExpirationDates[i] = time.Now().Add(archivedScheduleCacheTime)
+						return nil
+					}
+					return err
+				}
+
+				singleSchedules[i], cacheExpirationDates[i] = trimSchedule(schedule, from, to), cacheExpirationDate
+				return nil
+			})
+		}
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, nil, time.Time{}, err
+	}
+
+	aggregateSchedule := mergeSchedules(slices.DeleteFunc(singleSchedules, func(schedule *Schedule) bool {
+		return schedule == nil
+	}))
+	// the same schedule is present once per period
+	headers := make([]ScheduleHeader, 0, len(scheduleIds))
+	for _, header := range aggregateSchedule.Headers {
+		if !slices.ContainsFunc(headers, func(other ScheduleHeader) bool { return other.Id == header.Id }) {
+			headers = append(headers, header)
+		}
+	}
+	aggregateSchedule.Headers = headers
+
+	return aggregateSchedule, overlappingPeriods, minTime(cacheExpirationDates), nil
+}
+
+// UEK lists the academic year next to its semesters, fetching all of them would download every item twice. Longest
+// periods are picked first, so the year wins over the semesters it contains
+func coveringPeriods(periods []SchedulePeriod, from time.Time, to time.Time) []SchedulePeriod {
+	overlappingPeriods := []SchedulePeriod{}
+	for _, period := range periods {
+		if period.Start.Before(to) && period.End.After(from) {
+			overlappingPeriods = append(overlappingPeriods, period)
+		}
+	}
+	slices.SortStableFunc(overlappingPeriods, func(a SchedulePeriod, b SchedulePeriod) int {
+		return cmp.Compare(b.End.Sub(b.Start), a.End.Sub(a.Start))
+	})
+
+	pickedPeriods := []SchedulePeriod{}
+	for _, period := range overlappingPeriods {
+		if !slices.ContainsFunc(pickedPeriods, func(picked SchedulePeriod) bool {
+			return period.Start.Before(picked.End) && period.End.After(picked.Start)
+		}) {
+			pickedPeriods = append(pickedPeriods, period)
+		}
+	}
+	slices.SortFunc(pickedPeriods, func(a SchedulePeriod, b SchedulePeriod) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return pickedPeriods
+}
+
+func trimSchedule(schedule *Schedule, from time.Time, to time.Time) *Schedule {
+	trimmedItems := []*ScheduleItem{}
+	for _, item := range schedule.Items {
+		if !item.Start.Before(to) {
+			break
+		}
+		if item.End.After(from) {
+			trimmedItems = append(trimmedItems, item)
+		}
+	}
+
+	return &Schedule{
+		Header:   schedule.Header,
+		Items:    trimmedItems,
+		Warnings: schedule.Warnings,
+	}
+}
@@ -0,0 +1,85 @@
+package uek
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCoveringPeriods(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, uekLocation)
+	}
+	winter := SchedulePeriod{Id: 1, Start: date(2025, 10, 1), End: date(2026, 2, 20)}
+	summer := SchedulePeriod{Id: 2, Start: date(2026, 2, 20), End: date(2026, 10, 1)}
+	year := SchedulePeriod{Id: 3, Start: date(2025, 10, 1), End: date(2026, 10, 1)}
+	archivedYear := SchedulePeriod{Id: -1, Start: date(2024, 10, 1), End: date(2025, 10, 1), Archived: true}
+	archivedSummer := SchedulePeriod{Id: -2, Start: date(2025, 2, 20), End: date(2025, 10, 1), Archived: true}
+	allPeriods := []SchedulePeriod{winter, summer, year, archivedSummer, archivedYear}
+
+	for _, tc := range []struct {
+		name     string
+		periods  []SchedulePeriod
+		from     time.Time
+		to       time.Time
+		expected []int
+	}{
+		{name: "year wins over the semester", periods: allPeriods, from: date(2025, 11, 3), to: date(2025, 11, 10), expected: []int{3}},
+		{name: "year wins over both semesters", periods: allPeriods, from: date(2026, 2, 16), to: date(2026, 2, 23), expected: []int{3}},
+		{name: "archived year wins over archived semester", periods: allPeriods, from: date(2025, 9, 22), to: date(2025, 9, 29), expected: []int{-1}},
+		{name: "across academic years", periods: allPeriods, from: date(2025, 9, 29), to: date(2025, 10, 6), expected: []int{-1, 3}},
+		{name: "semesters without a year", periods: []SchedulePeriod{summer, winter}, from: date(2026, 2, 16), to: date(2026, 2, 23), expected: []int{1, 2}},
+		{name: "outside every period", periods: allPeriods, from: date(2030, 1, 1), to: date(2030, 1, 8), expected: []int{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := []int{}
+			for _, period := range coveringPeriods(tc.periods, tc.from, tc.to) {
+				ids = append(ids, period.Id)
+			}
+			if !slices.Equal(ids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestTrimSchedule(t *testing.T) {
+	at := func(day int, hour int) time.Time {
+		return time.Date(2026, 10, day, hour, 0, 0, 0, uekLocation)
+	}
+	item := func(subject string, start time.Time, end time.Time) *ScheduleItem {
+		return &ScheduleItem{Start: start, End: end, Subject: subject}
+	}
+	schedule := &Schedule{
+		Header: ScheduleHeader{Id: 1, Name: "KrDZEa1011"},
+		Items: []*ScheduleItem{
+			item("before", at(18, 8), at(18, 10)),
+			item("ends at from", at(18, 22), at(19, 0)),
+			item("crosses from", at(18, 23), at(19, 1)),
+			item("inside", at(19, 8), at(19, 10)),
+			item("crosses to", at(20, 23), at(21, 1)),
+			item("starts at to", at(21, 0), at(21, 2)),
+			item("after", at(21, 8), at(21, 10)),
+		},
+		Warnings: []ParseWarning{{ScheduleId: 1, ItemIndex: -1, Reason: ParseWarningReasonInvalidDate, Action: ParseWarningActionDropped}},
+	}
+
+	trimmed := trimSchedule(schedule, at(19, 0), at(21, 0))
+	subjects := []string{}
+	for _, item := range trimmed.Items {
+		subjects = append(subjects, item.Subject)
+	}
+	if expected := []string{"crosses from", "inside", "crosses to"}; !slices.Equal(subjects, expected) {
+		t.Errorf("expected %v, got %v", expected, subjects)
+	}
+	if trimmed.Header != schedule.Header || !slices.Equal(trimmed.Warnings, schedule.Warnings) {
+		t.Errorf("expected the header and warnings to be kept, got %+v", trimmed)
+	}
+	if len(schedule.Items) != 7 {
+		t.Error("expected the original schedule to be left as is")
+	}
+
+	if trimmed := trimSchedule(schedule, at(22, 0), at(23, 0)); trimmed.Items == nil || len(trimmed.Items) != 0 {
+		t.Errorf("expected an empty list, got %v", trimmed.Items)
+	}
+}
@@ -19,18 +19,21 @@ func (c *Client) GetGroupings(ctx context.Context) (*Groupings, time.Time, error
 		}
 	}
 
-	freshGroupings, freshGroupingsExpirationDate, _, _, err := c.getFreshGroupingsAndPeriods(ctx)
+	freshGroupings, freshGroupingsExpirationDate, freshPeriods, freshPeriodsExpirationDate, err := c.getFreshGroupingsAndPeriods(ctx)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
+	if c.cfg.Cache != nil {
+		go c.cfg.Cache.PutGroupingsAndPeriods(freshGroupingsExpirationDate, freshGroupings, freshPeriodsExpirationDate, freshPeriods)
+	}
+
 	return freshGroupings, freshGroupingsExpirationDate, nil
 }
 
 // adding "okres" param always makes response include period info, even in non-schedule calls
 func (c *Client) getFreshGroupingsAndPeriods(ctx context.Context) (*Groupings, time.Time, []SchedulePeriod, time.Time, error) {
-	const groupingsUrl = baseUrl + "?okres=1&xml"
-	res, err := c.fetchAndUnmarshalXML(ctx, groupingsUrl)
+	res, err := c.fetchAndUnmarshalXML(ctx, c.baseUrl()+"?okres=1&xml")
 	if err != nil {
 		return nil, time.Time{}, nil, time.Time{}, err
 	}
@@ -40,11 +43,11 @@ func (c *Client) getFreshGroupingsAndPeriods(ctx context.Context) (*Groupings, t
 	groupings := res.extractGroupings()
 	periods, err := res.extractPeriods()
 	if err != nil {
-		return nil, time.Time{}, nil, time.Time{}, fmt.Errorf("failed to parse periods: %w", err)
+		return nil, time.Time{}, nil, time.Time{}, newInvalidResponseError(fmt.Errorf("failed to parse periods: %w", err))
 	}
 
-	if c.cfg.Cache != nil {
-		go c.cfg.Cache.PutGroupingsAndPeriods(groupingsExpirationDate, groupings, periodsExpirationDate, periods)
+	if c.cfg.Archive != nil {
+		go c.cfg.Archive.UpdatePeriods(periods)
 	}
 
 	return groupings, groupingsExpirationDate, periods, periodsExpirationDate, nil
@@ -7,8 +7,13 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
+// headers of a grouping are fetched one request per grouping
+const maxConcurrentHeaderRequests = 4
+
 type ScheduleHeader struct {
 	Id   int    `json:"id"`
 	Name string `json:"name"`
@@ -21,13 +26,10 @@ func (c *Client) GetHeaders(ctx context.Context, scheduleType ScheduleType, grou
 		}
 	}
 
-	res, err := c.fetchAndUnmarshalXML(ctx, fmt.Sprintf("%s?typ=%s&grupa=%s&xml", baseUrl, scheduleType.asOriginal(), url.QueryEscape(groupingName)))
+	headers, expirationDate, err := c.getFreshHeaders(ctx, scheduleType, groupingName)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
-	expirationDate := time.Now().Add(c.cfg.CacheTimes.Headers)
-
-	headers := res.extractHeaders(scheduleType)
 
 	if c.cfg.Cache != nil {
 		go c.cfg.Cache.PutHeaders(expirationDate, scheduleType, groupingName, headers)
@@ -36,6 +38,48 @@ func (c *Client) GetHeaders(ctx context.Context, scheduleType ScheduleType, grou
 	return headers, expirationDate, nil
 }
 
+// GetRoomHeadersByGrouping returns headers of rooms in every room grouping (building)
+func (c *Client) GetRoomHeadersByGrouping(ctx context.Context) (map[string][]ScheduleHeader, time.Time, error) {
+	groupings, cacheExpirationDate, err := c.GetGroupings(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(maxConcurrentHeaderRequests)
+	headersByGrouping := make([][]ScheduleHeader, len(groupings.Rooms))
+	cacheExpirationDates := make([]time.Time, len(groupings.Rooms)+1)
+	cacheExpirationDates[len(groupings.Rooms)] = cacheExpirationDate
+
+	for i, groupingName := range groupings.Rooms {
+		eg.Go(func() (err error) {
+			headersByGrouping[i], cacheExpirationDates[i], err = c.GetHeaders(egCtx, ScheduleTypeRoom, groupingName)
+			return
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	res := make(map[string][]ScheduleHeader, len(groupings.Rooms))
+	for i, groupingName := range groupings.Rooms {
+		res[groupingName] = headersByGrouping[i]
+	}
+
+	return res, minTime(cacheExpirationDates), nil
+}
+
+func (c *Client) getFreshHeaders(ctx context.Context, scheduleType ScheduleType, groupingName string) ([]ScheduleHeader, time.Time, error) {
+	res, err := c.fetchAndUnmarshalXML(ctx, fmt.Sprintf("%s?typ=%s&grupa=%s&xml", c.baseUrl(), scheduleType.asOriginal(), url.QueryEscape(groupingName)))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	expirationDate := time.Now().Add(c.cfg.CacheTimes.Headers)
+
+	return res.extractHeaders(scheduleType), expirationDate, nil
+}
+
 func (res *responseBody) extractHeaders(requestedScheduleType ScheduleType) []ScheduleHeader {
 	headers := make([]ScheduleHeader, 0, len(res.Zasob))
 
@@ -0,0 +1,76 @@
+package uek
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type ItemChange struct {
+	Before *ScheduleItem `json:"before"`
+	After  *ScheduleItem `json:"after"`
+}
+
+type ItemsDiff struct {
+	Added   []*ScheduleItem `json:"added,omitempty"`
+	Removed []*ScheduleItem `json:"removed,omitempty"`
+	Changed []ItemChange    `json:"changed,omitempty"`
+}
+
+func (d *ItemsDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// items are matched by time, subject and type, anything else differing makes it a change
+func itemIdentity(item *ScheduleItem) string {
+	return fmt.Sprintf("%s|%s|%s|%s", item.Start.UTC().Format(time.RFC3339), item.End.UTC().Format(time.RFC3339), item.Subject, item.Type)
+}
+
+// DiffItems compares two versions of the same schedule, item order doesn't matter
+func DiffItems(before []*ScheduleItem, after []*ScheduleItem) *ItemsDiff {
+	d := &ItemsDiff{}
+
+	beforeByIdentity := map[string][]*ScheduleItem{}
+	for _, item := range before {
+		identity := itemIdentity(item)
+		beforeByIdentity[identity] = append(beforeByIdentity[identity], item)
+	}
+
+	unmatchedAfter := []*ScheduleItem{}
+	for _, item := range after {
+		identity := itemIdentity(item)
+		candidates := beforeByIdentity[identity]
+		if len(candidates) == 0 {
+			unmatchedAfter = append(unmatchedAfter, item)
+			continue
+		}
+
+		// prefer an identical item, so reordering doesn't show up as changes
+		matchIndex := slices.IndexFunc(candidates, func(candidate *ScheduleItem) bool {
+			return itemsEqual(candidate, item)
+		})
+		if matchIndex == -1 {
+			matchIndex = 0
+			d.Changed = append(d.Changed, ItemChange{
+				Before: candidates[0],
+				After:  item,
+			})
+		}
+		beforeByIdentity[identity] = slices.Delete(candidates, matchIndex, matchIndex+1)
+	}
+	d.Added = unmatchedAfter
+
+	for _, item := range before {
+		identity := itemIdentity(item)
+		if index := slices.Index(beforeByIdentity[identity], item); index != -1 {
+			d.Removed = append(d.Removed, item)
+			beforeByIdentity[identity] = slices.Delete(beforeByIdentity[identity], index, index+1)
+		}
+	}
+
+	return d
+}
+
+func itemsEqual(a *ScheduleItem, b *ScheduleItem) bool {
+	return a.EqualIgnoringGroups(b) && slices.Equal(a.Groups, b.Groups)
+}
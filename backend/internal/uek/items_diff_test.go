@@ -0,0 +1,44 @@
+package uek
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffItems(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 45, 0, 0, time.UTC)
+	newItem := func(subject string, room string, offset time.Duration) *ScheduleItem {
+		return &ScheduleItem{
+			Start:   start.Add(offset),
+			End:     start.Add(offset + 90*time.Minute),
+			Subject: subject,
+			Type:    "wykład",
+			Room:    &ScheduleItemRoom{Name: room},
+		}
+	}
+
+	unchanged := newItem("Matematyka", "Paw.A 010", 0)
+	moved := newItem("Ekonomia", "Paw.A 010", 2*time.Hour)
+	removed := newItem("Prawo", "Paw.B 101", 4*time.Hour)
+	added := newItem("Statystyka", "Paw.C 201", 6*time.Hour)
+	movedAfter := newItem("Ekonomia", "Paw.D 301", 2*time.Hour)
+
+	d := DiffItems(
+		[]*ScheduleItem{unchanged, moved, removed},
+		[]*ScheduleItem{added, movedAfter, newItem("Matematyka", "Paw.A 010", 0)},
+	)
+
+	if len(d.Added) != 1 || d.Added[0] != added {
+		t.Errorf("expected %q to be added, got %v", added.Subject, d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0] != removed {
+		t.Errorf("expected %q to be removed, got %v", removed.Subject, d.Removed)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].Before != moved || d.Changed[0].After != movedAfter {
+		t.Errorf("expected %q to be changed, got %v", moved.Subject, d.Changed)
+	}
+
+	if d := DiffItems([]*ScheduleItem{unchanged, moved}, []*ScheduleItem{moved, unchanged}); !d.IsEmpty() {
+		t.Errorf("expected reordering to produce an empty diff, got %+v", d)
+	}
+}
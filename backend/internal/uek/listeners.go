@@ -0,0 +1,40 @@
+package uek
+
+import (
+	"time"
+)
+
+// ScheduleFetch describes a schedule freshly fetched from UEK, cache hits are not reported
+type ScheduleFetch struct {
+	ScheduleType   ScheduleType
+	ScheduleId     int
+	PeriodId       int
+	Schedule       *Schedule
+	ExpirationDate time.Time
+}
+
+// OnScheduleFetched registers a listener called in its own goroutine after every fresh schedule fetch.
+// Listeners must not modify the schedule
+func (c *Client) OnScheduleFetched(listener func(fetch ScheduleFetch)) (remove func()) {
+	c.listenersMu.Lock()
+	defer c.listenersMu.Unlock()
+
+	listenerId := c.nextListenerId
+	c.nextListenerId++
+	c.scheduleFetchListeners[listenerId] = listener
+
+	return func() {
+		c.listenersMu.Lock()
+		defer c.listenersMu.Unlock()
+		delete(c.scheduleFetchListeners, listenerId)
+	}
+}
+
+func (c *Client) notifyScheduleFetched(fetch ScheduleFetch) {
+	c.listenersMu.RLock()
+	defer c.listenersMu.RUnlock()
+
+	for _, listener := range c.scheduleFetchListeners {
+		go listener(fetch)
+	}
+}
@@ -0,0 +1,72 @@
+package uek
+
+import (
+	"log/slog"
+	"maps"
+)
+
+type ParseWarningReason string
+
+const (
+	ParseWarningReasonInvalidDate     ParseWarningReason = "invalidDate"
+	ParseWarningReasonStartAfterEnd   ParseWarningReason = "startAfterEnd"
+	ParseWarningReasonInvalidMoodleId ParseWarningReason = "invalidMoodleId"
+)
+
+type ParseWarningAction string
+
+const (
+	ParseWarningActionDropped  ParseWarningAction = "dropped"
+	ParseWarningActionRepaired ParseWarningAction = "repaired"
+)
+
+// describes a malformed part of a schedule response that was skipped or repaired in lenient parsing mode
+type ParseWarning struct {
+	ScheduleId int `json:"scheduleId"`
+	// -1 for problems with the schedule itself
+	ItemIndex int                `json:"itemIndex"`
+	Reason    ParseWarningReason `json:"reason"`
+	Action    ParseWarningAction `json:"action"`
+	Message   string             `json:"message"`
+}
+
+type parseError struct {
+	reason ParseWarningReason
+	err    error
+}
+
+func (pe *parseError) Error() string {
+	return pe.err.Error()
+}
+
+func (pe *parseError) Unwrap() error {
+	return pe.err
+}
+
+// ParseWarningCounts returns how many warnings of each reason were reported since the client was created
+func (c *Client) ParseWarningCounts() map[ParseWarningReason]int {
+	c.parseWarningsMu.Lock()
+	defer c.parseWarningsMu.Unlock()
+
+	return maps.Clone(c.parseWarningCounts)
+}
+
+func (c *Client) reportParseWarnings(scheduleType ScheduleType, periodId int, warnings []ParseWarning) {
+	c.parseWarningsMu.Lock()
+	for _, warning := range warnings {
+		c.parseWarningCounts[warning.Reason]++
+	}
+	c.parseWarningsMu.Unlock()
+
+	for _, warning := range warnings {
+		c.logger.Warn("Malformed schedule response part",
+			slog.String("scheduleType", string(scheduleType)),
+			slog.Int("scheduleId", warning.ScheduleId),
+			slog.Int("periodId", periodId),
+			slog.Int("itemIndex", warning.ItemIndex),
+			slog.String("reason", string(warning.Reason)),
+			slog.String("action", string(warning.Action)),
+			slog.String("message", warning.Message),
+		)
+	}
+}
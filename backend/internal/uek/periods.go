@@ -7,24 +7,53 @@ import (
 )
 
 type SchedulePeriod struct {
-	Id    int       `json:"id"`
-	Start time.Time `json:"start"`
-	End   time.Time `json:"end"`
+	Id       int       `json:"id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Archived bool      `json:"archived,omitempty"`
 }
 
 func (c *Client) GetSchedulePeriods(ctx context.Context) ([]SchedulePeriod, time.Time, error) {
 	if c.cfg.Cache != nil {
 		if periods, validUntil, ok := c.cfg.Cache.GetPeriods(ctx); ok {
-			return periods, validUntil, nil
+			return c.withArchivedPeriods(ctx, periods), validUntil, nil
 		}
 	}
 
-	_, _, freshPeriods, periodsExpirationDate, err := c.getFreshGroupingsAndPeriods(ctx)
+	freshGroupings, freshGroupingsExpirationDate, freshPeriods, periodsExpirationDate, err := c.getFreshGroupingsAndPeriods(ctx)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
-	return freshPeriods, periodsExpirationDate, nil
+	if c.cfg.Cache != nil {
+		go c.cfg.Cache.PutGroupingsAndPeriods(freshGroupingsExpirationDate, freshGroupings, periodsExpirationDate, freshPeriods)
+	}
+
+	return c.withArchivedPeriods(ctx, freshPeriods), periodsExpirationDate, nil
+}
+
+// CurrentYearPeriodId picks the longest period containing now, which spans the whole academic year
+func CurrentYearPeriodId(periods []SchedulePeriod, now time.Time) (int, bool) {
+	var longestPeriodContainingNowId *int
+	longestPeriodContainingNowDuration := time.Duration(0)
+
+	for _, period := range periods {
+		if period.Start.After(now) || period.End.Before(now) {
+			continue
+		}
+
+		periodDuration := period.End.Sub(period.Start)
+		if periodDuration > longestPeriodContainingNowDuration {
+			longestPeriodContainingNowId = &period.Id
+			longestPeriodContainingNowDuration = periodDuration
+		}
+	}
+
+	if longestPeriodContainingNowId == nil {
+		return 0, false
+	}
+
+	return *longestPeriodContainingNowId, true
 }
 
 func (res *responseBody) extractPeriods() ([]SchedulePeriod, error) {
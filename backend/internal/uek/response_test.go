@@ -0,0 +1,212 @@
+package uek
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+const responsesTestdataDirectoryPath = "testdata/responses"
+
+type responseGolden struct {
+	Schedule        *Schedule        `json:"schedule,omitempty"`
+	SchedulePeriods []SchedulePeriod `json:"schedulePeriods,omitempty"`
+	ScheduleError   string           `json:"scheduleError,omitempty"`
+	// only present when strict parsing fails
+	LenientSchedule      *Schedule        `json:"lenientSchedule,omitempty"`
+	LenientScheduleError string           `json:"lenientScheduleError,omitempty"`
+	Headers              []ScheduleHeader `json:"headers,omitempty"`
+	Groupings            *Groupings       `json:"groupings,omitempty"`
+	Periods              []SchedulePeriod `json:"periods,omitempty"`
+	PeriodsError         string           `json:"periodsError,omitempty"`
+}
+
+// runs every parser that applies to the response, schedule type and id are taken from the response itself
+func extractResponseGolden(res *responseBody) *responseGolden {
+	golden := &responseGolden{}
+
+	if res.Id != "" {
+		scheduleType, _ := res.Typ.asNormal()
+		if !scheduleType.IsValid() {
+			scheduleType = ScheduleTypeGroup
+		}
+		scheduleId, _ := strconv.Atoi(res.Id)
+
+		var err error
+		if golden.Schedule, golden.SchedulePeriods, err = res.extractSchedule(scheduleType, scheduleId, true); err != nil {
+			golden.ScheduleError = err.Error()
+
+			if golden.LenientSchedule, _, err = res.extractSchedule(scheduleType, scheduleId, false); err != nil {
+				golden.LenientScheduleError = err.Error()
+			}
+		}
+	}
+
+	if scheduleType, err := res.Typ.asNormal(); err == nil && len(res.Zasob) > 0 {
+		golden.Headers = res.extractHeaders(scheduleType)
+	}
+
+	if len(res.Grupowanie) > 0 {
+		golden.Groupings = res.extractGroupings()
+	}
+
+	var err error
+	if golden.Periods, err = res.extractPeriods(); err != nil {
+		golden.PeriodsError = err.Error()
+	}
+
+	return golden
+}
+
+func TestResponseParsersGolden(t *testing.T) {
+	xmlFilePaths, err := filepath.Glob(filepath.Join(responsesTestdataDirectoryPath, "*.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xmlFilePaths) == 0 {
+		t.Fatalf("no fixtures in %s", responsesTestdataDirectoryPath)
+	}
+
+	for _, xmlFilePath := range xmlFilePaths {
+		t.Run(filepath.Base(xmlFilePath), func(t *testing.T) {
+			xmlBuff, err := os.ReadFile(xmlFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := &responseBody{}
+			if err := xml.Unmarshal(xmlBuff, res); err != nil {
+				t.Fatalf("failed to decode fixture: %v", err)
+			}
+
+			actual, err := json.MarshalIndent(extractResponseGolden(res), "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			goldenFilePath := strings.TrimSuffix(xmlFilePath, ".xml") + ".golden.json"
+			if *updateGolden {
+				if err := os.WriteFile(goldenFilePath, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(goldenFilePath)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+
+			if !bytes.Equal(bytes.ReplaceAll(expected, []byte("\r\n"), []byte("\n")), actual) {
+				t.Errorf("output does not match %s (run with -update to accept changes)\nexpected:\n%s\nactual:\n%s", goldenFilePath, expected, actual)
+			}
+		})
+	}
+}
+
+func addResponseFixturesToFuzzCorpus(f *testing.F) {
+	xmlFilePaths, err := filepath.Glob(filepath.Join(responsesTestdataDirectoryPath, "*.xml"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, xmlFilePath := range xmlFilePaths {
+		xmlBuff, err := os.ReadFile(xmlFilePath)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(xmlBuff)
+	}
+}
+
+func checkScheduleInvariants(t *testing.T, schedule *Schedule) {
+	for i, item := range schedule.Items {
+		if item.Start.After(item.End) {
+			t.Fatalf("item %d starts after it ends: %v > %v", i, item.Start, item.End)
+		}
+
+		if i > 0 && schedule.Items[i-1].Compare(item) > 0 {
+			t.Fatalf("items %d and %d are not sorted", i-1, i)
+		}
+	}
+}
+
+func FuzzResponseBody(f *testing.F) {
+	addResponseFixturesToFuzzCorpus(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		res := &responseBody{}
+		if err := xml.Unmarshal(data, res); err != nil {
+			return
+		}
+
+		golden := extractResponseGolden(res)
+		if golden.Schedule != nil {
+			checkScheduleInvariants(t, golden.Schedule)
+		}
+		if golden.LenientSchedule != nil {
+			checkScheduleInvariants(t, golden.LenientSchedule)
+		}
+	})
+}
+
+func FuzzExtractScheduleItem(f *testing.F) {
+	f.Add("2026-10-05", "08:00", "09:30 (2g.)", "wykład", "Mikroekonomia", "-123", "Paw.C 101", "A, B")
+	f.Add("2026-10-05", "9:45", "11:15", "lektorat", "Język angielski - grupa przedmiotów", "", `<a href="https://e-uczelnia.uek.krakow.pl">Platforma Moodle</a>`, "")
+	f.Add("2026-03-29", "01:30", "03:30", "ćwiczenia", "Zmiana czasu", "456", `<a href="">x</a>`, ",")
+
+	f.Fuzz(func(t *testing.T, termin, odGodz, doGodz, typ, przedmiot, moodle, sala, grupa string) {
+		for _, scheduleType := range []ScheduleType{ScheduleTypeGroup, ScheduleTypeLecturer, ScheduleTypeRoom} {
+			res := &responseBody{
+				Typ:   scheduleType.asOriginal(),
+				Id:    "1",
+				Nazwa: "fuzz",
+			}
+			for range 2 {
+				res.Zajecia = append(res.Zajecia, responseBodyItem{
+					Termin:    termin,
+					OdGodz:    odGodz,
+					DoGodz:    doGodz,
+					Typ:       typ,
+					Przedmiot: przedmiot,
+					Sala:      sala,
+					Grupa:     grupa,
+					Nauczyciel: []responseBodyItemLecturer{
+						{
+							Moodle: moodle,
+							Nazwa:  "dr Fuzz",
+						},
+					},
+				})
+			}
+			// second item starts earlier whenever possible, to exercise sorting
+			res.Zajecia[1].OdGodz = "00:00"
+
+			if schedule, _, err := res.extractSchedule(scheduleType, 1, true); err == nil {
+				checkScheduleInvariants(t, schedule)
+			}
+
+			// item-level problems must never fail the whole schedule in lenient mode
+			schedule, _, err := res.extractSchedule(scheduleType, 1, false)
+			if err != nil {
+				t.Fatalf("lenient parsing failed: %v", err)
+			}
+			checkScheduleInvariants(t, schedule)
+
+			for _, item := range schedule.Items {
+				if item.Room != nil && item.Room.URL != "" && item.Room.Name == "" {
+					t.Fatalf("online room without name: %+v", item.Room)
+				}
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package uek
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"slices"
@@ -11,8 +12,9 @@ import (
 )
 
 type Schedule struct {
-	Header ScheduleHeader  `json:"header"`
-	Items  []*ScheduleItem `json:"items"`
+	Header   ScheduleHeader  `json:"header"`
+	Items    []*ScheduleItem `json:"items"`
+	Warnings []ParseWarning  `json:"warnings,omitempty"`
 }
 
 type ScheduleItem struct {
@@ -55,21 +57,38 @@ func (a *ScheduleItem) Compare(b *ScheduleItem) int {
 	return strings.Compare(a.Type, b.Type)
 }
 
+// IsCancelled reports the slot of a class moved to another date, the replacement is a separate item
+func (item *ScheduleItem) IsCancelled() bool {
+	return item.Type == "przeniesienie zajęć"
+}
+
+// IsReservation reports a room booked for something other than classes
+func (item *ScheduleItem) IsReservation() bool {
+	return item.Type == "rezerwacja" || item.Type == "wstępna rezerwacja"
+}
+
+// IsClass reports whether the item takes place and counts towards study hours
+func (item *ScheduleItem) IsClass() bool {
+	return !item.IsCancelled() && !item.IsReservation()
+}
+
+// GetSchedule returns a single schedule, negative period ids are read from the archive
+func (c *Client) GetSchedule(ctx context.Context, scheduleType ScheduleType, scheduleId int, periodId int) (*Schedule, time.Time, error) {
+	return c.getSchedule(ctx, scheduleType, scheduleId, periodId)
+}
+
 func (c *Client) getSchedule(ctx context.Context, scheduleType ScheduleType, scheduleId int, periodId int) (*Schedule, time.Time, error) {
+	if IsArchivedPeriodId(periodId) {
+		return c.getArchivedSchedule(ctx, scheduleType, scheduleId, periodId)
+	}
+
 	if c.cfg.Cache != nil {
 		if schedule, validUntil, ok := c.cfg.Cache.GetSchedule(ctx, scheduleType, scheduleId, periodId); ok {
 			return schedule, validUntil, nil
 		}
 	}
-	scheduleExpirationDate := time.Now().Add(c.cfg.CacheTimes.Schedules)
-	periodsExpirationDate := time.Now().Add(c.cfg.CacheTimes.Periods)
 
-	res, err := c.fetchAndUnmarshalXML(ctx, fmt.Sprintf("%s?typ=%s&id=%d&okres=%d&xml", baseUrl, scheduleType.asOriginal(), scheduleId, periodId))
-	if err != nil {
-		return nil, time.Time{}, err
-	}
-
-	schedule, periods, err := res.extractSchedule(scheduleType, scheduleId)
+	schedule, scheduleExpirationDate, periods, periodsExpirationDate, err := c.getFreshSchedule(ctx, scheduleType, scheduleId, periodId)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
@@ -81,9 +100,37 @@ func (c *Client) getSchedule(ctx context.Context, scheduleType ScheduleType, sch
 	return schedule, scheduleExpirationDate, nil
 }
 
+func (c *Client) getFreshSchedule(ctx context.Context, scheduleType ScheduleType, scheduleId int, periodId int) (*Schedule, time.Time, []SchedulePeriod, time.Time, error) {
+	fetchStartTime := time.Now()
+	periodsExpirationDate := fetchStartTime.Add(c.cfg.CacheTimes.Periods)
+
+	res, err := c.fetchAndUnmarshalXML(ctx, fmt.Sprintf("%s?typ=%s&id=%d&okres=%d&xml", c.baseUrl(), scheduleType.asOriginal(), scheduleId, periodId))
+	if err != nil {
+		return nil, time.Time{}, nil, time.Time{}, err
+	}
+
+	schedule, periods, err := res.extractSchedule(scheduleType, scheduleId, c.cfg.StrictParsing)
+	if err != nil {
+		return nil, time.Time{}, nil, time.Time{}, newInvalidResponseError(err)
+	}
+	c.reportParseWarnings(scheduleType, periodId, schedule.Warnings)
+	c.archiveSchedule(scheduleType, scheduleId, periodId, periods, schedule)
+	scheduleExpirationDate := fetchStartTime.Add(c.scheduleCacheTime(scheduleType, periods, periodId, fetchStartTime))
+	c.notifyScheduleFetched(ScheduleFetch{
+		ScheduleType:   scheduleType,
+		ScheduleId:     scheduleId,
+		PeriodId:       periodId,
+		Schedule:       schedule,
+		ExpirationDate: scheduleExpirationDate,
+	})
+
+	return schedule, scheduleExpirationDate, periods, periodsExpirationDate, nil
+}
+
 var scheduleItemRoomLinkRegex = regexp.MustCompile(`^<a href="(.+)">(.+)<\/a>$`)
 
-func (res *responseBody) extractSchedule(requestedScheduleType ScheduleType, requestedScheduleId int) (*Schedule, []SchedulePeriod, error) {
+// in non-strict mode malformed items are dropped or repaired and reported as warnings instead of failing the whole schedule
+func (res *responseBody) extractSchedule(requestedScheduleType ScheduleType, requestedScheduleId int, strict bool) (*Schedule, []SchedulePeriod, error) {
 	receivedScheduleType, err := res.Typ.asNormal()
 	if err != nil {
 		return nil, nil, err
@@ -106,12 +153,26 @@ func (res *responseBody) extractSchedule(requestedScheduleType ScheduleType, req
 		return nil, nil, fmt.Errorf("missing schedule name")
 	}
 
+	var warnings []ParseWarning
+	addWarning := func(itemIndex int, reason ParseWarningReason, action ParseWarningAction, err error) {
+		warnings = append(warnings, ParseWarning{
+			ScheduleId: receivedScheduleId,
+			ItemIndex:  itemIndex,
+			Reason:     reason,
+			Action:     action,
+			Message:    err.Error(),
+		})
+	}
+
 	scheduleMoodleId := 0
 	scheduleMoodleIdRaw := strings.TrimSpace(res.Idcel)
 	if scheduleMoodleIdRaw != "" {
 		scheduleMoodleId, err = parseMoodleId(scheduleMoodleIdRaw)
 		if err != nil {
-			return nil, nil, err
+			if strict {
+				return nil, nil, err
+			}
+			addWarning(-1, ParseWarningReasonInvalidMoodleId, ParseWarningActionRepaired, err)
 		}
 	}
 
@@ -143,18 +204,18 @@ func (res *responseBody) extractSchedule(requestedScheduleType ScheduleType, req
 
 			item.Start, err = parseScheduleDate(resItem.Termin + " " + resItem.OdGodz)
 			if err != nil {
-				err = fmt.Errorf("failed to parse item end date: %w", err)
+				err = &parseError{ParseWarningReasonInvalidDate, fmt.Errorf("failed to parse item start date: %w", err)}
 				return
 			}
 
 			item.End, err = parseScheduleDate(resItem.Termin + " " + strings.Split(resItem.DoGodz, " ")[0])
 			if err != nil {
-				err = fmt.Errorf("failed to parse item end date: %w", err)
+				err = &parseError{ParseWarningReasonInvalidDate, fmt.Errorf("failed to parse item end date: %w", err)}
 				return
 			}
 
 			if item.Start.After(item.End) {
-				err = fmt.Errorf("start time is after end time")
+				err = &parseError{ParseWarningReasonStartAfterEnd, fmt.Errorf("start time is after end time")}
 				return
 			}
 
@@ -187,7 +248,12 @@ func (res *responseBody) extractSchedule(requestedScheduleType ScheduleType, req
 						lecturerMoodleId, err = parseMoodleId(resItemLecturer.Moodle)
 						if err != nil {
 							err = fmt.Errorf("%w at lecturer index %d", err, j)
-							return
+							if strict {
+								return
+							}
+							// the lecturer is still worth showing without a moodle link
+							addWarning(i, ParseWarningReasonInvalidMoodleId, ParseWarningActionRepaired, err)
+							lecturerMoodleId, err = 0, nil
 						}
 					}
 
@@ -216,6 +282,10 @@ func (res *responseBody) extractSchedule(requestedScheduleType ScheduleType, req
 			items = append(items, item)
 			return
 		}(); err != nil {
+			if pe := (*parseError)(nil); !strict && errors.As(err, &pe) {
+				addWarning(i, pe.reason, ParseWarningActionDropped, err)
+				continue
+			}
 			return nil, nil, fmt.Errorf("%w at item index %d", err, i)
 		}
 	}
@@ -234,7 +304,8 @@ func (res *responseBody) extractSchedule(requestedScheduleType ScheduleType, req
 			Id:   receivedScheduleId,
 			Name: scheduleName,
 		},
-		Items: items,
+		Items:    items,
+		Warnings: warnings,
 	}, periods, nil
 }
 
@@ -258,6 +329,11 @@ var uekLocation = func() *time.Location {
 	return loc
 }()
 
+// Location returns the time zone schedules are published in
+func Location() *time.Location {
+	return uekLocation
+}
+
 func parseScheduleDate(input string) (time.Time, error) {
 	return time.ParseInLocation("2006-01-02 15:04", input, uekLocation)
 }
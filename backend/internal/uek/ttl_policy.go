@@ -0,0 +1,181 @@
+package uek
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type PeriodTiming string
+
+const (
+	PeriodTimingPast    PeriodTiming = "past"
+	PeriodTimingCurrent PeriodTiming = "current"
+	PeriodTimingFuture  PeriodTiming = "future"
+)
+
+// TTLRule matches when all of its set conditions match, empty conditions match anything
+type TTLRule struct {
+	ScheduleType ScheduleType `json:"scheduleType,omitempty"`
+	Period       PeriodTiming `json:"period,omitempty"`
+	// inclusive "MM-DD" dates compared against the current date, the window may wrap around the new year
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// e.g. "15m", "720h"
+	TTL string `json:"ttl"`
+
+	ttl      time.Duration
+	fromDate monthDay
+	toDate   monthDay
+}
+
+// TTLPolicy picks schedule cache times, the first matching rule wins, e.g.
+//
+//	{"rules": [
+//		{"period": "past", "ttl": "720h"},
+//		{"period": "current", "from": "09-20", "to": "10-15", "ttl": "5m"},
+//		{"scheduleType": "room", "ttl": "1h"}
+//	]}
+type TTLPolicy struct {
+	Rules []TTLRule `json:"rules"`
+}
+
+type monthDay struct {
+	month time.Month
+	day   int
+}
+
+func (md monthDay) compare(other monthDay) int {
+	if md.month != other.month {
+		return int(md.month) - int(other.month)
+	}
+	return md.day - other.day
+}
+
+func ParseTTLPolicy(data []byte) (*TTLPolicy, error) {
+	policy := &TTLPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to decode policy: %w", err)
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+
+		if rule.ScheduleType != "" && !rule.ScheduleType.IsValid() {
+			return nil, fmt.Errorf("rule %d: invalid schedule type: %s", i, rule.ScheduleType)
+		}
+
+		switch rule.Period {
+		case "", PeriodTimingPast, PeriodTimingCurrent, PeriodTimingFuture:
+		default:
+			return nil, fmt.Errorf("rule %d: invalid period: %s", i, rule.Period)
+		}
+
+		if (rule.From == "") != (rule.To == "") {
+			return nil, fmt.Errorf("rule %d: from and to must be set together", i)
+		}
+		if rule.From != "" {
+			var err error
+			if rule.fromDate, err = parseMonthDay(rule.From); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid from: %w", i, err)
+			}
+			if rule.toDate, err = parseMonthDay(rule.To); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid to: %w", i, err)
+			}
+		}
+
+		ttl, err := time.ParseDuration(rule.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("rule %d: invalid ttl: %s", i, rule.TTL)
+		}
+		rule.ttl = ttl
+	}
+
+	return policy, nil
+}
+
+func LoadTTLPolicyFile(filePath string) (*TTLPolicy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTTLPolicy(data)
+}
+
+func parseMonthDay(input string) (monthDay, error) {
+	// leap year, so that 02-29 is accepted
+	date, err := time.Parse("2006-01-02", "2024-"+input)
+	if err != nil {
+		return monthDay{}, err
+	}
+
+	return monthDay{month: date.Month(), day: date.Day()}, nil
+}
+
+func (rule *TTLRule) matches(scheduleType ScheduleType, periodTiming PeriodTiming, now time.Time) bool {
+	if rule.ScheduleType != "" && rule.ScheduleType != scheduleType {
+		return false
+	}
+
+	if rule.Period != "" && rule.Period != periodTiming {
+		return false
+	}
+
+	if rule.From != "" {
+		nowInUek := now.In(uekLocation)
+		today := monthDay{month: nowInUek.Month(), day: nowInUek.Day()}
+
+		if rule.fromDate.compare(rule.toDate) <= 0 {
+			if today.compare(rule.fromDate) < 0 || today.compare(rule.toDate) > 0 {
+				return false
+			}
+		} else if today.compare(rule.fromDate) < 0 && today.compare(rule.toDate) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// periodTiming is empty if the period is unknown, then only rules without a period condition match
+func (policy *TTLPolicy) scheduleTTL(scheduleType ScheduleType, periodTiming PeriodTiming, now time.Time) (time.Duration, bool) {
+	if policy == nil {
+		return 0, false
+	}
+
+	for i := range policy.Rules {
+		if policy.Rules[i].matches(scheduleType, periodTiming, now) {
+			return policy.Rules[i].ttl, true
+		}
+	}
+
+	return 0, false
+}
+
+func getPeriodTiming(periods []SchedulePeriod, periodId int, now time.Time) PeriodTiming {
+	for _, period := range periods {
+		if period.Id != periodId {
+			continue
+		}
+
+		if period.End.Before(now) {
+			return PeriodTimingPast
+		}
+		if period.Start.After(now) {
+			return PeriodTimingFuture
+		}
+		return PeriodTimingCurrent
+	}
+
+	return ""
+}
+
+func (c *Client) scheduleCacheTime(scheduleType ScheduleType, periods []SchedulePeriod, periodId int, now time.Time) time.Duration {
+	if ttl, ok := c.cfg.TTLPolicy.scheduleTTL(scheduleType, getPeriodTiming(periods, periodId, now), now); ok {
+		return ttl
+	}
+
+	return c.cfg.CacheTimes.Schedules
+}
@@ -0,0 +1,159 @@
+package uek
+
+import (
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+)
+
+func TestParseTTLPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "empty", input: `{}`},
+		{name: "all conditions", input: `{"rules": [{"scheduleType": "room", "period": "current", "from": "12-15", "to": "01-15", "ttl": "5m"}]}`},
+		{name: "leap day", input: `{"rules": [{"from": "02-29", "to": "03-01", "ttl": "1h"}]}`},
+		{name: "invalid json", input: `{"rules": [`, wantErr: true},
+		{name: "invalid schedule type", input: `{"rules": [{"scheduleType": "building", "ttl": "1h"}]}`, wantErr: true},
+		{name: "invalid period", input: `{"rules": [{"period": "someday", "ttl": "1h"}]}`, wantErr: true},
+		{name: "from without to", input: `{"rules": [{"from": "09-20", "ttl": "1h"}]}`, wantErr: true},
+		{name: "to without from", input: `{"rules": [{"to": "09-20", "ttl": "1h"}]}`, wantErr: true},
+		{name: "invalid from", input: `{"rules": [{"from": "13-01", "to": "01-15", "ttl": "1h"}]}`, wantErr: true},
+		{name: "invalid to", input: `{"rules": [{"from": "01-01", "to": "02-30", "ttl": "1h"}]}`, wantErr: true},
+		{name: "missing ttl", input: `{"rules": [{"period": "past"}]}`, wantErr: true},
+		{name: "invalid ttl", input: `{"rules": [{"ttl": "soon"}]}`, wantErr: true},
+		{name: "zero ttl", input: `{"rules": [{"ttl": "0s"}]}`, wantErr: true},
+		{name: "negative ttl", input: `{"rules": [{"ttl": "-1h"}]}`, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseTTLPolicy([]byte(tc.input))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error: %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func mustParseTTLPolicy(t *testing.T, input string) *TTLPolicy {
+	t.Helper()
+
+	policy, err := ParseTTLPolicy([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return policy
+}
+
+func TestTTLRuleMatchesDateWindow(t *testing.T) {
+	policy := mustParseTTLPolicy(t, `{"rules": [
+		{"from": "09-20", "to": "10-15", "ttl": "1m"},
+		{"from": "12-15", "to": "01-15", "ttl": "2m"}
+	]}`)
+	regular, wrapping := &policy.Rules[0], &policy.Rules[1]
+
+	for _, tc := range []struct {
+		name         string
+		now          time.Time
+		wantRegular  bool
+		wantWrapping bool
+	}{
+		{name: "before regular window", now: time.Date(2025, 9, 19, 23, 59, 0, 0, uekLocation)},
+		{name: "regular window start", now: time.Date(2025, 9, 20, 0, 0, 0, 0, uekLocation), wantRegular: true},
+		{name: "regular window end", now: time.Date(2025, 10, 15, 23, 59, 0, 0, uekLocation), wantRegular: true},
+		{name: "after regular window", now: time.Date(2025, 10, 16, 0, 0, 0, 0, uekLocation)},
+		{name: "before wrapping window", now: time.Date(2025, 12, 14, 12, 0, 0, 0, uekLocation)},
+		{name: "wrapping window start", now: time.Date(2025, 12, 15, 0, 0, 0, 0, uekLocation), wantWrapping: true},
+		{name: "wrapping window new year's eve", now: time.Date(2025, 12, 31, 23, 59, 0, 0, uekLocation), wantWrapping: true},
+		{name: "wrapping window new year", now: time.Date(2026, 1, 1, 0, 0, 0, 0, uekLocation), wantWrapping: true},
+		{name: "wrapping window end", now: time.Date(2026, 1, 15, 12, 0, 0, 0, uekLocation), wantWrapping: true},
+		{name: "after wrapping window", now: time.Date(2026, 1, 16, 0, 0, 0, 0, uekLocation)},
+		// 23:30 UTC on 12-14 is already 12-15 in Kraków
+		{name: "date is taken in uek location", now: time.Date(2025, 12, 14, 23, 30, 0, 0, time.UTC), wantWrapping: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := regular.matches(ScheduleTypeGroup, PeriodTimingCurrent, tc.now); actual != tc.wantRegular {
+				t.Errorf("regular window: expected %t, got %t", tc.wantRegular, actual)
+			}
+			if actual := wrapping.matches(ScheduleTypeGroup, PeriodTimingCurrent, tc.now); actual != tc.wantWrapping {
+				t.Errorf("wrapping window: expected %t, got %t", tc.wantWrapping, actual)
+			}
+		})
+	}
+}
+
+func TestGetPeriodTiming(t *testing.T) {
+	periods := []SchedulePeriod{
+		{Id: 1, Start: time.Date(2025, 2, 20, 0, 0, 0, 0, uekLocation), End: time.Date(2025, 9, 19, 0, 0, 0, 0, uekLocation)},
+		{Id: 2, Start: time.Date(2025, 9, 20, 0, 0, 0, 0, uekLocation), End: time.Date(2026, 2, 19, 0, 0, 0, 0, uekLocation)},
+		{Id: 3, Start: time.Date(2026, 2, 20, 0, 0, 0, 0, uekLocation), End: time.Date(2026, 9, 19, 0, 0, 0, 0, uekLocation)},
+	}
+	now := time.Date(2025, 10, 19, 12, 0, 0, 0, uekLocation)
+
+	for _, tc := range []struct {
+		periodId int
+		expected PeriodTiming
+	}{
+		{periodId: 1, expected: PeriodTimingPast},
+		{periodId: 2, expected: PeriodTimingCurrent},
+		{periodId: 3, expected: PeriodTimingFuture},
+		{periodId: 4, expected: ""},
+	} {
+		if actual := getPeriodTiming(periods, tc.periodId, now); actual != tc.expected {
+			t.Errorf("period %d: expected %q, got %q", tc.periodId, tc.expected, actual)
+		}
+	}
+}
+
+func TestScheduleCacheTime(t *testing.T) {
+	periods := []SchedulePeriod{
+		{Id: 1, Start: time.Date(2025, 2, 20, 0, 0, 0, 0, uekLocation), End: time.Date(2025, 9, 19, 0, 0, 0, 0, uekLocation)},
+		{Id: 2, Start: time.Date(2025, 9, 20, 0, 0, 0, 0, uekLocation), End: time.Date(2026, 2, 19, 0, 0, 0, 0, uekLocation)},
+		{Id: 3, Start: time.Date(2026, 2, 20, 0, 0, 0, 0, uekLocation), End: time.Date(2026, 9, 19, 0, 0, 0, 0, uekLocation)},
+	}
+	policy := mustParseTTLPolicy(t, `{"rules": [
+		{"period": "past", "ttl": "720h"},
+		{"period": "current", "from": "09-20", "to": "10-15", "ttl": "5m"},
+		{"scheduleType": "room", "ttl": "1h"},
+		{"period": "future", "ttl": "6h"}
+	]}`)
+	client := NewClient(ClientConfig{
+		CacheTimes: config.CacheTimes{Schedules: 15 * time.Minute},
+		TTLPolicy:  policy,
+	})
+	startOfSemester := time.Date(2025, 10, 1, 12, 0, 0, 0, uekLocation)
+	midSemester := time.Date(2025, 11, 20, 12, 0, 0, 0, uekLocation)
+
+	for _, tc := range []struct {
+		name         string
+		scheduleType ScheduleType
+		periodId     int
+		now          time.Time
+		expected     time.Duration
+	}{
+		{name: "past period", scheduleType: ScheduleTypeGroup, periodId: 1, now: midSemester, expected: 720 * time.Hour},
+		{name: "past period wins over schedule type", scheduleType: ScheduleTypeRoom, periodId: 1, now: midSemester, expected: 720 * time.Hour},
+		{name: "current period in window", scheduleType: ScheduleTypeGroup, periodId: 2, now: startOfSemester, expected: 5 * time.Minute},
+		{name: "current period in window wins over schedule type", scheduleType: ScheduleTypeRoom, periodId: 2, now: startOfSemester, expected: 5 * time.Minute},
+		{name: "current period outside window falls back", scheduleType: ScheduleTypeGroup, periodId: 2, now: midSemester, expected: 15 * time.Minute},
+		{name: "current period outside window by schedule type", scheduleType: ScheduleTypeRoom, periodId: 2, now: midSemester, expected: time.Hour},
+		{name: "schedule type wins over later future rule", scheduleType: ScheduleTypeRoom, periodId: 3, now: midSemester, expected: time.Hour},
+		{name: "future period", scheduleType: ScheduleTypeLecturer, periodId: 3, now: midSemester, expected: 6 * time.Hour},
+		{name: "unknown period only matches rules without period", scheduleType: ScheduleTypeGroup, periodId: 9, now: startOfSemester, expected: 15 * time.Minute},
+		{name: "unknown period by schedule type", scheduleType: ScheduleTypeRoom, periodId: 9, now: startOfSemester, expected: time.Hour},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := client.scheduleCacheTime(tc.scheduleType, periods, tc.periodId, tc.now); actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+
+	withoutPolicy := NewClient(ClientConfig{CacheTimes: config.CacheTimes{Schedules: 15 * time.Minute}})
+	if actual := withoutPolicy.scheduleCacheTime(ScheduleTypeGroup, periods, 1, midSemester); actual != 15*time.Minute {
+		t.Errorf("expected the default cache time without a policy, got %s", actual)
+	}
+}
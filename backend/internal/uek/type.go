@@ -10,6 +10,8 @@ const (
 	ScheduleTypeRoom     ScheduleType = "room"
 )
 
+var ScheduleTypes = []ScheduleType{ScheduleTypeGroup, ScheduleTypeLecturer, ScheduleTypeRoom}
+
 func (st ScheduleType) IsValid() bool {
 	return st == ScheduleTypeGroup || st == ScheduleTypeLecturer || st == ScheduleTypeRoom
 }
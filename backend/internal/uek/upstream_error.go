@@ -0,0 +1,110 @@
+package uek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type UpstreamErrorKind string
+
+const (
+	UpstreamErrorKindTimeout         UpstreamErrorKind = "timeout"
+	UpstreamErrorKindUnreachable     UpstreamErrorKind = "unreachable"
+	UpstreamErrorKindRateLimited     UpstreamErrorKind = "rateLimited"
+	UpstreamErrorKindBadStatus       UpstreamErrorKind = "badStatus"
+	UpstreamErrorKindInvalidResponse UpstreamErrorKind = "invalidResponse"
+)
+
+// UpstreamError describes a failed request to UEK, errors caused by the caller's context being canceled are never wrapped in it
+type UpstreamError struct {
+	Kind UpstreamErrorKind
+	// only for rate limited and bad status errors
+	StatusCode int
+	// as sent by UEK, 0 if not sent
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("upstream %s: %s", e.Kind, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+func newRequestError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	kind := UpstreamErrorKindUnreachable
+	if netErr := net.Error(nil); errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		kind = UpstreamErrorKindTimeout
+	}
+
+	return &UpstreamError{
+		Kind: kind,
+		Err:  err,
+	}
+}
+
+func newStatusError(res *http.Response) error {
+	kind := UpstreamErrorKindBadStatus
+	if res.StatusCode == http.StatusTooManyRequests {
+		kind = UpstreamErrorKindRateLimited
+	}
+
+	return &UpstreamError{
+		Kind:       kind,
+		StatusCode: res.StatusCode,
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+		Err:        fmt.Errorf("unexpected status code: %d", res.StatusCode),
+	}
+}
+
+func newInvalidResponseError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	// a body cut off by a timeout is not the upstream's fault
+	if upstreamErr := (*UpstreamError)(nil); errors.As(err, &upstreamErr) {
+		return err
+	}
+	if netErr := net.Error(nil); errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &UpstreamError{
+			Kind: UpstreamErrorKindTimeout,
+			Err:  err,
+		}
+	}
+
+	return &UpstreamError{
+		Kind: UpstreamErrorKindInvalidResponse,
+		Err:  err,
+	}
+}
+
+// accepts both delay-seconds and http-date forms
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,148 @@
+package uek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// mimics net/http client timeouts
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestNewRequestError(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		err          error
+		expectedKind UpstreamErrorKind
+	}{
+		{name: "canceled", err: fmt.Errorf("get: %w", context.Canceled)},
+		{name: "deadline exceeded", err: fmt.Errorf("get: %w", context.DeadlineExceeded), expectedKind: UpstreamErrorKindTimeout},
+		{name: "net timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, expectedKind: UpstreamErrorKindTimeout},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, expectedKind: UpstreamErrorKindUnreachable},
+		{name: "dns failure", err: &net.DNSError{Err: "no such host", Name: "planzajec.uek.krakow.pl"}, expectedKind: UpstreamErrorKindUnreachable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newRequestError(tc.err)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected the original error to be wrapped, got %v", err)
+			}
+
+			upstreamErr := (*UpstreamError)(nil)
+			if !errors.As(err, &upstreamErr) {
+				if tc.expectedKind != "" {
+					t.Fatalf("expected an upstream error, got %v", err)
+				}
+				return
+			}
+			if tc.expectedKind == "" {
+				t.Fatalf("expected the error to be returned as is, got %v", err)
+			}
+			if upstreamErr.Kind != tc.expectedKind {
+				t.Errorf("expected kind %s, got %s", tc.expectedKind, upstreamErr.Kind)
+			}
+		})
+	}
+}
+
+func TestNewInvalidResponseError(t *testing.T) {
+	requestErr := &UpstreamError{Kind: UpstreamErrorKindUnreachable, Err: errors.New("connection reset")}
+
+	for _, tc := range []struct {
+		name         string
+		err          error
+		expectedKind UpstreamErrorKind
+	}{
+		{name: "canceled", err: fmt.Errorf("read body: %w", context.Canceled)},
+		{name: "already an upstream error", err: fmt.Errorf("read body: %w", requestErr), expectedKind: UpstreamErrorKindUnreachable},
+		{name: "deadline exceeded while reading", err: fmt.Errorf("read body: %w", context.DeadlineExceeded), expectedKind: UpstreamErrorKindTimeout},
+		{name: "net timeout while reading", err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, expectedKind: UpstreamErrorKindTimeout},
+		{name: "malformed xml", err: errors.New("XML syntax error on line 1: unexpected EOF"), expectedKind: UpstreamErrorKindInvalidResponse},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newInvalidResponseError(tc.err)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected the original error to be wrapped, got %v", err)
+			}
+
+			upstreamErr := (*UpstreamError)(nil)
+			if !errors.As(err, &upstreamErr) {
+				if tc.expectedKind != "" {
+					t.Fatalf("expected an upstream error, got %v", err)
+				}
+				return
+			}
+			if tc.expectedKind == "" {
+				t.Fatalf("expected the error to be returned as is, got %v", err)
+			}
+			if upstreamErr.Kind != tc.expectedKind {
+				t.Errorf("expected kind %s, got %s", tc.expectedKind, upstreamErr.Kind)
+			}
+		})
+	}
+}
+
+func TestNewStatusError(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		statusCode         int
+		retryAfter         string
+		expectedKind       UpstreamErrorKind
+		expectedRetryAfter time.Duration
+	}{
+		{name: "rate limited", statusCode: http.StatusTooManyRequests, retryAfter: "120", expectedKind: UpstreamErrorKindRateLimited, expectedRetryAfter: 2 * time.Minute},
+		{name: "server error", statusCode: http.StatusInternalServerError, expectedKind: UpstreamErrorKindBadStatus},
+		{name: "unavailable", statusCode: http.StatusServiceUnavailable, retryAfter: "30", expectedKind: UpstreamErrorKindBadStatus, expectedRetryAfter: 30 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res := &http.Response{StatusCode: tc.statusCode, Header: http.Header{}}
+			if tc.retryAfter != "" {
+				res.Header.Set("Retry-After", tc.retryAfter)
+			}
+
+			upstreamErr := (*UpstreamError)(nil)
+			if !errors.As(newStatusError(res), &upstreamErr) {
+				t.Fatal("expected an upstream error")
+			}
+			if upstreamErr.Kind != tc.expectedKind || upstreamErr.StatusCode != tc.statusCode || upstreamErr.RetryAfter != tc.expectedRetryAfter {
+				t.Errorf("expected %s/%d/%s, got %s/%d/%s", tc.expectedKind, tc.statusCode, tc.expectedRetryAfter, upstreamErr.Kind, upstreamErr.StatusCode, upstreamErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		value    string
+		expected time.Duration
+		// http dates have second precision and are relative to now
+		tolerance time.Duration
+	}{
+		{name: "empty", value: "", expected: 0},
+		{name: "seconds", value: "120", expected: 2 * time.Minute},
+		{name: "seconds with whitespace", value: " 5 ", expected: 5 * time.Second},
+		{name: "zero seconds", value: "0", expected: 0},
+		{name: "negative seconds", value: "-10", expected: 0},
+		{name: "http date", value: time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat), expected: 90 * time.Second, tolerance: 2 * time.Second},
+		{name: "http date in the past", value: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), expected: 0},
+		{name: "rfc 850 date", value: time.Now().Add(time.Hour).UTC().Format(time.RFC850), expected: time.Hour, tolerance: 2 * time.Second},
+		{name: "garbage", value: "soon", expected: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := parseRetryAfter(tc.value)
+			if actual < tc.expected-tc.tolerance || actual > tc.expected+tc.tolerance {
+				t.Errorf("expected %s (±%s), got %s", tc.expected, tc.tolerance, actual)
+			}
+		})
+	}
+}
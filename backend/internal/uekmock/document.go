@@ -0,0 +1,131 @@
+package uekmock
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// mirrors xml returned by planzajec.uek.krakow.pl
+type document struct {
+	XMLName    xml.Name           `xml:"plan-zajec"`
+	Typ        string             `xml:"typ,attr,omitempty"`
+	Id         string             `xml:"id,attr,omitempty"`
+	Idcel      string             `xml:"idcel,attr,omitempty"`
+	Nazwa      string             `xml:"nazwa,attr,omitempty"`
+	Grupa      string             `xml:"grupa,attr,omitempty"`
+	Okres      []documentPeriod   `xml:"okres"`
+	Grupowanie []documentGrouping `xml:"grupowanie"`
+	Zasob      []documentResource `xml:"zasob"`
+	Zajecia    []documentItem     `xml:"zajecia"`
+}
+
+type documentPeriod struct {
+	Od string `xml:"od,attr"`
+	Do string `xml:"do,attr"`
+}
+
+type documentGrouping struct {
+	Typ   string `xml:"typ,attr"`
+	Grupa string `xml:"grupa,attr"`
+}
+
+type documentResource struct {
+	Typ   string `xml:"typ,attr"`
+	Id    string `xml:"id,attr"`
+	Nazwa string `xml:"nazwa,attr"`
+}
+
+type documentItem struct {
+	Termin     string             `xml:"termin"`
+	Dzien      string             `xml:"dzien"`
+	OdGodz     string             `xml:"od-godz"`
+	DoGodz     string             `xml:"do-godz"`
+	Przedmiot  string             `xml:"przedmiot"`
+	Typ        string             `xml:"typ"`
+	Nauczyciel []documentLecturer `xml:"nauczyciel"`
+	Sala       string             `xml:"sala"`
+	Grupa      string             `xml:"grupa,omitempty"`
+	Uwagi      string             `xml:"uwagi,omitempty"`
+}
+
+type documentLecturer struct {
+	Moodle string `xml:"moodle,attr,omitempty"`
+	Nazwa  string `xml:",chardata"`
+}
+
+var documentRoomLinkRegex = regexp.MustCompile(`^<a href="(.+)">(.+)<\/a>$`)
+
+func (item documentItem) RoomName() string {
+	if matches := documentRoomLinkRegex.FindStringSubmatch(strings.TrimSpace(item.Sala)); len(matches) > 0 {
+		return matches[2]
+	}
+
+	return strings.TrimSpace(item.Sala)
+}
+
+// empty for non-online rooms
+func (item documentItem) RoomURL() string {
+	if matches := documentRoomLinkRegex.FindStringSubmatch(strings.TrimSpace(item.Sala)); len(matches) > 0 {
+		return matches[1]
+	}
+
+	return ""
+}
+
+func readDocument(filePath string) (*document, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc := &document{}
+	if err := xml.NewDecoder(f).Decode(doc); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filePath, err)
+	}
+
+	return doc, nil
+}
+
+func writeDocument(filePath string, doc *document) error {
+	buff, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, append([]byte(xml.Header), buff...), 0644)
+}
+
+// inverse of getMockResponseFilePathFromQuery
+func getQueryFromMockResponseFileName(fileName string) (url.Values, bool) {
+	fileName, ok := strings.CutSuffix(path.Base(fileName), ".xml")
+	if !ok {
+		return nil, false
+	}
+
+	// grouping names can contain "-", but other parts cannot
+	typ, rest, ok := strings.Cut(fileName, "-")
+	if !ok {
+		return nil, false
+	}
+	restParts := strings.Split(rest, "-")
+	if len(restParts) < 3 {
+		return nil, false
+	}
+	grupa := strings.Join(restParts[:len(restParts)-2], "-")
+	id, okres := restParts[len(restParts)-2], restParts[len(restParts)-1]
+
+	query := url.Values{}
+	for key, value := range map[string]string{"typ": typ, "grupa": grupa, "id": id, "okres": okres} {
+		if value != "_" {
+			query.Set(key, strings.ReplaceAll(value, "+", "*"))
+		}
+	}
+
+	return query, true
+}
@@ -0,0 +1,205 @@
+package uekmock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type FaultKind string
+
+const (
+	FaultKindStatus500 FaultKind = "500"
+	FaultKindStatus503 FaultKind = "503"
+	FaultKindStatus429 FaultKind = "429"
+	FaultKindReset     FaultKind = "reset"
+	FaultKindTruncate  FaultKind = "truncate"
+	FaultKindMalformed FaultKind = "malformed"
+	FaultKindWrongId   FaultKind = "wrongid"
+	FaultKindSlowDrip  FaultKind = "slowdrip"
+)
+
+func (fk FaultKind) IsValid() bool {
+	switch fk {
+	case FaultKindStatus500, FaultKindStatus503, FaultKindStatus429, FaultKindReset, FaultKindTruncate, FaultKindMalformed, FaultKindWrongId, FaultKindSlowDrip:
+		return true
+	}
+
+	return false
+}
+
+func (fk FaultKind) statusCode() int {
+	switch fk {
+	case FaultKindStatus500:
+		return http.StatusInternalServerError
+	case FaultKindStatus503:
+		return http.StatusServiceUnavailable
+	case FaultKindStatus429:
+		return http.StatusTooManyRequests
+	}
+
+	return 0
+}
+
+type FaultRule struct {
+	Kind        FaultKind
+	Probability float64
+	// nil matches every url
+	URLPattern *regexp.Regexp
+}
+
+// ParseFaultRules parses rules separated by ";" in format "kind[:probability][@urlRegex]", e.g. "503:0.1;wrongid@typ=S;slowdrip:0.5@id=123"
+func ParseFaultRules(spec string) ([]FaultRule, error) {
+	rules := []FaultRule{}
+
+	for rawRule := range strings.SplitSeq(spec, ";") {
+		rawRule = strings.TrimSpace(rawRule)
+		if rawRule == "" {
+			continue
+		}
+
+		rule := FaultRule{
+			Probability: 1,
+		}
+
+		rawRule, rawPattern, hasPattern := strings.Cut(rawRule, "@")
+		if hasPattern {
+			var err error
+			if rule.URLPattern, err = regexp.Compile(strings.TrimSpace(rawPattern)); err != nil {
+				return nil, fmt.Errorf("invalid fault url pattern %q: %w", rawPattern, err)
+			}
+		}
+
+		rawKind, rawProbability, hasProbability := strings.Cut(rawRule, ":")
+		rule.Kind = FaultKind(strings.ToLower(strings.TrimSpace(rawKind)))
+		if !rule.Kind.IsValid() {
+			return nil, fmt.Errorf("invalid fault kind: %s", rawKind)
+		}
+
+		if hasProbability {
+			var err error
+			if rule.Probability, err = strconv.ParseFloat(strings.TrimSpace(rawProbability), 64); err != nil || rule.Probability < 0 || rule.Probability > 1 {
+				return nil, fmt.Errorf("invalid fault probability: %s", rawProbability)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// first matching rule wins, at most one fault is applied per request
+func pickFault(rules []FaultRule, targetUrl string) FaultKind {
+	for _, rule := range rules {
+		if rule.URLPattern != nil && !rule.URLPattern.MatchString(targetUrl) {
+			continue
+		}
+
+		if rule.Probability >= 1 || rand.Float64() < rule.Probability {
+			return rule.Kind
+		}
+	}
+
+	return ""
+}
+
+func makeStatusFaultResponse(req *http.Request, kind FaultKind) *http.Response {
+	statusCode := kind.statusCode()
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	if statusCode == http.StatusTooManyRequests {
+		header.Set("Retry-After", "5")
+	}
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(http.StatusText(statusCode))),
+		Request:    req,
+	}
+}
+
+var rootScheduleIdRegex = regexp.MustCompile(`(<plan-zajec\b[^>]*\sid=")(\d+)(")`)
+
+func applyBodyFault(ctx context.Context, kind FaultKind, body io.ReadCloser) (io.ReadCloser, error) {
+	if kind == FaultKindSlowDrip {
+		return &slowDripReader{
+			ctx:  ctx,
+			body: body,
+		}, nil
+	}
+
+	defer body.Close()
+	buff, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case FaultKindReset:
+		return io.NopCloser(io.MultiReader(bytes.NewReader(buff[:len(buff)/2]), &errorReader{
+			err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		})), nil
+	case FaultKindTruncate:
+		buff = buff[:len(buff)/2]
+	case FaultKindMalformed:
+		if closingTagIndex := bytes.LastIndex(buff, []byte("</plan-zajec>")); closingTagIndex > -1 {
+			buff = append(buff[:closingTagIndex:closingTagIndex], "</plan-zajecia>"...)
+		} else {
+			buff = append([]byte("<<"), buff...)
+		}
+	case FaultKindWrongId:
+		buff = rootScheduleIdRegex.ReplaceAllFunc(buff, func(match []byte) []byte {
+			submatches := rootScheduleIdRegex.FindSubmatch(match)
+			id, _ := strconv.Atoi(string(submatches[2]))
+			return fmt.Appendf(nil, "%s%d%s", submatches[1], id+1, submatches[3])
+		})
+	}
+
+	return io.NopCloser(bytes.NewReader(buff)), nil
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(_ []byte) (int, error) {
+	return 0, r.err
+}
+
+type slowDripReader struct {
+	ctx  context.Context
+	body io.ReadCloser
+}
+
+func (r *slowDripReader) Read(p []byte) (int, error) {
+	const chunkSize = 512
+	const chunkInterval = 50 * time.Millisecond
+
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	case <-time.After(chunkInterval):
+	}
+
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+
+	return r.body.Read(p)
+}
+
+func (r *slowDripReader) Close() error {
+	return r.body.Close()
+}
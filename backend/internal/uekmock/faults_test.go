@@ -0,0 +1,63 @@
+package uekmock
+
+import (
+	"testing"
+)
+
+func TestParseFaultRules(t *testing.T) {
+	testCases := []struct {
+		spec          string
+		expectedRules []FaultRule
+		expectedErr   bool
+	}{
+		{spec: "", expectedRules: []FaultRule{}},
+		{spec: "503", expectedRules: []FaultRule{{Kind: FaultKindStatus503, Probability: 1}}},
+		{spec: " 503:0.1 ; WrongId@typ=S ;", expectedRules: []FaultRule{{Kind: FaultKindStatus503, Probability: 0.1}, {Kind: FaultKindWrongId, Probability: 1}}},
+		{spec: "slowdrip:0.5@id=123", expectedRules: []FaultRule{{Kind: FaultKindSlowDrip, Probability: 0.5}}},
+		{spec: "404", expectedErr: true},
+		{spec: "500:1.5", expectedErr: true},
+		{spec: "500:x", expectedErr: true},
+		{spec: "reset@(", expectedErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.spec, func(t *testing.T) {
+			rules, err := ParseFaultRules(testCase.spec)
+			if testCase.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", rules)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(rules) != len(testCase.expectedRules) {
+				t.Fatalf("expected %d rules, got %+v", len(testCase.expectedRules), rules)
+			}
+			for i, rule := range rules {
+				if rule.Kind != testCase.expectedRules[i].Kind || rule.Probability != testCase.expectedRules[i].Probability {
+					t.Errorf("rule %d: expected %+v, got %+v", i, testCase.expectedRules[i], rule)
+				}
+			}
+		})
+	}
+}
+
+func TestPickFault(t *testing.T) {
+	rules, err := ParseFaultRules("wrongid@typ=S;500:0;503")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kind := pickFault(rules, "https://planzajec.uek.krakow.pl/index.php?typ=S&id=1&okres=1&xml"); kind != FaultKindWrongId {
+		t.Errorf("expected the first matching rule, got %q", kind)
+	}
+	if kind := pickFault(rules, "https://planzajec.uek.krakow.pl/index.php?typ=G&id=1&okres=1&xml"); kind != FaultKindStatus503 {
+		t.Errorf("expected rules with 0 probability to be skipped, got %q", kind)
+	}
+	if kind := pickFault(nil, "https://planzajec.uek.krakow.pl/index.php?typ=G&xml"); kind != "" {
+		t.Errorf("expected no fault, got %q", kind)
+	}
+}
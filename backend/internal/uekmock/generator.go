@@ -0,0 +1,643 @@
+package uekmock
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GeneratorOptions struct {
+	Seed      uint64
+	Groups    int
+	Lecturers int
+	Rooms     int
+	Years     int
+	// first academic year to generate, e.g. 2025 for 2025/2026, defaults to the current one
+	StartYear int
+}
+
+type GeneratorResult struct {
+	Files    int
+	Classes  int
+	Sessions int
+}
+
+type generatorLecturer struct {
+	id     int
+	name   string
+	moodle string
+}
+
+type generatorRoom struct {
+	id       int
+	name     string
+	building string
+	isHall   bool
+}
+
+type generatorGroup struct {
+	id   int
+	name string
+}
+
+type generatorCohort struct {
+	grouping string
+	groups   []*generatorGroup
+	weekend  bool
+	parity   int
+}
+
+type generatorSession struct {
+	start     time.Time
+	end       time.Time
+	subject   string
+	typ       string
+	lecturers []*generatorLecturer
+	// nil for online sessions
+	room      *generatorRoom
+	onlineURL string
+	groups    []*generatorGroup
+	extra     string
+	weekday   time.Weekday
+	slot      int
+}
+
+type generatorOccupancyKey struct {
+	kind     byte
+	id       int
+	semester int
+	weekday  time.Weekday
+	slot     int
+	// set for one-off sessions, weekly classes are zero
+	date time.Time
+}
+
+type generator struct {
+	opts       GeneratorOptions
+	rng        *rand.Rand
+	lecturers  []*generatorLecturer
+	rooms      []*generatorRoom
+	halls      []*generatorRoom
+	cohorts    []*generatorCohort
+	sessions   []*generatorSession
+	occupied   map[generatorOccupancyKey]bool
+	classCount int
+}
+
+var generatorSlots = [][2]string{
+	{"08:00", "09:30"},
+	{"09:45", "11:15"},
+	{"11:30", "13:00"},
+	{"13:15", "14:45"},
+	{"15:00", "16:30"},
+	{"16:45", "18:15"},
+	{"18:30", "20:00"},
+}
+
+// Generate writes a consistent set of mock responses (groupings, periods, headers and group/lecturer/room schedules) into directoryPath
+func Generate(directoryPath string, opts GeneratorOptions) (*GeneratorResult, error) {
+	if opts.Groups <= 0 || opts.Lecturers <= 0 || opts.Rooms <= 0 {
+		return nil, fmt.Errorf("group, lecturer and room counts must be positive")
+	}
+	opts.Years = max(opts.Years, 1)
+	if opts.StartYear == 0 {
+		now := time.Now()
+		opts.StartYear = now.Year()
+		if now.Month() < time.September {
+			opts.StartYear--
+		}
+	}
+
+	g := &generator{
+		opts:     opts,
+		rng:      rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x5eed)),
+		occupied: map[generatorOccupancyKey]bool{},
+	}
+
+	g.generateLecturers()
+	g.generateRooms()
+	g.generateCohorts()
+	for yearOffset := range opts.Years {
+		g.generateAcademicYear(opts.StartYear+yearOffset, yearOffset*2)
+	}
+	slices.SortFunc(g.sessions, func(a, b *generatorSession) int {
+		return a.start.Compare(b.start)
+	})
+
+	if err := os.MkdirAll(directoryPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	result := &GeneratorResult{
+		Classes:  g.classCount,
+		Sessions: len(g.sessions),
+	}
+	if err := g.writeDocuments(directoryPath, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rng.IntN(len(values))]
+}
+
+func (g *generator) nextId(previousId int) int {
+	return previousId + 1 + g.rng.IntN(20)
+}
+
+func (g *generator) generateLecturers() {
+	usedNames := map[string]bool{}
+	id := 1000
+
+	for range g.opts.Lecturers {
+		var name string
+		for attempt := 0; name == "" || usedNames[name]; attempt++ {
+			firstName, lastName := g.pick(generatorFirstNames), g.pick(generatorLastNames)
+			if attempt > 10 {
+				lastName += "-" + g.pick(generatorLastNames)
+			}
+			if strings.HasSuffix(firstName, "a") {
+				lastName = feminizeLastName(lastName)
+			}
+			name = fmt.Sprintf("%s %s %s", g.pick(generatorTitles), firstName, lastName)
+		}
+		usedNames[name] = true
+
+		id = g.nextId(id)
+		lecturer := &generatorLecturer{
+			id:   id,
+			name: name,
+		}
+
+		// moodle ids come with or without a leading "-", and some lecturers have none
+		switch roll := g.rng.IntN(10); {
+		case roll < 6:
+			lecturer.moodle = "-" + strconv.Itoa(10000+g.rng.IntN(90000))
+		case roll < 9:
+			lecturer.moodle = strconv.Itoa(10000 + g.rng.IntN(90000))
+		}
+
+		g.lecturers = append(g.lecturers, lecturer)
+	}
+}
+
+func feminizeLastName(lastName string) string {
+	parts := strings.Split(lastName, "-")
+	for i, part := range parts {
+		if strings.HasSuffix(part, "ski") || strings.HasSuffix(part, "cki") || strings.HasSuffix(part, "dzki") {
+			parts[i] = strings.TrimSuffix(part, "i") + "a"
+		}
+	}
+
+	return strings.Join(parts, "-")
+}
+
+func (g *generator) generateRooms() {
+	roomNumbersByBuilding := map[string]int{}
+	id := 2000
+
+	for i := range g.opts.Rooms {
+		building := generatorBuildings[i%len(generatorBuildings)]
+		roomNumbersByBuilding[building]++
+		roomNumber := roomNumbersByBuilding[building]
+
+		id = g.nextId(id)
+		room := &generatorRoom{
+			id:       id,
+			building: building,
+			isHall:   roomNumber%8 == 1,
+		}
+		if room.isHall {
+			room.name = fmt.Sprintf("%s aula %d", building, roomNumber/8+1)
+		} else {
+			room.name = fmt.Sprintf("%s %d%02d", building, roomNumber/10, roomNumber%10*3+1)
+		}
+
+		g.rooms = append(g.rooms, room)
+		if room.isHall {
+			g.halls = append(g.halls, room)
+		}
+	}
+}
+
+func (g *generator) generateCohorts() {
+	type cohortTemplate struct {
+		fieldIndex int
+		partTime   bool
+		stage      int
+		year       int
+	}
+
+	templates := []cohortTemplate{}
+	for fieldIndex := range generatorFieldsOfStudy {
+		for _, partTime := range []bool{false, true} {
+			// 3 years of bachelor's and 2 years of master's studies
+			for _, stageAndYear := range [][2]int{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {2, 2}} {
+				templates = append(templates, cohortTemplate{fieldIndex, partTime, stageAndYear[0], stageAndYear[1]})
+			}
+		}
+	}
+	g.rng.Shuffle(len(templates), func(i, j int) {
+		templates[i], templates[j] = templates[j], templates[i]
+	})
+
+	cohortCount := min(len(templates), max(1, g.opts.Groups/4))
+	id := 100000
+
+	for i, template := range templates[:cohortCount] {
+		field := generatorFieldsOfStudy[template.fieldIndex]
+		mode := "S"
+		if template.partTime {
+			mode = "N"
+		}
+
+		cohort := &generatorCohort{
+			grouping: fmt.Sprintf("%s (%s%d)", field.name, mode, template.stage),
+			weekend:  template.partTime,
+			parity:   i % 2,
+		}
+
+		groupCount := g.opts.Groups / cohortCount
+		if i < g.opts.Groups%cohortCount {
+			groupCount++
+		}
+		for groupNumber := 1; groupNumber <= groupCount; groupNumber++ {
+			id = g.nextId(id)
+			cohort.groups = append(cohort.groups, &generatorGroup{
+				id:   id,
+				name: fmt.Sprintf("%s%s%d-%d%d%d", field.code, mode, template.stage, template.year, template.year*2-1, groupNumber),
+			})
+		}
+
+		g.cohorts = append(g.cohorts, cohort)
+	}
+}
+
+func (g *generator) generateAcademicYear(year int, semesterIndexOffset int) {
+	winterStart := firstWeekdayOnOrAfter(time.Date(year, time.October, 1, 0, 0, 0, 0, time.UTC), time.Monday)
+	summerStart := firstWeekdayOnOrAfter(time.Date(year+1, time.February, 24, 0, 0, 0, 0, time.UTC), time.Monday)
+
+	g.generateSemester(semesterIndexOffset, winterStart, time.Date(year+1, time.February, 2, 0, 0, 0, 0, time.UTC))
+	g.generateSemester(semesterIndexOffset+1, summerStart, time.Date(year+1, time.June, 16, 0, 0, 0, 0, time.UTC))
+}
+
+func firstWeekdayOnOrAfter(date time.Time, weekday time.Weekday) time.Time {
+	for date.Weekday() != weekday {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+func isGeneratorHoliday(date time.Time) bool {
+	return (date.Month() == time.December && date.Day() >= 22) || (date.Month() == time.January && date.Day() <= 6) || (date.Month() == time.November && (date.Day() == 1 || date.Day() == 11)) || (date.Month() == time.May && date.Day() <= 3)
+}
+
+func (g *generator) generateSemester(semesterIndex int, teachingStart time.Time, examSessionStart time.Time) {
+	const teachingWeeks = 15
+
+	for _, cohort := range g.cohorts {
+		subjects := slices.Clone(generatorSubjects)
+		g.rng.Shuffle(len(subjects), func(i, j int) {
+			subjects[i], subjects[j] = subjects[j], subjects[i]
+		})
+		subjects = subjects[:6+g.rng.IntN(3)]
+
+		for _, subject := range subjects {
+			lectureLecturer := g.lecturerForSubject(subject)
+			if lecture := g.scheduleClass(cohort, semesterIndex, subject, "wykład", cohort.groups, lectureLecturer, true); lecture != nil {
+				g.generateSessions(lecture, cohort, teachingStart, teachingWeeks, g.rng.IntN(3) == 0)
+
+				g.scheduleExam(lecture, cohort, semesterIndex, examSessionStart)
+			}
+
+			exerciseType := g.pick([]string{"ćwiczenia", "ćwiczenia", "laboratorium", "projekt", "konwersatorium", "ćwiczenia e-learningowe"})
+			for _, group := range cohort.groups {
+				if exercise := g.scheduleClass(cohort, semesterIndex, subject, exerciseType, []*generatorGroup{group}, g.lecturerForSubject(subject), false); exercise != nil {
+					g.generateSessions(exercise, cohort, teachingStart, teachingWeeks, false)
+				}
+			}
+		}
+
+		// official uek schedules contain such placeholder, actual language classes are scheduled in separate language groups
+		if placeholder := g.scheduleClass(cohort, semesterIndex, g.pick(generatorLanguages)+" - grupa przedmiotów", "lektorat", cohort.groups, nil, false); placeholder != nil {
+			g.generateSessions(placeholder, cohort, teachingStart, teachingWeeks, false)
+		}
+	}
+}
+
+func (g *generator) lecturerForSubject(subject string) *generatorLecturer {
+	// each subject is taught by a stable subset of lecturers
+	subjectIndex := slices.Index(generatorSubjects, subject)
+	poolSize := max(1, len(g.lecturers)/len(generatorSubjects))
+	return g.lecturers[(subjectIndex*poolSize+g.rng.IntN(poolSize*2))%len(g.lecturers)]
+}
+
+// returns a template shared by all sessions of a class, nil if no free slot was found
+func (g *generator) scheduleClass(cohort *generatorCohort, semesterIndex int, subject string, typ string, groups []*generatorGroup, lecturer *generatorLecturer, needsHall bool) *generatorSession {
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	if cohort.weekend {
+		weekdays = []time.Weekday{time.Saturday, time.Sunday}
+	}
+
+	isOnline := typ == "ćwiczenia e-learningowe" || (typ != "lektorat" && g.rng.IntN(100) < 8)
+	needsRoom := !isOnline && typ != "lektorat"
+
+	for range 40 {
+		class := &generatorSession{
+			subject: subject,
+			typ:     typ,
+			groups:  groups,
+			weekday: weekdays[g.rng.IntN(len(weekdays))],
+			slot:    g.rng.IntN(len(generatorSlots)),
+		}
+
+		if lecturer != nil {
+			class.lecturers = []*generatorLecturer{lecturer}
+		}
+
+		if needsRoom {
+			candidates := g.rooms
+			if needsHall && len(g.halls) > 0 {
+				candidates = g.halls
+			}
+			class.room = candidates[g.rng.IntN(len(candidates))]
+		} else if isOnline {
+			class.onlineURL = fmt.Sprintf("https://e-uczelnia.uek.krakow.pl/course/view.php?id=%d", 1000+g.rng.IntN(9000))
+		}
+
+		if !g.occupy(class, semesterIndex, time.Time{}) {
+			continue
+		}
+		g.classCount++
+
+		return class
+	}
+
+	return nil
+}
+
+// exams of a lecture take the same groups, lecturer and room on one day of the exam session, no exam is added if no free slot was found
+func (g *generator) scheduleExam(lecture *generatorSession, cohort *generatorCohort, semesterIndex int, examSessionStart time.Time) {
+	for range 40 {
+		examDate := examSessionStart.AddDate(0, 0, g.rng.IntN(12))
+		if !cohort.weekend && examDate.Weekday() == time.Sunday {
+			examDate = examDate.AddDate(0, 0, 1)
+		}
+
+		exam := *lecture
+		exam.typ = "egzamin"
+		exam.extra = ""
+		exam.weekday = examDate.Weekday()
+		exam.slot = g.rng.IntN(len(generatorSlots))
+
+		if g.occupy(&exam, semesterIndex, examDate) {
+			g.addSession(&exam, examDate)
+			return
+		}
+	}
+}
+
+// marks groups, lecturers and the room of the session as busy in its slot, false if any of them already is.
+// The date is zero for weekly classes
+func (g *generator) occupy(session *generatorSession, semesterIndex int, date time.Time) bool {
+	keys := make([]generatorOccupancyKey, 0, len(session.groups)+len(session.lecturers)+1)
+	for _, group := range session.groups {
+		keys = append(keys, generatorOccupancyKey{'G', group.id, semesterIndex, session.weekday, session.slot, date})
+	}
+	for _, lecturer := range session.lecturers {
+		keys = append(keys, generatorOccupancyKey{'N', lecturer.id, semesterIndex, session.weekday, session.slot, date})
+	}
+	if session.room != nil {
+		keys = append(keys, generatorOccupancyKey{'S', session.room.id, semesterIndex, session.weekday, session.slot, date})
+	}
+
+	if slices.ContainsFunc(keys, func(key generatorOccupancyKey) bool {
+		return g.occupied[key]
+	}) {
+		return false
+	}
+
+	for _, key := range keys {
+		g.occupied[key] = true
+	}
+
+	return true
+}
+
+func (g *generator) generateSessions(class *generatorSession, cohort *generatorCohort, teachingStart time.Time, teachingWeeks int, biweekly bool) {
+	for week := range teachingWeeks {
+		// part-time studies have classes every other weekend
+		if (cohort.weekend && week%2 != cohort.parity) || (biweekly && week%2 == 1) {
+			continue
+		}
+
+		date := teachingStart.AddDate(0, 0, week*7+(int(class.weekday)+6)%7)
+		if isGeneratorHoliday(date) {
+			continue
+		}
+
+		session := *class
+		if g.rng.IntN(100) < 4 {
+			session.extra = g.pick(generatorExtras)
+		}
+		g.addSession(&session, date)
+	}
+}
+
+func (g *generator) addSession(session *generatorSession, date time.Time) {
+	slot := generatorSlots[session.slot]
+	session.start = parseGeneratorTime(date, slot[0])
+	session.end = parseGeneratorTime(date, slot[1])
+	g.sessions = append(g.sessions, session)
+}
+
+func parseGeneratorTime(date time.Time, hourAndMinute string) time.Time {
+	t, _ := time.Parse("15:04", hourAndMinute)
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+type generatorPeriod struct {
+	start time.Time
+	end   time.Time
+}
+
+func (g *generator) periods() []generatorPeriod {
+	periods := make([]generatorPeriod, 0, g.opts.Years*3)
+	for yearOffset := range g.opts.Years {
+		year := g.opts.StartYear + yearOffset
+		periods = append(periods,
+			generatorPeriod{time.Date(year, time.October, 1, 0, 0, 0, 0, time.UTC), time.Date(year+1, time.February, 20, 0, 0, 0, 0, time.UTC)},
+			generatorPeriod{time.Date(year+1, time.February, 21, 0, 0, 0, 0, time.UTC), time.Date(year+1, time.September, 30, 0, 0, 0, 0, time.UTC)},
+			generatorPeriod{time.Date(year, time.October, 1, 0, 0, 0, 0, time.UTC), time.Date(year+1, time.September, 30, 0, 0, 0, 0, time.UTC)},
+		)
+	}
+
+	return periods
+}
+
+func (g *generator) writeDocuments(directoryPath string, result *GeneratorResult) error {
+	periods := g.periods()
+	documentPeriods := make([]documentPeriod, 0, len(periods))
+	for _, period := range periods {
+		documentPeriods = append(documentPeriods, documentPeriod{
+			Od: period.start.Format("2006-01-02"),
+			Do: period.end.Format("2006-01-02"),
+		})
+	}
+
+	write := func(query url.Values, doc *document) error {
+		if err := writeDocument(getMockResponseFilePathFromQuery(directoryPath, query), doc); err != nil {
+			return fmt.Errorf("failed to write document for %s: %w", query.Encode(), err)
+		}
+		result.Files++
+		return nil
+	}
+
+	// groupings and periods
+	groupingsDoc := &document{
+		Okres: documentPeriods,
+	}
+	groupHeadersByGrouping := map[string][]documentResource{}
+	roomHeadersByBuilding := map[string][]documentResource{}
+	for _, cohort := range g.cohorts {
+		groupingsDoc.Grupowanie = append(groupingsDoc.Grupowanie, documentGrouping{Typ: "G", Grupa: cohort.grouping})
+		for _, group := range cohort.groups {
+			groupHeadersByGrouping[cohort.grouping] = append(groupHeadersByGrouping[cohort.grouping], documentResource{Typ: "G", Id: strconv.Itoa(group.id), Nazwa: group.name})
+		}
+	}
+	for _, room := range g.rooms {
+		if _, ok := roomHeadersByBuilding[room.building]; !ok {
+			groupingsDoc.Grupowanie = append(groupingsDoc.Grupowanie, documentGrouping{Typ: "S", Grupa: room.building})
+		}
+		roomHeadersByBuilding[room.building] = append(roomHeadersByBuilding[room.building], documentResource{Typ: "S", Id: strconv.Itoa(room.id), Nazwa: room.name})
+	}
+	if err := write(url.Values{"okres": {"1"}}, groupingsDoc); err != nil {
+		return err
+	}
+
+	// headers
+	for grouping, headers := range groupHeadersByGrouping {
+		if err := write(url.Values{"typ": {"G"}, "grupa": {grouping}}, &document{Typ: "G", Grupa: grouping, Zasob: headers}); err != nil {
+			return err
+		}
+	}
+	for building, headers := range roomHeadersByBuilding {
+		if err := write(url.Values{"typ": {"S"}, "grupa": {building}}, &document{Typ: "S", Grupa: building, Zasob: headers}); err != nil {
+			return err
+		}
+	}
+	lecturerHeaders := make([]documentResource, 0, len(g.lecturers))
+	for _, lecturer := range g.lecturers {
+		lecturerHeaders = append(lecturerHeaders, documentResource{Typ: "N", Id: strconv.Itoa(lecturer.id), Nazwa: lecturer.name})
+	}
+	if err := write(url.Values{"typ": {"N"}}, &document{Typ: "N", Zasob: lecturerHeaders}); err != nil {
+		return err
+	}
+
+	// schedules
+	sessionsByGroup := map[*generatorGroup][]*generatorSession{}
+	sessionsByLecturer := map[*generatorLecturer][]*generatorSession{}
+	sessionsByRoom := map[*generatorRoom][]*generatorSession{}
+	for _, session := range g.sessions {
+		for _, group := range session.groups {
+			sessionsByGroup[group] = append(sessionsByGroup[group], session)
+		}
+		for _, lecturer := range session.lecturers {
+			sessionsByLecturer[lecturer] = append(sessionsByLecturer[lecturer], session)
+		}
+		if session.room != nil {
+			sessionsByRoom[session.room] = append(sessionsByRoom[session.room], session)
+		}
+	}
+
+	writeSchedule := func(typ string, id int, name string, idcel string, sessions []*generatorSession) error {
+		for periodIndex, period := range periods {
+			doc := &document{
+				Typ:   typ,
+				Id:    strconv.Itoa(id),
+				Idcel: idcel,
+				Nazwa: name,
+				Okres: documentPeriods,
+			}
+
+			for _, session := range sessions {
+				if session.start.Before(period.start) || !session.start.Before(period.end.AddDate(0, 0, 1)) {
+					continue
+				}
+				doc.Zajecia = append(doc.Zajecia, session.documentItem(typ))
+			}
+
+			if err := write(url.Values{"typ": {typ}, "id": {strconv.Itoa(id)}, "okres": {strconv.Itoa(periodIndex + 1)}}, doc); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, cohort := range g.cohorts {
+		for _, group := range cohort.groups {
+			if err := writeSchedule("G", group.id, group.name, "", sessionsByGroup[group]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, lecturer := range g.lecturers {
+		if err := writeSchedule("N", lecturer.id, lecturer.name, lecturer.moodle, sessionsByLecturer[lecturer]); err != nil {
+			return err
+		}
+	}
+	for _, room := range g.rooms {
+		if err := writeSchedule("S", room.id, room.name, "", sessionsByRoom[room]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (session *generatorSession) documentItem(scheduleTyp string) documentItem {
+	item := documentItem{
+		Termin:    session.start.Format("2006-01-02"),
+		Dzien:     generatorWeekdayNames[session.start.Weekday()],
+		OdGodz:    session.start.Format("15:04"),
+		DoGodz:    fmt.Sprintf("%s (%dg.)", session.end.Format("15:04"), int(session.end.Sub(session.start).Minutes())/45),
+		Przedmiot: session.subject,
+		Typ:       session.typ,
+		Uwagi:     session.extra,
+	}
+
+	if scheduleTyp != "N" {
+		for _, lecturer := range session.lecturers {
+			item.Nauczyciel = append(item.Nauczyciel, documentLecturer{
+				Moodle: lecturer.moodle,
+				Nazwa:  lecturer.name,
+			})
+		}
+	}
+
+	if scheduleTyp != "S" {
+		if session.room != nil {
+			item.Sala = session.room.name
+		} else if session.onlineURL != "" {
+			item.Sala = fmt.Sprintf(`<a href="%s">Platforma Moodle</a>`, session.onlineURL)
+		}
+	}
+
+	if scheduleTyp != "G" {
+		groupNames := make([]string, 0, len(session.groups))
+		for _, group := range session.groups {
+			groupNames = append(groupNames, group.name)
+		}
+		item.Grupa = strings.Join(groupNames, ", ")
+	}
+
+	return item
+}
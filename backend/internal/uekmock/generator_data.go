@@ -0,0 +1,128 @@
+package uekmock
+
+var generatorFieldsOfStudy = []struct {
+	name string
+	code string
+}{
+	{"Administracja", "ADMI"},
+	{"Analityka Gospodarcza", "ANGO"},
+	{"Zarządzanie", "ZARZ"},
+	{"Ekonomia", "EKON"},
+	{"Finanse i Rachunkowość", "FIRA"},
+	{"Informatyka Stosowana", "INFS"},
+	{"Gospodarka Przestrzenna", "GOPR"},
+	{"Stosunki Międzynarodowe", "STMI"},
+	{"Towaroznawstwo", "TOWA"},
+	{"Rachunkowość i Controlling", "RACO"},
+	{"Zarządzanie Publiczne", "ZAPU"},
+	{"Ekonomia Cyfrowa", "EKCY"},
+	{"Prawo w Biznesie", "PRBI"},
+	{"Logistyka", "LOGI"},
+	{"Turystyka i Rekreacja", "TURE"},
+	{"Marketing i Komunikacja Rynkowa", "MAKR"},
+	{"Inżynieria Jakości", "INJA"},
+	{"Zarządzanie Zasobami Ludzkimi", "ZAZL"},
+	{"Rynki Finansowe", "RYFI"},
+	{"Ekonomia Społeczna", "EKSP"},
+}
+
+var generatorSubjects = []string{
+	"Mikroekonomia",
+	"Makroekonomia",
+	"Matematyka",
+	"Statystyka opisowa",
+	"Ekonometria",
+	"Podstawy zarządzania",
+	"Prawo gospodarcze",
+	"Rachunkowość finansowa",
+	"Finanse przedsiębiorstw",
+	"Marketing",
+	"Bazy danych",
+	"Programowanie obiektowe",
+	"Algorytmy i struktury danych",
+	"Systemy operacyjne",
+	"Sieci komputerowe",
+	"Inżynieria oprogramowania",
+	"Analiza danych",
+	"Badania operacyjne",
+	"Historia myśli ekonomicznej",
+	"Socjologia",
+	"Psychologia w zarządzaniu",
+	"Etyka biznesu",
+	"Polityka gospodarcza",
+	"Międzynarodowe stosunki gospodarcze",
+	"Logistyka w przedsiębiorstwie",
+	"Zarządzanie projektami",
+	"Controlling",
+	"Rynki finansowe",
+	"Bankowość",
+	"Ubezpieczenia",
+	"Prawo cywilne",
+	"Prawo administracyjne",
+	"Geografia ekonomiczna",
+	"Towaroznawstwo ogólne",
+	"Zarządzanie jakością",
+	"Ochrona własności intelektualnej",
+	"Technologie informacyjne",
+	"Seminarium dyplomowe",
+	"Wychowanie fizyczne",
+	"Metody ilościowe w ekonomii",
+}
+
+var generatorLanguages = []string{
+	"Język angielski",
+	"Język niemiecki",
+	"Język hiszpański",
+	"Język francuski",
+	"Język rosyjski",
+	"Język włoski",
+}
+
+var generatorFirstNames = []string{
+	"Anna", "Maria", "Katarzyna", "Małgorzata", "Agnieszka", "Barbara", "Ewa", "Krystyna", "Elżbieta", "Magdalena",
+	"Joanna", "Aleksandra", "Monika", "Zofia", "Dorota", "Beata", "Jadwiga", "Justyna", "Urszula", "Paulina",
+	"Piotr", "Krzysztof", "Andrzej", "Tomasz", "Paweł", "Jan", "Michał", "Marcin", "Stanisław", "Jakub",
+	"Adam", "Marek", "Łukasz", "Grzegorz", "Mateusz", "Wojciech", "Mariusz", "Dariusz", "Zbigniew", "Jerzy",
+}
+
+var generatorLastNames = []string{
+	"Nowak", "Kowalski", "Wiśniewski", "Wójcik", "Kowalczyk", "Kamiński", "Lewandowski", "Zieliński", "Szymański", "Woźniak",
+	"Dąbrowski", "Kozłowski", "Jankowski", "Mazur", "Wojciechowski", "Kwiatkowski", "Krawczyk", "Kaczmarek", "Piotrowski", "Grabowski",
+	"Zając", "Pawłowski", "Michalski", "Król", "Wieczorek", "Jabłoński", "Wróbel", "Nowakowski", "Majewski", "Olszewski",
+	"Stępień", "Malinowski", "Jaworski", "Adamczyk", "Dudek", "Nowicki", "Pawlak", "Górski", "Witkowski", "Walczak",
+}
+
+var generatorTitles = []string{
+	"mgr",
+	"dr",
+	"dr",
+	"dr",
+	"dr hab.",
+	"dr hab., prof. UEK",
+	"prof. dr hab.",
+}
+
+var generatorBuildings = []string{
+	"Paw.A",
+	"Paw.B",
+	"Paw.C",
+	"Paw.D",
+	"Paw.E",
+	"Paw.F",
+	"Paw.G",
+	"Paw.H",
+	"Bud.Gł.",
+	"Ćwiczeniówka",
+	"Paw.Sportowy",
+}
+
+var generatorExtras = []string{
+	"Zajęcia odwołane",
+	"Zmiana sali",
+	"Termin dodatkowy",
+	"Zajęcia przeniesione z innego terminu",
+	"Kolokwium",
+	"Zajęcia w formie zdalnej - szczegóły na Moodle",
+}
+
+var generatorWeekdayNames = []string{"Nd", "Pn", "Wt", "Śr", "Cz", "Pt", "Sb"}
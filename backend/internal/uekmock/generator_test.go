@@ -0,0 +1,168 @@
+package uekmock
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	opts := GeneratorOptions{Seed: 7, Groups: 4, Lecturers: 8, Rooms: 4, StartYear: 2026}
+	firstDirectoryPath, secondDirectoryPath := t.TempDir(), t.TempDir()
+
+	firstResult, err := Generate(firstDirectoryPath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondResult, err := Generate(secondDirectoryPath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *firstResult != *secondResult {
+		t.Fatalf("results differ: %+v, %+v", firstResult, secondResult)
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join(firstDirectoryPath, "*.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileNames) != firstResult.Files {
+		t.Fatalf("expected %d files, found %d", firstResult.Files, len(fileNames))
+	}
+	for _, fileName := range fileNames {
+		first, _ := os.ReadFile(fileName)
+		second, err := os.ReadFile(filepath.Join(secondDirectoryPath, filepath.Base(fileName)))
+		if err != nil || !bytes.Equal(first, second) {
+			t.Fatalf("%s differs between runs", filepath.Base(fileName))
+		}
+	}
+}
+
+// every class in a group schedule must show up in the schedules of its lecturers and its room
+func TestGenerateIsConsistent(t *testing.T) {
+	directoryPath := t.TempDir()
+	result, err := Generate(directoryPath, GeneratorOptions{Seed: 1, Groups: 8, Lecturers: 12, Rooms: 6, StartYear: 2026})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Classes == 0 || result.Sessions == 0 {
+		t.Fatalf("nothing was generated: %+v", result)
+	}
+
+	documents := map[string]*document{}
+	read := func(query url.Values) *document {
+		t.Helper()
+		filePath := getMockResponseFilePathFromQuery(directoryPath, query)
+		if doc, ok := documents[filePath]; ok {
+			return doc
+		}
+
+		doc, err := readDocument(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		documents[filePath] = doc
+		return doc
+	}
+	const yearPeriod = "3"
+	sameClass := func(a documentItem) func(documentItem) bool {
+		return func(b documentItem) bool {
+			return a.Termin == b.Termin && a.OdGodz == b.OdGodz && a.Przedmiot == b.Przedmiot && a.Typ == b.Typ
+		}
+	}
+
+	groupings := read(url.Values{"okres": {"1"}})
+	if len(groupings.Okres) != 3 {
+		t.Fatalf("expected 3 periods, got %d", len(groupings.Okres))
+	}
+
+	lecturerIds := map[string]string{}
+	for _, header := range read(url.Values{"typ": {"N"}}).Zasob {
+		lecturerIds[header.Nazwa] = header.Id
+	}
+	roomIds := map[string]string{}
+	for _, grouping := range groupings.Grupowanie {
+		if grouping.Typ == "S" {
+			for _, header := range read(url.Values{"typ": {"S"}, "grupa": {grouping.Grupa}}).Zasob {
+				roomIds[header.Nazwa] = header.Id
+			}
+		}
+	}
+
+	checkedItems := 0
+	for _, grouping := range groupings.Grupowanie {
+		if grouping.Typ != "G" {
+			continue
+		}
+
+		for _, header := range read(url.Values{"typ": {"G"}, "grupa": {grouping.Grupa}}).Zasob {
+			schedule := read(url.Values{"typ": {"G"}, "id": {header.Id}, "okres": {yearPeriod}})
+			if schedule.Id != header.Id || schedule.Nazwa != header.Nazwa {
+				t.Fatalf("schedule %s doesn't match its header %+v", schedule.Id, header)
+			}
+
+			for _, item := range schedule.Zajecia {
+				for _, lecturer := range item.Nauczyciel {
+					lecturerSchedule := read(url.Values{"typ": {"N"}, "id": {lecturerIds[lecturer.Nazwa]}, "okres": {yearPeriod}})
+					if lecturerSchedule.Idcel != lecturer.Moodle {
+						t.Errorf("moodle id of %s differs between schedules", lecturer.Nazwa)
+					}
+					if !slices.ContainsFunc(lecturerSchedule.Zajecia, sameClass(item)) {
+						t.Errorf("%s %s %s of %s is missing from the schedule of %s", item.Termin, item.OdGodz, item.Przedmiot, header.Nazwa, lecturer.Nazwa)
+					}
+				}
+
+				if item.RoomURL() == "" && item.RoomName() != "" {
+					roomSchedule := read(url.Values{"typ": {"S"}, "id": {roomIds[item.RoomName()]}, "okres": {yearPeriod}})
+					if !slices.ContainsFunc(roomSchedule.Zajecia, sameClass(item)) {
+						t.Errorf("%s %s %s of %s is missing from the schedule of %s", item.Termin, item.OdGodz, item.Przedmiot, header.Nazwa, item.RoomName())
+					}
+				}
+				checkedItems++
+			}
+		}
+	}
+	if checkedItems == 0 {
+		t.Fatal("no group schedule items were checked")
+	}
+}
+
+func TestGenerateAvoidsDoubleBooking(t *testing.T) {
+	directoryPath := t.TempDir()
+	if _, err := Generate(directoryPath, GeneratorOptions{Seed: 1, Groups: 8, Lecturers: 6, Rooms: 3, StartYear: 2026}); err != nil {
+		t.Fatal(err)
+	}
+
+	// year schedules of groups, lecturers and rooms
+	fileNames, err := filepath.Glob(filepath.Join(directoryPath, "*-3.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkedExams := 0
+	for _, fileName := range fileNames {
+		doc, err := readDocument(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the same class is listed once per group in lecturer and room schedules
+		classesByTime := map[string]documentItem{}
+		for _, item := range doc.Zajecia {
+			key := item.Termin + " " + item.OdGodz
+			if other, ok := classesByTime[key]; ok && (other.Przedmiot != item.Przedmiot || other.Typ != item.Typ || doc.Typ == "G") {
+				t.Errorf("%s: %s %s and %s %s overlap at %s", filepath.Base(fileName), other.Przedmiot, other.Typ, item.Przedmiot, item.Typ, key)
+			}
+			classesByTime[key] = item
+
+			if item.Typ == "egzamin" {
+				checkedExams++
+			}
+		}
+	}
+	if checkedExams == 0 {
+		t.Fatal("no exams were checked")
+	}
+}
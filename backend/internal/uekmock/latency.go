@@ -0,0 +1,88 @@
+package uekmock
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+type latencyDistribution string
+
+const (
+	latencyDistributionFixed       latencyDistribution = "fixed"
+	latencyDistributionUniform     latencyDistribution = "uniform"
+	latencyDistributionNormal      latencyDistribution = "normal"
+	latencyDistributionExponential latencyDistribution = "exp"
+)
+
+// Latency describes how long the mock waits before responding, e.g. "fixed:500ms", "uniform:100ms-2s", "normal:500ms,150ms" (mean, stddev) or "exp:300ms" (mean)
+type Latency struct {
+	distribution latencyDistribution
+	a            time.Duration
+	b            time.Duration
+}
+
+func ParseLatency(spec string) (*Latency, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	distribution, params, hasDistribution := strings.Cut(spec, ":")
+	if !hasDistribution {
+		distribution, params = string(latencyDistributionFixed), spec
+	}
+
+	l := &Latency{
+		distribution: latencyDistribution(strings.TrimSpace(distribution)),
+	}
+
+	var err error
+	switch l.distribution {
+	case latencyDistributionFixed, latencyDistributionExponential:
+		if l.a, err = time.ParseDuration(strings.TrimSpace(params)); err != nil {
+			return nil, fmt.Errorf("invalid latency duration: %w", err)
+		}
+	case latencyDistributionUniform, latencyDistributionNormal:
+		separator := "-"
+		if l.distribution == latencyDistributionNormal {
+			separator = ","
+		}
+
+		rawA, rawB, ok := strings.Cut(params, separator)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s latency params: %s", l.distribution, params)
+		}
+		if l.a, err = time.ParseDuration(strings.TrimSpace(rawA)); err != nil {
+			return nil, fmt.Errorf("invalid latency duration: %w", err)
+		}
+		if l.b, err = time.ParseDuration(strings.TrimSpace(rawB)); err != nil {
+			return nil, fmt.Errorf("invalid latency duration: %w", err)
+		}
+		if l.distribution == latencyDistributionUniform && l.b < l.a {
+			return nil, fmt.Errorf("invalid uniform latency range: %s", params)
+		}
+	default:
+		return nil, fmt.Errorf("unknown latency distribution: %s", l.distribution)
+	}
+
+	return l, nil
+}
+
+func (l *Latency) sample() time.Duration {
+	var d time.Duration
+	switch l.distribution {
+	case latencyDistributionFixed:
+		d = l.a
+	case latencyDistributionUniform:
+		d = l.a + time.Duration(rand.Int64N(int64(l.b-l.a)+1))
+	case latencyDistributionNormal:
+		d = l.a + time.Duration(rand.NormFloat64()*float64(l.b))
+	case latencyDistributionExponential:
+		d = time.Duration(math.Round(rand.ExpFloat64() * float64(l.a)))
+	}
+
+	return max(d, 0)
+}
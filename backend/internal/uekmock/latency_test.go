@@ -0,0 +1,59 @@
+package uekmock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLatency(t *testing.T) {
+	testCases := []struct {
+		spec        string
+		expected    *Latency
+		expectedErr bool
+	}{
+		{spec: "", expected: nil},
+		{spec: "500ms", expected: &Latency{distribution: latencyDistributionFixed, a: 500 * time.Millisecond}},
+		{spec: "fixed:1s", expected: &Latency{distribution: latencyDistributionFixed, a: time.Second}},
+		{spec: "uniform: 100ms - 2s", expected: &Latency{distribution: latencyDistributionUniform, a: 100 * time.Millisecond, b: 2 * time.Second}},
+		{spec: "normal:500ms,150ms", expected: &Latency{distribution: latencyDistributionNormal, a: 500 * time.Millisecond, b: 150 * time.Millisecond}},
+		{spec: "exp:300ms", expected: &Latency{distribution: latencyDistributionExponential, a: 300 * time.Millisecond}},
+		{spec: "uniform:2s-100ms", expectedErr: true},
+		{spec: "uniform:100ms", expectedErr: true},
+		{spec: "normal:500ms-150ms", expectedErr: true},
+		{spec: "fixed:soon", expectedErr: true},
+		{spec: "poisson:1s", expectedErr: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.spec, func(t *testing.T) {
+			latency, err := ParseLatency(testCase.spec)
+			if testCase.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", latency)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if (latency == nil) != (testCase.expected == nil) || (latency != nil && *latency != *testCase.expected) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, latency)
+			}
+		})
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	uniform := &Latency{distribution: latencyDistributionUniform, a: 100 * time.Millisecond, b: 200 * time.Millisecond}
+	normal := &Latency{distribution: latencyDistributionNormal, a: 0, b: time.Second}
+
+	for range 1000 {
+		if d := uniform.sample(); d < uniform.a || d > uniform.b {
+			t.Fatalf("uniform sample %s out of range", d)
+		}
+		if d := normal.sample(); d < 0 {
+			t.Fatalf("negative normal sample %s", d)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package uekmock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	FaultsHeader  = "X-Mock-Faults"
+	LatencyHeader = "X-Mock-Latency"
+)
+
+type requestOverridesContextKey struct{}
+
+type requestOverrides struct {
+	faultRules []FaultRule
+	latency    *Latency
+}
+
+// WithRequestOverrides attaches fault rules and latency from X-Mock-* headers to ctx, so that they replace configured ones for upstream requests made with it
+func WithRequestOverrides(ctx context.Context, header http.Header) (context.Context, error) {
+	rawFaults, rawLatency := header.Get(FaultsHeader), header.Get(LatencyHeader)
+	if rawFaults == "" && rawLatency == "" {
+		return ctx, nil
+	}
+
+	overrides := &requestOverrides{}
+	var faultsErr, latencyErr error
+
+	if rawFaults != "" {
+		if overrides.faultRules, faultsErr = ParseFaultRules(rawFaults); faultsErr != nil {
+			faultsErr = fmt.Errorf("invalid %s header: %w", FaultsHeader, faultsErr)
+		}
+	}
+
+	if rawLatency != "" {
+		if overrides.latency, latencyErr = ParseLatency(rawLatency); latencyErr != nil {
+			latencyErr = fmt.Errorf("invalid %s header: %w", LatencyHeader, latencyErr)
+		}
+	}
+
+	if err := errors.Join(faultsErr, latencyErr); err != nil {
+		return ctx, err
+	}
+
+	return context.WithValue(ctx, requestOverridesContextKey{}, overrides), nil
+}
+
+func requestOverridesFromContext(ctx context.Context) *requestOverrides {
+	overrides, _ := ctx.Value(requestOverridesContextKey{}).(*requestOverrides)
+	return overrides
+}
@@ -1,10 +1,12 @@
 package uekmock
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/szczursonn/uek-planzajec-v3/internal/config"
@@ -12,29 +14,90 @@ import (
 
 type RoundTripper struct {
 	config.Mock
+	faultRules []FaultRule
+	latency    *Latency
+}
+
+func NewRoundTripper(cfg config.Mock) (*RoundTripper, error) {
+	t := &RoundTripper{
+		Mock: cfg,
+	}
+
+	var err error
+	if t.faultRules, err = ParseFaultRules(cfg.Faults); err != nil {
+		return nil, fmt.Errorf("failed to parse faults: %w", err)
+	}
+
+	if t.latency, err = ParseLatency(cfg.Latency); err != nil {
+		return nil, fmt.Errorf("failed to parse latency: %w", err)
+	}
+
+	return t, nil
 }
 
 func (t *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	faultRules, latency := t.faultRules, t.latency
+	if overrides := requestOverridesFromContext(req.Context()); overrides != nil {
+		if overrides.faultRules != nil {
+			faultRules = overrides.faultRules
+		}
+		if overrides.latency != nil {
+			latency = overrides.latency
+		}
+	}
+
+	delay := t.Delay
+	if latency != nil {
+		delay = latency.sample()
+	}
+
+	// a failing upstream is as slow to answer as a working one
+	fault := pickFault(faultRules, req.URL.String())
+	if fault.statusCode() != 0 {
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		return makeStatusFaultResponse(req, fault), nil
+	}
+
 	f, err := os.Open(getMockResponseFilePathFromQuery(t.DirectoryPath, req.URL.Query()))
 	if err != nil {
-		if t.Passthrough && strings.Contains(err.Error(), "cannot find") {
+		if t.Passthrough && errors.Is(err, fs.ErrNotExist) {
 			return http.DefaultTransport.RoundTrip(req)
 		}
 
 		return nil, fmt.Errorf("failed to open mock file: %w", err)
 	}
 
-	if t.Delay > 0 {
-		select {
-		case <-req.Context().Done():
-			return nil, req.Context().Err()
-		case <-time.After(t.Delay):
-		}
+	if err := sleep(req.Context(), delay); err != nil {
+		f.Close()
+		return nil, err
 	}
 
-	return &http.Response{
+	res := &http.Response{
 		StatusCode: http.StatusOK,
 		Header:     http.Header{},
 		Body:       f,
-	}, nil
+	}
+
+	if fault != "" {
+		if res.Body, err = applyBodyFault(req.Context(), fault, f); err != nil {
+			return nil, fmt.Errorf("failed to apply %s fault: %w", fault, err)
+		}
+	}
+
+	return res, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
 }
@@ -0,0 +1,53 @@
+package uekmock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+)
+
+func TestRoundTripperDelaysStatusFaults(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	for _, tc := range []struct {
+		name           string
+		cfg            config.Mock
+		expectedStatus int
+	}{
+		{name: "delay", cfg: config.Mock{Delay: delay, Faults: "503"}, expectedStatus: http.StatusServiceUnavailable},
+		{name: "latency profile", cfg: config.Mock{Latency: "fixed:100ms", Faults: "429"}, expectedStatus: http.StatusTooManyRequests},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.DirectoryPath = t.TempDir()
+			roundTripper, err := NewRoundTripper(tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			res, err := roundTripper.RoundTrip(httptest.NewRequest(http.MethodGet, "https://planzajec.uek.krakow.pl/index.php?typ=G&xml", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tc.expectedStatus {
+				t.Errorf("expected %d, got %d", tc.expectedStatus, res.StatusCode)
+			}
+			if elapsed := time.Since(start); elapsed < delay {
+				t.Errorf("expected the fault to be delayed by %s, responded after %s", delay, elapsed)
+			}
+
+			// cancelled while waiting
+			ctx, cancelCtx := context.WithTimeout(t.Context(), delay/10)
+			defer cancelCtx()
+			if _, err := roundTripper.RoundTrip(httptest.NewRequestWithContext(ctx, http.MethodGet, "https://planzajec.uek.krakow.pl/index.php?typ=G&xml", nil)); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected the deadline to be exceeded, got %v", err)
+			}
+		})
+	}
+}
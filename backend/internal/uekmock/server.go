@@ -0,0 +1,212 @@
+package uekmock
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+//go:embed server.html.tmpl
+var serverHTMLTemplateSource string
+
+var serverHTMLTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"inc": func(i int) int {
+		return i + 1
+	},
+}).Parse(serverHTMLTemplateSource))
+
+// Server imitates planzajec.uek.krakow.pl, serving mock responses over http
+type Server struct {
+	httpServer   http.Server
+	roundTripper *RoundTripper
+	debug        bool
+	logger       *slog.Logger
+}
+
+type serverHTMLPage struct {
+	Title         string
+	DirectoryPath string
+	Fixtures      []serverFixture
+	Document      *document
+}
+
+type serverFixture struct {
+	FileName string
+	Query    template.URL
+	Size     int64
+	ModTime  time.Time
+}
+
+func NewServer(addr string, roundTripper *RoundTripper, debug bool, logger *slog.Logger) *Server {
+	srv := &Server{
+		httpServer: http.Server{
+			Addr:              addr,
+			ReadHeaderTimeout: 15 * time.Second,
+			IdleTimeout:       time.Minute,
+			Handler:           http.NewServeMux(),
+			ErrorLog:          slog.NewLogLogger(logger.With(slog.String("source", "http.Server")).Handler(), slog.LevelError),
+		},
+		roundTripper: roundTripper,
+		debug:        debug,
+		logger:       logger,
+	}
+
+	mux := srv.httpServer.Handler.(*http.ServeMux)
+	mux.HandleFunc("GET /{$}", srv.handleFixtureList)
+	mux.HandleFunc("GET /index.php", srv.handleIndexPHP)
+
+	return srv
+}
+
+func (srv *Server) Run() error {
+	if err := srv.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (srv *Server) Shutdown(ctx context.Context) error {
+	return srv.httpServer.Shutdown(ctx)
+}
+
+func (srv *Server) handleFixtureList(w http.ResponseWriter, r *http.Request) {
+	dirEntries, err := os.ReadDir(srv.roundTripper.DirectoryPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		srv.logger.Error("Failed to read mock directory", slog.Any("err", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	fixtures := make([]serverFixture, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		query, ok := getQueryFromMockResponseFileName(dirEntry.Name())
+		if dirEntry.IsDir() || !ok {
+			continue
+		}
+
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+
+		fixtures = append(fixtures, serverFixture{
+			FileName: dirEntry.Name(),
+			Query:    template.URL(query.Encode()),
+			Size:     fileInfo.Size(),
+			ModTime:  fileInfo.ModTime(),
+		})
+	}
+	slices.SortFunc(fixtures, func(a, b serverFixture) int {
+		return strings.Compare(a.FileName, b.FileName)
+	})
+
+	srv.respondHTML(w, &serverHTMLPage{
+		Title:         fmt.Sprintf("Mock UEK - %d plików", len(fixtures)),
+		DirectoryPath: srv.roundTripper.DirectoryPath,
+		Fixtures:      fixtures,
+	})
+}
+
+func (srv *Server) handleIndexPHP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if srv.debug {
+		var err error
+		if ctx, err = WithRequestOverrides(ctx, r.Header); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	query := r.URL.Query()
+	wantsXML := query.Has("xml")
+	query.Set("xml", "")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uek.DefaultBaseUrl+"?"+query.Encode(), nil)
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	req.Header.Set("User-Agent", uek.UserAgent)
+
+	res, err := srv.roundTripper.RoundTrip(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			http.Error(w, "Not Found", http.StatusNotFound)
+		case ctx.Err() != nil:
+		default:
+			srv.logger.Error("Failed to get mock response", slog.String("query", r.URL.RawQuery), slog.Any("err", err))
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		}
+		return
+	}
+	defer res.Body.Close()
+
+	srv.logger.Debug("Serving mock response", slog.String("query", r.URL.RawQuery), slog.Int("statusCode", res.StatusCode), slog.Bool("xml", wantsXML))
+
+	if res.StatusCode != http.StatusOK {
+		if retryAfter := res.Header.Get("Retry-After"); retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		http.Error(w, http.StatusText(res.StatusCode), res.StatusCode)
+		return
+	}
+
+	if wantsXML {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, res.Body); err != nil {
+			// imitate a broken upstream connection instead of sending a clean end of response
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+
+	doc := &document{}
+	if err := xml.NewDecoder(res.Body).Decode(doc); err != nil {
+		srv.logger.Error("Failed to decode mock response", slog.String("query", r.URL.RawQuery), slog.Any("err", err))
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	title := doc.Nazwa
+	if title == "" {
+		title = doc.Grupa
+	}
+	if title == "" {
+		title = "Plan zajęć UEK"
+	}
+
+	srv.respondHTML(w, &serverHTMLPage{
+		Title:    title,
+		Document: doc,
+	})
+}
+
+func (srv *Server) respondHTML(w http.ResponseWriter, page *serverHTMLPage) {
+	buff := &bytes.Buffer{}
+	if err := serverHTMLTemplate.Execute(buff, page); err != nil {
+		srv.logger.Error("Failed to render html", slog.Any("err", err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buff.Bytes())
+}
@@ -0,0 +1,117 @@
+package uekmock
+
+import (
+	"encoding/xml"
+	"html"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+)
+
+func TestServer(t *testing.T) {
+	directoryPath := t.TempDir()
+	if _, err := Generate(directoryPath, GeneratorOptions{Seed: 1, Groups: 2, Lecturers: 4, Rooms: 2}); err != nil {
+		t.Fatal(err)
+	}
+	roundTripper, err := NewRoundTripper(config.Mock{DirectoryPath: directoryPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer("", roundTripper, false, slog.New(slog.DiscardHandler))
+	testServer := httptest.NewServer(srv.httpServer.Handler)
+	t.Cleanup(testServer.Close)
+
+	get := func(path string) (int, string, string) {
+		t.Helper()
+		res, err := http.Get(testServer.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, res.Header.Get("Content-Type"), string(body)
+	}
+
+	groupingsFileName, headersFileName, scheduleFileName := "_-_-_-1.xml", "", ""
+	fileNames, _ := filepath.Glob(filepath.Join(directoryPath, "G-*.xml"))
+	for _, fileName := range fileNames {
+		query, _ := getQueryFromMockResponseFileName(fileName)
+		switch {
+		case query.Has("grupa") && headersFileName == "":
+			headersFileName = filepath.Base(fileName)
+		case query.Has("id") && scheduleFileName == "":
+			scheduleFileName = filepath.Base(fileName)
+		}
+	}
+	if headersFileName == "" || scheduleFileName == "" {
+		t.Fatalf("expected headers and schedule fixtures, got %v", fileNames)
+	}
+	queryOf := func(fileName string) string {
+		query, _ := getQueryFromMockResponseFileName(fileName)
+		return query.Encode()
+	}
+
+	statusCode, contentType, body := get("/")
+	if statusCode != http.StatusOK || !strings.HasPrefix(contentType, "text/html") {
+		t.Fatalf("unexpected listing response: %d %s", statusCode, contentType)
+	}
+	for _, fileName := range []string{groupingsFileName, headersFileName, scheduleFileName} {
+		if !strings.Contains(body, fileName) || !strings.Contains(html.UnescapeString(body), `href="index.php?`+queryOf(fileName)+`"`) {
+			t.Errorf("expected %s to be listed", fileName)
+		}
+	}
+
+	for _, fileName := range []string{groupingsFileName, headersFileName, scheduleFileName} {
+		expected, err := os.ReadFile(filepath.Join(directoryPath, fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := &document{}
+		if err := xml.Unmarshal(expected, doc); err != nil {
+			t.Fatal(err)
+		}
+
+		statusCode, contentType, body := get("/index.php?" + queryOf(fileName) + "&xml")
+		if statusCode != http.StatusOK || !strings.HasPrefix(contentType, "text/xml") || body != string(expected) {
+			t.Errorf("%s: expected the fixture as xml, got %d %s", fileName, statusCode, contentType)
+		}
+
+		statusCode, contentType, body = get("/index.php?" + queryOf(fileName))
+		if statusCode != http.StatusOK || !strings.HasPrefix(contentType, "text/html") {
+			t.Fatalf("%s: unexpected html response: %d %s", fileName, statusCode, contentType)
+		}
+		// links lead to the next level
+		switch {
+		case len(doc.Grupowanie) > 0:
+			if !strings.Contains(body, "index.php?typ="+doc.Grupowanie[0].Typ+"&amp;grupa=") {
+				t.Errorf("%s: expected links to groupings", fileName)
+			}
+		case len(doc.Zasob) > 0:
+			if !strings.Contains(body, "index.php?typ="+doc.Zasob[0].Typ+"&amp;id="+doc.Zasob[0].Id+"&amp;") {
+				t.Errorf("%s: expected links to schedules", fileName)
+			}
+		default:
+			if !strings.Contains(body, "<title>"+template.HTMLEscapeString(doc.Nazwa)+"</title>") || len(doc.Zajecia) == 0 || !strings.Contains(body, template.HTMLEscapeString(doc.Zajecia[0].Przedmiot)) {
+				t.Errorf("%s: expected the schedule to be rendered", fileName)
+			}
+		}
+	}
+
+	// missing fixtures aren't proxied to UEK
+	for _, path := range []string{"/index.php?typ=G&id=999999&okres=1", "/index.php?typ=G&id=999999&okres=1&xml"} {
+		if statusCode, _, _ := get(path); statusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, statusCode)
+		}
+	}
+}
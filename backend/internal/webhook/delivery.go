@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	IdHeader        = "X-Webhook-Id"
+	TimestampHeader = "X-Webhook-Timestamp"
+	// hex encoded HMAC-SHA256 of "<timestamp>.<body>", prefixed with "sha256="
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+type PayloadType string
+
+const (
+	PayloadTypeScheduleChanged PayloadType = "schedule.changed"
+	PayloadTypePing            PayloadType = "ping"
+)
+
+type Payload struct {
+	Id             string           `json:"id"`
+	Type           PayloadType      `json:"type"`
+	SubscriptionId string           `json:"subscriptionId"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	Schedule       *PayloadSchedule `json:"schedule,omitempty"`
+	Events         []EventType      `json:"events,omitempty"`
+	Changes        *uek.ItemsDiff   `json:"changes,omitempty"`
+}
+
+type PayloadSchedule struct {
+	Type     uek.ScheduleType `json:"type"`
+	Id       int              `json:"id"`
+	Name     string           `json:"name"`
+	PeriodId int              `json:"periodId"`
+}
+
+type Delivery struct {
+	Id        string            `json:"id"`
+	Type      PayloadType       `json:"type"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Succeeded bool              `json:"succeeded"`
+	Attempts  []DeliveryAttempt `json:"attempts"`
+}
+
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Sign returns the value of the signature header
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature in constant time, receivers should also reject old timestamps
+func Verify(secret string, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Ping sends a test delivery to the subscription
+func (m *Manager) Ping(id string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := m.find(id)
+	if sub == nil {
+		return "", false
+	}
+
+	return m.enqueueDelivery(sub, Payload{
+		Type: PayloadTypePing,
+	}), true
+}
+
+// must be called with mu held
+func (m *Manager) enqueueDelivery(sub *Subscription, payload Payload) string {
+	payload.Id = uuid.NewString()
+	payload.SubscriptionId = sub.Id
+	payload.CreatedAt = time.Now()
+
+	delivery := &Delivery{
+		Id:        payload.Id,
+		Type:      payload.Type,
+		CreatedAt: payload.CreatedAt,
+		Attempts:  []DeliveryAttempt{},
+	}
+	sub.Deliveries = append(sub.Deliveries, delivery)
+	if len(sub.Deliveries) > maxDeliveryLogSize {
+		sub.Deliveries = sub.Deliveries[len(sub.Deliveries)-maxDeliveryLogSize:]
+	}
+	m.saveSubscriptions()
+
+	body, _ := json.Marshal(payload)
+	m.deliveriesWg.Add(1)
+	go func() {
+		defer m.deliveriesWg.Done()
+		m.deliver(sub.Id, sub.URL, sub.Secret, delivery, body)
+	}()
+
+	return delivery.Id
+}
+
+func (m *Manager) deliver(subscriptionId string, targetUrl string, secret string, delivery *Delivery, body []byte) {
+	logger := m.logger.With(slog.String("subscriptionId", subscriptionId), slog.String("deliveryId", delivery.Id))
+
+	backoff := m.cfg.RetryBackoff
+	for attemptNumber := 1; ; attemptNumber++ {
+		attempt, retryable := m.attemptDelivery(targetUrl, secret, delivery.Id, body)
+		// interrupted by Close, not the receiver's fault
+		if m.ctx.Err() != nil && (attempt.Error != "" || attempt.StatusCode >= 300) {
+			logger.Debug("Webhook delivery interrupted by shutdown", slog.Int("attempt", attemptNumber))
+			return
+		}
+
+		m.mu.Lock()
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		delivery.Succeeded = attempt.Error == "" && attempt.StatusCode < 300
+		done := delivery.Succeeded || !retryable || attemptNumber >= m.cfg.MaxAttempts
+		if done {
+			m.finishDelivery(subscriptionId, delivery, logger)
+		}
+		m.saveSubscriptions()
+		m.mu.Unlock()
+
+		if done {
+			return
+		}
+
+		logger.Debug("Webhook delivery failed, retrying", slog.Int("attempt", attemptNumber), slog.Int("statusCode", attempt.StatusCode), slog.String("err", attempt.Error))
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// must be called with mu held
+func (m *Manager) finishDelivery(subscriptionId string, delivery *Delivery, logger *slog.Logger) {
+	sub := m.find(subscriptionId)
+	if sub == nil {
+		return
+	}
+
+	if delivery.Succeeded {
+		sub.ConsecutiveFailures = 0
+		logger.Debug("Webhook delivered")
+		return
+	}
+
+	sub.ConsecutiveFailures++
+	logger.Warn("Webhook delivery failed", slog.Int("consecutiveFailures", sub.ConsecutiveFailures))
+	if m.cfg.DisableAfter > 0 && sub.ConsecutiveFailures >= m.cfg.DisableAfter && !sub.Disabled {
+		sub.Disabled = true
+		sub.DisabledReason = fmt.Sprintf("%d consecutive failed deliveries", sub.ConsecutiveFailures)
+		logger.Warn("Webhook subscription disabled", slog.String("reason", sub.DisabledReason))
+	}
+}
+
+func (m *Manager) attemptDelivery(targetUrl string, secret string, deliveryId string, body []byte) (DeliveryAttempt, bool) {
+	attempt := DeliveryAttempt{
+		At: time.Now(),
+	}
+
+	req, err := http.NewRequestWithContext(m.ctx, http.MethodPost, targetUrl, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+
+	timestamp := strconv.FormatInt(attempt.At.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", uek.UserAgent)
+	req.Header.Set(IdHeader, deliveryId)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	res, err := m.cfg.HttpClient.Do(req)
+	attempt.DurationMs = time.Since(attempt.At).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, m.ctx.Err() == nil
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	attempt.StatusCode = res.StatusCode
+	if res.StatusCode >= 300 {
+		attempt.Error = res.Status
+	}
+
+	// other client errors won't go away by retrying
+	retryable := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusRequestTimeout
+	return attempt, retryable
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"slices"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+type EventType string
+
+const (
+	EventTypeItemsAdded   EventType = "items.added"
+	EventTypeItemsRemoved EventType = "items.removed"
+	// same class with a different room, lecturers, groups or note
+	EventTypeItemsChanged EventType = "items.changed"
+)
+
+var EventTypes = []EventType{EventTypeItemsAdded, EventTypeItemsRemoved, EventTypeItemsChanged}
+
+func (et EventType) IsValid() bool {
+	return slices.Contains(EventTypes, et)
+}
+
+// keeps only the parts matching the filter, empty filter matches everything
+func filterChanges(d *uek.ItemsDiff, eventTypes []EventType) *uek.ItemsDiff {
+	if len(eventTypes) == 0 {
+		return d
+	}
+
+	filtered := &uek.ItemsDiff{}
+	if slices.Contains(eventTypes, EventTypeItemsAdded) {
+		filtered.Added = d.Added
+	}
+	if slices.Contains(eventTypes, EventTypeItemsRemoved) {
+		filtered.Removed = d.Removed
+	}
+	if slices.Contains(eventTypes, EventTypeItemsChanged) {
+		filtered.Changed = d.Changed
+	}
+
+	return filtered
+}
+
+func changeEventTypes(d *uek.ItemsDiff) []EventType {
+	eventTypes := []EventType{}
+	if len(d.Added) > 0 {
+		eventTypes = append(eventTypes, EventTypeItemsAdded)
+	}
+	if len(d.Removed) > 0 {
+		eventTypes = append(eventTypes, EventTypeItemsRemoved)
+	}
+	if len(d.Changed) > 0 {
+		eventTypes = append(eventTypes, EventTypeItemsChanged)
+	}
+
+	return eventTypes
+}
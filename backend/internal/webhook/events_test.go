@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+func TestFilterChanges(t *testing.T) {
+	item := &uek.ScheduleItem{Subject: "Matematyka", Type: "wykład"}
+	d := &uek.ItemsDiff{
+		Added:   []*uek.ScheduleItem{item},
+		Removed: []*uek.ScheduleItem{item},
+		Changed: []uek.ItemChange{{Before: item, After: item}},
+	}
+
+	filtered := filterChanges(d, []EventType{EventTypeItemsRemoved})
+	if len(filtered.Added) != 0 || len(filtered.Changed) != 0 || len(filtered.Removed) != 1 {
+		t.Errorf("expected only removals after filtering, got %+v", filtered)
+	}
+	if eventTypes := changeEventTypes(filtered); len(eventTypes) != 1 || eventTypes[0] != EventTypeItemsRemoved {
+		t.Errorf("expected only the removal event, got %v", eventTypes)
+	}
+
+	if unfiltered := filterChanges(d, nil); unfiltered != d {
+		t.Errorf("expected an empty filter to keep everything, got %+v", unfiltered)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"type":"ping"}`)
+	signature := Sign("secret", "1790000000", body)
+
+	if !Verify("secret", "1790000000", body, signature) {
+		t.Error("expected signature to verify")
+	}
+	if Verify("other", "1790000000", body, signature) {
+		t.Error("expected signature with a different secret to be rejected")
+	}
+	if Verify("secret", "1790000001", body, signature) {
+		t.Error("expected signature with a different timestamp to be rejected")
+	}
+}
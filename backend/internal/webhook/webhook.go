@@ -0,0 +1,380 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+	"github.com/szczursonn/uek-planzajec-v3/internal/snapshot"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	maxScheduleIdsPerSubscription = 20
+	// per subscription, older deliveries are dropped
+	maxDeliveryLogSize = 20
+)
+
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+type Config struct {
+	// directory for subscriptions and schedule snapshots
+	Path       string
+	Uek        *uek.Client
+	HttpClient *http.Client
+	// subscribed schedules are requested this often, so changes are noticed without user traffic
+	PollInterval time.Duration
+	MaxAttempts  int
+	// doubled after every failed attempt
+	RetryBackoff time.Duration
+	// consecutive failed deliveries after which a subscription is disabled
+	DisableAfter int
+	Logger       *slog.Logger
+}
+
+type Subscription struct {
+	Id           string           `json:"id"`
+	ScheduleType uek.ScheduleType `json:"scheduleType"`
+	ScheduleIds  []int            `json:"scheduleIds"`
+	// empty means every event
+	Events    []EventType `json:"events,omitempty"`
+	URL       string      `json:"url"`
+	Secret    string      `json:"secret,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Disabled  bool        `json:"disabled,omitempty"`
+	// why the subscription was disabled
+	DisabledReason      string      `json:"disabledReason,omitempty"`
+	ConsecutiveFailures int         `json:"consecutiveFailures,omitempty"`
+	Deliveries          []*Delivery `json:"deliveries,omitempty"`
+}
+
+type CreateSubscriptionRequest struct {
+	ScheduleType uek.ScheduleType `json:"scheduleType"`
+	ScheduleIds  []int            `json:"scheduleIds"`
+	Events       []EventType      `json:"events"`
+	URL          string           `json:"url"`
+	// generated when empty
+	Secret string `json:"secret"`
+}
+
+type Manager struct {
+	cfg            Config
+	logger         *slog.Logger
+	mu             sync.Mutex
+	subscriptions  []*Subscription
+	snapshots      *snapshot.Store
+	backgroundWg   sync.WaitGroup
+	deliveriesWg   sync.WaitGroup
+	ctx            context.Context
+	cancelCtx      context.CancelFunc
+	removeListener func()
+}
+
+func New(cfg Config) (*Manager, error) {
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+	cfg.MaxAttempts = max(cfg.MaxAttempts, 1)
+
+	m := &Manager{
+		cfg:           cfg,
+		logger:        cfg.Logger,
+		subscriptions: []*Subscription{},
+	}
+	if m.logger == nil {
+		m.logger = slog.Default()
+	}
+	m.snapshots = snapshot.New(snapshot.Config{
+		Path:   filepath.Join(cfg.Path, "snapshots"),
+		Uek:    cfg.Uek,
+		Logger: m.logger,
+	})
+
+	if err := jsonfile.Read(m.subscriptionsFilePath(), &m.subscriptions); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	m.ctx, m.cancelCtx = context.WithCancel(context.Background())
+	m.removeListener = cfg.Uek.OnScheduleFetched(func(fetch uek.ScheduleFetch) {
+		m.goBackground(func() {
+			m.handleScheduleFetch(fetch)
+		})
+	})
+	if cfg.PollInterval > 0 {
+		m.goBackground(m.pollWorker)
+	}
+
+	return m, nil
+}
+
+// Close stops polling and waits for pending deliveries to give up
+func (m *Manager) Close() {
+	m.removeListener()
+	m.mu.Lock()
+	m.cancelCtx()
+	m.mu.Unlock()
+	// background work can still enqueue deliveries
+	m.backgroundWg.Wait()
+	m.deliveriesWg.Wait()
+}
+
+// runs f unless the manager is closed, Close waits for it to return
+func (m *Manager) goBackground(f func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	m.backgroundWg.Add(1)
+	go func() {
+		defer m.backgroundWg.Done()
+		f()
+	}()
+}
+
+func (m *Manager) subscriptionsFilePath() string {
+	return filepath.Join(m.cfg.Path, "subscriptions.json")
+}
+
+// must be called with mu held
+func (m *Manager) saveSubscriptions() {
+	if err := jsonfile.Write(m.subscriptionsFilePath(), m.subscriptions); err != nil {
+		m.logger.Error("Failed to save subscriptions", slog.Any("err", err))
+	}
+}
+
+// secrets are only returned once, on creation
+func redacted(sub *Subscription) Subscription {
+	copied := *sub
+	copied.Secret = ""
+	// deliveries are updated in the background
+	copied.Deliveries = make([]*Delivery, 0, len(sub.Deliveries))
+	for _, delivery := range sub.Deliveries {
+		copiedDelivery := *delivery
+		copiedDelivery.Attempts = slices.Clone(delivery.Attempts)
+		copied.Deliveries = append(copied.Deliveries, &copiedDelivery)
+	}
+	return copied
+}
+
+func (m *Manager) List() []Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscriptions := make([]Subscription, 0, len(m.subscriptions))
+	for _, sub := range m.subscriptions {
+		subscriptions = append(subscriptions, redacted(sub))
+	}
+
+	return subscriptions
+}
+
+func (m *Manager) Get(id string) (Subscription, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sub := m.find(id); sub != nil {
+		return redacted(sub), true
+	}
+
+	return Subscription{}, false
+}
+
+// must be called with mu held
+func (m *Manager) find(id string) *Subscription {
+	index := slices.IndexFunc(m.subscriptions, func(sub *Subscription) bool {
+		return sub.Id == id
+	})
+	if index == -1 {
+		return nil
+	}
+
+	return m.subscriptions[index]
+}
+
+func (m *Manager) Create(req CreateSubscriptionRequest) (Subscription, error) {
+	if !req.ScheduleType.IsValid() {
+		return Subscription{}, fmt.Errorf("%w: invalid schedule type", ErrInvalidSubscription)
+	}
+	if len(req.ScheduleIds) == 0 || len(req.ScheduleIds) > maxScheduleIdsPerSubscription {
+		return Subscription{}, fmt.Errorf("%w: between 1 and %d schedule ids are required", ErrInvalidSubscription, maxScheduleIdsPerSubscription)
+	}
+	for _, eventType := range req.Events {
+		if !eventType.IsValid() {
+			return Subscription{}, fmt.Errorf("%w: unknown event %q", ErrInvalidSubscription, eventType)
+		}
+	}
+	targetUrl, err := url.Parse(req.URL)
+	if err != nil || (targetUrl.Scheme != "http" && targetUrl.Scheme != "https") || targetUrl.Host == "" {
+		return Subscription{}, fmt.Errorf("%w: url must be an absolute http(s) url", ErrInvalidSubscription)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = rand.Text()
+	}
+
+	sub := &Subscription{
+		Id:           uuid.NewString(),
+		ScheduleType: req.ScheduleType,
+		ScheduleIds:  slices.Compact(slices.Sorted(slices.Values(req.ScheduleIds))),
+		Events:       req.Events,
+		URL:          targetUrl.String(),
+		Secret:       secret,
+		CreatedAt:    time.Now(),
+	}
+
+	m.mu.Lock()
+	m.subscriptions = append(m.subscriptions, sub)
+	m.saveSubscriptions()
+	created := *sub
+	m.mu.Unlock()
+
+	m.logger.Info("Webhook subscription created", slog.String("id", sub.Id), slog.String("url", sub.URL))
+	// changes can only be detected once there is a snapshot to compare with
+	m.goBackground(func() {
+		m.pollSubscription(m.ctx, &created)
+	})
+
+	return created, nil
+}
+
+func (m *Manager) Delete(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lengthBefore := len(m.subscriptions)
+	m.subscriptions = slices.DeleteFunc(m.subscriptions, func(sub *Subscription) bool {
+		return sub.Id == id
+	})
+	if len(m.subscriptions) == lengthBefore {
+		return false
+	}
+
+	m.saveSubscriptions()
+	m.logger.Info("Webhook subscription deleted", slog.String("id", id))
+	return true
+}
+
+// Enable re-enables an automatically disabled subscription
+func (m *Manager) Enable(id string) (Subscription, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := m.find(id)
+	if sub == nil {
+		return Subscription{}, false
+	}
+
+	sub.Disabled, sub.DisabledReason, sub.ConsecutiveFailures = false, "", 0
+	m.saveSubscriptions()
+
+	return redacted(sub), true
+}
+
+// must be called with mu held
+func (m *Manager) matchingSubscriptions(scheduleType uek.ScheduleType, scheduleId int) []*Subscription {
+	matching := []*Subscription{}
+	for _, sub := range m.subscriptions {
+		if !sub.Disabled && sub.ScheduleType == scheduleType && slices.Contains(sub.ScheduleIds, scheduleId) {
+			matching = append(matching, sub)
+		}
+	}
+
+	return matching
+}
+
+func (m *Manager) handleScheduleFetch(fetch uek.ScheduleFetch) {
+	m.mu.Lock()
+	isSubscribed := len(m.matchingSubscriptions(fetch.ScheduleType, fetch.ScheduleId)) > 0
+	m.mu.Unlock()
+	if !isSubscribed {
+		return
+	}
+
+	d := m.snapshots.Update(m.ctx, fetch)
+	if d == nil || d.IsEmpty() {
+		return
+	}
+	m.logger.Info("Schedule change detected", slog.String("scheduleType", string(fetch.ScheduleType)), slog.Int("scheduleId", fetch.ScheduleId), slog.Int("periodId", fetch.PeriodId), slog.Int("added", len(d.Added)), slog.Int("removed", len(d.Removed)), slog.Int("changed", len(d.Changed)))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sub := range m.matchingSubscriptions(fetch.ScheduleType, fetch.ScheduleId) {
+		filtered := filterChanges(d, sub.Events)
+		if filtered.IsEmpty() {
+			continue
+		}
+
+		m.enqueueDelivery(sub, Payload{
+			Type: PayloadTypeScheduleChanged,
+			Schedule: &PayloadSchedule{
+				Type:     fetch.ScheduleType,
+				Id:       fetch.ScheduleId,
+				Name:     fetch.Schedule.Header.Name,
+				PeriodId: fetch.PeriodId,
+			},
+			Events:  changeEventTypes(filtered),
+			Changes: filtered,
+		})
+	}
+}
+
+func (m *Manager) pollWorker() {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+			subscriptions := make([]Subscription, 0, len(m.subscriptions))
+			for _, sub := range m.subscriptions {
+				if !sub.Disabled {
+					subscriptions = append(subscriptions, *sub)
+				}
+			}
+			m.mu.Unlock()
+
+			for _, sub := range subscriptions {
+				m.pollSubscription(m.ctx, &sub)
+			}
+		}
+	}
+}
+
+// requests schedules of the current year, the client reports them to handleScheduleFetch if the cached copy expired
+func (m *Manager) pollSubscription(ctx context.Context, sub *Subscription) {
+	periodId, ok := m.snapshots.CurrentYearPeriodId(ctx)
+	if !ok {
+		return
+	}
+
+	for _, scheduleId := range sub.ScheduleIds {
+		schedule, _, err := m.cfg.Uek.GetSchedule(ctx, sub.ScheduleType, scheduleId, periodId)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				m.logger.Warn("Failed to get schedule for webhook poll", slog.String("scheduleType", string(sub.ScheduleType)), slog.Int("scheduleId", scheduleId), slog.Int("periodId", periodId), slog.Any("err", err))
+			}
+			continue
+		}
+
+		m.snapshots.Ensure(sub.ScheduleType, scheduleId, periodId, schedule)
+	}
+}
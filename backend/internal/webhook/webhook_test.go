@@ -0,0 +1,328 @@
+package webhook
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+
+	mockDirectoryPath := t.TempDir()
+	if _, err := uekmock.Generate(mockDirectoryPath, uekmock.GeneratorOptions{
+		Seed:      1,
+		Groups:    2,
+		Lecturers: 4,
+		Rooms:     2,
+	}); err != nil {
+		t.Fatalf("failed to generate mock responses: %v", err)
+	}
+
+	mockRoundTripper, err := uekmock.NewRoundTripper(config.Mock{
+		Enabled:       true,
+		DirectoryPath: mockDirectoryPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to create mock round tripper: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m, err := New(Config{
+		Path: t.TempDir(),
+		Uek: uek.NewClient(uek.ClientConfig{
+			HttpClient: &http.Client{
+				Transport: mockRoundTripper,
+			},
+			Logger: logger,
+		}),
+		MaxAttempts: 1,
+		Logger:      logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+// returns the id of the first group and the ids of the year period and a semester within it
+func testScheduleAndPeriods(t *testing.T, m *Manager) (int, int, int) {
+	t.Helper()
+
+	groupings, _, err := m.cfg.Uek.GetGroupings(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, _, err := m.cfg.Uek.GetHeaders(t.Context(), uek.ScheduleTypeGroup, groupings.Groups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	periods, _, err := m.cfg.Uek.GetSchedulePeriods(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	yearPeriodId, ok := uek.CurrentYearPeriodId(periods, now)
+	if !ok {
+		t.Fatal("no current year period")
+	}
+	for _, period := range periods {
+		if period.Id != yearPeriodId && !period.Start.After(now) && !period.End.Before(now) {
+			return headers[0].Id, yearPeriodId, period.Id
+		}
+	}
+
+	t.Fatal("no current semester period")
+	return 0, 0, 0
+}
+
+func TestChangesAreOnlyDiffedForTheYearPeriod(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	m := newTestManager(t)
+	scheduleId, yearPeriodId, semesterPeriodId := testScheduleAndPeriods(t, m)
+
+	sub, err := m.Create(CreateSubscriptionRequest{
+		ScheduleType: uek.ScheduleTypeGroup,
+		ScheduleIds:  []int{scheduleId},
+		URL:          receiver.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.pollSubscription(t.Context(), &sub)
+
+	snapshotFilePaths, _ := filepath.Glob(filepath.Join(m.cfg.Path, "snapshots", "*.json"))
+	if len(snapshotFilePaths) != 1 || filepath.Base(snapshotFilePaths[0]) != fmt.Sprintf("%s-%d-%d.json", uek.ScheduleTypeGroup, scheduleId, yearPeriodId) {
+		t.Errorf("expected only a snapshot of the year period, got %v", snapshotFilePaths)
+	}
+
+	schedule, _, err := m.cfg.Uek.GetSchedule(t.Context(), uek.ScheduleTypeGroup, scheduleId, yearPeriodId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedule.Items) == 0 {
+		t.Fatal("mock schedule is empty")
+	}
+	changedSchedule := &uek.Schedule{Header: schedule.Header, Items: schedule.Items[1:]}
+	deliveryCount := func() int {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.find(sub.Id).Deliveries)
+	}
+
+	m.handleScheduleFetch(uek.ScheduleFetch{ScheduleType: uek.ScheduleTypeGroup, ScheduleId: scheduleId, PeriodId: semesterPeriodId, Schedule: changedSchedule})
+	if count := deliveryCount(); count != 0 {
+		t.Fatalf("expected no delivery for the semester period, got %d", count)
+	}
+
+	m.handleScheduleFetch(uek.ScheduleFetch{ScheduleType: uek.ScheduleTypeGroup, ScheduleId: scheduleId, PeriodId: yearPeriodId, Schedule: changedSchedule})
+	if count := deliveryCount(); count != 1 {
+		t.Fatalf("expected one delivery for the year period, got %d", count)
+	}
+}
+
+func TestDeliveryInterruptedByCloseIsNotCounted(t *testing.T) {
+	requestReceived := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the connection is only watched for the client going away once the body was read
+		io.Copy(io.Discard, r.Body)
+		close(requestReceived)
+		<-r.Context().Done()
+	}))
+	defer receiver.Close()
+
+	m := newTestManager(t)
+	m.cfg.DisableAfter = 1
+	scheduleId, _, _ := testScheduleAndPeriods(t, m)
+
+	sub, err := m.Create(CreateSubscriptionRequest{
+		ScheduleType: uek.ScheduleTypeGroup,
+		ScheduleIds:  []int{scheduleId},
+		URL:          receiver.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Ping(sub.Id); !ok {
+		t.Fatal("subscription not found")
+	}
+
+	select {
+	case <-requestReceived:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery was never attempted")
+	}
+	m.Close()
+
+	stored, ok := m.Get(sub.Id)
+	if !ok {
+		t.Fatal("subscription not found")
+	}
+	if stored.ConsecutiveFailures != 0 || stored.Disabled {
+		t.Errorf("expected the interrupted delivery not to count, got %d failures, disabled: %t", stored.ConsecutiveFailures, stored.Disabled)
+	}
+	if attempts := stored.Deliveries[0].Attempts; len(attempts) != 0 {
+		t.Errorf("expected no recorded attempts, got %+v", attempts)
+	}
+}
+
+// responds with the statuses in order, repeating the last one
+func newTestReceiver(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	requestCount := &atomic.Int32{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(requestCount.Add(1)) - 1
+		w.WriteHeader(statuses[min(i, len(statuses)-1)])
+	}))
+	t.Cleanup(receiver.Close)
+
+	return receiver, requestCount
+}
+
+func createTestSubscription(t *testing.T, m *Manager, url string) Subscription {
+	t.Helper()
+
+	scheduleId, _, _ := testScheduleAndPeriods(t, m)
+	sub, err := m.Create(CreateSubscriptionRequest{
+		ScheduleType: uek.ScheduleTypeGroup,
+		ScheduleIds:  []int{scheduleId},
+		URL:          url,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sub
+}
+
+// pings and waits for the delivery to give up
+func pingAndWait(t *testing.T, m *Manager, id string) *Delivery {
+	t.Helper()
+
+	deliveryId, ok := m.Ping(id)
+	if !ok {
+		t.Fatal("subscription not found")
+	}
+	m.deliveriesWg.Wait()
+
+	stored, _ := m.Get(id)
+	for _, delivery := range stored.Deliveries {
+		if delivery.Id == deliveryId {
+			return delivery
+		}
+	}
+	t.Fatal("delivery not logged")
+	return nil
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		statuses          []int
+		expectedAttempts  int
+		expectedSucceeded bool
+	}{
+		{name: "server errors are retried up to max attempts", statuses: []int{http.StatusServiceUnavailable}, expectedAttempts: 3},
+		{name: "retrying stops after a success", statuses: []int{http.StatusBadGateway, http.StatusNoContent}, expectedAttempts: 2, expectedSucceeded: true},
+		{name: "too many requests is retried", statuses: []int{http.StatusTooManyRequests}, expectedAttempts: 3},
+		{name: "client errors are not retried", statuses: []int{http.StatusNotFound}, expectedAttempts: 1},
+		{name: "success", statuses: []int{http.StatusOK}, expectedAttempts: 1, expectedSucceeded: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			receiver, requestCount := newTestReceiver(t, tc.statuses...)
+			m := newTestManager(t)
+			m.cfg.MaxAttempts = 3
+			m.cfg.RetryBackoff = time.Millisecond
+			sub := createTestSubscription(t, m, receiver.URL)
+
+			delivery := pingAndWait(t, m, sub.Id)
+			if count := int(requestCount.Load()); count != tc.expectedAttempts {
+				t.Errorf("expected %d requests, got %d", tc.expectedAttempts, count)
+			}
+			if len(delivery.Attempts) != tc.expectedAttempts || delivery.Succeeded != tc.expectedSucceeded {
+				t.Errorf("expected %d attempts and succeeded %t, got %+v", tc.expectedAttempts, tc.expectedSucceeded, delivery)
+			}
+			for i, attempt := range delivery.Attempts {
+				if expectedStatus := tc.statuses[min(i, len(tc.statuses)-1)]; attempt.StatusCode != expectedStatus {
+					t.Errorf("attempt %d: expected status %d, got %d", i, expectedStatus, attempt.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionDisabledAfterConsecutiveFailures(t *testing.T) {
+	receiver, _ := newTestReceiver(t, http.StatusInternalServerError, http.StatusNoContent, http.StatusInternalServerError)
+	m := newTestManager(t)
+	m.cfg.DisableAfter = 2
+	sub := createTestSubscription(t, m, receiver.URL)
+
+	assertState := func(expectedFailures int, expectedDisabled bool) {
+		t.Helper()
+		stored, _ := m.Get(sub.Id)
+		if stored.ConsecutiveFailures != expectedFailures || stored.Disabled != expectedDisabled {
+			t.Fatalf("expected %d failures and disabled %t, got %d and %t", expectedFailures, expectedDisabled, stored.ConsecutiveFailures, stored.Disabled)
+		}
+	}
+
+	pingAndWait(t, m, sub.Id)
+	assertState(1, false)
+	// a success resets the count
+	pingAndWait(t, m, sub.Id)
+	assertState(0, false)
+	pingAndWait(t, m, sub.Id)
+	assertState(1, false)
+	pingAndWait(t, m, sub.Id)
+	assertState(2, true)
+	if stored, _ := m.Get(sub.Id); stored.DisabledReason == "" {
+		t.Error("expected a reason for disabling")
+	}
+
+	if _, ok := m.Enable(sub.Id); !ok {
+		t.Fatal("subscription not found")
+	}
+	assertState(0, false)
+	if stored, _ := m.Get(sub.Id); stored.DisabledReason != "" {
+		t.Errorf("expected the reason to be cleared, got %q", stored.DisabledReason)
+	}
+}
+
+func TestDeliveryLogIsCapped(t *testing.T) {
+	receiver, _ := newTestReceiver(t, http.StatusNoContent)
+	m := newTestManager(t)
+	sub := createTestSubscription(t, m, receiver.URL)
+
+	deliveryIds := []string{}
+	for range maxDeliveryLogSize + 5 {
+		deliveryIds = append(deliveryIds, pingAndWait(t, m, sub.Id).Id)
+	}
+
+	stored, _ := m.Get(sub.Id)
+	if len(stored.Deliveries) != maxDeliveryLogSize {
+		t.Fatalf("expected %d logged deliveries, got %d", maxDeliveryLogSize, len(stored.Deliveries))
+	}
+	// the oldest are dropped
+	for i, delivery := range stored.Deliveries {
+		if expectedId := deliveryIds[5+i]; delivery.Id != expectedId {
+			t.Errorf("delivery %d: expected %s, got %s", i, expectedId, delivery.Id)
+		}
+	}
+}
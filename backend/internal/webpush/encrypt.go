@@ -0,0 +1,92 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	authSecretLength = 16
+	saltLength       = 16
+	// push services only have to accept 4096 byte bodies
+	recordSize = 4096
+	// salt, record size, key id length and the 65 byte key id
+	headerLength = saltLength + 4 + 1 + 65
+	// minus the header, the aes-gcm tag and the padding delimiter
+	maxPayloadLength = recordSize - headerLength - 16 - 1
+)
+
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// encrypt encrypts the payload for the user agent with the aes128gcm content coding, as described in RFC 8291
+func encrypt(uaPublicKey *ecdh.PublicKey, authSecret []byte, plaintext []byte) ([]byte, error) {
+	asPrivateKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltLength)
+	rand.Read(salt)
+
+	return encryptWithKeys(uaPublicKey, authSecret, plaintext, asPrivateKey, salt)
+}
+
+// the ephemeral key and salt are parameters only so the RFC test vector can be reproduced
+func encryptWithKeys(uaPublicKey *ecdh.PublicKey, authSecret []byte, plaintext []byte, asPrivateKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(plaintext) > maxPayloadLength {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d allowed", ErrPayloadTooLarge, len(plaintext), maxPayloadLength)
+	}
+	if len(authSecret) != authSecretLength {
+		return nil, fmt.Errorf("auth secret must be %d bytes", authSecretLength)
+	}
+
+	ecdhSecret, err := asPrivateKey.ECDH(uaPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	uaPublic, asPublic := uaPublicKey.Bytes(), asPrivateKey.PublicKey().Bytes()
+	keyInfo := make([]byte, 0, 14+len(uaPublic)+len(asPublic))
+	keyInfo = append(keyInfo, "WebPush: info\x00"...)
+	keyInfo = append(keyInfo, uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	contentEncryptionKey, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, headerLength, headerLength+len(plaintext)+1+gcm.Overhead())
+	copy(body, salt)
+	binary.BigEndian.PutUint32(body[saltLength:], recordSize)
+	body[saltLength+4] = byte(len(asPublic))
+	copy(body[saltLength+5:], asPublic)
+
+	// single record, so it ends with the last record delimiter and no further padding
+	record := append(plaintext[:len(plaintext):len(plaintext)], 0x02)
+	return gcm.Seal(body, nonce, record, nil), nil
+}
@@ -0,0 +1,252 @@
+package webpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	tickInterval = time.Minute
+	// changes to classes further away aren't urgent enough for a notification
+	changeAlertHorizon = 7 * 24 * time.Hour
+	changeAlertTTL     = 24 * time.Hour
+)
+
+var weekdayAbbreviations = [...]string{"nd", "pn", "wt", "śr", "czw", "pt", "sb"}
+
+// every tick sends reminders for classes starting within the window that moved past since the previous tick.
+// Requesting the schedules also refreshes expired ones, which is how changes get noticed
+func (m *Manager) scheduler() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	windowStart := time.Now().Add(m.cfg.ReminderLead)
+	for {
+		var now time.Time
+		select {
+		case <-m.ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		m.pruneExpired(now)
+
+		windowEnd := now.Add(m.cfg.ReminderLead)
+		m.sendReminders(now, windowStart, windowEnd)
+		windowStart = windowEnd
+	}
+}
+
+// browsers report when a subscription will stop working
+func (m *Manager) pruneExpired(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiredEndpoints := []string{}
+	for _, sel := range m.selections {
+		for _, sub := range sel.Subscriptions {
+			if !sub.ExpirationTime.IsZero() && sub.ExpirationTime.Before(now) {
+				expiredEndpoints = append(expiredEndpoints, sub.Endpoint)
+			}
+		}
+	}
+	if len(expiredEndpoints) == 0 {
+		return
+	}
+
+	for _, endpoint := range expiredEndpoints {
+		m.removeEndpoint(endpoint)
+	}
+	m.saveSubscriptions()
+	m.logger.Info("Expired push subscriptions removed", slog.Int("count", len(expiredEndpoints)))
+}
+
+func (m *Manager) copySelections() []Selection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	selections := make([]Selection, 0, len(m.selections))
+	for _, sel := range m.selections {
+		copied := *sel
+		copied.Subscriptions = make([]*Subscription, 0, len(sel.Subscriptions))
+		for _, sub := range sel.Subscriptions {
+			copiedSub := *sub
+			copied.Subscriptions = append(copied.Subscriptions, &copiedSub)
+		}
+		selections = append(selections, copied)
+	}
+
+	return selections
+}
+
+// reminds about classes starting in (windowStart, windowEnd], cancelled slots are skipped
+func (m *Manager) sendReminders(now time.Time, windowStart time.Time, windowEnd time.Time) {
+	for _, sel := range m.copySelections() {
+		ctx, cancelCtx := context.WithTimeout(m.ctx, 30*time.Second)
+		aggregateSchedule, _, _, err := m.cfg.Uek.GetAggregateScheduleInRange(ctx, sel.ScheduleType, sel.ScheduleIds, windowStart, windowEnd)
+		cancelCtx()
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				m.logger.Warn("Failed to get schedule for push reminders", slog.String("scheduleType", string(sel.ScheduleType)), slog.Any("scheduleIds", sel.ScheduleIds), slog.Any("err", err))
+			}
+			continue
+		}
+
+		for _, item := range aggregateSchedule.Items {
+			if !item.Start.After(windowStart) || item.Start.After(windowEnd) || item.IsCancelled() || sel.isHidden(item) {
+				continue
+			}
+
+			n, opts := reminderNotification(item, now)
+			for _, sub := range sel.Subscriptions {
+				if sub.Reminders {
+					m.pushInBackground(*sub, n, opts)
+				}
+			}
+		}
+	}
+}
+
+func reminderNotification(item *uek.ScheduleItem, now time.Time) (notification, pushOptions) {
+	minutesLeft := int(math.Round(item.Start.Sub(now).Minutes()))
+	start, end := item.Start.In(uek.Location()), item.End.In(uek.Location())
+
+	n := notification{
+		Title: fmt.Sprintf("Za %d min: %s", minutesLeft, item.Subject),
+		Body:  fmt.Sprintf("%s, %s-%s", item.Type, start.Format("15:04"), end.Format("15:04")),
+		Tag:   fmt.Sprintf("reminder-%d", start.Unix()),
+	}
+	if item.Room != nil {
+		if item.Room.URL != "" {
+			n.Body += ", online"
+			n.Url = item.Room.URL
+		} else {
+			n.Body += ", sala " + item.Room.Name
+		}
+	}
+
+	// useless once the class has started
+	return n, pushOptions{
+		TTL:     max(item.Start.Sub(now), time.Minute),
+		Urgency: UrgencyHigh,
+	}
+}
+
+func (m *Manager) handleScheduleFetch(fetch uek.ScheduleFetch) {
+	matching := []Selection{}
+	for _, sel := range m.copySelections() {
+		if sel.ScheduleType == fetch.ScheduleType && slices.Contains(sel.ScheduleIds, fetch.ScheduleId) {
+			matching = append(matching, sel)
+		}
+	}
+	if len(matching) == 0 {
+		return
+	}
+
+	d := m.snapshots.Update(m.ctx, fetch)
+	if d == nil || d.IsEmpty() {
+		return
+	}
+
+	now := time.Now()
+	for _, sel := range matching {
+		relevant := relevantChanges(d, &sel, now)
+		if relevant.IsEmpty() {
+			continue
+		}
+
+		n := changeNotification(relevant, fetch.Schedule.Header.Name)
+		for _, sub := range sel.Subscriptions {
+			if sub.ChangeAlerts {
+				m.pushInBackground(*sub, n, pushOptions{
+					TTL:     changeAlertTTL,
+					Urgency: UrgencyNormal,
+					Topic:   "schedule-changes",
+				})
+			}
+		}
+	}
+}
+
+// upcoming, not hidden classes only
+func relevantChanges(d *uek.ItemsDiff, sel *Selection, now time.Time) *uek.ItemsDiff {
+	isRelevant := func(item *uek.ScheduleItem) bool {
+		return item.End.After(now) && item.Start.Before(now.Add(changeAlertHorizon)) && !sel.isHidden(item)
+	}
+
+	relevant := &uek.ItemsDiff{}
+	for _, item := range d.Added {
+		if isRelevant(item) {
+			relevant.Added = append(relevant.Added, item)
+		}
+	}
+	for _, item := range d.Removed {
+		if isRelevant(item) {
+			relevant.Removed = append(relevant.Removed, item)
+		}
+	}
+	for _, change := range d.Changed {
+		if isRelevant(change.After) {
+			relevant.Changed = append(relevant.Changed, change)
+		}
+	}
+
+	return relevant
+}
+
+func changeNotification(d *uek.ItemsDiff, scheduleName string) notification {
+	n := notification{
+		Title: "Zmiana w planie: " + scheduleName,
+		Tag:   "changes",
+	}
+
+	describe := func(item *uek.ScheduleItem) string {
+		start := item.Start.In(uek.Location())
+		return fmt.Sprintf("%s (%s %s %s)", item.Subject, weekdayAbbreviations[start.Weekday()], start.Format("02.01"), start.Format("15:04"))
+	}
+
+	switch changeCount := len(d.Added) + len(d.Removed) + len(d.Changed); {
+	case changeCount > 1:
+		counts := []string{}
+		for _, count := range []struct {
+			label string
+			count int
+		}{
+			{"nowe zajęcia", len(d.Added)},
+			{"odwołane", len(d.Removed)},
+			{"zmienione", len(d.Changed)},
+		} {
+			if count.count > 0 {
+				counts = append(counts, fmt.Sprintf("%s: %d", count.label, count.count))
+			}
+		}
+		n.Body = "Zmiany w najbliższym tygodniu, " + strings.Join(counts, ", ")
+	case len(d.Added) == 1:
+		n.Body = "Nowe zajęcia: " + describe(d.Added[0])
+	case len(d.Removed) == 1:
+		n.Body = "Odwołane: " + describe(d.Removed[0])
+	default:
+		n.Body = "Zmienione: " + describe(d.Changed[0].After)
+		if after := d.Changed[0].After.Room; after != nil && !roomsEqual(d.Changed[0].Before.Room, after) {
+			n.Body += ", sala " + after.Name
+		}
+	}
+
+	return n
+}
+
+func roomsEqual(a *uek.ScheduleItemRoom, b *uek.ScheduleItemRoom) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Name == b.Name && a.URL == b.URL
+}
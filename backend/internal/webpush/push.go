@@ -0,0 +1,180 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+// payload read by the service worker's push handler
+type notification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// notifications with the same tag replace each other on the device
+	Tag string `json:"tag,omitempty"`
+	// opened when the notification is clicked, the app when empty
+	Url string `json:"url,omitempty"`
+	// unix milliseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+type Urgency string
+
+const (
+	UrgencyLow    Urgency = "low"
+	UrgencyNormal Urgency = "normal"
+	UrgencyHigh   Urgency = "high"
+)
+
+type pushOptions struct {
+	// how long the push service keeps the message for an offline device
+	TTL     time.Duration
+	Urgency Urgency
+	// pending messages with the same topic are replaced by the push service
+	Topic string
+}
+
+// SendTest pushes a test notification, returns false if the subscription doesn't exist
+func (m *Manager) SendTest(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := m.findById(id)
+	if sub == nil {
+		return false
+	}
+
+	m.pushInBackground(*sub, notification{
+		Title: "Plan zajęć UEK",
+		Body:  "Powiadomienia działają",
+		Tag:   "test",
+	}, pushOptions{
+		TTL:     time.Hour,
+		Urgency: UrgencyNormal,
+	})
+
+	return true
+}
+
+func (m *Manager) pushInBackground(sub Subscription, n notification, opts pushOptions) {
+	n.Timestamp = time.Now().UnixMilli()
+
+	m.pushWg.Add(1)
+	go func() {
+		defer m.pushWg.Done()
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case m.pushSemaphore <- struct{}{}:
+		}
+		statusCode, err := m.push(m.ctx, &sub, n, opts)
+		<-m.pushSemaphore
+
+		m.recordPushOutcome(&sub, statusCode, err)
+	}()
+}
+
+func (m *Manager) push(ctx context.Context, sub *Subscription, n notification, opts pushOptions) (int, error) {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return 0, err
+	}
+
+	p256dh, err := decodeBase64URL(sub.P256dh)
+	if err != nil {
+		return 0, err
+	}
+	uaPublicKey, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return 0, err
+	}
+	authSecret, err := decodeBase64URL(sub.Auth)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := encrypt(uaPublicKey, authSecret, payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+
+	authorization, err := vapidAuthorization(m.vapidKey, sub.Endpoint, m.cfg.Subject, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to sign vapid token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(opts.TTL.Seconds())))
+	req.Header.Set("User-Agent", uek.UserAgent)
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", string(opts.Urgency))
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	res, err := m.cfg.HttpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	return res.StatusCode, nil
+}
+
+// gone subscriptions are removed right away, failing ones after maxConsecutiveFailures
+func (m *Manager) recordPushOutcome(pushed *Subscription, statusCode int, err error) {
+	logger := m.logger.With(slog.String("subscriptionId", pushed.Id), slog.String("pushService", endpointHost(pushed.Endpoint)))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// unsubscribed or re-subscribed with a different endpoint in the meantime
+	sub := m.findById(pushed.Id)
+	if sub == nil || sub.Endpoint != pushed.Endpoint {
+		return
+	}
+	// canceled by shutdown, not the subscription's fault
+	if err != nil && m.ctx.Err() != nil {
+		return
+	}
+
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		// persisted with the next change, not worth a write per push
+		sub.LastPushedAt = time.Now()
+		sub.ConsecutiveFailures = 0
+		return
+
+	case err == nil && (statusCode == http.StatusNotFound || statusCode == http.StatusGone):
+		m.removeEndpoint(sub.Endpoint)
+		logger.Info("Push subscription expired, removed", slog.Int("statusCode", statusCode))
+
+	default:
+		sub.ConsecutiveFailures++
+		logger.Warn("Push failed", slog.Int("statusCode", statusCode), slog.Any("err", err), slog.Int("consecutiveFailures", sub.ConsecutiveFailures))
+		if sub.ConsecutiveFailures >= maxConsecutiveFailures {
+			m.removeEndpoint(sub.Endpoint)
+			logger.Warn("Push subscription removed after repeated failures")
+		}
+	}
+
+	m.saveSubscriptions()
+}
@@ -0,0 +1,152 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// reverses encrypt with the user agent's keys, as a browser would
+func decrypt(t *testing.T, uaPrivateKey *ecdh.PrivateKey, authSecret []byte, body []byte) []byte {
+	t.Helper()
+
+	salt, asPublic := body[:saltLength], body[saltLength+5:headerLength]
+	asPublicKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdhSecret, err := uaPrivateKey.ECDH(asPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPrivateKey.PublicKey().Bytes()...), asPublic...)
+	ikm, _ := hkdf.Key(sha256.New, ecdhSecret, authSecret, string(keyInfo), 32)
+	contentEncryptionKey, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(contentEncryptionKey)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, body[headerLength:], nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if record[len(record)-1] != 0x02 {
+		t.Fatalf("missing last record delimiter")
+	}
+
+	return record[:len(record)-1]
+}
+
+// checks the ES256 signature against the key sent alongside it, returns the claims
+func verifyVAPIDAuthorization(t *testing.T, authorization string) map[string]any {
+	t.Helper()
+
+	token, publicKey, ok := strings.Cut(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+	if !ok {
+		t.Fatalf("malformed authorization %q", authorization)
+	}
+	publicKeyBytes, _ := base64.RawURLEncoding.DecodeString(publicKey)
+	key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), publicKeyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if len(signature) != 64 || !ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		t.Fatal("invalid vapid signature")
+	}
+
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	claims := map[string]any{}
+	json.Unmarshal(claimsJSON, &claims)
+	return claims
+}
+
+func TestPushDelivery(t *testing.T) {
+	uaPrivateKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, authSecretLength)
+	rand.Read(authSecret)
+
+	mu := sync.Mutex{}
+	statusCode := http.StatusCreated
+	received := []notification{}
+	pushService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := verifyVAPIDAuthorization(t, r.Header.Get("Authorization"))
+		if claims["aud"] != "http://"+r.Host || claims["sub"] != "mailto:admin@example.com" {
+			t.Errorf("unexpected claims %v", claims)
+		}
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+			t.Errorf("missing push headers")
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		n := notification{}
+		if err := json.Unmarshal(decrypt(t, uaPrivateKey, authSecret, body), &n); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, n)
+		w.WriteHeader(statusCode)
+	}))
+	defer pushService.Close()
+
+	uekClient := newTestUekClient(t)
+	m := newTestManager(t, uekClient)
+	m.cfg.HttpClient = pushService.Client()
+	scheduleIds := testScheduleIds(t, uekClient)
+
+	req := SubscribeRequest{
+		ScheduleType:   "group",
+		ScheduleIds:    []int{scheduleIds[1], scheduleIds[0], scheduleIds[1]},
+		HiddenSubjects: []string{"Wychowanie fizyczne"},
+	}
+	req.Subscription.Endpoint = pushService.URL + "/push/abc"
+	req.Subscription.Keys.P256dh = base64.URLEncoding.EncodeToString(uaPrivateKey.PublicKey().Bytes())
+	req.Subscription.Keys.Auth = base64.RawURLEncoding.EncodeToString(authSecret)
+	id, err := m.Subscribe(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selections := m.List()
+	if len(selections) != 1 || len(selections[0].ScheduleIds) != 2 || len(selections[0].Subscriptions) != 1 || selections[0].Subscriptions[0].Auth != "" {
+		t.Fatalf("unexpected selections %+v", selections)
+	}
+
+	if !m.SendTest(id) {
+		t.Fatal("subscription not found")
+	}
+	m.pushWg.Wait()
+	if len(received) != 1 || received[0].Title == "" {
+		t.Fatalf("expected a decryptable notification, got %+v", received)
+	}
+
+	// the browser unsubscribed, the push service reports the endpoint as gone
+	mu.Lock()
+	statusCode = http.StatusGone
+	mu.Unlock()
+	m.SendTest(id)
+	m.pushWg.Wait()
+	if selections := m.List(); len(selections) != 0 {
+		t.Errorf("expected gone subscription to be removed, got %+v", selections)
+	}
+}
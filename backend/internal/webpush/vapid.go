@@ -0,0 +1,126 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+)
+
+// push services reject tokens valid for longer than 24h
+const vapidTokenTTL = 12 * time.Hour
+
+type vapidKeyFile struct {
+	PrivateKey string `json:"privateKey"`
+}
+
+// loads the configured key, or the one generated on first start, so existing browser subscriptions stay valid
+func loadOrCreateVAPIDKey(filePath string, configuredKey string) (*ecdsa.PrivateKey, bool, error) {
+	if configuredKey != "" {
+		key, err := parseVAPIDPrivateKey(configuredKey)
+		return key, false, err
+	}
+
+	keyFile := vapidKeyFile{}
+	if err := jsonfile.Read(filePath, &keyFile); err == nil {
+		key, err := parseVAPIDPrivateKey(keyFile.PrivateKey)
+		return key, false, err
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, false, err
+	}
+	keyBytes, err := key.Bytes()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := jsonfile.Write(filePath, vapidKeyFile{
+		PrivateKey: base64.RawURLEncoding.EncodeToString(keyBytes),
+	}); err != nil {
+		return nil, false, fmt.Errorf("failed to save generated key: %w", err)
+	}
+
+	return key, true, nil
+}
+
+func parseVAPIDPrivateKey(s string) (*ecdsa.PrivateKey, error) {
+	keyBytes, err := decodeBase64URL(s)
+	if err != nil {
+		return nil, fmt.Errorf("vapid private key must be base64url encoded: %w", err)
+	}
+
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	return key, nil
+}
+
+// browsers and push services use both padded and unpadded base64url
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(trimBase64Padding(s))
+}
+
+func trimBase64Padding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func vapidPublicKey(key *ecdsa.PrivateKey) string {
+	publicKeyBytes, _ := key.PublicKey.Bytes()
+	return base64.RawURLEncoding.EncodeToString(publicKeyBytes)
+}
+
+// vapidAuthorization returns the Authorization header value (RFC 8292) for requests to the endpoint
+func vapidAuthorization(key *ecdsa.PrivateKey, endpoint string, subject string, now time.Time) (string, error) {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := json.Marshal(map[string]string{
+		"typ": "JWT",
+		"alg": "ES256",
+	})
+	claims, err := json.Marshal(struct {
+		Audience  string `json:"aud"`
+		ExpiresAt int64  `json:"exp"`
+		Subject   string `json:"sub"`
+	}{
+		Audience:  endpointUrl.Scheme + "://" + endpointUrl.Host,
+		ExpiresAt: now.Add(vapidTokenTTL).Unix(),
+		Subject:   subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	// jws wants the raw r || s form, not asn.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", signingInput, base64.RawURLEncoding.EncodeToString(signature), vapidPublicKey(key)), nil
+}
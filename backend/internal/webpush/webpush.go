@@ -0,0 +1,449 @@
+package webpush
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/szczursonn/uek-planzajec-v3/internal/jsonfile"
+	"github.com/szczursonn/uek-planzajec-v3/internal/snapshot"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+)
+
+const (
+	MaxScheduleIdsPerSelection = 20
+	MaxHiddenSubjects          = 200
+	// subscriptions failing this many times in a row are dropped, gone ones are dropped right away
+	maxConsecutiveFailures = 10
+	maxSubscriptions       = 10000
+	// browsers of one class subscribe to the same selection, more than this is someone scripting it
+	maxSubscriptionsPerSelection = 1000
+)
+
+var (
+	ErrInvalidSubscription      = errors.New("invalid subscription")
+	ErrSubscriptionLimitReached = errors.New("subscription limit reached")
+	errPrivateAddress           = errors.New("push services must have public addresses")
+)
+
+type Config struct {
+	// directory for subscriptions, schedule snapshots and the generated vapid key
+	Path string
+	Uek  *uek.Client
+	// defaults to a client that refuses to connect to private addresses
+	HttpClient *http.Client
+	// contact url for push service operators, "mailto:" or "https:"
+	Subject string
+	// base64url encoded P-256 private key, generated and saved to Path when empty
+	VAPIDPrivateKey string
+	// how long before a class the reminder is sent
+	ReminderLead time.Duration
+	Logger       *slog.Logger
+}
+
+// Selection groups subscriptions that want notifications for the same classes, so schedules are checked once per selection
+type Selection struct {
+	ScheduleType   uek.ScheduleType `json:"scheduleType"`
+	ScheduleIds    []int            `json:"scheduleIds"`
+	HiddenSubjects []string         `json:"hiddenSubjects,omitempty"`
+	Subscriptions  []*Subscription  `json:"subscriptions"`
+}
+
+func (sel *Selection) key() string {
+	return selectionKey(sel.ScheduleType, sel.ScheduleIds, sel.HiddenSubjects)
+}
+
+func selectionKey(scheduleType uek.ScheduleType, scheduleIds []int, hiddenSubjects []string) string {
+	sb := strings.Builder{}
+	sb.WriteString(string(scheduleType))
+	for _, scheduleId := range scheduleIds {
+		sb.WriteString("," + strconv.Itoa(scheduleId))
+	}
+	for _, subject := range hiddenSubjects {
+		sb.WriteString("|" + subject)
+	}
+
+	return sb.String()
+}
+
+func (sel *Selection) isHidden(item *uek.ScheduleItem) bool {
+	_, found := slices.BinarySearch(sel.HiddenSubjects, item.Subject)
+	return found
+}
+
+type Subscription struct {
+	Id string `json:"id"`
+	// unguessable url of the browser's push service, identifies the subscription
+	Endpoint string `json:"endpoint"`
+	// base64url encoded, from PushSubscription.getKey
+	P256dh         string    `json:"p256dh"`
+	Auth           string    `json:"auth"`
+	ExpirationTime time.Time `json:"expirationTime,omitzero"`
+	Reminders      bool      `json:"reminders"`
+	ChangeAlerts   bool      `json:"changeAlerts"`
+	CreatedAt      time.Time `json:"createdAt"`
+	// of the last successful push
+	LastPushedAt        time.Time `json:"lastPushedAt,omitzero"`
+	ConsecutiveFailures int       `json:"consecutiveFailures,omitempty"`
+}
+
+type SubscribeRequest struct {
+	// as serialized by PushSubscription.toJSON
+	Subscription struct {
+		Endpoint string `json:"endpoint"`
+		// unix milliseconds
+		ExpirationTime *int64 `json:"expirationTime,omitempty"`
+		Keys           struct {
+			P256dh string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		} `json:"keys"`
+	} `json:"subscription"`
+	ScheduleType   uek.ScheduleType `json:"scheduleType"`
+	ScheduleIds    []int            `json:"scheduleIds"`
+	HiddenSubjects []string         `json:"hiddenSubjects,omitempty"`
+	// both default to true
+	Reminders    *bool `json:"reminders,omitempty"`
+	ChangeAlerts *bool `json:"changeAlerts,omitempty"`
+}
+
+type Manager struct {
+	cfg        Config
+	logger     *slog.Logger
+	vapidKey   *ecdsa.PrivateKey
+	mu         sync.Mutex
+	selections map[string]*Selection
+	snapshots  *snapshot.Store
+	pushWg     sync.WaitGroup
+	// limits concurrent requests to push services
+	pushSemaphore chan struct{}
+	ctx           context.Context
+	cancelCtx     context.CancelFunc
+	// only for tests, push services are always https
+	allowInsecureEndpoints bool
+}
+
+func New(cfg Config) (*Manager, error) {
+	if !strings.HasPrefix(cfg.Subject, "mailto:") && !strings.HasPrefix(cfg.Subject, "https:") {
+		return nil, errors.New("subject must be a mailto: or https: url")
+	}
+	if cfg.HttpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// the proxy would be dialed instead of the push service
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: refusePrivateAddresses,
+		}).DialContext
+		cfg.HttpClient = &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		}
+	}
+
+	m := &Manager{
+		cfg:           cfg,
+		logger:        cfg.Logger,
+		selections:    map[string]*Selection{},
+		pushSemaphore: make(chan struct{}, 8),
+	}
+	if m.logger == nil {
+		m.logger = slog.Default()
+	}
+	m.snapshots = snapshot.New(snapshot.Config{
+		Path:   filepath.Join(cfg.Path, "snapshots"),
+		Uek:    cfg.Uek,
+		Logger: m.logger,
+	})
+
+	var generated bool
+	var err error
+	if m.vapidKey, generated, err = loadOrCreateVAPIDKey(filepath.Join(cfg.Path, "vapid.json"), cfg.VAPIDPrivateKey); err != nil {
+		return nil, fmt.Errorf("failed to load vapid key: %w", err)
+	}
+	if generated {
+		m.logger.Info("Generated vapid key", slog.String("publicKey", m.PublicKey()))
+	}
+
+	selections := []*Selection{}
+	if err := jsonfile.Read(m.subscriptionsFilePath(), &selections); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+	for _, sel := range selections {
+		m.selections[sel.key()] = sel
+	}
+
+	m.ctx, m.cancelCtx = context.WithCancel(context.Background())
+	cfg.Uek.OnScheduleFetched(m.handleScheduleFetch)
+	go m.scheduler()
+
+	return m, nil
+}
+
+// Close stops the scheduler and waits for pending pushes
+func (m *Manager) Close() {
+	m.cancelCtx()
+	m.pushWg.Wait()
+}
+
+// PublicKey is the applicationServerKey browsers subscribe with
+func (m *Manager) PublicKey() string {
+	return vapidPublicKey(m.vapidKey)
+}
+
+func (m *Manager) subscriptionsFilePath() string {
+	return filepath.Join(m.cfg.Path, "subscriptions.json")
+}
+
+// must be called with mu held
+func (m *Manager) saveSubscriptions() {
+	selections := make([]*Selection, 0, len(m.selections))
+	for _, key := range slices.Sorted(maps.Keys(m.selections)) {
+		selections = append(selections, m.selections[key])
+	}
+	if err := jsonfile.Write(m.subscriptionsFilePath(), selections); err != nil {
+		m.logger.Error("Failed to save subscriptions", slog.Any("err", err))
+	}
+}
+
+// List returns copies of all selections, keys are left out
+func (m *Manager) List() []Selection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	selections := make([]Selection, 0, len(m.selections))
+	for _, sel := range m.selections {
+		copied := *sel
+		copied.Subscriptions = make([]*Subscription, 0, len(sel.Subscriptions))
+		for _, sub := range sel.Subscriptions {
+			copiedSub := *sub
+			copiedSub.P256dh, copiedSub.Auth = "", ""
+			copied.Subscriptions = append(copied.Subscriptions, &copiedSub)
+		}
+		selections = append(selections, copied)
+	}
+	slices.SortFunc(selections, func(a Selection, b Selection) int {
+		return strings.Compare(a.key(), b.key())
+	})
+
+	return selections
+}
+
+// Subscribe adds the browser subscription, or moves it if the endpoint is already subscribed to a different selection
+func (m *Manager) Subscribe(ctx context.Context, req SubscribeRequest) (string, error) {
+	if err := m.validateEndpoint(req.Subscription.Endpoint); err != nil {
+		return "", err
+	}
+	p256dh, err := decodeBase64URL(req.Subscription.Keys.P256dh)
+	if err != nil {
+		return "", fmt.Errorf("%w: p256dh must be base64url encoded", ErrInvalidSubscription)
+	}
+	if _, err := ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return "", fmt.Errorf("%w: p256dh is not a P-256 public key", ErrInvalidSubscription)
+	}
+	if auth, err := decodeBase64URL(req.Subscription.Keys.Auth); err != nil || len(auth) != authSecretLength {
+		return "", fmt.Errorf("%w: auth must be %d base64url encoded bytes", ErrInvalidSubscription, authSecretLength)
+	}
+	if !req.ScheduleType.IsValid() {
+		return "", fmt.Errorf("%w: invalid schedule type", ErrInvalidSubscription)
+	}
+	if len(req.ScheduleIds) == 0 || len(req.ScheduleIds) > MaxScheduleIdsPerSelection {
+		return "", fmt.Errorf("%w: between 1 and %d schedule ids are required", ErrInvalidSubscription, MaxScheduleIdsPerSelection)
+	}
+	if len(req.HiddenSubjects) > MaxHiddenSubjects {
+		return "", fmt.Errorf("%w: at most %d hidden subjects are allowed", ErrInvalidSubscription, MaxHiddenSubjects)
+	}
+
+	sub := &Subscription{
+		Id:           uuid.NewString(),
+		Endpoint:     req.Subscription.Endpoint,
+		P256dh:       trimBase64Padding(req.Subscription.Keys.P256dh),
+		Auth:         trimBase64Padding(req.Subscription.Keys.Auth),
+		Reminders:    req.Reminders == nil || *req.Reminders,
+		ChangeAlerts: req.ChangeAlerts == nil || *req.ChangeAlerts,
+		CreatedAt:    time.Now(),
+	}
+	if req.Subscription.ExpirationTime != nil {
+		sub.ExpirationTime = time.UnixMilli(*req.Subscription.ExpirationTime)
+	}
+	scheduleIds := slices.Compact(slices.Sorted(slices.Values(req.ScheduleIds)))
+	hiddenSubjects := slices.Compact(slices.Sorted(slices.Values(req.HiddenSubjects)))
+
+	// also checks that the schedules exist, they are polled for as long as the subscription lasts
+	now := time.Now()
+	if _, _, _, err := m.cfg.Uek.GetAggregateScheduleInRange(ctx, req.ScheduleType, scheduleIds, now, now.Add(changeAlertHorizon)); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := selectionKey(req.ScheduleType, scheduleIds, hiddenSubjects)
+	sel := m.selections[key]
+	previous, previousKey := m.findByEndpoint(sub.Endpoint)
+	// browsers resubscribe on every page load, which shouldn't rewrite the file each time
+	if previous != nil && previousKey == key && previous.sameSettings(sub) {
+		return previous.Id, nil
+	}
+	if previous == nil && m.subscriptionCount() >= maxSubscriptions {
+		m.logger.Warn("Push subscription rejected, too many subscriptions", slog.Int("count", maxSubscriptions))
+		return "", ErrSubscriptionLimitReached
+	}
+	if previousKey != key && sel != nil && len(sel.Subscriptions) >= maxSubscriptionsPerSelection {
+		m.logger.Warn("Push subscription rejected, too many subscriptions for selection", slog.String("selection", key))
+		return "", ErrSubscriptionLimitReached
+	}
+
+	// keeps the id, so clients can tell a re-subscription apart from a new one
+	if previous != nil {
+		m.removeEndpoint(sub.Endpoint)
+		sub.Id, sub.CreatedAt = previous.Id, previous.CreatedAt
+		// the previous selection may have been dropped along with its last subscription
+		sel = m.selections[key]
+	}
+
+	if sel == nil {
+		sel = &Selection{
+			ScheduleType:   req.ScheduleType,
+			ScheduleIds:    scheduleIds,
+			HiddenSubjects: hiddenSubjects,
+		}
+		m.selections[key] = sel
+	}
+	sel.Subscriptions = append(sel.Subscriptions, sub)
+	m.saveSubscriptions()
+	m.logger.Info("Push subscription saved", slog.String("id", sub.Id), slog.String("pushService", endpointHost(sub.Endpoint)))
+
+	return sub.Id, nil
+}
+
+func (m *Manager) Unsubscribe(endpoint string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := m.removeEndpoint(endpoint)
+	if sub == nil {
+		return false
+	}
+
+	m.saveSubscriptions()
+	m.logger.Info("Push subscription removed", slog.String("id", sub.Id))
+	return true
+}
+
+// must be called with mu held, empty selections are dropped
+func (m *Manager) removeEndpoint(endpoint string) *Subscription {
+	for key, sel := range m.selections {
+		index := slices.IndexFunc(sel.Subscriptions, func(sub *Subscription) bool {
+			return sub.Endpoint == endpoint
+		})
+		if index == -1 {
+			continue
+		}
+
+		sub := sel.Subscriptions[index]
+		sel.Subscriptions = slices.Delete(sel.Subscriptions, index, index+1)
+		if len(sel.Subscriptions) == 0 {
+			delete(m.selections, key)
+		}
+		return sub
+	}
+
+	return nil
+}
+
+// must be called with mu held, also returns the key of the subscription's selection
+func (m *Manager) findByEndpoint(endpoint string) (*Subscription, string) {
+	for key, sel := range m.selections {
+		for _, sub := range sel.Subscriptions {
+			if sub.Endpoint == endpoint {
+				return sub, key
+			}
+		}
+	}
+
+	return nil, ""
+}
+
+// must be called with mu held
+func (m *Manager) subscriptionCount() int {
+	count := 0
+	for _, sel := range m.selections {
+		count += len(sel.Subscriptions)
+	}
+
+	return count
+}
+
+func (sub *Subscription) sameSettings(other *Subscription) bool {
+	return sub.P256dh == other.P256dh && sub.Auth == other.Auth && sub.ExpirationTime.Equal(other.ExpirationTime) && sub.Reminders == other.Reminders && sub.ChangeAlerts == other.ChangeAlerts
+}
+
+// must be called with mu held
+func (m *Manager) findById(id string) *Subscription {
+	for _, sel := range m.selections {
+		for _, sub := range sel.Subscriptions {
+			if sub.Id == id {
+				return sub
+			}
+		}
+	}
+
+	return nil
+}
+
+// push services are public https servers, anything else would let subscribers make the server send requests into its own network
+func (m *Manager) validateEndpoint(endpoint string) error {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || endpointUrl.Host == "" || len(endpoint) > 2048 {
+		return fmt.Errorf("%w: endpoint must be an absolute url", ErrInvalidSubscription)
+	}
+	if m.allowInsecureEndpoints {
+		return nil
+	}
+
+	hostname := endpointUrl.Hostname()
+	if endpointUrl.Scheme != "https" || net.ParseIP(hostname) != nil || hostname == "localhost" || !strings.Contains(hostname, ".") {
+		return fmt.Errorf("%w: endpoint must be an https url of a push service", ErrInvalidSubscription)
+	}
+
+	return nil
+}
+
+// a Dialer.Control hook, the check happens after resolving, so names pointing into the server's own network are refused too
+func refusePrivateAddresses(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if addr := addrPort.Addr().Unmap(); !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return fmt.Errorf("%w: %s", errPrivateAddress, addr)
+	}
+
+	return nil
+}
+
+func endpointHost(endpoint string) string {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+
+	return endpointUrl.Host
+}
@@ -0,0 +1,307 @@
+package webpush
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/szczursonn/uek-planzajec-v3/internal/config"
+	"github.com/szczursonn/uek-planzajec-v3/internal/snapshot"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uek"
+	"github.com/szczursonn/uek-planzajec-v3/internal/uekmock"
+)
+
+func newTestUekClient(t *testing.T) *uek.Client {
+	t.Helper()
+
+	mockDirectoryPath := t.TempDir()
+	if _, err := uekmock.Generate(mockDirectoryPath, uekmock.GeneratorOptions{
+		Seed:      1,
+		Groups:    2,
+		Lecturers: 4,
+		Rooms:     2,
+	}); err != nil {
+		t.Fatalf("failed to generate mock responses: %v", err)
+	}
+
+	mockRoundTripper, err := uekmock.NewRoundTripper(config.Mock{
+		Enabled:       true,
+		DirectoryPath: mockDirectoryPath,
+	})
+	if err != nil {
+		t.Fatalf("failed to create mock round tripper: %v", err)
+	}
+
+	return uek.NewClient(uek.ClientConfig{
+		HttpClient: &http.Client{
+			Transport: mockRoundTripper,
+		},
+		Logger: slog.New(slog.DiscardHandler),
+	})
+}
+
+// returns ids of the group schedules in the mock
+func testScheduleIds(t *testing.T, uekClient *uek.Client) []int {
+	t.Helper()
+
+	groupings, _, err := uekClient.GetGroupings(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, _, err := uekClient.GetHeaders(t.Context(), uek.ScheduleTypeGroup, groupings.Groups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduleIds := make([]int, 0, len(headers))
+	for _, header := range headers {
+		scheduleIds = append(scheduleIds, header.Id)
+	}
+	return scheduleIds
+}
+
+func newTestManager(t *testing.T, uekClient *uek.Client) *Manager {
+	t.Helper()
+
+	vapidKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	m := &Manager{
+		cfg: Config{
+			Path:    t.TempDir(),
+			Uek:     uekClient,
+			Subject: "mailto:admin@example.com",
+		},
+		logger:                 slog.New(slog.DiscardHandler),
+		vapidKey:               vapidKey,
+		selections:             map[string]*Selection{},
+		pushSemaphore:          make(chan struct{}, 1),
+		allowInsecureEndpoints: true,
+	}
+	m.snapshots = snapshot.New(snapshot.Config{
+		Path:   filepath.Join(m.cfg.Path, "snapshots"),
+		Uek:    uekClient,
+		Logger: m.logger,
+	})
+	m.ctx, m.cancelCtx = context.WithCancel(context.Background())
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+func testSubscribeRequest(endpoint string, scheduleIds ...int) SubscribeRequest {
+	uaPrivateKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, authSecretLength)
+	rand.Read(authSecret)
+
+	req := SubscribeRequest{
+		ScheduleType: uek.ScheduleTypeGroup,
+		ScheduleIds:  scheduleIds,
+	}
+	req.Subscription.Endpoint = endpoint
+	req.Subscription.Keys.P256dh = base64.RawURLEncoding.EncodeToString(uaPrivateKey.PublicKey().Bytes())
+	req.Subscription.Keys.Auth = base64.RawURLEncoding.EncodeToString(authSecret)
+	return req
+}
+
+func TestSubscribeRejectsUnknownSchedules(t *testing.T) {
+	m := newTestManager(t, newTestUekClient(t))
+
+	if _, err := m.Subscribe(t.Context(), testSubscribeRequest("https://push.example.com/a", 999999)); err == nil {
+		t.Fatal("expected an unknown schedule to be rejected")
+	}
+	if selections := m.List(); len(selections) != 0 {
+		t.Errorf("expected no subscriptions, got %+v", selections)
+	}
+}
+
+func TestResubscribingWithSameSettingsDoesNotWrite(t *testing.T) {
+	uekClient := newTestUekClient(t)
+	m := newTestManager(t, uekClient)
+	scheduleIds := testScheduleIds(t, uekClient)
+
+	req := testSubscribeRequest("https://push.example.com/a", scheduleIds[0])
+	id, err := m.Subscribe(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(m.subscriptionsFilePath()); err != nil {
+		t.Fatal(err)
+	}
+
+	if resubscribedId, err := m.Subscribe(t.Context(), req); err != nil || resubscribedId != id {
+		t.Fatalf("expected the same id %s, got %s, err: %v", id, resubscribedId, err)
+	}
+	if _, err := os.Stat(m.subscriptionsFilePath()); err == nil {
+		t.Error("expected no write for an unchanged subscription")
+	}
+
+	// moving to another selection keeps the id
+	if movedId, err := m.Subscribe(t.Context(), testSubscribeRequest("https://push.example.com/a", scheduleIds[1])); err != nil || movedId != id {
+		t.Fatalf("expected the same id %s, got %s, err: %v", id, movedId, err)
+	}
+	if _, err := os.Stat(m.subscriptionsFilePath()); err != nil {
+		t.Errorf("expected the moved subscription to be saved: %v", err)
+	}
+	if selections := m.List(); len(selections) != 1 || selections[0].ScheduleIds[0] != scheduleIds[1] {
+		t.Errorf("expected one selection of the other schedule, got %+v", selections)
+	}
+}
+
+func TestSubscriptionLimits(t *testing.T) {
+	uekClient := newTestUekClient(t)
+	m := newTestManager(t, uekClient)
+	scheduleIds := testScheduleIds(t, uekClient)
+
+	fullSelectionKey := selectionKey(uek.ScheduleTypeGroup, scheduleIds[:1], nil)
+	fullSelection := &Selection{
+		ScheduleType: uek.ScheduleTypeGroup,
+		ScheduleIds:  scheduleIds[:1],
+	}
+	for i := range maxSubscriptionsPerSelection {
+		fullSelection.Subscriptions = append(fullSelection.Subscriptions, &Subscription{
+			Id:       fmt.Sprint(i),
+			Endpoint: fmt.Sprintf("https://push.example.com/%d", i),
+		})
+	}
+	m.selections[fullSelectionKey] = fullSelection
+
+	if _, err := m.Subscribe(t.Context(), testSubscribeRequest("https://push.example.com/new", scheduleIds[0])); !errors.Is(err, ErrSubscriptionLimitReached) {
+		t.Errorf("expected the full selection to be rejected, got %v", err)
+	}
+	if _, err := m.Subscribe(t.Context(), testSubscribeRequest("https://push.example.com/new", scheduleIds[1])); err != nil {
+		t.Errorf("expected another selection to be accepted, got %v", err)
+	}
+
+	for i := range maxSubscriptions - m.subscriptionCount() {
+		fullSelection.Subscriptions = append(fullSelection.Subscriptions, &Subscription{
+			Id:       fmt.Sprint("filler", i),
+			Endpoint: fmt.Sprint("https://push.example.com/filler", i),
+		})
+	}
+	if _, err := m.Subscribe(t.Context(), testSubscribeRequest("https://push.example.com/another", scheduleIds[1])); !errors.Is(err, ErrSubscriptionLimitReached) {
+		t.Errorf("expected a new subscription over the total limit to be rejected, got %v", err)
+	}
+	// already counted
+	if _, err := m.Subscribe(t.Context(), testSubscribeRequest("https://push.example.com/0", scheduleIds[1])); err != nil {
+		t.Errorf("expected an existing subscription to move over the total limit, got %v", err)
+	}
+}
+
+func TestRefusePrivateAddresses(t *testing.T) {
+	for address, allowed := range map[string]bool{
+		"142.250.74.78:443":        true,
+		"[2a00:1450:400d::5f]:443": true,
+		"127.0.0.1:443":            false,
+		"10.0.0.1:443":             false,
+		"172.16.5.4:443":           false,
+		"192.168.1.1:443":          false,
+		"169.254.169.254:80":       false,
+		"0.0.0.0:443":              false,
+		"[::1]:443":                false,
+		"[fdaa::3]:443":            false,
+		"[fe80::1]:443":            false,
+		"[::ffff:10.0.0.1]:443":    false,
+	} {
+		err := refusePrivateAddresses("tcp", address, nil)
+		if allowed && err != nil {
+			t.Errorf("expected %s to be allowed, got %v", address, err)
+		}
+		if !allowed && !errors.Is(err, errPrivateAddress) {
+			t.Errorf("expected %s to be refused, got %v", address, err)
+		}
+	}
+}
+
+func TestDefaultClientRefusesPrivateAddresses(t *testing.T) {
+	m, err := New(Config{
+		Path:    t.TempDir(),
+		Uek:     newTestUekClient(t),
+		Subject: "mailto:admin@example.com",
+		Logger:  slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, err := m.cfg.HttpClient.Get("http://127.0.0.1:1/"); !errors.Is(err, errPrivateAddress) {
+		t.Errorf("expected the connection to be refused, got %v", err)
+	}
+}
+
+func TestChangesAreOnlyPushedForTheYearPeriod(t *testing.T) {
+	pushCount := atomic.Int32{}
+	pushService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushCount.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer pushService.Close()
+
+	uekClient := newTestUekClient(t)
+	m := newTestManager(t, uekClient)
+	m.cfg.HttpClient = pushService.Client()
+	scheduleId := testScheduleIds(t, uekClient)[0]
+	if _, err := m.Subscribe(t.Context(), testSubscribeRequest(pushService.URL+"/push/abc", scheduleId)); err != nil {
+		t.Fatal(err)
+	}
+
+	periods, _, err := uekClient.GetSchedulePeriods(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	yearPeriodId, ok := uek.CurrentYearPeriodId(periods, now)
+	if !ok {
+		t.Fatal("no current year period")
+	}
+	semesterPeriodIndex := slices.IndexFunc(periods, func(period uek.SchedulePeriod) bool {
+		return period.Id != yearPeriodId && !period.Start.After(now) && !period.End.Before(now)
+	})
+	if semesterPeriodIndex == -1 {
+		t.Fatal("no current semester period")
+	}
+
+	schedule, _, err := uekClient.GetSchedule(t.Context(), uek.ScheduleTypeGroup, scheduleId, yearPeriodId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := now.Add(24 * time.Hour)
+	changedSchedule := &uek.Schedule{
+		Header: schedule.Header,
+		Items: append(slices.Clone(schedule.Items), &uek.ScheduleItem{
+			Start:   start,
+			End:     start.Add(90 * time.Minute),
+			Subject: "Zajęcia dodatkowe",
+			Type:    "wykład",
+		}),
+	}
+
+	semesterPeriodId := periods[semesterPeriodIndex].Id
+	for _, periodId := range []int{yearPeriodId, semesterPeriodId} {
+		m.handleScheduleFetch(uek.ScheduleFetch{ScheduleType: uek.ScheduleTypeGroup, ScheduleId: scheduleId, PeriodId: periodId, Schedule: schedule})
+	}
+	m.handleScheduleFetch(uek.ScheduleFetch{ScheduleType: uek.ScheduleTypeGroup, ScheduleId: scheduleId, PeriodId: semesterPeriodId, Schedule: changedSchedule})
+	m.pushWg.Wait()
+	if count := pushCount.Load(); count != 0 {
+		t.Fatalf("expected no push for the semester period, got %d", count)
+	}
+
+	m.handleScheduleFetch(uek.ScheduleFetch{ScheduleType: uek.ScheduleTypeGroup, ScheduleId: scheduleId, PeriodId: yearPeriodId, Schedule: changedSchedule})
+	m.pushWg.Wait()
+	if count := pushCount.Load(); count != 1 {
+		t.Fatalf("expected one push for the year period, got %d", count)
+	}
+}